@@ -1,7 +1,9 @@
 package main
 
 import (
+	"GoodFood-BE/internal/audit"
 	"GoodFood-BE/internal/database"
+	"GoodFood-BE/internal/payment"
 	redisdatabase "GoodFood-BE/internal/redis-database"
 	"GoodFood-BE/internal/server"
 	"context"
@@ -77,6 +79,13 @@ func main() {
 	redisdatabase.InitRedis()
 	defer redisdatabase.Client.Close()
 
+	//Delete audit log entries past their retention period
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+	audit.StartRetention(backgroundCtx)
+	//Mark abandoned payment attempts expired
+	payment.StartExpiry(backgroundCtx)
+
 	//Initialize Fiber server
 	server := server.New()
 
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"GoodFood-BE/internal/auth"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+)
+
+// jwtkeys manages the signing keys in JWT_KEYS_DIR.
+//
+//	jwtkeys rotate [-alg EdDSA|RS256] [-keep 3]   adds a new active key and drops the oldest ones beyond -keep
+//	jwtkeys list                                  prints the keys and which one is active
+//
+// Running servers pick up the new manifest within a minute, no restart is needed.
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	dir := os.Getenv("JWT_KEYS_DIR")
+	if dir == "" {
+		log.Fatal("JWT_KEYS_DIR is not set")
+	}
+
+	switch os.Args[1] {
+	case "rotate":
+		fs := flag.NewFlagSet("rotate", flag.ExitOnError)
+		alg := fs.String("alg", auth.AlgEdDSA, "signing algorithm: EdDSA or RS256")
+		keep := fs.Int("keep", 3, "number of keys kept for verification, including the new one")
+		_ = fs.Parse(os.Args[2:])
+
+		kid, err := auth.RotateSigningKey(dir, *alg, *keep)
+		if err != nil {
+			log.Fatalf("Could not rotate key: %v", err)
+		}
+		fmt.Printf("New active key %s (%s)\n", kid, *alg)
+	case "list":
+		m, err := auth.ReadKeySetManifest(dir)
+		if err != nil {
+			log.Fatalf("Could not read keys: %v", err)
+		}
+		for _, k := range m.Keys {
+			marker := " "
+			if k.Kid == m.Active {
+				marker = "*"
+			}
+			fmt.Printf("%s %s %s %s\n", marker, k.Kid, k.Alg, k.CreatedAt.Format("2006-01-02 15:04:05"))
+		}
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: jwtkeys rotate [-alg EdDSA|RS256] [-keep N] | jwtkeys list")
+	os.Exit(2)
+}
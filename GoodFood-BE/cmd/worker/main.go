@@ -1,6 +1,7 @@
 package main
 
 import (
+	"GoodFood-BE/internal/database"
 	"GoodFood-BE/internal/jobs"
 	"fmt"
 	"log"
@@ -12,6 +13,10 @@ import (
 //function main initializes and starts the Asynq worker server.
 //Connects to Redis, configures concurrency, and register task handlers.
 func main(){
+	//Data export jobs read the database
+	dbService := database.New()
+	defer dbService.Close()
+
 	//Configuring Redis connection for Asynq
 	addrStr := fmt.Sprintf("%s:%s",os.Getenv("REDIS_HOST"),os.Getenv("REDIS_PORT"))
 	fmt.Println(addrStr);
@@ -26,6 +31,13 @@ func main(){
 	mux := asynq.NewServeMux()
 	mux.HandleFunc(jobs.TypeResetPasswordEmail, jobs.HandleResetPasswordEmailTask)
 	mux.HandleFunc(jobs.TypeSendContactMessage,jobs.HandleContactCustomerSent)
+	mux.HandleFunc(jobs.TypeVerifyEmail,jobs.HandleVerifyEmailTask)
+	mux.HandleFunc(jobs.TypePasswordChangedEmail,jobs.HandlePasswordChangedEmailTask)
+	mux.HandleFunc(jobs.TypeOTPEmail,jobs.HandleOTPEmailTask)
+	mux.HandleFunc(jobs.TypeSendSMS,jobs.HandleSendSMSTask)
+	mux.HandleFunc(jobs.TypeDataExport,jobs.HandleDataExportTask)
+	mux.HandleFunc(jobs.TypeDataExportExpire,jobs.HandleDataExportExpireTask)
+	mux.HandleFunc(jobs.TypeDeleteImages,jobs.HandleDeleteImagesTask)
 
 	//Start the server and log fatal error if failed to run
 	if err := srv.Run(mux); err != nil{
@@ -0,0 +1,152 @@
+package audit
+
+import (
+	"GoodFood-BE/internal/auth"
+	"GoodFood-BE/models"
+	"context"
+	"encoding/json"
+	"log"
+
+	"github.com/aarondl/null/v8"
+	"github.com/aarondl/sqlboiler/v4/boil"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Actions recorded in audit_log.action.
+const (
+	ActionAccountLockout     = "account.lockout"
+	ActionAccountUnlock      = "account.unlock"
+	ActionMFAEnabled         = "account.mfa_enabled"
+	ActionMFADisabled        = "account.mfa_disabled"
+	ActionIdentityLinked     = "account.identity_linked"
+	ActionIdentityUnlinked   = "account.identity_unlinked"
+	ActionPasswordSet        = "account.password_set"
+	ActionAccountDeleted     = "account.deleted"
+	ActionDataExport         = "account.data_export"
+	ActionAccountUpdate      = "account.update"
+	ActionInvoiceUpdate      = "invoice.update"
+	ActionInvoiceRefund      = "invoice.refund"
+	ActionRefundResolve      = "invoice.refund_resolve"
+	ActionInvoiceShip        = "invoice.ship"
+	ActionInvoiceUnship      = "invoice.unship"
+	ActionProductUpdate      = "product.update"
+	ActionProductTypeUpdate  = "product_type.update"
+	ActionReviewReplyCreate  = "review_reply.create"
+	ActionReviewReplyUpdate  = "review_reply.update"
+	ActionAPIKeyCreate       = "api_key.create"
+	ActionAPIKeyRevoke       = "api_key.revoke"
+	ActionShippingRateUpdate = "shipping_rate.update"
+	ActionShippingZoneCreate = "shipping_zone.create"
+	ActionShippingZoneUpdate = "shipping_zone.update"
+	ActionShippingZoneDelete = "shipping_zone.delete"
+)
+
+// Entity types recorded in audit_log."entityType".
+const (
+	EntityAccount      = "account"
+	EntityInvoice      = "invoice"
+	EntityProduct      = "product"
+	EntityProductType  = "product_type"
+	EntityReviewReply  = "review_reply"
+	EntityAPIKey       = "api_key"
+	EntityShippingRate = "shipping_rate"
+	EntityShippingZone = "shipping_zone"
+)
+
+// Entry is one row of audit_log. ActorID/ActorUsername stay empty for events raised by the system.
+// Before and After are the entity around a mutation, any value that marshals to a JSON object;
+// Record stores them with the fields that changed. Leave Before nil for creations.
+type Entry struct {
+	ActorID       null.Int
+	ActorUsername null.String
+	Action        string
+	EntityType    string
+	EntityID      string
+	Metadata      interface{}
+	Before        interface{}
+	After         interface{}
+	IP            string
+	UserAgent     string
+}
+
+// FromRequest starts an entry with the actor, IP and user agent of the current request.
+// The actor is the account resolved by the auth middlewares, if any.
+func FromRequest(c *fiber.Ctx, action, entityType, entityID string) Entry {
+	entry := Entry{
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+		IP:         c.IP(),
+		UserAgent:  c.Get("User-Agent"),
+	}
+	if account, ok := c.Locals("account").(*models.Account); ok && account != nil {
+		entry.ActorID = null.IntFrom(account.AccountID)
+		entry.ActorUsername = null.StringFrom(account.Username)
+	} else if username, ok := c.Locals("username").(string); ok && username != "" {
+		entry.ActorUsername = null.StringFrom(username)
+	} else if apiKey := auth.GetAPIKey(c); apiKey != nil {
+		entry.ActorUsername = null.StringFrom("api-key:" + apiKey.DisplayPrefix())
+	}
+	return entry
+}
+
+// Record inserts entry into audit_log.
+func Record(ctx context.Context, entry Entry) error {
+	var metadata null.JSON
+	if entry.Metadata != nil {
+		b, err := jsonColumn(entry.Metadata)
+		if err != nil {
+			return err
+		}
+		metadata = b
+	}
+	//Compare the values before redacting them, so changes to sensitive fields are still listed
+	before, err := rawSnapshot(entry.Before)
+	if err != nil {
+		return err
+	}
+	after, err := rawSnapshot(entry.After)
+	if err != nil {
+		return err
+	}
+	var beforeJSON, afterJSON, changesJSON null.JSON
+	if before != nil || after != nil {
+		if changesJSON, err = jsonColumn(Diff(before, after)); err != nil {
+			return err
+		}
+	}
+	redact(before)
+	redact(after)
+	if before != nil {
+		if beforeJSON, err = jsonColumn(before); err != nil {
+			return err
+		}
+	}
+	if after != nil {
+		if afterJSON, err = jsonColumn(after); err != nil {
+			return err
+		}
+	}
+
+	_, err = boil.GetContextDB().ExecContext(ctx, `
+		INSERT INTO audit_log ("actorID", "actorUsername", action, "entityType", "entityID", metadata, before, after, changes, ip, "userAgent")
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7, $8, $9, NULLIF($10, ''), NULLIF($11, ''))`,
+		entry.ActorID, entry.ActorUsername, entry.Action, entry.EntityType, entry.EntityID, metadata,
+		beforeJSON, afterJSON, changesJSON, entry.IP, entry.UserAgent)
+	return err
+}
+
+func jsonColumn(v interface{}) (null.JSON, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return null.JSON{}, err
+	}
+	return null.JSONFrom(b), nil
+}
+
+// RecordBestEffort records entry without failing the request; errors are only logged.
+func RecordBestEffort(ctx context.Context, entry Entry) {
+	if err := Record(ctx, entry); err != nil {
+		log.Printf("audit: failed to record %s on %s %s: %v", entry.Action, entry.EntityType, entry.EntityID, err)
+	}
+}
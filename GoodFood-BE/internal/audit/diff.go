@@ -0,0 +1,99 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// RedactedValue replaces the value of sensitive fields in snapshots.
+const RedactedValue = "[redacted]"
+
+// redactedFields are never written to the log as is: secrets, and the personal data of customers that
+// account deletion has to erase. A change is still recorded, without the values.
+var redactedFields = map[string]bool{
+	"password":        true,
+	"secret":          true,
+	"token":           true,
+	"email":           true,
+	"phonenumber":     true,
+	"fullname":        true,
+	"address":         true,
+	"specificaddress": true,
+	"receivename":     true,
+	"receivephone":    true,
+	"receiveaddress":  true,
+}
+
+// Change is the value of one field before and after a mutation. Nil means the field was absent.
+type Change struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// Snapshot converts v to the JSON object stored in audit_log, redacting sensitive fields.
+// It returns nil for a nil v.
+func Snapshot(v interface{}) (map[string]interface{}, error) {
+	snapshot, err := rawSnapshot(v)
+	redact(snapshot)
+	return snapshot, err
+}
+
+// rawSnapshot converts v to a JSON object with the values of every field, for Diff to compare.
+func rawSnapshot(v interface{}) (map[string]interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.IsNil() {
+		return nil, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := map[string]interface{}{}
+	if err := json.Unmarshal(b, &snapshot); err != nil {
+		return nil, fmt.Errorf("audit: %T does not marshal to an object: %w", v, err)
+	}
+	return snapshot, nil
+}
+
+// redact replaces the value of the sensitive fields of snapshot in place.
+func redact(snapshot map[string]interface{}) {
+	for field := range snapshot {
+		if redactedFields[strings.ToLower(field)] {
+			snapshot[field] = RedactedValue
+		}
+	}
+}
+
+// Diff returns the top-level fields whose value differs between two snapshots. Sensitive fields that changed
+// are listed with their values redacted; snapshots that are redacted already make them compare equal.
+func Diff(before, after map[string]interface{}) map[string]Change {
+	changes := map[string]Change{}
+	for field, from := range before {
+		to, ok := after[field]
+		if !ok || !reflect.DeepEqual(from, to) {
+			changes[field] = Change{From: from, To: to}
+		}
+	}
+	for field, to := range after {
+		if _, ok := before[field]; !ok {
+			changes[field] = Change{To: to}
+		}
+	}
+	for field, c := range changes {
+		if !redactedFields[strings.ToLower(field)] {
+			continue
+		}
+		if c.From != nil {
+			c.From = RedactedValue
+		}
+		if c.To != nil {
+			c.To = RedactedValue
+		}
+		changes[field] = c
+	}
+	return changes
+}
@@ -0,0 +1,65 @@
+package audit
+
+import (
+	"GoodFood-BE/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshotRedactsSensitiveFields(t *testing.T) {
+	snapshot, err := Snapshot(&models.Account{AccountID: 7, Username: "alice", Password: "$2a$10$hash"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "alice", snapshot["username"])
+	assert.Equal(t, RedactedValue, snapshot["password"])
+
+	var nilAccount *models.Account
+	snapshot, err = Snapshot(nilAccount)
+	assert.NoError(t, err)
+	assert.Nil(t, snapshot)
+
+	_, err = Snapshot([]int{1, 2})
+	assert.Error(t, err)
+}
+
+func TestDiff(t *testing.T) {
+	before, _ := Snapshot(models.ProductType{ProductTypeID: 3, TypeName: "Drinks", Status: true})
+	after, _ := Snapshot(models.ProductType{ProductTypeID: 3, TypeName: "Beverages", Status: true})
+	assert.Equal(t, map[string]Change{"typeName": {From: "Drinks", To: "Beverages"}}, Diff(before, after))
+
+	created := Diff(nil, map[string]interface{}{"reply": "Thanks!"})
+	assert.Equal(t, map[string]Change{"reply": {To: "Thanks!"}}, created)
+
+	removed := Diff(map[string]interface{}{"note": "leave at door"}, map[string]interface{}{})
+	assert.Equal(t, map[string]Change{"note": {From: "leave at door"}}, removed)
+}
+
+func TestPersonalDataIsRedacted(t *testing.T) {
+	before, err := rawSnapshot(&models.Invoice{InvoiceID: 4, ReceiveName: "Alice", ReceivePhone: "0900000000", ReceiveAddress: "1 Main St"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	after, _ := rawSnapshot(&models.Invoice{InvoiceID: 4, ReceiveName: "Bob", ReceivePhone: "0900000000", ReceiveAddress: "1 Main St"})
+
+	//The change is listed without the names
+	assert.Equal(t, map[string]Change{"receiveName": {From: RedactedValue, To: RedactedValue}}, Diff(before, after))
+
+	snapshot, _ := Snapshot(&models.Account{AccountID: 7, Username: "alice", Email: "alice@example.com", FullName: "Alice"})
+	assert.Equal(t, RedactedValue, snapshot["email"])
+	assert.Equal(t, RedactedValue, snapshot["fullName"])
+	assert.Equal(t, RedactedValue, snapshot["phoneNumber"])
+}
+
+func TestFilterWhere(t *testing.T) {
+	where, args := Filter{}.where()
+	assert.Empty(t, where)
+	assert.Empty(t, args)
+
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	where, args = Filter{ActorID: 4, Action: "account.", EntityType: "account", From: from}.where()
+	assert.Equal(t, `WHERE "actorID" = $1 AND action LIKE $2 AND "entityType" = $3 AND "createdAt" >= $4`, where)
+	assert.Equal(t, []interface{}{4, "account.%", "account", from}, args)
+}
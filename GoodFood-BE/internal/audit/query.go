@@ -0,0 +1,81 @@
+package audit
+
+import (
+	"GoodFood-BE/internal/dto"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aarondl/sqlboiler/v4/boil"
+	"github.com/aarondl/sqlboiler/v4/queries"
+)
+
+// Filter selects audit_log rows. Zero values are ignored; To is exclusive.
+type Filter struct {
+	ActorID    int
+	Action     string
+	EntityType string
+	EntityID   string
+	From       time.Time
+	To         time.Time
+}
+
+// where builds the WHERE clause of f with its positional arguments.
+func (f Filter) where() (string, []interface{}) {
+	var conds []string
+	var args []interface{}
+	add := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+	if f.ActorID != 0 {
+		add(`"actorID" = $%d`, f.ActorID)
+	}
+	if f.Action != "" {
+		//A trailing dot selects a whole family of actions, e.g. "account."
+		if strings.HasSuffix(f.Action, ".") {
+			add(`action LIKE $%d`, f.Action+"%")
+		} else {
+			add(`action = $%d`, f.Action)
+		}
+	}
+	if f.EntityType != "" {
+		add(`"entityType" = $%d`, f.EntityType)
+	}
+	if f.EntityID != "" {
+		add(`"entityID" = $%d`, f.EntityID)
+	}
+	if !f.From.IsZero() {
+		add(`"createdAt" >= $%d`, f.From)
+	}
+	if !f.To.IsZero() {
+		add(`"createdAt" < $%d`, f.To)
+	}
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return "WHERE " + strings.Join(conds, " AND "), args
+}
+
+// Count returns the number of rows matching f.
+func Count(ctx context.Context, f Filter) (int, error) {
+	where, args := f.where()
+	var total int
+	err := boil.GetContextDB().QueryRowContext(ctx, `SELECT COUNT(*) FROM audit_log `+where, args...).Scan(&total)
+	return total, err
+}
+
+// List returns the rows matching f, newest first.
+func List(ctx context.Context, f Filter, limit, offset int) ([]dto.AuditLogEntry, error) {
+	where, args := f.where()
+	args = append(args, limit, offset)
+	entries := []dto.AuditLogEntry{}
+	err := queries.Raw(fmt.Sprintf(`
+		SELECT "auditLogID", "actorID", "actorUsername", action, "entityType", "entityID",
+		       metadata, before, after, changes, ip, "userAgent", "createdAt"
+		FROM audit_log %s
+		ORDER BY "createdAt" DESC, "auditLogID" DESC
+		LIMIT $%d OFFSET $%d`, where, len(args)-1, len(args)), args...).Bind(ctx, boil.GetContextDB(), &entries)
+	return entries, err
+}
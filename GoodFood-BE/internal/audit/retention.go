@@ -0,0 +1,78 @@
+package audit
+
+import (
+	"context"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/aarondl/sqlboiler/v4/boil"
+)
+
+// DefaultRetentionDays is how long entries are kept when AUDIT_RETENTION_DAYS is not set.
+const DefaultRetentionDays = 365
+
+// purgeInterval is how often StartRetention deletes expired entries.
+const purgeInterval = 24 * time.Hour
+
+// purgeBatchSize bounds each DELETE so a large backlog does not lock the table for long.
+const purgeBatchSize = 5000
+
+// Retention returns how long entries are kept, from AUDIT_RETENTION_DAYS. 0 means forever.
+func Retention() time.Duration {
+	days := DefaultRetentionDays
+	if v := os.Getenv("AUDIT_RETENTION_DAYS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			days = n
+		}
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// Purge deletes the entries created before cutoff and returns how many were removed.
+func Purge(ctx context.Context, cutoff time.Time) (int64, error) {
+	var total int64
+	for {
+		res, err := boil.GetContextDB().ExecContext(ctx, `
+			DELETE FROM audit_log WHERE "auditLogID" IN (
+				SELECT "auditLogID" FROM audit_log WHERE "createdAt" < $1 LIMIT $2
+			)`, cutoff, purgeBatchSize)
+		if err != nil {
+			return total, err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return total, err
+		}
+		total += n
+		if n < purgeBatchSize {
+			return total, nil
+		}
+	}
+}
+
+// StartRetention purges expired entries now and then once a day until ctx is done.
+// It does nothing when retention is disabled.
+func StartRetention(ctx context.Context) {
+	retention := Retention()
+	if retention == 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(purgeInterval)
+		defer ticker.Stop()
+		for {
+			if n, err := Purge(ctx, time.Now().Add(-retention)); err != nil {
+				log.Printf("audit: retention purge failed: %v", err)
+			} else if n > 0 {
+				log.Printf("audit: purged %d entries older than %s", n, retention)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
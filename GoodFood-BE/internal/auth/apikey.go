@@ -0,0 +1,368 @@
+package auth
+
+import (
+	"GoodFood-BE/internal/dto"
+	"GoodFood-BE/internal/service"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/aarondl/null/v8"
+	"github.com/aarondl/sqlboiler/v4/boil"
+	"github.com/gofiber/fiber/v2"
+	"github.com/lib/pq"
+)
+
+// APIKeyHeader carries the key of machine clients.
+const APIKeyHeader = "X-API-Key"
+
+// apiKeyTag starts every key so leaked keys are easy to recognize, e.g. in secret scanners.
+const apiKeyTag = "gf"
+
+var (
+	ErrInvalidAPIKey       = errors.New("invalid, expired or revoked API key")
+	ErrAPIKeyIPNotAllowed  = errors.New("API key is not allowed from this address")
+	ErrAPIKeyNotFound      = errors.New("API key not found")
+	ErrInvalidAPIKeyScope  = errors.New("invalid API key scope")
+	ErrInvalidAPIKeyIPRule = errors.New("invalid IP address or CIDR range")
+)
+
+// APIKeyScopes are the permissions an API key can be given. Managing users and reading the audit log stay human-only.
+// A key passes the PermAdminAccess check of the admin routes, every admin route also checks one of these.
+var APIKeyScopes = []Permission{
+	PermDashboardRead,
+	PermOrdersRead, PermOrdersWrite,
+	PermProductsRead, PermProductsWrite,
+	PermReviewsRead, PermReviewsWrite,
+	PermStatisticsRead,
+}
+
+// APIKey is one row of api_key, without its hash.
+type APIKey struct {
+	APIKeyID   int            `json:"apiKeyID"`
+	Name       string         `json:"name"`
+	Prefix     string         `json:"prefix"`
+	Scopes     pq.StringArray `json:"scopes"`
+	AllowedIPs pq.StringArray `json:"allowedIPs"`
+	ExpiresAt  null.Time      `json:"expiresAt"`
+	CreatedBy  null.Int       `json:"createdBy"`
+	CreatedAt  time.Time      `json:"createdAt"`
+	RevokedAt  null.Time      `json:"revokedAt"`
+	LastUsedAt null.Time      `json:"lastUsedAt"`
+	LastUsedIP null.String    `json:"lastUsedIP"`
+	UsageCount int64          `json:"usageCount"`
+}
+
+// NewAPIKey holds the settings of a key to create. A zero ExpiresAt never expires.
+type NewAPIKey struct {
+	Name       string
+	Scopes     []string
+	AllowedIPs []string
+	ExpiresAt  null.Time
+	CreatedBy  int
+}
+
+const apiKeyColumns = `"apiKeyID", name, prefix, scopes, "allowedIPs", "expiresAt", "createdBy", "createdAt",
+	"revokedAt", "lastUsedAt", "lastUsedIP", "usageCount"`
+
+func (k *APIKey) scanFields() []interface{} {
+	return []interface{}{&k.APIKeyID, &k.Name, &k.Prefix, &k.Scopes, &k.AllowedIPs, &k.ExpiresAt, &k.CreatedBy,
+		&k.CreatedAt, &k.RevokedAt, &k.LastUsedAt, &k.LastUsedIP, &k.UsageCount}
+}
+
+// DisplayPrefix returns the public start of the key, enough for a human to tell keys apart.
+func (k *APIKey) DisplayPrefix() string {
+	return apiKeyTag + "_" + k.Prefix
+}
+
+// HasScopes reports whether the key was given every permission in perms.
+// PermAdminAccess is implied: it only gates the admin routes, which also check a scope of their own.
+func (k *APIKey) HasScopes(perms ...Permission) bool {
+	for _, perm := range perms {
+		if perm == PermAdminAccess {
+			continue
+		}
+		found := false
+		for _, scope := range k.Scopes {
+			if Permission(scope) == perm {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// IsValidAPIKeyScope reports whether scope can be given to an API key.
+func IsValidAPIKeyScope(scope string) bool {
+	for _, s := range APIKeyScopes {
+		if Permission(scope) == s {
+			return true
+		}
+	}
+	return false
+}
+
+// generateAPIKey returns a new key "gf_{prefix}_{secret}" with its prefix and hash.
+func generateAPIKey() (key, prefix, hash string, err error) {
+	b := make([]byte, 8+32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", "", err
+	}
+	prefix = hex.EncodeToString(b[:8])
+	key = fmt.Sprintf("%s_%s_%s", apiKeyTag, prefix, hex.EncodeToString(b[8:]))
+	return key, prefix, hashAPIKey(key), nil
+}
+
+// hashAPIKey hashes a key for storage. Keys are random 256-bit secrets, so a fast hash is enough.
+func hashAPIKey(key string) string {
+	h := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(h[:])
+}
+
+// apiKeyPrefix returns the prefix of a key, false if it is not formatted like one.
+func apiKeyPrefix(key string) (string, bool) {
+	parts := strings.Split(key, "_")
+	if len(parts) != 3 || parts[0] != apiKeyTag || len(parts[1]) != 16 || len(parts[2]) != 64 {
+		return "", false
+	}
+	return parts[1], true
+}
+
+// normalizeAllowedIPs validates an allowlist made of IP addresses and CIDR ranges.
+func normalizeAllowedIPs(rules []string) ([]string, error) {
+	normalized := []string{}
+	for _, rule := range rules {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+		if _, ipNet, err := net.ParseCIDR(rule); err == nil {
+			normalized = append(normalized, ipNet.String())
+			continue
+		}
+		ip := net.ParseIP(rule)
+		if ip == nil {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidAPIKeyIPRule, rule)
+		}
+		normalized = append(normalized, ip.String())
+	}
+	return normalized, nil
+}
+
+// ipAllowed reports whether ip matches the allowlist. An empty allowlist accepts any address.
+func ipAllowed(rules []string, ip string) bool {
+	if len(rules) == 0 {
+		return true
+	}
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+	for _, rule := range rules {
+		if _, ipNet, err := net.ParseCIDR(rule); err == nil {
+			if ipNet.Contains(addr) {
+				return true
+			}
+		} else if allowed := net.ParseIP(rule); allowed != nil && allowed.Equal(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateAPIKey stores a new key and returns it with the plaintext key, which is never shown again.
+func CreateAPIKey(ctx context.Context, params NewAPIKey) (*APIKey, string, error) {
+	scopes := []string{}
+	for _, scope := range params.Scopes {
+		if !IsValidAPIKeyScope(scope) {
+			return nil, "", fmt.Errorf("%w: %q", ErrInvalidAPIKeyScope, scope)
+		}
+		scopes = append(scopes, scope)
+	}
+	if len(scopes) == 0 {
+		return nil, "", fmt.Errorf("%w: at least one scope is required", ErrInvalidAPIKeyScope)
+	}
+	allowedIPs, err := normalizeAllowedIPs(params.AllowedIPs)
+	if err != nil {
+		return nil, "", err
+	}
+	key, prefix, hash, err := generateAPIKey()
+	if err != nil {
+		return nil, "", err
+	}
+
+	apiKey := &APIKey{}
+	err = boil.GetContextDB().QueryRowContext(ctx, `
+		INSERT INTO api_key (name, prefix, "keyHash", scopes, "allowedIPs", "expiresAt", "createdBy")
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING `+apiKeyColumns,
+		params.Name, prefix, hash, pq.StringArray(scopes), pq.StringArray(allowedIPs), params.ExpiresAt, params.CreatedBy,
+	).Scan(apiKey.scanFields()...)
+	if err != nil {
+		return nil, "", err
+	}
+	return apiKey, key, nil
+}
+
+// ListAPIKeys returns every key, newest first.
+func ListAPIKeys(ctx context.Context) ([]*APIKey, error) {
+	rows, err := boil.GetContextDB().QueryContext(ctx, `SELECT `+apiKeyColumns+` FROM api_key ORDER BY "apiKeyID" DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	keys := []*APIKey{}
+	for rows.Next() {
+		k := &APIKey{}
+		if err := rows.Scan(k.scanFields()...); err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+// FindAPIKey returns one key, ErrAPIKeyNotFound if it does not exist.
+func FindAPIKey(ctx context.Context, apiKeyID int) (*APIKey, error) {
+	k := &APIKey{}
+	err := boil.GetContextDB().QueryRowContext(ctx, `SELECT `+apiKeyColumns+` FROM api_key WHERE "apiKeyID" = $1`, apiKeyID).
+		Scan(k.scanFields()...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAPIKeyNotFound
+	}
+	return k, err
+}
+
+// RevokeAPIKey disables a key for good. Revoking it again is a no-op.
+func RevokeAPIKey(ctx context.Context, apiKeyID int) error {
+	res, err := boil.GetContextDB().ExecContext(ctx, `
+		UPDATE api_key SET "revokedAt" = COALESCE("revokedAt", now()) WHERE "apiKeyID" = $1`, apiKeyID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return err
+}
+
+// APIKeyUsage returns the number of requests made with a key on each of the last days, oldest first.
+// Days without requests are left out.
+func APIKeyUsage(ctx context.Context, apiKeyID, days int) ([]dto.APIKeyUsageDay, error) {
+	rows, err := boil.GetContextDB().QueryContext(ctx, `
+		SELECT day, requests FROM api_key_usage WHERE "apiKeyID" = $1 AND day > CURRENT_DATE - $2::integer ORDER BY day`,
+		apiKeyID, days)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	usage := []dto.APIKeyUsageDay{}
+	for rows.Next() {
+		var day time.Time
+		var requests int64
+		if err := rows.Scan(&day, &requests); err != nil {
+			return nil, err
+		}
+		usage = append(usage, dto.APIKeyUsageDay{Day: day.Format("2006-01-02"), Requests: requests})
+	}
+	return usage, rows.Err()
+}
+
+// AuthenticateAPIKey returns the active key matching key when it may be used from ip.
+func AuthenticateAPIKey(ctx context.Context, key, ip string) (*APIKey, error) {
+	prefix, ok := apiKeyPrefix(key)
+	if !ok {
+		return nil, ErrInvalidAPIKey
+	}
+	k := &APIKey{}
+	var hash string
+	err := boil.GetContextDB().QueryRowContext(ctx, `
+		SELECT `+apiKeyColumns+`, "keyHash" FROM api_key WHERE prefix = $1`, prefix).
+		Scan(append(k.scanFields(), &hash)...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(hashAPIKey(key))) != 1 {
+		return nil, ErrInvalidAPIKey
+	}
+	if k.RevokedAt.Valid || (k.ExpiresAt.Valid && time.Now().After(k.ExpiresAt.Time)) {
+		return nil, ErrInvalidAPIKey
+	}
+	if !ipAllowed(k.AllowedIPs, ip) {
+		return nil, ErrAPIKeyIPNotAllowed
+	}
+	return k, nil
+}
+
+// recordAPIKeyUsage counts one request made with a key. It runs after the response, so failures are only logged.
+func recordAPIKeyUsage(apiKeyID int, ip string) {
+	ctx := context.Background()
+	db := boil.GetContextDB()
+	if _, err := db.ExecContext(ctx, `
+		UPDATE api_key SET "lastUsedAt" = now(), "lastUsedIP" = $2, "usageCount" = "usageCount" + 1
+		WHERE "apiKeyID" = $1`, apiKeyID, ip); err != nil {
+		log.Printf("api key %d: failed to record usage: %v", apiKeyID, err)
+		return
+	}
+	if _, err := db.ExecContext(ctx, `
+		INSERT INTO api_key_usage ("apiKeyID", day, requests) VALUES ($1, CURRENT_DATE, 1)
+		ON CONFLICT ("apiKeyID", day) DO UPDATE SET requests = api_key_usage.requests + 1`, apiKeyID); err != nil {
+		log.Printf("api key %d: failed to record usage: %v", apiKeyID, err)
+	}
+}
+
+// APIKeyMiddleware authenticates requests sending an X-API-Key header and hands every other request to AuthMiddleware.
+// Use it in place of AuthMiddleware on routes machine clients may call; RequirePermission then checks the key scopes.
+// Keys have no account, so routes that need one (AccountMiddleware, GetAccount) keep rejecting them.
+func APIKeyMiddleware(c *fiber.Ctx) error {
+	//Nested groups run the middleware again, the token or key is only checked (and counted) once
+	if GetTokenClaims(c) != nil || GetAPIKey(c) != nil {
+		return c.Next()
+	}
+	key := c.Get(APIKeyHeader)
+	if key == "" {
+		return AuthMiddleware(c)
+	}
+
+	//fiber strings point into the request buffer, the usage goroutine outlives it
+	ip := strings.Clone(c.IP())
+	apiKey, err := AuthenticateAPIKey(c.Context(), key, ip)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidAPIKey):
+			return service.SendError(c, 401, "Invalid or expired API key")
+		case errors.Is(err, ErrAPIKeyIPNotAllowed):
+			return service.SendError(c, 403, "This API key is not allowed from your IP address")
+		default:
+			return service.SendError(c, 500, err.Error())
+		}
+	}
+	c.Locals("apiKey", apiKey)
+	go recordAPIKeyUsage(apiKey.APIKeyID, ip)
+	return c.Next()
+}
+
+// GetAPIKey returns the API key that authenticated the current request, nil for requests made by a user.
+func GetAPIKey(c *fiber.Ctx) *APIKey {
+	apiKey, ok := c.Locals("apiKey").(*APIKey)
+	if !ok {
+		return nil
+	}
+	return apiKey
+}
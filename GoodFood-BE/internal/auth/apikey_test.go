@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateAPIKey(t *testing.T) {
+	key, prefix, hash, err := generateAPIKey()
+	if !assert.NoError(t, err) {
+		return
+	}
+	parsed, ok := apiKeyPrefix(key)
+	assert.True(t, ok)
+	assert.Equal(t, prefix, parsed)
+	assert.Equal(t, hashAPIKey(key), hash)
+	assert.Len(t, hash, 64)
+
+	other, _, _, err := generateAPIKey()
+	assert.NoError(t, err)
+	assert.NotEqual(t, key, other)
+
+	for _, bad := range []string{"", "gf_abc", "xx_" + prefix + "_" + hash, "gf_" + prefix + "_short"} {
+		_, ok := apiKeyPrefix(bad)
+		assert.False(t, ok, bad)
+	}
+}
+
+func TestIPAllowlist(t *testing.T) {
+	rules, err := normalizeAllowedIPs([]string{" 10.0.0.0/8 ", "203.0.113.7", "", "2001:db8::/32"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []string{"10.0.0.0/8", "203.0.113.7", "2001:db8::/32"}, rules)
+
+	assert.True(t, ipAllowed(rules, "10.1.2.3"))
+	assert.True(t, ipAllowed(rules, "203.0.113.7"))
+	assert.True(t, ipAllowed(rules, "2001:db8::1"))
+	assert.False(t, ipAllowed(rules, "203.0.113.8"))
+	assert.False(t, ipAllowed(rules, "not-an-ip"))
+	assert.True(t, ipAllowed(nil, "198.51.100.1"), "an empty allowlist accepts any address")
+
+	_, err = normalizeAllowedIPs([]string{"10.0.0.300"})
+	assert.ErrorIs(t, err, ErrInvalidAPIKeyIPRule)
+}
+
+func TestAPIKeyScopes(t *testing.T) {
+	assert.True(t, IsValidAPIKeyScope(string(PermOrdersRead)))
+	assert.False(t, IsValidAPIKeyScope(string(PermUsersWrite)), "user management stays human-only")
+	assert.False(t, IsValidAPIKeyScope(string(PermAdminAccess)))
+
+	key := &APIKey{Scopes: []string{string(PermOrdersRead)}}
+	assert.True(t, key.HasScopes(PermAdminAccess, PermOrdersRead))
+	assert.False(t, key.HasScopes(PermOrdersWrite))
+}
+
+func TestRequirePermissionChecksAPIKeyScopes(t *testing.T) {
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("apiKey", &APIKey{Scopes: []string{string(PermOrdersRead)}})
+		return c.Next()
+	})
+	ok := func(c *fiber.Ctx) error { return c.SendStatus(200) }
+	app.Get("/orders", RequirePermission(PermAdminAccess), RequirePermission(PermOrdersRead), ok)
+	app.Put("/orders", RequirePermission(PermOrdersWrite), ok)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/orders", nil))
+	if assert.NoError(t, err) {
+		assert.Equal(t, 200, resp.StatusCode)
+	}
+	resp, err = app.Test(httptest.NewRequest("PUT", "/orders", nil))
+	if assert.NoError(t, err) {
+		assert.Equal(t, 403, resp.StatusCode)
+	}
+}
+
+func TestAPIKeyMiddlewareRunsOncePerRequest(t *testing.T) {
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		//An outer group already verified the token of this request
+		c.Locals("claims", &Claims{Username: "admin"})
+		return c.Next()
+	})
+	app.Get("/admin/orders", APIKeyMiddleware, func(c *fiber.Ctx) error { return c.SendStatus(200) })
+
+	//Checking the token again would reject the request, which carries none
+	resp, err := app.Test(httptest.NewRequest("GET", "/admin/orders", nil))
+	if assert.NoError(t, err) {
+		assert.Equal(t, 200, resp.StatusCode)
+	}
+}
@@ -3,6 +3,8 @@ package auth
 import (
 	"GoodFood-BE/internal/dto"
 	redisdatabase "GoodFood-BE/internal/redis-database"
+	"GoodFood-BE/models"
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
@@ -10,18 +12,34 @@ import (
 	"os"
 	"time"
 
+	"github.com/aarondl/sqlboiler/v4/boil"
+	"github.com/aarondl/sqlboiler/v4/queries/qm"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 )
 
-// Used to sign token and verify token
+// Signs tokens when JWT_KEYS_DIR is not set, verifies legacy HS256 tokens and derives the other secrets of the package.
 var secretKey = []byte(os.Getenv("JWT_SECRET"))
 
+// Values of the "typ" claim.
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+)
+
+// ErrWrongTokenType is returned when e.g. a refresh token is sent as a bearer token.
+var ErrWrongTokenType = errors.New("wrong token type")
+
 // Struct that stores info in JWT
-// Username is to save into c.Locals().
+// Username and Role are saved into c.Locals(). TokenVersion is checked against token_version:{username}.
+// MFA is set on the tokens of sessions that passed the second factor at login, and kept across rotations.
 type Claims struct {
-	Username string `json:"username"`
+	Username     string `json:"username"`
+	Role         string `json:"role"`
+	TokenVersion int64  `json:"ver"`
+	Type         string `json:"typ"`
+	MFA          bool   `json:"mfa,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -32,18 +50,27 @@ func HashToken(token string) string {
 }
 
 // Used to create accessToken (expires in 15mins) and refreshToken (with jti) returns them plus the jti.
-func CreateToken(username string) (accessTokenStr, refreshTokenStr, refreshJTI string, err error) {
+// mfaVerified records that the session passed the second factor.
+func CreateToken(username, role string, mfaVerified bool) (accessTokenStr, refreshTokenStr, refreshJTI string, err error) {
+	ver, err := currentTokenVersion(username)
+	if err != nil {
+		return "", "", "", err
+	}
 
 	//Creating accessToken 15mins
 	accessTokenClaims := Claims{
-		Username: username,
+		Username:     username,
+		Role:         role,
+		TokenVersion: ver,
+		Type:         TokenTypeAccess,
+		MFA:          mfaVerified,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(15 * time.Minute)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
-	accessToken := jwt.NewWithClaims(jwt.SigningMethodHS256, accessTokenClaims)
-	accessTokenString, err := accessToken.SignedString(secretKey)
+	accessTokenString, err := signClaims(accessTokenClaims)
 	if err != nil {
 		return "", "", "", err
 	}
@@ -51,15 +78,18 @@ func CreateToken(username string) (accessTokenStr, refreshTokenStr, refreshJTI s
 	//Refresh token with jti
 	jti := uuid.New().String()
 	refreshClaims := Claims{
-		Username: username,
+		Username:     username,
+		Role:         role,
+		TokenVersion: ver,
+		Type:         TokenTypeRefresh,
+		MFA:          mfaVerified,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ID: jti,
+			ID:        jti,
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(7 * 24 * time.Hour)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
-	refreshToken := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshClaims)
-	refreshTokenStr, err = refreshToken.SignedString(secretKey)
+	refreshTokenStr, err = signClaims(refreshClaims)
 	if err != nil {
 		return "", "", "", err
 	}
@@ -67,11 +97,9 @@ func CreateToken(username string) (accessTokenStr, refreshTokenStr, refreshJTI s
 	return accessTokenString, refreshTokenStr, jti, nil
 }
 
-// VerifyToken parses and validates a JWT string and returns Claims.
+// VerifyToken parses and validates a JWT string of any type and returns Claims.
 func VerifyToken(tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return secretKey, nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, verificationKey)
 	if err != nil {
 		return nil, err
 	}
@@ -84,18 +112,49 @@ func VerifyToken(tokenString string) (*Claims, error) {
 	return claims, nil
 }
 
-// SaveRefreshToken stores a new refresh token in Redis and adds jti to user's set.
+// checkTokenType rejects claims of another type than tokenType.
+// Refresh tokens issued before the typ claim existed are still accepted: the refresh flow
+// only works for tokens whose hash is stored in Redis, which access tokens never are.
+func checkTokenType(claims *Claims, tokenType string) error {
+	if claims.Type == tokenType || (tokenType == TokenTypeRefresh && claims.Type == "") {
+		return nil
+	}
+	return ErrWrongTokenType
+}
+
+// VerifyRefreshToken verifies a token like VerifyToken and requires it to be a refresh token.
+func VerifyRefreshToken(tokenString string) (*Claims, error) {
+	claims, err := VerifyToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkTokenType(claims, TokenTypeRefresh); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// SaveRefreshToken stores the refresh token of a new session in Redis and adds jti to user's set.
 // Key pattern: refresh:{jti} -> JSON(refreshRecord) with TTL = expiresAt - now.
 // Also maintain a set user_refresh:{username} with members = jti (to support revoke-all or list sessions).
 func SaveRefreshToken(jti, refreshToken, username, userAgent, ip string, expiresAt time.Time) error {
-	hash := HashToken(refreshToken)
+	now := time.Now()
 	rec := dto.RefreshRecord{
-		Username:  username,
-		TokenHash: hash,
-		ExpiresAt: expiresAt,
-		UserAgent: userAgent,
-		IP:        ip,
+		Username:   username,
+		SessionID:  uuid.New().String(),
+		ExpiresAt:  expiresAt,
+		CreatedAt:  now,
+		LastUsedAt: now,
+		UserAgent:  userAgent,
+		IP:         ip,
 	}
+	return saveRefreshRecord(jti, refreshToken, rec)
+}
+
+// saveRefreshRecord writes rec under refresh:{jti} and registers jti in the user's set.
+func saveRefreshRecord(jti, refreshToken string, rec dto.RefreshRecord) error {
+	rec.TokenHash = HashToken(refreshToken)
+	username, expiresAt := rec.Username, rec.ExpiresAt
 	b, _ := json.Marshal(rec)
 	key := "refresh:" + jti
 	ttl := time.Until(expiresAt)
@@ -114,8 +173,10 @@ func SaveRefreshToken(jti, refreshToken, username, userAgent, ip string, expires
 		_ = redisdatabase.Client.Del(redisdatabase.Ctx, key).Err()
 		return err
 	}
-	// Set same TTL on the set membership? Redis sets don't have per-member TTL. We can set expire on set key to be >= token TTL.
-	_ = redisdatabase.Client.Expire(redisdatabase.Ctx, setKey, time.Hour*24*8).Err() // keep set around; optional: tune
+	// Redis sets don't have per-member TTL, so keep the set alive one day past its newest token
+	// and drop members whose refresh record has already expired.
+	_ = redisdatabase.Client.Expire(redisdatabase.Ctx, setKey, ttl+24*time.Hour).Err()
+	_ = PruneUserSessions(username)
 	return nil
 }
 
@@ -167,7 +228,7 @@ func RevokeAllUserRefreshTokens(username string) error {
 
 // ValidateRefreshAndRotate verifies incoming refresh token, checks Redis record, rotates tokens.
 func ValidateRefreshAndRotate(refreshToken, userAgent, ip string) (newAccess string, newRefresh string, newJTI string, err error) {
-	claims, err := VerifyToken(refreshToken)
+	claims, err := VerifyActiveToken(refreshToken, TokenTypeRefresh)
 	if err != nil {
 		return "", "", "", err
 	}
@@ -187,12 +248,39 @@ func ValidateRefreshAndRotate(refreshToken, userAgent, ip string) (newAccess str
 		return "", "", "", errors.New("refresh token reuse detected")
 	}
 
-	newAccess, newRefresh, newJti, err := CreateToken(claims.Username)
+	// Reload the role so that role changes apply from the next rotation on
+	account, err := models.Accounts(qm.Where("username = ?", claims.Username)).One(context.Background(), boil.GetContextDB())
+	if err != nil {
+		return "", "", "", errors.New("account not found")
+	}
+	if !account.Status {
+		_ = RevokeAllUserRefreshTokens(claims.Username)
+		return "", "", "", errors.New("account is disabled")
+	}
+
+	newAccess, newRefresh, newJti, err := CreateToken(claims.Username, account.Role, claims.MFA)
 	if err != nil {
 		return "", "", "", err
 	}
-	newClaims, _ := VerifyToken(newRefresh)
-	_ = SaveRefreshToken(newJti, newRefresh, claims.Username, userAgent, ip, newClaims.ExpiresAt.Time)
+	newClaims, _ := VerifyRefreshToken(newRefresh)
+	// The rotated token continues the same session
+	sessionID := rec.SessionID
+	if sessionID == "" {
+		sessionID = jti
+	}
+	createdAt := rec.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+	_ = saveRefreshRecord(newJti, newRefresh, dto.RefreshRecord{
+		Username:   claims.Username,
+		SessionID:  sessionID,
+		ExpiresAt:  newClaims.ExpiresAt.Time,
+		CreatedAt:  createdAt,
+		LastUsedAt: time.Now(),
+		UserAgent:  userAgent,
+		IP:         ip,
+	})
 	_ = RevokeRefreshToken(jti)
 	return newAccess, newRefresh, newJti, nil
 }
@@ -2,7 +2,12 @@ package auth
 
 import (
 	"GoodFood-BE/internal/service"
+	"GoodFood-BE/models"
+	"errors"
 	"strings"
+
+	"github.com/aarondl/sqlboiler/v4/boil"
+	"github.com/aarondl/sqlboiler/v4/queries/qm"
 	"github.com/gofiber/fiber/v2"
 )
 
@@ -22,13 +27,15 @@ func AuthMiddleware(c *fiber.Ctx) error{
 
 	tokenString := splitToken[1]
 
-	claims,err := VerifyToken(tokenString)
+	claims,err := VerifyActiveToken(tokenString, TokenTypeAccess)
 	if err != nil{
 		return service.SendError(c,401,"Invalid or expired token");
 	}
 
-	//Save the username into context so that other handlers could use it
+	//Save the username and role into context so that other handlers could use it
 	c.Locals("username",claims.Username)
+	c.Locals("role",claims.Role)
+	c.Locals("claims",claims)
 
 
 	return c.Next()
@@ -51,13 +58,15 @@ func OptionalAuthMiddleware(c *fiber.Ctx) error {
 	tokenString := splitToken[1]
 
 	// Verify the token
-	claims, err := VerifyToken(tokenString)
+	claims, err := VerifyActiveToken(tokenString, TokenTypeAccess)
 	if err != nil {
 		return service.SendError(c, 401, "Invalid or expired token")
 	}
 
-	///Save the username into context so that other handlers could use it
+	///Save the username and role into context so that other handlers could use it
 	c.Locals("username",claims.Username)
+	c.Locals("role",claims.Role)
+	c.Locals("claims",claims)
 
 	return c.Next()
 }
@@ -69,4 +78,58 @@ func GetAuthenticatedUser(c *fiber.Ctx) string{
 		return ""
 	}
 	return username
-}
\ No newline at end of file
+}
+
+//Helper function to fetch the claims of the access token sent with the request. Returns nil if there is none.
+func GetTokenClaims(c *fiber.Ctx) *Claims{
+	claims, ok := c.Locals("claims").(*Claims)
+	if !ok {
+		return nil
+	}
+	return claims
+}
+
+// AccountMiddleware resolves the account behind the access token once per request and stores it in c.Locals("account").
+// Used after AuthMiddleware on customer routes so handlers never trust an accountID sent by the client.
+func AccountMiddleware(c *fiber.Ctx) error{
+	if _, err := loadRequestAccount(c); err != nil{
+		return accountError(c,err)
+	}
+	return c.Next()
+}
+
+//Helper function to fetch the account resolved by AccountMiddleware in handlers. Returns nil if there is none.
+func GetAccount(c *fiber.Ctx) *models.Account{
+	account, ok := c.Locals("account").(*models.Account)
+	if !ok {
+		return nil
+	}
+	return account
+}
+
+// ErrAccountDisabled is returned by loadRequestAccount for accounts with status = false.
+var ErrAccountDisabled = errors.New("account is disabled")
+
+// accountError writes the response for an account that could not be loaded by loadRequestAccount.
+func accountError(c *fiber.Ctx, err error) error{
+	if errors.Is(err, ErrAccountDisabled){
+		return service.SendError(c,403,"Account is disabled")
+	}
+	return service.SendError(c,401,"Account not found")
+}
+
+// loadRequestAccount returns the authenticated account, loading it once per request.
+func loadRequestAccount(c *fiber.Ctx) (*models.Account, error){
+	if account := GetAccount(c); account != nil{
+		return account, nil
+	}
+	account, err := models.Accounts(qm.Where("username = ?", GetAuthenticatedUser(c))).One(c.Context(), boil.GetContextDB())
+	if err != nil{
+		return nil, err
+	}
+	if !account.Status{
+		return nil, ErrAccountDisabled
+	}
+	c.Locals("account", account)
+	return account, nil
+}
@@ -0,0 +1,33 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmailVerificationToken(t *testing.T) {
+	token, err := CreateEmailVerificationToken(7, "user@example.com")
+	assert.NoError(t, err)
+
+	claims, err := VerifyEmailVerificationToken(token)
+	assert.NoError(t, err)
+	assert.Equal(t, 7, claims.AccountID)
+	assert.Equal(t, "user@example.com", claims.Email)
+
+	//Verification tokens and session tokens are signed with different keys
+	_, err = VerifyToken(token)
+	assert.Error(t, err)
+
+	_, err = VerifyEmailVerificationToken(token + "x")
+	assert.Error(t, err)
+}
+
+func TestEmailVerificationRequired(t *testing.T) {
+	t.Setenv("REQUIRE_VERIFIED_EMAIL", "checkout, review")
+	assert.True(t, emailVerificationRequired(ActionCheckout))
+	assert.True(t, emailVerificationRequired(ActionReview))
+
+	t.Setenv("REQUIRE_VERIFIED_EMAIL", "")
+	assert.False(t, emailVerificationRequired(ActionCheckout))
+}
@@ -0,0 +1,138 @@
+package auth
+
+import (
+	"GoodFood-BE/models"
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/aarondl/sqlboiler/v4/boil"
+	"github.com/aarondl/sqlboiler/v4/queries/qm"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// NoPasswordHash is the password column of accounts created through a social login.
+// It is not a bcrypt hash, so no password ever matches it until the user sets one.
+const NoPasswordHash = "!"
+
+var (
+	ErrIdentityNotLinked       = errors.New("provider is not linked to this account")
+	ErrIdentityLinkedElsewhere = errors.New("this provider account is already linked to another account")
+	ErrProviderAlreadyLinked   = errors.New("another account of this provider is already linked")
+	ErrLastLoginMethod         = errors.New("cannot remove the last login method")
+)
+
+// ListIdentities returns the social logins linked to an account, oldest first.
+func ListIdentities(ctx context.Context, exec boil.ContextExecutor, accountID int) (models.OauthAccountSlice, error) {
+	return models.OauthAccounts(
+		models.OauthAccountWhere.AccountID.EQ(accountID),
+		qm.OrderBy("\"oauthID\" ASC"),
+	).All(ctx, exec)
+}
+
+// FindIdentity returns the link of a provider account, sql.ErrNoRows if it is not linked to anyone.
+func FindIdentity(ctx context.Context, exec boil.ContextExecutor, provider, providerUserID string) (*models.OauthAccount, error) {
+	return models.OauthAccounts(
+		models.OauthAccountWhere.Provider.EQ(provider),
+		models.OauthAccountWhere.ProviderUserID.EQ(providerUserID),
+	).One(ctx, exec)
+}
+
+// LinkIdentity links a provider account to accountID. Linking the same identity twice is a no-op.
+// It fails with ErrIdentityLinkedElsewhere when the identity belongs to another account and with
+// ErrProviderAlreadyLinked when the account already has a different identity of that provider.
+func LinkIdentity(ctx context.Context, exec boil.ContextExecutor, accountID int, provider, providerUserID string) error {
+	res, err := exec.ExecContext(ctx, `
+		INSERT INTO oauth_account ("accountID", provider, "providerUserID")
+		VALUES ($1, $2, $3)
+		ON CONFLICT DO NOTHING`, accountID, provider, providerUserID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 1 {
+		return err
+	}
+
+	//One of the unique constraints refused the row, find out which
+	existing, err := FindIdentity(ctx, exec, provider, providerUserID)
+	if err == nil {
+		if existing.AccountID == accountID {
+			return nil
+		}
+		return ErrIdentityLinkedElsewhere
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrProviderAlreadyLinked
+	}
+	return err
+}
+
+// UnlinkIdentity removes the provider link of acc, refusing to remove the last way to sign in.
+// The account row is locked so two concurrent unlinks cannot both pass the check.
+func UnlinkIdentity(ctx context.Context, acc *models.Account, provider string) error {
+	tx, err := boil.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	locked, err := models.Accounts(models.AccountWhere.AccountID.EQ(acc.AccountID), qm.For("UPDATE")).One(ctx, tx)
+	if err != nil {
+		return err
+	}
+	identities, err := ListIdentities(ctx, tx, acc.AccountID)
+	if err != nil {
+		return err
+	}
+	var target *models.OauthAccount
+	for _, identity := range identities {
+		if identity.Provider == provider {
+			target = identity
+		}
+	}
+	if target == nil {
+		return ErrIdentityNotLinked
+	}
+	if len(identities) == 1 && !HasLocalPassword(locked, identities) {
+		return ErrLastLoginMethod
+	}
+	if _, err := target.Delete(ctx, tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// HasLocalPassword reports whether acc can sign in with a username and password.
+// identities are the links of acc, used to recognise accounts created before NoPasswordHash existed.
+func HasLocalPassword(acc *models.Account, identities models.OauthAccountSlice) bool {
+	if acc.Password == "" || acc.Password == NoPasswordHash {
+		return false
+	}
+	return !isLegacyOAuthPassword(acc, identities)
+}
+
+// isLegacyOAuthPassword detects accounts created by a social login before NoPasswordHash:
+// their username and password were both set to the provider user ID.
+func isLegacyOAuthPassword(acc *models.Account, identities models.OauthAccountSlice) bool {
+	for _, identity := range identities {
+		if identity.ProviderUserID == acc.Username {
+			return bcrypt.CompareHashAndPassword([]byte(acc.Password), []byte(acc.Username)) == nil
+		}
+	}
+	return false
+}
+
+// ClearLegacyOAuthPassword replaces the guessable password of a legacy social account by NoPasswordHash.
+// It returns whether the account was one.
+func ClearLegacyOAuthPassword(ctx context.Context, exec boil.ContextExecutor, acc *models.Account) (bool, error) {
+	identities, err := ListIdentities(ctx, exec, acc.AccountID)
+	if err != nil {
+		return false, err
+	}
+	if !isLegacyOAuthPassword(acc, identities) {
+		return false, nil
+	}
+	acc.Password = NoPasswordHash
+	_, err = acc.Update(ctx, exec, boil.Whitelist(models.AccountColumns.Password))
+	return true, err
+}
@@ -0,0 +1,41 @@
+package auth
+
+import (
+	"GoodFood-BE/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestHasLocalPassword(t *testing.T) {
+	hash := func(password string) string {
+		b, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+		assert.NoError(t, err)
+		return string(b)
+	}
+	google := models.OauthAccountSlice{{Provider: "google", ProviderUserID: "1234567890"}}
+
+	tests := []struct {
+		name       string
+		account    models.Account
+		identities models.OauthAccountSlice
+		want       bool
+	}{
+		{"regular account", models.Account{Username: "alice", Password: hash("secret-password")}, nil, true},
+		{"social account", models.Account{Username: "1234567890", Password: NoPasswordHash}, google, false},
+		{"legacy social account", models.Account{Username: "1234567890", Password: hash("1234567890")}, google, false},
+		{"legacy social account that reset its password", models.Account{Username: "1234567890", Password: hash("a real password")}, google, true},
+		{"linked regular account", models.Account{Username: "alice", Password: hash("alice")}, google, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, HasLocalPassword(&tt.account, tt.identities))
+		})
+	}
+}
+
+func TestNoPasswordHashNeverMatches(t *testing.T) {
+	assert.Error(t, bcrypt.CompareHashAndPassword([]byte(NoPasswordHash), []byte("")))
+	assert.Error(t, bcrypt.CompareHashAndPassword([]byte(NoPasswordHash), []byte(NoPasswordHash)))
+}
@@ -0,0 +1,348 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Supported asymmetric signing algorithms.
+const (
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+// keySetManifest is the file listing the signing keys of JWT_KEYS_DIR.
+const keySetManifest = "keys.json"
+
+// keyReloadInterval is how often the manifest is checked for a rotation done by another process.
+const keyReloadInterval = 30 * time.Second
+
+// ErrLegacyToken is returned for HS256 tokens without a kid once a key set is configured, unless
+// JWT_LEGACY_HS256_UNTIL still allows them.
+var ErrLegacyToken = errors.New("legacy HS256 tokens are no longer accepted")
+
+// KeySetManifest describes the keys in JWT_KEYS_DIR. Active signs new tokens,
+// every listed key still verifies, so tokens signed before a rotation keep working until they expire.
+type KeySetManifest struct {
+	Active string    `json:"active"`
+	Keys   []KeyInfo `json:"keys"`
+}
+
+// KeyInfo is one entry of the manifest. The private key is stored next to it as {kid}.pem (PKCS#8).
+type KeyInfo struct {
+	Kid       string    `json:"kid"`
+	Alg       string    `json:"alg"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// signingKey is a loaded key of the key set.
+type signingKey struct {
+	kid     string
+	alg     string
+	private crypto.Signer
+}
+
+// keySet is the loaded content of JWT_KEYS_DIR, modTime/checked drive the reloads.
+type keySet struct {
+	dir     string
+	active  *signingKey
+	keys    map[string]*signingKey
+	modTime time.Time
+	checked time.Time
+}
+
+var (
+	keysMu     sync.Mutex
+	loadedKeys *keySet
+)
+
+// currentKeySet returns the key set of JWT_KEYS_DIR, reloading it when the manifest changed.
+// It returns nil when JWT_KEYS_DIR is not set, in which case tokens are signed with HS256 and JWT_SECRET.
+func currentKeySet() (*keySet, error) {
+	dir := os.Getenv("JWT_KEYS_DIR")
+	if dir == "" {
+		return nil, nil
+	}
+
+	keysMu.Lock()
+	defer keysMu.Unlock()
+	if loadedKeys != nil && loadedKeys.dir == dir && time.Since(loadedKeys.checked) < keyReloadInterval {
+		return loadedKeys, nil
+	}
+
+	info, err := os.Stat(filepath.Join(dir, keySetManifest))
+	if err != nil {
+		return nil, err
+	}
+	if loadedKeys != nil && loadedKeys.dir == dir && info.ModTime().Equal(loadedKeys.modTime) {
+		loadedKeys.checked = time.Now()
+		return loadedKeys, nil
+	}
+
+	ks, err := loadKeySet(dir)
+	if err != nil {
+		//Keep serving with the previous keys if a rotation left the directory half written
+		if loadedKeys != nil && loadedKeys.dir == dir {
+			loadedKeys.checked = time.Now()
+			return loadedKeys, nil
+		}
+		return nil, err
+	}
+	ks.modTime = info.ModTime()
+	ks.checked = time.Now()
+	loadedKeys = ks
+	return ks, nil
+}
+
+// ReadKeySetManifest reads the manifest of dir, returning an empty one if it does not exist yet.
+func ReadKeySetManifest(dir string) (*KeySetManifest, error) {
+	b, err := os.ReadFile(filepath.Join(dir, keySetManifest))
+	if errors.Is(err, os.ErrNotExist) {
+		return &KeySetManifest{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var m KeySetManifest
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func loadKeySet(dir string) (*keySet, error) {
+	m, err := ReadKeySetManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+	ks := &keySet{dir: dir, keys: map[string]*signingKey{}}
+	for _, info := range m.Keys {
+		priv, err := readPrivateKey(filepath.Join(dir, info.Kid+".pem"))
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %w", info.Kid, err)
+		}
+		if err := checkKeyAlg(info.Alg, priv); err != nil {
+			return nil, fmt.Errorf("key %s: %w", info.Kid, err)
+		}
+		ks.keys[info.Kid] = &signingKey{kid: info.Kid, alg: info.Alg, private: priv}
+	}
+	ks.active = ks.keys[m.Active]
+	if ks.active == nil {
+		return nil, fmt.Errorf("active key %q not found in %s", m.Active, dir)
+	}
+	return ks, nil
+}
+
+func readPrivateKey(path string) (crypto.Signer, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, errors.New("no PEM block")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("unsupported key type")
+	}
+	return signer, nil
+}
+
+func checkKeyAlg(alg string, key crypto.Signer) error {
+	switch key.(type) {
+	case *rsa.PrivateKey:
+		if alg == AlgRS256 {
+			return nil
+		}
+	case ed25519.PrivateKey:
+		if alg == AlgEdDSA {
+			return nil
+		}
+	}
+	return fmt.Errorf("key type does not match alg %s", alg)
+}
+
+// signClaims signs claims with the active key, or with HS256 when no key set is configured.
+func signClaims(claims jwt.Claims) (string, error) {
+	ks, err := currentKeySet()
+	if err != nil {
+		return "", err
+	}
+	if ks == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secretKey)
+	}
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(ks.active.alg), claims)
+	token.Header["kid"] = ks.active.kid
+	return token.SignedString(ks.active.private)
+}
+
+// legacyHS256Allowed reports whether HS256 tokens signed before the key set was configured are still accepted.
+// JWT_LEGACY_HS256_UNTIL (RFC 3339) opens that window while the old sessions expire; unset, invalid or
+// past, they are rejected.
+func legacyHS256Allowed(now time.Time) bool {
+	until, err := time.Parse(time.RFC3339, os.Getenv("JWT_LEGACY_HS256_UNTIL"))
+	return err == nil && now.Before(until)
+}
+
+// verificationKey is the jwt.Keyfunc of session tokens. Tokens with a kid must be signed by that key
+// of the key set with its own algorithm; tokens without one are HS256 tokens signed with JWT_SECRET,
+// accepted when no key set is configured or, once one is, while legacyHS256Allowed.
+func verificationKey(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	ks, err := currentKeySet()
+	if err != nil {
+		return nil, err
+	}
+	if kid == "" {
+		if token.Method != jwt.SigningMethodHS256 || len(secretKey) == 0 {
+			return nil, errors.New("unexpected signing method")
+		}
+		if ks != nil && !legacyHS256Allowed(time.Now()) {
+			return nil, ErrLegacyToken
+		}
+		return secretKey, nil
+	}
+
+	if ks == nil {
+		return nil, errors.New("unknown key id")
+	}
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, errors.New("unknown key id")
+	}
+	if token.Method.Alg() != key.alg {
+		return nil, errors.New("unexpected signing method")
+	}
+	return key.private.Public(), nil
+}
+
+// GenerateSigningKey creates a key for alg and returns it PEM encoded (PKCS#8).
+func GenerateSigningKey(alg string) ([]byte, error) {
+	var key crypto.Signer
+	var err error
+	switch alg {
+	case AlgRS256:
+		key, err = rsa.GenerateKey(rand.Reader, 2048)
+	case AlgEdDSA:
+		_, key, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, fmt.Errorf("unsupported alg %s", alg)
+	}
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+// RotateSigningKey adds a new active key of alg to dir and drops the oldest keys beyond keep.
+// Dropped keys stop verifying, so keep should cover at least the refresh token lifetime between rotations.
+func RotateSigningKey(dir, alg string, keep int) (string, error) {
+	if keep < 1 {
+		keep = 1
+	}
+	m, err := ReadKeySetManifest(dir)
+	if err != nil {
+		return "", err
+	}
+	pemBytes, err := GenerateSigningKey(alg)
+	if err != nil {
+		return "", err
+	}
+	suffix := make([]byte, 3)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", err
+	}
+	kid := time.Now().UTC().Format("20060102T150405Z") + "-" + hex.EncodeToString(suffix)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(filepath.Join(dir, kid+".pem"), pemBytes, 0o600); err != nil {
+		return "", err
+	}
+
+	m.Keys = append(m.Keys, KeyInfo{Kid: kid, Alg: alg, CreatedAt: time.Now().UTC()})
+	m.Active = kid
+	var dropped []KeyInfo
+	if len(m.Keys) > keep {
+		dropped = m.Keys[:len(m.Keys)-keep]
+		m.Keys = m.Keys[len(m.Keys)-keep:]
+	}
+
+	//Write the manifest atomically so running servers never read a partial file
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	tmp := filepath.Join(dir, keySetManifest+".tmp")
+	if err := os.WriteFile(tmp, b, 0o600); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp, filepath.Join(dir, keySetManifest)); err != nil {
+		return "", err
+	}
+	for _, k := range dropped {
+		_ = os.Remove(filepath.Join(dir, k.Kid+".pem"))
+	}
+	return kid, nil
+}
+
+// JWK is the public part of a signing key in RFC 7517 format.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS returns the public keys that verify session tokens. It is empty in HS256 mode.
+func JWKS() ([]JWK, error) {
+	ks, err := currentKeySet()
+	if err != nil || ks == nil {
+		return []JWK{}, err
+	}
+	keys := make([]JWK, 0, len(ks.keys))
+	for _, k := range ks.keys {
+		jwk := JWK{Kid: k.kid, Alg: k.alg, Use: "sig"}
+		switch pub := k.private.Public().(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		}
+		keys = append(keys, jwk)
+	}
+	return keys, nil
+}
@@ -0,0 +1,118 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func signTestClaims(t *testing.T, typ string) string {
+	t.Helper()
+	token, err := signClaims(Claims{Username: "user0", Type: typ, RegisteredClaims: jwt.RegisteredClaims{ID: "jti"}})
+	assert.NoError(t, err)
+	return token
+}
+
+func TestKeySetSignsAndVerifiesWithKid(t *testing.T) {
+	for _, alg := range []string{AlgEdDSA, AlgRS256} {
+		t.Run(alg, func(t *testing.T) {
+			dir := t.TempDir()
+			t.Setenv("JWT_KEYS_DIR", dir)
+			kid, err := RotateSigningKey(dir, alg, 2)
+			assert.NoError(t, err)
+
+			token := signTestClaims(t, TokenTypeAccess)
+			parsed, _, err := jwt.NewParser().ParseUnverified(token, &Claims{})
+			assert.NoError(t, err)
+			assert.Equal(t, kid, parsed.Header["kid"])
+			assert.Equal(t, alg, parsed.Method.Alg())
+
+			claims, err := VerifyToken(token)
+			assert.NoError(t, err)
+			assert.Equal(t, "user0", claims.Username)
+
+			jwks, err := JWKS()
+			assert.NoError(t, err)
+			assert.Len(t, jwks, 1)
+			assert.Equal(t, kid, jwks[0].Kid)
+		})
+	}
+}
+
+func TestRotationKeepsPreviousKeys(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("JWT_KEYS_DIR", dir)
+	_, err := RotateSigningKey(dir, AlgEdDSA, 2)
+	assert.NoError(t, err)
+	oldToken := signTestClaims(t, TokenTypeAccess)
+
+	//Force a reload instead of waiting for keyReloadInterval
+	_, err = RotateSigningKey(dir, AlgRS256, 2)
+	assert.NoError(t, err)
+	loadedKeys = nil
+	_, err = VerifyToken(oldToken)
+	assert.NoError(t, err)
+
+	//Dropped keys no longer verify
+	_, err = RotateSigningKey(dir, AlgRS256, 2)
+	assert.NoError(t, err)
+	loadedKeys = nil
+	_, err = VerifyToken(oldToken)
+	assert.Error(t, err)
+
+	m, err := ReadKeySetManifest(dir)
+	assert.NoError(t, err)
+	assert.Len(t, m.Keys, 2)
+}
+
+func TestTokenTypeIsEnforced(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("JWT_KEYS_DIR", dir)
+	_, err := RotateSigningKey(dir, AlgEdDSA, 1)
+	assert.NoError(t, err)
+
+	refresh := signTestClaims(t, TokenTypeRefresh)
+	claims, err := VerifyToken(refresh)
+	assert.NoError(t, err)
+	assert.ErrorIs(t, checkTokenType(claims, TokenTypeAccess), ErrWrongTokenType)
+	assert.NoError(t, checkTokenType(claims, TokenTypeRefresh))
+
+	//Refresh tokens from before the typ claim are still accepted, access tokens are not
+	legacy := &Claims{}
+	assert.NoError(t, checkTokenType(legacy, TokenTypeRefresh))
+	assert.ErrorIs(t, checkTokenType(legacy, TokenTypeAccess), ErrWrongTokenType)
+}
+
+func TestLegacyHS256Tokens(t *testing.T) {
+	previous := secretKey
+	secretKey = []byte("test-secret")
+	t.Cleanup(func() { secretKey = previous })
+	t.Setenv("JWT_KEYS_DIR", "")
+	t.Setenv("JWT_LEGACY_HS256_UNTIL", "")
+
+	//Without a key set HS256 is how tokens are signed
+	legacy := signTestClaims(t, TokenTypeAccess)
+	_, err := VerifyToken(legacy)
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+	t.Setenv("JWT_KEYS_DIR", dir)
+	_, err = RotateSigningKey(dir, AlgEdDSA, 1)
+	assert.NoError(t, err)
+	_, err = VerifyToken(legacy)
+	assert.ErrorIs(t, err, ErrLegacyToken, "off by default once a key set is configured")
+
+	t.Setenv("JWT_LEGACY_HS256_UNTIL", time.Now().Add(time.Hour).Format(time.RFC3339))
+	_, err = VerifyToken(legacy)
+	assert.NoError(t, err)
+
+	t.Setenv("JWT_LEGACY_HS256_UNTIL", time.Now().Add(-time.Hour).Format(time.RFC3339))
+	_, err = VerifyToken(legacy)
+	assert.ErrorIs(t, err, ErrLegacyToken)
+
+	t.Setenv("JWT_LEGACY_HS256_UNTIL", "soon")
+	_, err = VerifyToken(legacy)
+	assert.ErrorIs(t, err, ErrLegacyToken)
+}
@@ -0,0 +1,146 @@
+package auth
+
+import (
+	redisdatabase "GoodFood-BE/internal/redis-database"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Login throttling policy. Failures are counted per username and per IP inside LoginFailureWindow.
+// From LoginDelayAfter failures on a username each new attempt has to wait an exponentially growing delay,
+// and at LoginLockoutAfter failures the username is locked for LoginLockoutDuration.
+const (
+	LoginFailureWindow   = 15 * time.Minute
+	LoginDelayAfter      = 3
+	LoginMaxDelay        = 30 * time.Second
+	LoginLockoutAfter    = 10
+	LoginLockoutDuration = 15 * time.Minute
+	LoginIPLimit         = 50
+)
+
+var (
+	ErrLoginLocked    = errors.New("account temporarily locked")
+	ErrLoginThrottled = errors.New("too many login attempts")
+)
+
+// LoginBlockedError tells the caller how long to wait before trying again.
+type LoginBlockedError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (e *LoginBlockedError) Error() string { return e.Err.Error() }
+func (e *LoginBlockedError) Unwrap() error { return e.Err }
+
+// Key patterns, usernames are lower-cased so "Admin" and "admin" share counters:
+// login_fail:user={username} / login_fail:ip={ip} -> failure count with TTL = LoginFailureWindow.
+// login_next:{username} -> marker whose TTL is the delay before the next attempt is allowed.
+// login_lock:{username} -> marker whose TTL is the remaining lockout.
+func loginFailUserKey(username string) string {
+	return "login_fail:user=" + strings.ToLower(username)
+}
+
+func loginFailIPKey(ip string) string {
+	return "login_fail:ip=" + ip
+}
+
+func loginNextKey(username string) string {
+	return "login_next:" + strings.ToLower(username)
+}
+
+func loginLockKey(username string) string {
+	return "login_lock:" + strings.ToLower(username)
+}
+
+// CheckLoginAllowed returns a *LoginBlockedError if username or ip may not attempt a login right now.
+func CheckLoginAllowed(username, ip string) error {
+	pipe := redisdatabase.Client.Pipeline()
+	lockTTL := pipe.PTTL(redisdatabase.Ctx, loginLockKey(username))
+	nextTTL := pipe.PTTL(redisdatabase.Ctx, loginNextKey(username))
+	ipFails := pipe.Get(redisdatabase.Ctx, loginFailIPKey(ip))
+	ipTTL := pipe.PTTL(redisdatabase.Ctx, loginFailIPKey(ip))
+	if _, err := pipe.Exec(redisdatabase.Ctx); err != nil && err != redis.Nil {
+		return err
+	}
+
+	if ttl := lockTTL.Val(); ttl > 0 {
+		return &LoginBlockedError{Err: ErrLoginLocked, RetryAfter: ttl}
+	}
+	if n, _ := ipFails.Int64(); n >= LoginIPLimit {
+		return &LoginBlockedError{Err: ErrLoginThrottled, RetryAfter: ipTTL.Val()}
+	}
+	if ttl := nextTTL.Val(); ttl > 0 {
+		return &LoginBlockedError{Err: ErrLoginThrottled, RetryAfter: ttl}
+	}
+	return nil
+}
+
+// loginDelay returns the wait imposed after the given number of failures.
+func loginDelay(failures int64) time.Duration {
+	if failures < LoginDelayAfter {
+		return 0
+	}
+	delay := time.Second
+	for i := int64(LoginDelayAfter); i < failures && delay < LoginMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > LoginMaxDelay {
+		delay = LoginMaxDelay
+	}
+	return delay
+}
+
+// RegisterLoginFailure counts a failed attempt and reports whether it just locked the username.
+func RegisterLoginFailure(username, ip string) (bool, error) {
+	pipe := redisdatabase.Client.TxPipeline()
+	userFails := pipe.Incr(redisdatabase.Ctx, loginFailUserKey(username))
+	pipe.ExpireNX(redisdatabase.Ctx, loginFailUserKey(username), LoginFailureWindow)
+	pipe.Incr(redisdatabase.Ctx, loginFailIPKey(ip))
+	pipe.ExpireNX(redisdatabase.Ctx, loginFailIPKey(ip), LoginFailureWindow)
+	if _, err := pipe.Exec(redisdatabase.Ctx); err != nil {
+		return false, err
+	}
+
+	failures := userFails.Val()
+	if failures >= LoginLockoutAfter {
+		locked, err := redisdatabase.Client.SetNX(redisdatabase.Ctx, loginLockKey(username), failures, LoginLockoutDuration).Result()
+		if err != nil {
+			return false, err
+		}
+		_ = redisdatabase.Client.Del(redisdatabase.Ctx, loginFailUserKey(username), loginNextKey(username)).Err()
+		return locked, nil
+	}
+	if delay := loginDelay(failures); delay > 0 {
+		return false, redisdatabase.Client.Set(redisdatabase.Ctx, loginNextKey(username), 1, delay).Err()
+	}
+	return false, nil
+}
+
+// ResetLoginFailures clears the per-username counters after a successful login.
+func ResetLoginFailures(username string) error {
+	return redisdatabase.Client.Del(redisdatabase.Ctx, loginFailUserKey(username), loginNextKey(username)).Err()
+}
+
+// UnlockLogin lifts a lockout and clears the failure counters of username. Returns whether it was locked.
+func UnlockLogin(username string) (bool, error) {
+	deleted, err := redisdatabase.Client.Del(redisdatabase.Ctx, loginLockKey(username)).Result()
+	if err != nil {
+		return false, err
+	}
+	if err := ResetLoginFailures(username); err != nil {
+		return false, err
+	}
+	return deleted > 0, nil
+}
+
+// LoginLockRemaining returns how long username stays locked, 0 if it is not.
+func LoginLockRemaining(username string) (time.Duration, error) {
+	ttl, err := redisdatabase.Client.PTTL(redisdatabase.Ctx, loginLockKey(username)).Result()
+	if err != nil || ttl < 0 {
+		return 0, err
+	}
+	return ttl, nil
+}
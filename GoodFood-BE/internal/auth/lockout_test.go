@@ -0,0 +1,26 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoginDelay(t *testing.T) {
+	tests := []struct {
+		failures int64
+		want     time.Duration
+	}{
+		{failures: 0, want: 0},
+		{failures: LoginDelayAfter - 1, want: 0},
+		{failures: LoginDelayAfter, want: time.Second},
+		{failures: LoginDelayAfter + 1, want: 2 * time.Second},
+		{failures: LoginDelayAfter + 3, want: 8 * time.Second},
+		{failures: LoginDelayAfter + 20, want: LoginMaxDelay},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, loginDelay(tt.failures), "failures=%d", tt.failures)
+	}
+}
@@ -0,0 +1,354 @@
+package auth
+
+import (
+	redisdatabase "GoodFood-BE/internal/redis-database"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aarondl/sqlboiler/v4/boil"
+	"github.com/redis/go-redis/v9"
+)
+
+// Two-factor settings.
+const (
+	MFARecoveryCodeCount = 10
+	MFAChallengeTTL      = 5 * time.Minute
+	MFAChallengeAttempts = 5
+)
+
+var (
+	ErrMFANotEnrolled      = errors.New("two-factor authentication is not set up")
+	ErrMFAAlreadyEnabled   = errors.New("two-factor authentication is already enabled")
+	ErrInvalidMFACode      = errors.New("invalid two-factor code")
+	ErrInvalidMFAChallenge = errors.New("invalid or expired two-factor challenge")
+)
+
+// mfaKey encrypts TOTP secrets at rest. MFA_ENCRYPTION_KEY should be set in production,
+// otherwise a key is derived from the JWT secret.
+var mfaKey = func() []byte {
+	if k := os.Getenv("MFA_ENCRYPTION_KEY"); k != "" {
+		h := sha256.Sum256([]byte(k))
+		return h[:]
+	}
+	h := sha256.Sum256(append([]byte("mfa:"), secretKey...))
+	return h[:]
+}()
+
+func encryptMFASecret(secret string) (string, error) {
+	block, err := aes.NewCipher(mfaKey)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte(secret), nil)), nil
+}
+
+func decryptMFASecret(enc string) (string, error) {
+	raw, err := base64.StdEncoding.DecodeString(enc)
+	if err != nil {
+		return "", err
+	}
+	block, err := aes.NewCipher(mfaKey)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	if len(raw) < gcm.NonceSize() {
+		return "", errors.New("malformed secret")
+	}
+	plain, err := gcm.Open(nil, raw[:gcm.NonceSize()], raw[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
+
+// mfaRecord is the account_mfa row of an account.
+type mfaRecord struct {
+	Secret       string
+	Enabled      bool
+	LastUsedStep int64
+}
+
+func loadMFARecord(ctx context.Context, accountID int) (*mfaRecord, error) {
+	var rec mfaRecord
+	var enc string
+	err := boil.GetContextDB().QueryRowContext(ctx,
+		`SELECT secret, enabled, "lastUsedStep" FROM account_mfa WHERE "accountID" = $1`, accountID,
+	).Scan(&enc, &rec.Enabled, &rec.LastUsedStep)
+	if err == sql.ErrNoRows {
+		return nil, ErrMFANotEnrolled
+	}
+	if err != nil {
+		return nil, err
+	}
+	if rec.Secret, err = decryptMFASecret(enc); err != nil {
+		return nil, err
+	}
+	return &rec, nil
+}
+
+// MFAEnabled reports whether the account has confirmed a TOTP enrollment.
+func MFAEnabled(ctx context.Context, accountID int) (bool, error) {
+	var enabled bool
+	err := boil.GetContextDB().QueryRowContext(ctx,
+		`SELECT enabled FROM account_mfa WHERE "accountID" = $1`, accountID,
+	).Scan(&enabled)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return enabled, err
+}
+
+// BeginMFAEnrollment stores a new pending secret for the account and returns it.
+// Starting again before confirming replaces the pending secret.
+func BeginMFAEnrollment(ctx context.Context, accountID int) (string, error) {
+	enabled, err := MFAEnabled(ctx, accountID)
+	if err != nil {
+		return "", err
+	}
+	if enabled {
+		return "", ErrMFAAlreadyEnabled
+	}
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		return "", err
+	}
+	enc, err := encryptMFASecret(secret)
+	if err != nil {
+		return "", err
+	}
+	_, err = boil.GetContextDB().ExecContext(ctx, `
+		INSERT INTO account_mfa ("accountID", secret, enabled, "lastUsedStep")
+		VALUES ($1, $2, false, 0)
+		ON CONFLICT ("accountID") DO UPDATE SET secret = EXCLUDED.secret, "lastUsedStep" = 0, "createdAt" = now()
+		WHERE account_mfa.enabled = false`, accountID, enc)
+	if err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+// ConfirmMFAEnrollment enables two-factor once the user proves the app generates valid codes,
+// and returns the recovery codes in plain text. They are never shown again.
+func ConfirmMFAEnrollment(ctx context.Context, accountID int, code string) ([]string, error) {
+	rec, err := loadMFARecord(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	if rec.Enabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	step, ok := ValidateTOTP(rec.Secret, code, time.Now(), rec.LastUsedStep)
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	tx, err := boil.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE account_mfa SET enabled = true, "lastUsedStep" = $2, "confirmedAt" = now()
+		WHERE "accountID" = $1`, accountID, step); err != nil {
+		return nil, err
+	}
+	codes, err := replaceRecoveryCodes(ctx, tx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	return codes, tx.Commit()
+}
+
+// RegenerateRecoveryCodes invalidates every recovery code of the account and issues new ones.
+func RegenerateRecoveryCodes(ctx context.Context, accountID int) ([]string, error) {
+	tx, err := boil.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	codes, err := replaceRecoveryCodes(ctx, tx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	return codes, tx.Commit()
+}
+
+// replaceRecoveryCodes deletes the old codes and inserts MFARecoveryCodeCount new hashed ones.
+func replaceRecoveryCodes(ctx context.Context, exec boil.ContextExecutor, accountID int) ([]string, error) {
+	if _, err := exec.ExecContext(ctx, `DELETE FROM mfa_recovery_code WHERE "accountID" = $1`, accountID); err != nil {
+		return nil, err
+	}
+	codes := make([]string, 0, MFARecoveryCodeCount)
+	for i := 0; i < MFARecoveryCodeCount; i++ {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := exec.ExecContext(ctx,
+			`INSERT INTO mfa_recovery_code ("accountID", "codeHash") VALUES ($1, $2)`,
+			accountID, HashToken(normalizeRecoveryCode(code))); err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+// generateRecoveryCode returns a code like "k7fq-2m9x".
+func generateRecoveryCode() (string, error) {
+	b := make([]byte, 5)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	s := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b))
+	return s[:4] + "-" + s[4:8], nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+}
+
+// VerifyMFACode checks a TOTP code or, failing that, consumes a recovery code of the account.
+func VerifyMFACode(ctx context.Context, accountID int, code string) error {
+	rec, err := loadMFARecord(ctx, accountID)
+	if err != nil {
+		return err
+	}
+	if !rec.Enabled {
+		return ErrMFANotEnrolled
+	}
+	code = strings.TrimSpace(code)
+
+	if step, ok := ValidateTOTP(rec.Secret, code, time.Now(), rec.LastUsedStep); ok {
+		//Only one request may use a given step
+		res, err := boil.GetContextDB().ExecContext(ctx,
+			`UPDATE account_mfa SET "lastUsedStep" = $2 WHERE "accountID" = $1 AND "lastUsedStep" < $2`, accountID, step)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 1 {
+			return nil
+		}
+		return ErrInvalidMFACode
+	}
+
+	res, err := boil.GetContextDB().ExecContext(ctx, `
+		UPDATE mfa_recovery_code SET "usedAt" = now()
+		WHERE "accountID" = $1 AND "codeHash" = $2 AND "usedAt" IS NULL`,
+		accountID, HashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 1 {
+		return nil
+	}
+	return ErrInvalidMFACode
+}
+
+// RemainingRecoveryCodes counts the unused recovery codes of the account.
+func RemainingRecoveryCodes(ctx context.Context, accountID int) (int, error) {
+	var n int
+	err := boil.GetContextDB().QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM mfa_recovery_code WHERE "accountID" = $1 AND "usedAt" IS NULL`, accountID,
+	).Scan(&n)
+	return n, err
+}
+
+// DisableMFA removes the enrollment and recovery codes of the account.
+func DisableMFA(ctx context.Context, accountID int) error {
+	tx, err := boil.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, `DELETE FROM mfa_recovery_code WHERE "accountID" = $1`, accountID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM account_mfa WHERE "accountID" = $1`, accountID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Key pattern: mfa_challenge:{sha256(token)} -> hash {accountID, attempts} with TTL = MFAChallengeTTL.
+// A challenge is what the client holds between the password step and the second factor.
+func mfaChallengeKey(token string) string {
+	return "mfa_challenge:" + HashToken(token)
+}
+
+// CreateMFAChallenge issues the token that stands for "password verified, second factor pending".
+func CreateMFAChallenge(accountID int) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	key := mfaChallengeKey(token)
+	pipe := redisdatabase.Client.TxPipeline()
+	pipe.HSet(redisdatabase.Ctx, key, "accountID", accountID, "attempts", 0)
+	pipe.Expire(redisdatabase.Ctx, key, MFAChallengeTTL)
+	if _, err := pipe.Exec(redisdatabase.Ctx); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// ResolveMFAChallenge returns the account of a challenge and counts one attempt against it.
+// The challenge is destroyed once MFAChallengeAttempts is exceeded.
+func ResolveMFAChallenge(token string) (int, error) {
+	if token == "" {
+		return 0, ErrInvalidMFAChallenge
+	}
+	key := mfaChallengeKey(token)
+	accountID, err := redisdatabase.Client.HGet(redisdatabase.Ctx, key, "accountID").Result()
+	if err == redis.Nil {
+		return 0, ErrInvalidMFAChallenge
+	}
+	if err != nil {
+		return 0, err
+	}
+	attempts, err := redisdatabase.Client.HIncrBy(redisdatabase.Ctx, key, "attempts", 1).Result()
+	if err != nil {
+		return 0, err
+	}
+	if attempts > MFAChallengeAttempts {
+		_ = redisdatabase.Client.Del(redisdatabase.Ctx, key).Err()
+		return 0, ErrInvalidMFAChallenge
+	}
+	return strconv.Atoi(accountID)
+}
+
+// ConsumeMFAChallenge deletes a challenge. Returns false if another request already used it.
+func ConsumeMFAChallenge(token string) (bool, error) {
+	n, err := redisdatabase.Client.Del(redisdatabase.Ctx, mfaChallengeKey(token)).Result()
+	return n == 1, err
+}
+
+// AdminMFARequired reports whether accounts with admin access must have two-factor enabled.
+// Off until ADMIN_2FA_REQUIRED=true, so existing admins can enroll before they are locked out of the admin routes.
+func AdminMFARequired() bool {
+	return os.Getenv("ADMIN_2FA_REQUIRED") == "true"
+}
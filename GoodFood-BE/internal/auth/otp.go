@@ -0,0 +1,96 @@
+package auth
+
+import (
+	redisdatabase "GoodFood-BE/internal/redis-database"
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// OTP settings shared by every purpose.
+const (
+	OTPLength      = 6
+	OTPTTL         = 5 * time.Minute
+	OTPMaxAttempts = 5
+)
+
+// OTP purposes, each one has its own keyspace so a code can only be used for what it was sent for.
+const (
+	OTPPurposePasswordReset = "reset"
+	OTPPurposePhone         = "phone"
+)
+
+var (
+	ErrInvalidOTP         = errors.New("invalid or expired code")
+	ErrOTPTooManyAttempts = errors.New("too many attempts, please request a new code")
+)
+
+// Key pattern: otp:{purpose}:{subject} -> hash {code: sha256(code), attempts: n, data: payload} with TTL = OTPTTL.
+func otpKey(purpose, subject string) string {
+	return "otp:" + purpose + ":" + subject
+}
+
+// GenerateOTP creates a numeric code for subject and stores it with optional data returned on success.
+// A new code replaces the previous one and resets the attempt counter.
+func GenerateOTP(purpose, subject, data string) (string, error) {
+	max := big.NewInt(1)
+	for i := 0; i < OTPLength; i++ {
+		max.Mul(max, big.NewInt(10))
+	}
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", err
+	}
+	code := fmt.Sprintf("%0*d", OTPLength, n)
+
+	key := otpKey(purpose, subject)
+	pipe := redisdatabase.Client.TxPipeline()
+	pipe.Del(redisdatabase.Ctx, key)
+	pipe.HSet(redisdatabase.Ctx, key, "code", HashToken(code), "attempts", 0, "data", data)
+	pipe.Expire(redisdatabase.Ctx, key, OTPTTL)
+	if _, err := pipe.Exec(redisdatabase.Ctx); err != nil {
+		return "", err
+	}
+	return code, nil
+}
+
+// VerifyOTP checks code for subject and consumes it on success, returning the data stored with it.
+// Every call counts as an attempt; after OTPMaxAttempts the code is destroyed.
+func VerifyOTP(purpose, subject, code string) (string, error) {
+	key := otpKey(purpose, subject)
+	attempts, err := redisdatabase.Client.HIncrBy(redisdatabase.Ctx, key, "attempts", 1).Result()
+	if err != nil {
+		return "", err
+	}
+	stored, err := redisdatabase.Client.HGetAll(redisdatabase.Ctx, key).Result()
+	if err != nil && err != redis.Nil {
+		return "", err
+	}
+	//HIncrBy creates the hash when the code expired or never existed
+	if stored["code"] == "" {
+		_ = redisdatabase.Client.Del(redisdatabase.Ctx, key).Err()
+		return "", ErrInvalidOTP
+	}
+	if attempts > OTPMaxAttempts {
+		_ = redisdatabase.Client.Del(redisdatabase.Ctx, key).Err()
+		return "", ErrOTPTooManyAttempts
+	}
+	if subtle.ConstantTimeCompare([]byte(stored["code"]), []byte(HashToken(code))) != 1 {
+		return "", ErrInvalidOTP
+	}
+
+	//Only the request that deletes the key may use the code
+	deleted, err := redisdatabase.Client.Del(redisdatabase.Ctx, key).Result()
+	if err != nil {
+		return "", err
+	}
+	if deleted == 0 {
+		return "", ErrInvalidOTP
+	}
+	return stored["data"], nil
+}
@@ -0,0 +1,81 @@
+package auth
+
+import (
+	redisdatabase "GoodFood-BE/internal/redis-database"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// PasswordResetTTL is how long a reset link stays valid.
+const PasswordResetTTL = 15 * time.Minute
+
+// ErrInvalidResetToken is returned for unknown, expired or already used reset tokens.
+var ErrInvalidResetToken = errors.New("invalid or expired token")
+
+// Key patterns:
+// resetPass:token={sha256(token)} -> accountID, so a Redis dump never exposes a usable token.
+// resetPass:account={accountID} -> hash of the latest token, so issuing a new link kills the previous one.
+func resetTokenKey(tokenHash string) string {
+	return "resetPass:token=" + tokenHash
+}
+
+func resetAccountKey(accountID int) string {
+	return "resetPass:account=" + strconv.Itoa(accountID)
+}
+
+// CreatePasswordResetToken issues a random reset token for accountID and returns it in plain text.
+func CreatePasswordResetToken(accountID int) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	hash := HashToken(token)
+
+	//Invalidate the previous link of this account
+	if old, err := redisdatabase.Client.Get(redisdatabase.Ctx, resetAccountKey(accountID)).Result(); err == nil {
+		_ = redisdatabase.Client.Del(redisdatabase.Ctx, resetTokenKey(old)).Err()
+	}
+
+	pipe := redisdatabase.Client.TxPipeline()
+	pipe.Set(redisdatabase.Ctx, resetTokenKey(hash), accountID, PasswordResetTTL)
+	pipe.Set(redisdatabase.Ctx, resetAccountKey(accountID), hash, PasswordResetTTL)
+	if _, err := pipe.Exec(redisdatabase.Ctx); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// PeekPasswordResetToken returns the account a reset token belongs to without using it up.
+func PeekPasswordResetToken(token string) (int, error) {
+	if token == "" {
+		return 0, ErrInvalidResetToken
+	}
+	accountID, err := redisdatabase.Client.Get(redisdatabase.Ctx, resetTokenKey(HashToken(token))).Int()
+	if err == redis.Nil {
+		return 0, ErrInvalidResetToken
+	}
+	return accountID, err
+}
+
+// ConsumePasswordResetToken atomically deletes a reset token and returns its account,
+// so two concurrent requests can never both use the same link.
+func ConsumePasswordResetToken(token string) (int, error) {
+	if token == "" {
+		return 0, ErrInvalidResetToken
+	}
+	accountID, err := redisdatabase.Client.GetDel(redisdatabase.Ctx, resetTokenKey(HashToken(token))).Int()
+	if err == redis.Nil {
+		return 0, ErrInvalidResetToken
+	}
+	if err != nil {
+		return 0, err
+	}
+	_ = redisdatabase.Client.Del(redisdatabase.Ctx, resetAccountKey(accountID)).Err()
+	return accountID, nil
+}
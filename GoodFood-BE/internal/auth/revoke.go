@@ -0,0 +1,91 @@
+package auth
+
+import (
+	redisdatabase "GoodFood-BE/internal/redis-database"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrTokenRevoked is returned for tokens issued before the last RevokeUserTokens call of their user.
+var ErrTokenRevoked = errors.New("token has been revoked")
+
+// Key pattern: token_version:{username} -> counter copied into the "ver" claim of every token.
+// Bumping it invalidates all access and refresh tokens issued so far with a single GET per request.
+func tokenVersionKey(username string) string {
+	return "token_version:" + username
+}
+
+// currentTokenVersion returns the token version of a user, 0 if it was never bumped.
+func currentTokenVersion(username string) (int64, error) {
+	ver, err := redisdatabase.Client.Get(redisdatabase.Ctx, tokenVersionKey(username)).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return ver, err
+}
+
+// RevokeUserTokens invalidates every outstanding access and refresh token of a user.
+// Used on password change/reset and when an account gets disabled.
+func RevokeUserTokens(username string) error {
+	if err := redisdatabase.Client.Incr(redisdatabase.Ctx, tokenVersionKey(username)).Err(); err != nil {
+		return err
+	}
+	return RevokeAllUserRefreshTokens(username)
+}
+
+// Key pattern: revoked_access:{jti} -> "1" with TTL = remaining lifetime of the access token.
+// Lets a single device log out without touching the other sessions of the user.
+func revokedAccessKey(jti string) string {
+	return "revoked_access:" + jti
+}
+
+// RevokeAccessToken denylists one access token until it expires.
+func RevokeAccessToken(claims *Claims) error {
+	if claims == nil || claims.ID == "" || claims.ExpiresAt == nil {
+		return nil
+	}
+	ttl := time.Until(claims.ExpiresAt.Time)
+	if ttl <= 0 {
+		return nil
+	}
+	return redisdatabase.Client.Set(redisdatabase.Ctx, revokedAccessKey(claims.ID), "1", ttl).Err()
+}
+
+// VerifyActiveToken verifies a token of tokenType like VerifyToken and also rejects it if it has been revoked.
+// Both revocation checks are done in one Redis round trip.
+func VerifyActiveToken(tokenString, tokenType string) (*Claims, error) {
+	claims, err := VerifyToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkTokenType(claims, tokenType); err != nil {
+		return nil, err
+	}
+
+	pipe := redisdatabase.Client.Pipeline()
+	verCmd := pipe.Get(redisdatabase.Ctx, tokenVersionKey(claims.Username))
+	var deniedCmd *redis.IntCmd
+	if claims.ID != "" {
+		deniedCmd = pipe.Exists(redisdatabase.Ctx, revokedAccessKey(claims.ID))
+	}
+	if _, err := pipe.Exec(redisdatabase.Ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	ver, err := verCmd.Int64()
+	if err == redis.Nil {
+		ver, err = 0, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if claims.TokenVersion != ver {
+		return nil, ErrTokenRevoked
+	}
+	if deniedCmd != nil && deniedCmd.Val() > 0 {
+		return nil, ErrTokenRevoked
+	}
+	return claims, nil
+}
@@ -0,0 +1,42 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHasPermission(t *testing.T) {
+	tests := []struct {
+		name  string
+		role  string
+		perms []Permission
+		want  bool
+	}{
+		{name: "Customer has no admin access", role: RoleCustomer, perms: []Permission{PermAdminAccess}, want: false},
+		{name: "Staff can update orders", role: RoleStaff, perms: []Permission{PermOrdersRead, PermOrdersWrite}, want: true},
+		{name: "Staff cannot edit products", role: RoleStaff, perms: []Permission{PermProductsWrite}, want: false},
+		{name: "Manager can read statistics", role: RoleManager, perms: []Permission{PermStatisticsRead}, want: true},
+		{name: "Manager cannot edit users", role: RoleManager, perms: []Permission{PermUsersWrite}, want: false},
+		{name: "Superadmin can edit users", role: RoleSuperAdmin, perms: []Permission{PermUsersRead, PermUsersWrite}, want: true},
+		{name: "Only superadmin reads the audit log", role: RoleManager, perms: []Permission{PermAuditRead}, want: false},
+		{name: "Superadmin reads the audit log", role: RoleSuperAdmin, perms: []Permission{PermAuditRead}, want: true},
+		{name: "Only superadmin manages API keys", role: RoleManager, perms: []Permission{PermAPIKeysManage}, want: false},
+		{name: "Superadmin manages API keys", role: RoleSuperAdmin, perms: []Permission{PermAPIKeysManage}, want: true},
+		{name: "Unknown role", role: "root", perms: []Permission{PermAdminAccess}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, HasPermission(tt.role, tt.perms...))
+		})
+	}
+}
+
+func TestCanAssignRole(t *testing.T) {
+	assert.True(t, CanAssignRole(RoleSuperAdmin, RoleManager))
+	assert.True(t, CanAssignRole(RoleManager, RoleManager))
+	assert.False(t, CanAssignRole(RoleManager, RoleSuperAdmin))
+	assert.False(t, CanAssignRole(RoleStaff, "root"))
+	assert.False(t, CanAssignRole("", RoleCustomer))
+}
@@ -0,0 +1,148 @@
+package auth
+
+import (
+	"GoodFood-BE/internal/dto"
+	redisdatabase "GoodFood-BE/internal/redis-database"
+	"sort"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// userSessions loads every live refresh record of a user keyed by jti.
+// Records saved before sessions were tracked have no SessionID, their jti is used instead.
+func userSessions(username string) (map[string]*dto.RefreshRecord, error) {
+	jtis, err := redisdatabase.Client.SMembers(redisdatabase.Ctx, "user_refresh:"+username).Result()
+	if err != nil {
+		return nil, err
+	}
+	records := make(map[string]*dto.RefreshRecord, len(jtis))
+	for _, jti := range jtis {
+		rec, err := GetRefreshRecord(jti)
+		if err != nil {
+			return nil, err
+		}
+		if rec == nil {
+			continue
+		}
+		if rec.SessionID == "" {
+			rec.SessionID = jti
+		}
+		records[jti] = rec
+	}
+	return records, nil
+}
+
+// PruneUserSessions removes jtis whose refresh record has expired from user_refresh:{username}.
+func PruneUserSessions(username string) error {
+	setKey := "user_refresh:" + username
+	jtis, err := redisdatabase.Client.SMembers(redisdatabase.Ctx, setKey).Result()
+	if err != nil {
+		return err
+	}
+	if len(jtis) == 0 {
+		return nil
+	}
+
+	pipe := redisdatabase.Client.Pipeline()
+	exists := make([]*redis.IntCmd, len(jtis))
+	for i, jti := range jtis {
+		exists[i] = pipe.Exists(redisdatabase.Ctx, "refresh:"+jti)
+	}
+	if _, err := pipe.Exec(redisdatabase.Ctx); err != nil {
+		return err
+	}
+
+	stale := []interface{}{}
+	for i, cmd := range exists {
+		if cmd.Val() == 0 {
+			stale = append(stale, jtis[i])
+		}
+	}
+	if len(stale) == 0 {
+		return nil
+	}
+	return redisdatabase.Client.SRem(redisdatabase.Ctx, setKey, stale...).Err()
+}
+
+// ListUserSessions returns the active sessions of a user, most recently used first.
+// currentSessionID marks the session of the caller, pass "" when listing for someone else.
+func ListUserSessions(username, currentSessionID string) ([]dto.SessionResponse, error) {
+	if err := PruneUserSessions(username); err != nil {
+		return nil, err
+	}
+	records, err := userSessions(username)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]dto.SessionResponse, 0, len(records))
+	for _, rec := range records {
+		sessions = append(sessions, dto.SessionResponse{
+			SessionID:  rec.SessionID,
+			UserAgent:  rec.UserAgent,
+			IP:         rec.IP,
+			CreatedAt:  rec.CreatedAt,
+			LastUsedAt: rec.LastUsedAt,
+			ExpiresAt:  rec.ExpiresAt,
+			Current:    currentSessionID != "" && rec.SessionID == currentSessionID,
+		})
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastUsedAt.After(sessions[j].LastUsedAt)
+	})
+	return sessions, nil
+}
+
+// RevokeUserSession revokes one session of a user. Returns false if the session does not exist.
+func RevokeUserSession(username, sessionID string) (bool, error) {
+	records, err := userSessions(username)
+	if err != nil {
+		return false, err
+	}
+	found := false
+	for jti, rec := range records {
+		if rec.SessionID == sessionID {
+			if err := RevokeRefreshToken(jti); err != nil {
+				return false, err
+			}
+			found = true
+		}
+	}
+	return found, nil
+}
+
+// RevokeOtherUserSessions revokes every session of a user except keepSessionID ("log out everywhere else").
+func RevokeOtherUserSessions(username, keepSessionID string) error {
+	records, err := userSessions(username)
+	if err != nil {
+		return err
+	}
+	for jti, rec := range records {
+		if rec.SessionID == keepSessionID {
+			continue
+		}
+		if err := RevokeRefreshToken(jti); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SessionIDFromRefreshToken returns the session a refresh token belongs to, or "" if it is invalid or revoked.
+func SessionIDFromRefreshToken(refreshToken string) string {
+	if refreshToken == "" {
+		return ""
+	}
+	claims, err := VerifyRefreshToken(refreshToken)
+	if err != nil || claims.ID == "" {
+		return ""
+	}
+	rec, err := GetRefreshRecord(claims.ID)
+	if err != nil || rec == nil || rec.TokenHash != HashToken(refreshToken) {
+		return ""
+	}
+	if rec.SessionID == "" {
+		return claims.ID
+	}
+	return rec.SessionID
+}
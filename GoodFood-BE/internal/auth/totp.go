@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// RFC 6238 parameters used by every authenticator app by default.
+const (
+	TOTPDigits = 6
+	TOTPPeriod = 30
+	TOTPIssuer = "GoodFood24h"
+	// totpSkew accepts codes from one step before and after the current one to absorb clock drift.
+	totpSkew = 1
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateTOTPSecret returns a random 160-bit secret encoded in base32, as expected by authenticator apps.
+func GenerateTOTPSecret() (string, error) {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(b), nil
+}
+
+// TOTPProvisioningURI builds the otpauth:// URI rendered as a QR code during enrollment.
+func TOTPProvisioningURI(accountName, secret string) string {
+	label := url.PathEscape(TOTPIssuer + ":" + accountName)
+	q := url.Values{}
+	q.Set("secret", secret)
+	q.Set("issuer", TOTPIssuer)
+	q.Set("algorithm", "SHA1")
+	q.Set("digits", fmt.Sprint(TOTPDigits))
+	q.Set("period", fmt.Sprint(TOTPPeriod))
+	return "otpauth://totp/" + label + "?" + q.Encode()
+}
+
+// totpStep returns the RFC 6238 time step of t.
+func totpStep(t time.Time) int64 {
+	return t.Unix() / TOTPPeriod
+}
+
+// hotp computes the RFC 4226 code of key for counter.
+func hotp(key []byte, counter int64, digits int) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(counter))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	mod := uint32(1)
+	for i := 0; i < digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", digits, value%mod)
+}
+
+// TOTPCode returns the code of secret at time t.
+func TOTPCode(secret string, t time.Time) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", err
+	}
+	return hotp(key, totpStep(t), TOTPDigits), nil
+}
+
+// ValidateTOTP checks code against secret around time t and returns the matched time step.
+// Steps at or before lastStep are refused so a code cannot be replayed.
+func ValidateTOTP(secret, code string, t time.Time, lastStep int64) (int64, bool) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil || len(code) != TOTPDigits {
+		return 0, false
+	}
+	current := totpStep(t)
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if step <= lastStep {
+			continue
+		}
+		if hmac.Equal([]byte(hotp(key, step, TOTPDigits)), []byte(code)) {
+			return step, true
+		}
+	}
+	return 0, false
+}
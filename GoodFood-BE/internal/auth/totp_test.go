@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// RFC 6238 appendix B, SHA1 seed, truncated to 8 digits.
+func TestHOTPMatchesRFC6238Vectors(t *testing.T) {
+	key := []byte("12345678901234567890")
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{unix: 59, want: "94287082"},
+		{unix: 1111111109, want: "07081804"},
+		{unix: 1234567890, want: "89005924"},
+		{unix: 2000000000, want: "69279037"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, hotp(key, tt.unix/TOTPPeriod, 8))
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+	now := time.Unix(1111111109, 0)
+	code, err := TOTPCode(secret, now)
+	assert.NoError(t, err)
+	assert.Equal(t, "081804", code)
+
+	step, ok := ValidateTOTP(secret, code, now, 0)
+	assert.True(t, ok)
+	assert.Equal(t, totpStep(now), step)
+
+	//Previous step is still accepted to absorb clock drift
+	_, ok = ValidateTOTP(secret, code, now.Add(TOTPPeriod*time.Second), 0)
+	assert.True(t, ok)
+
+	//Replayed and wrong codes are refused
+	_, ok = ValidateTOTP(secret, code, now, step)
+	assert.False(t, ok)
+	_, ok = ValidateTOTP(secret, "000000", now, 0)
+	assert.False(t, ok)
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	uri := TOTPProvisioningURI("user@example.com", "JBSWY3DPEHPK3PXP")
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/GoodFood24h:user@example.com?"))
+	assert.Contains(t, uri, "secret=JBSWY3DPEHPK3PXP")
+	assert.Contains(t, uri, "issuer=GoodFood24h")
+}
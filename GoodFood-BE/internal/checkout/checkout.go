@@ -0,0 +1,268 @@
+// Package checkout prices and places orders from the catalog. Clients only choose products, quantities,
+// an address and a payment method; every amount stored on an invoice is computed here.
+package checkout
+
+import (
+	"GoodFood-BE/internal/dto"
+	"GoodFood-BE/internal/money"
+	"GoodFood-BE/internal/order"
+	"GoodFood-BE/internal/payment"
+	"GoodFood-BE/internal/shipping"
+	"GoodFood-BE/models"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aarondl/null/v8"
+	"github.com/aarondl/sqlboiler/v4/boil"
+	"github.com/aarondl/sqlboiler/v4/queries/qm"
+)
+
+// Limits of one order.
+const (
+	MaxLines           = 50
+	MaxQuantityPerLine = 99
+	MaxNoteLength      = 255
+)
+
+var (
+	ErrEmptyOrder           = errors.New("the order has no products")
+	ErrTooManyLines         = fmt.Errorf("an order can have at most %d different products", MaxLines)
+	ErrInvalidQuantity      = fmt.Errorf("quantities must be between 1 and %d", MaxQuantityPerLine)
+	ErrProductUnavailable   = errors.New("product is unavailable")
+	ErrAddressNotFound      = errors.New("delivery address not found")
+	ErrInvalidPaymentMethod = errors.New("unknown payment method")
+	ErrNoteTooLong          = fmt.Errorf("the note can be at most %d characters", MaxNoteLength)
+)
+
+// Order is a placed order with its lines, pricing and the code of its payment method.
+type Order struct {
+	Invoice       *models.Invoice           `json:"invoice"`
+	Details       models.InvoiceDetailSlice `json:"invoiceDetails"`
+	Quote         *dto.CheckoutQuote        `json:"quote"`
+	PaymentMethod string                    `json:"paymentMethod"`
+}
+
+// normalizeItems merges the lines of a same product and validates quantities. The order of first appearance is kept.
+func normalizeItems(items []dto.CheckoutItem) ([]dto.CheckoutItem, error) {
+	if len(items) == 0 {
+		return nil, ErrEmptyOrder
+	}
+	merged := []dto.CheckoutItem{}
+	index := map[int]int{}
+	for _, item := range items {
+		if item.Quantity < 1 || item.Quantity > MaxQuantityPerLine {
+			return nil, ErrInvalidQuantity
+		}
+		if i, ok := index[item.ProductID]; ok {
+			merged[i].Quantity += item.Quantity
+			if merged[i].Quantity > MaxQuantityPerLine {
+				return nil, ErrInvalidQuantity
+			}
+			continue
+		}
+		index[item.ProductID] = len(merged)
+		merged = append(merged, item)
+	}
+	if len(merged) > MaxLines {
+		return nil, ErrTooManyLines
+	}
+	return merged, nil
+}
+
+// priceItems prices and weighs normalized items with the given products, without shipping.
+// Missing and inactive products are rejected.
+func priceItems(items []dto.CheckoutItem, products map[int]*models.Product) (*dto.CheckoutQuote, error) {
+	q := &dto.CheckoutQuote{Lines: make([]dto.CheckoutLine, 0, len(items))}
+	var subtotal money.Money
+	for _, item := range items {
+		product, ok := products[item.ProductID]
+		if !ok || !product.Status {
+			return nil, fmt.Errorf("%w: %d", ErrProductUnavailable, item.ProductID)
+		}
+		lineTotal, err := product.Price.Mul(int64(item.Quantity))
+		if err != nil {
+			return nil, err
+		}
+		if subtotal, err = subtotal.Add(lineTotal); err != nil {
+			return nil, err
+		}
+		q.WeightGrams += lineGrams(product, item.Quantity)
+		q.Lines = append(q.Lines, dto.CheckoutLine{
+			ProductID:   product.ProductID,
+			ProductName: product.ProductName,
+			Price:       product.Price,
+			Quantity:    item.Quantity,
+			LineTotal:   lineTotal,
+		})
+	}
+	q.Subtotal = subtotal
+	q.TotalPrice = subtotal
+	return q, nil
+}
+
+// quote prices items with the current catalog, without shipping. Inside a transaction, lock makes the products
+// read-only until commit so a price cannot change between pricing and insertion.
+func quote(ctx context.Context, exec boil.ContextExecutor, items []dto.CheckoutItem, lock bool) ([]dto.CheckoutItem, *dto.CheckoutQuote, error) {
+	items, err := normalizeItems(items)
+	if err != nil {
+		return nil, nil, err
+	}
+	ids := make([]interface{}, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.ProductID)
+	}
+	mods := []qm.QueryMod{qm.WhereIn("\"productID\" IN ?", ids...)}
+	if lock {
+		mods = append(mods, qm.For("SHARE"))
+	}
+	found, err := models.Products(mods...).All(ctx, exec)
+	if err != nil {
+		return nil, nil, err
+	}
+	products := make(map[int]*models.Product, len(found))
+	for _, p := range found {
+		products[p.ProductID] = p
+	}
+	q, err := priceItems(items, products)
+	if err != nil {
+		return nil, nil, err
+	}
+	return items, q, nil
+}
+
+// Quote prices an order of the account with the current catalog and shipping rates without placing it.
+// Without an address, shipping is priced for the default zone.
+func Quote(ctx context.Context, accountID int, req dto.CheckoutRequest) (*dto.CheckoutQuote, error) {
+	exec := boil.GetContextDB()
+	dest := shipping.Destination{}
+	if req.AddressID != 0 {
+		var err error
+		if _, dest, err = addressDestination(ctx, exec, accountID, req.AddressID); err != nil {
+			return nil, err
+		}
+	}
+	_, q, err := quote(ctx, exec, req.Items, false)
+	if err != nil {
+		return nil, err
+	}
+	s, err := shipping.QuoteFor(ctx, exec, dest, q.WeightGrams, q.Subtotal)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyShipping(q, s); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+// receiveAddress formats an address the way it is printed on the invoice.
+func receiveAddress(address *models.Address) string {
+	parts := []string{}
+	for _, part := range []string{address.SpecificAddress, address.Address} {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// PlaceOrder prices req from the catalog and, in one transaction, inserts the invoice with its details
+// and removes the ordered products from the cart of the account.
+func PlaceOrder(ctx context.Context, accountID int, req dto.CheckoutRequest) (*Order, error) {
+	note := strings.TrimSpace(req.Note)
+	if len([]rune(note)) > MaxNoteLength {
+		return nil, ErrNoteTooLong
+	}
+
+	tx, err := boil.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	provider, err := payment.ResolveMethod(ctx, tx, req.PaymentMethod)
+	if errors.Is(err, payment.ErrUnknownMethod) {
+		return nil, ErrInvalidPaymentMethod
+	}
+	if err != nil {
+		return nil, err
+	}
+	address, dest, err := addressDestination(ctx, tx, accountID, req.AddressID)
+	if err != nil {
+		return nil, err
+	}
+	items, q, err := quote(ctx, tx, req.Items, true)
+	if err != nil {
+		return nil, err
+	}
+	s, err := shipping.QuoteFor(ctx, tx, dest, q.WeightGrams, q.Subtotal)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyShipping(q, s); err != nil {
+		return nil, err
+	}
+	placedID, err := order.StatusID(ctx, tx, order.StatusPlaced)
+	if err != nil {
+		return nil, err
+	}
+
+	invoice := &models.Invoice{
+		CreatedAt:       time.Now(),
+		ShippingFee:     q.ShippingFee,
+		TotalPrice:      q.TotalPrice,
+		PaymentMethod:   !provider.Online(),
+		Status:          false,
+		ReceiveAddress:  receiveAddress(address),
+		ReceiveName:     address.FullName,
+		ReceivePhone:    address.PhoneNumber,
+		AccountID:       accountID,
+		InvoiceStatusID: placedID,
+	}
+	if note != "" {
+		invoice.Note = null.StringFrom(note)
+	}
+	if err := invoice.Insert(ctx, tx, boil.Infer()); err != nil {
+		return nil, err
+	}
+	if err := payment.SetInvoiceMethod(ctx, tx, invoice.InvoiceID, provider); err != nil {
+		return nil, err
+	}
+	if err := order.RecordDestination(ctx, tx, invoice.InvoiceID, address); err != nil {
+		return nil, err
+	}
+	placedBy := order.Actor{Role: order.RoleCustomer, AccountID: null.IntFrom(accountID)}
+	if err := order.RecordPlaced(ctx, tx, invoice, placedBy); err != nil {
+		return nil, err
+	}
+
+	details := make(models.InvoiceDetailSlice, 0, len(q.Lines))
+	productIDs := make([]int, 0, len(items))
+	for _, line := range q.Lines {
+		detail := &models.InvoiceDetail{
+			Quantity:  line.Quantity,
+			Price:     line.Price,
+			ProductID: line.ProductID,
+			InvoiceID: invoice.InvoiceID,
+		}
+		if err := detail.Insert(ctx, tx, boil.Infer()); err != nil {
+			return nil, err
+		}
+		details = append(details, detail)
+		productIDs = append(productIDs, line.ProductID)
+	}
+
+	if _, err := models.CartDetails(
+		models.CartDetailWhere.AccountID.EQ(accountID),
+		models.CartDetailWhere.ProductID.IN(productIDs),
+	).DeleteAll(ctx, tx); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &Order{Invoice: invoice, Details: details, Quote: q, PaymentMethod: provider.Method()}, nil
+}
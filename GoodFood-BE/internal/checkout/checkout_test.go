@@ -0,0 +1,63 @@
+package checkout
+
+import (
+	"GoodFood-BE/internal/dto"
+	"GoodFood-BE/internal/money"
+	"GoodFood-BE/internal/shipping"
+	"GoodFood-BE/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeItems(t *testing.T) {
+	items, err := normalizeItems([]dto.CheckoutItem{
+		{ProductID: 2, Quantity: 1},
+		{ProductID: 1, Quantity: 3},
+		{ProductID: 2, Quantity: 4},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []dto.CheckoutItem{{ProductID: 2, Quantity: 5}, {ProductID: 1, Quantity: 3}}, items)
+
+	_, err = normalizeItems(nil)
+	assert.ErrorIs(t, err, ErrEmptyOrder)
+	_, err = normalizeItems([]dto.CheckoutItem{{ProductID: 1, Quantity: 0}})
+	assert.ErrorIs(t, err, ErrInvalidQuantity)
+	_, err = normalizeItems([]dto.CheckoutItem{{ProductID: 1, Quantity: -2}})
+	assert.ErrorIs(t, err, ErrInvalidQuantity)
+	_, err = normalizeItems([]dto.CheckoutItem{{ProductID: 1, Quantity: MaxQuantityPerLine}, {ProductID: 1, Quantity: 1}})
+	assert.ErrorIs(t, err, ErrInvalidQuantity, "merged lines are capped too")
+}
+
+func TestPriceItems(t *testing.T) {
+	products := map[int]*models.Product{
+		1: {ProductID: 1, ProductName: "Pho", Price: 45000, Status: true, Weight: 650.5},
+		2: {ProductID: 2, ProductName: "Banh mi", Price: 20000, Status: true, Weight: 200},
+		3: {ProductID: 3, ProductName: "Retired", Price: 10000, Status: false},
+	}
+
+	quote, err := priceItems([]dto.CheckoutItem{{ProductID: 1, Quantity: 2}, {ProductID: 2, Quantity: 1}}, products)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Len(t, quote.Lines, 2)
+	assert.Equal(t, money.VND(90000), quote.Lines[0].LineTotal)
+	assert.Equal(t, money.VND(110000), quote.Subtotal)
+	assert.Equal(t, 1501, quote.WeightGrams)
+	assert.Equal(t, money.VND(110000), quote.TotalPrice, "shipping is added by applyShipping")
+
+	assert.NoError(t, applyShipping(quote, &shipping.Quote{Zone: "INNER", Fee: 15000}))
+	assert.Equal(t, "INNER", quote.ShippingZone)
+	assert.Equal(t, money.VND(15000), quote.ShippingFee)
+	assert.Equal(t, money.VND(125000), quote.TotalPrice)
+
+	_, err = priceItems([]dto.CheckoutItem{{ProductID: 3, Quantity: 1}}, products)
+	assert.ErrorIs(t, err, ErrProductUnavailable, "inactive products cannot be bought")
+	_, err = priceItems([]dto.CheckoutItem{{ProductID: 42, Quantity: 1}}, products)
+	assert.ErrorIs(t, err, ErrProductUnavailable)
+}
+
+func TestReceiveAddress(t *testing.T) {
+	assert.Equal(t, "12 Le Loi, Ben Nghe, Quan 1, HCM", receiveAddress(&models.Address{SpecificAddress: "12 Le Loi", Address: "Ben Nghe, Quan 1, HCM"}))
+	assert.Equal(t, "Ben Nghe", receiveAddress(&models.Address{SpecificAddress: " ", Address: "Ben Nghe"}))
+}
@@ -0,0 +1,64 @@
+package checkout
+
+import (
+	"GoodFood-BE/internal/dto"
+	"GoodFood-BE/internal/shipping"
+	"GoodFood-BE/models"
+	"context"
+	"database/sql"
+	"errors"
+	"math"
+
+	"github.com/aarondl/sqlboiler/v4/boil"
+)
+
+// lineGrams returns the weight of quantity units of product. product.weight is in grams.
+func lineGrams(product *models.Product, quantity int) int {
+	return int(math.Round(float64(product.Weight) * float64(quantity)))
+}
+
+// addressDestination loads an address of the account and returns where it is.
+func addressDestination(ctx context.Context, exec boil.ContextExecutor, accountID, addressID int) (*models.Address, shipping.Destination, error) {
+	address, err := models.Addresses(
+		models.AddressWhere.AddressID.EQ(addressID),
+		models.AddressWhere.AccountID.EQ(accountID),
+	).One(ctx, exec)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, shipping.Destination{}, ErrAddressNotFound
+	}
+	if err != nil {
+		return nil, shipping.Destination{}, err
+	}
+	dest := shipping.Destination{ProvinceID: address.ProvinceID, DistrictID: address.DistrictID, WardID: address.WardID}
+	return address, dest, nil
+}
+
+// applyShipping adds the shipping fee of s to q.
+func applyShipping(q *dto.CheckoutQuote, s *shipping.Quote) error {
+	total, err := q.Subtotal.Add(s.Fee)
+	if err != nil {
+		return err
+	}
+	q.ShippingZone = s.Zone
+	q.ShippingFee = s.Fee
+	q.TotalPrice = total
+	return nil
+}
+
+// ShippingQuote prices the delivery of items to an address of the account, or to the area named in req
+// when it has no address.
+func ShippingQuote(ctx context.Context, accountID int, req dto.ShippingQuoteRequest) (*shipping.Quote, error) {
+	exec := boil.GetContextDB()
+	dest := shipping.Destination{ProvinceID: req.ProvinceID, DistrictID: req.DistrictID, WardID: req.WardID}
+	if req.AddressID != 0 {
+		var err error
+		if _, dest, err = addressDestination(ctx, exec, accountID, req.AddressID); err != nil {
+			return nil, err
+		}
+	}
+	_, q, err := quote(ctx, exec, req.Items, false)
+	if err != nil {
+		return nil, err
+	}
+	return shipping.QuoteFor(ctx, exec, dest, q.WeightGrams, q.Subtotal)
+}
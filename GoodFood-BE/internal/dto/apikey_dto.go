@@ -0,0 +1,18 @@
+package dto
+
+import "time"
+
+// CreateAPIKeyRequest is the body of the admin API key creation endpoint. A nil ExpiresAt never expires
+// and an empty AllowedIPs accepts any address.
+type CreateAPIKeyRequest struct {
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	AllowedIPs []string   `json:"allowedIPs"`
+	ExpiresAt  *time.Time `json:"expiresAt"`
+}
+
+// APIKeyUsageDay is the number of requests made with an API key on one day.
+type APIKeyUsageDay struct {
+	Day      string `json:"day"`
+	Requests int64  `json:"requests"`
+}
@@ -0,0 +1,24 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/aarondl/null/v8"
+)
+
+// AuditLogEntry is one row of audit_log as returned by the admin audit API.
+type AuditLogEntry struct {
+	AuditLogID    int64       `boil:"auditLogID" json:"auditLogID"`
+	ActorID       null.Int    `boil:"actorID" json:"actorID"`
+	ActorUsername null.String `boil:"actorUsername" json:"actorUsername"`
+	Action        string      `boil:"action" json:"action"`
+	EntityType    string      `boil:"entityType" json:"entityType"`
+	EntityID      null.String `boil:"entityID" json:"entityID"`
+	Metadata      null.JSON   `boil:"metadata" json:"metadata"`
+	Before        null.JSON   `boil:"before" json:"before"`
+	After         null.JSON   `boil:"after" json:"after"`
+	Changes       null.JSON   `boil:"changes" json:"changes"`
+	IP            null.String `boil:"ip" json:"ip"`
+	UserAgent     null.String `boil:"userAgent" json:"userAgent"`
+	CreatedAt     time.Time   `boil:"createdAt" json:"createdAt"`
+}
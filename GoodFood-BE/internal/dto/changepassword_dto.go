@@ -1,11 +1,15 @@
 package dto
 
 type ChangePassRequest struct{
-	AccountID int `json:"accountID"`
 	OldPassword string `json:"oldPassword"`
 	NewPassword string `json:"newPassword"`
 	ConfirmPassword string `json:"confirmPassword"`
 }
+// SetPasswordRequest is the body used by accounts created through a social login to add a password.
+type SetPasswordRequest struct{
+	NewPassword string `json:"newPassword"`
+	ConfirmPassword string `json:"confirmPassword"`
+}
 type ChangePassErr struct{
 	ErrOldPassword string `json:"errOldPassword"`
 	ErrNewPassword string `json:"errNewPassword"`
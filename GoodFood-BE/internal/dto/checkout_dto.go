@@ -0,0 +1,48 @@
+package dto
+
+import "GoodFood-BE/internal/money"
+
+// CheckoutItem is one product of a checkout request.
+type CheckoutItem struct {
+	ProductID int `json:"productID"`
+	Quantity  int `json:"quantity"`
+}
+
+// CheckoutRequest is everything the client decides about an order. Prices, shipping and totals are computed by the server.
+// PaymentMethod is the code of an enabled payment method, such as "COD" or "VNPAY"; "ONLINE" is still accepted for VNPay.
+type CheckoutRequest struct {
+	Items         []CheckoutItem `json:"items"`
+	AddressID     int            `json:"addressID"`
+	PaymentMethod string         `json:"paymentMethod"`
+	Note          string         `json:"note"`
+}
+
+// CheckoutLine is one priced line of an order.
+type CheckoutLine struct {
+	ProductID   int         `json:"productID"`
+	ProductName string      `json:"productName"`
+	Price       money.Money `json:"price"`
+	Quantity    int         `json:"quantity"`
+	LineTotal   money.Money `json:"lineTotal"`
+}
+
+// CheckoutQuote is the server-side pricing of an order. WeightGrams is the weight the shipping fee is computed from,
+// ShippingZone the shipping zone of the delivery address.
+type CheckoutQuote struct {
+	Lines        []CheckoutLine `json:"lines"`
+	Subtotal     money.Money    `json:"subtotal"`
+	WeightGrams  int            `json:"weightGrams"`
+	ShippingZone string         `json:"shippingZone"`
+	ShippingFee  money.Money    `json:"shippingFee"`
+	TotalPrice   money.Money    `json:"totalPrice"`
+}
+
+// ShippingQuoteRequest asks for the shipping fee of products delivered to an address of the caller,
+// or to the given province, district and ward when AddressID is 0.
+type ShippingQuoteRequest struct {
+	Items      []CheckoutItem `json:"items"`
+	AddressID  int            `json:"addressID"`
+	ProvinceID int            `json:"provinceID"`
+	DistrictID int            `json:"districtID"`
+	WardID     int            `json:"wardID"`
+}
@@ -1,15 +1,17 @@
 package dto
 
+import "GoodFood-BE/internal/money"
+
 type DashboardValues struct {
 	TotalProductSold int     `boil:"total_product_sold" json:"totalProductSold"`
-	TotalIncome      float64 `boil:"total_income" json:"totalIncome"`
+	TotalIncome      money.Money `boil:"total_income" json:"totalIncome"`
 	TotalUser        int     `boil:"total_user" json:"totalUser"`
 	TotalInvoice     int     `boil:"total_invoice" json:"totalInvoice"`
 }
 
 type MonthlyIncome struct {
 	Month       int     `boil:"month" json:"month"`
-	TotalIncome float64 `boil:"total_income" json:"totalIncome"`
+	TotalIncome money.Money `boil:"total_income" json:"totalIncome"`
 }
 
 type PieChart struct {
@@ -18,6 +20,6 @@ type PieChart struct {
 }
 
 type BarChart struct {
-	Month int     `boil:"month" json:"month"`
-	Value float64 `boil:"value" json:"value"`
+	Month int         `boil:"month" json:"month"`
+	Value money.Money `boil:"value" json:"value"`
 }
\ No newline at end of file
@@ -1,6 +1,7 @@
 package dto
 
 import (
+	"GoodFood-BE/internal/money"
 	"GoodFood-BE/models"
 
 	"github.com/aarondl/null/v8"
@@ -20,7 +21,9 @@ type InvoiceResponse struct{
 }
 
 //UpdateInvoiceStruct struct represents invoice metrics used for updating invoices.
+//Status is the code of the target status ("confirmed", "cancelled", ...); the exact StatusName is still accepted instead.
 type UpdateInvoiceStruct struct{
+	Status string `json:"status"`
 	StatusName string `json:"statusName"`
 	CancelReason null.String `json:"cancelReason"`
 }
@@ -31,7 +34,7 @@ type InvoiceDetailResponse struct{
 	InvoiceDetailID int     `boil:"invoiceDetailID"`
 	InvoiceID       int     `boil:"invoiceID"`
 	ProductID       int     `boil:"productID"`
-	Price           float64 `boil:"price"`
+	Price           money.Money `boil:"price"`
 	Quantity        int     `boil:"quantity"`
 
 	ProductName string `boil:"food"`
@@ -39,10 +42,3 @@ type InvoiceDetailResponse struct{
 	ReceivePhone string `boil:"phone"`
 	ReceiveAddress string `boil:"address"`
 }
-
-//InvoicePayload represents the payload received from front-end at Order payment module
-type InvoicePayload struct{
-	Invoice models.Invoice `json:"invoice"`
-	InvoiceDetails []models.InvoiceDetail `json:"invoiceDetails"`
-	Products []models.Product `json:"product"`
-}
\ No newline at end of file
@@ -0,0 +1,31 @@
+package dto
+
+// MFACodeRequest carries a TOTP code or a recovery code.
+type MFACodeRequest struct {
+	Code string `json:"code"`
+}
+
+// MFADisableRequest requires both factors to turn two-factor off.
+type MFADisableRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+// MFALoginRequest completes a login that returned mfaRequired.
+type MFALoginRequest struct {
+	MFAToken string `json:"mfaToken"`
+	Code     string `json:"code"`
+}
+
+// MFASetupResponse is shown once while enrolling; ProvisioningURI is rendered as a QR code by the client.
+type MFASetupResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioningURI"`
+}
+
+// MFAStatusResponse describes the two-factor state of the current account.
+type MFAStatusResponse struct {
+	Enabled                bool `json:"enabled"`
+	Required               bool `json:"required"`
+	RemainingRecoveryCodes int  `json:"remainingRecoveryCodes"`
+}
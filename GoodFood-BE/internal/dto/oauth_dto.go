@@ -0,0 +1,33 @@
+package dto
+
+import "time"
+
+// OAuthProfile is the identity a social login provider vouched for.
+// EmailVerified must only be true when the provider asserts it, it allows linking to an existing account by email.
+type OAuthProfile struct {
+	Provider       string
+	ProviderUserID string
+	Email          string
+	EmailVerified  bool
+	FullName       string
+	AvatarURL      string
+}
+
+// OIDCProviderInfo describes a configured OpenID Connect provider to the web client.
+type OIDCProviderInfo struct {
+	Name     string `json:"name"`
+	Issuer   string `json:"issuer"`
+	ClientID string `json:"clientID"`
+}
+
+// LinkedIdentity is a social login linked to the current account.
+type LinkedIdentity struct {
+	Provider string    `json:"provider"`
+	LinkedAt time.Time `json:"linkedAt"`
+}
+
+// LoginMethodsResponse lists how the current account can sign in.
+type LoginMethodsResponse struct {
+	HasPassword bool             `json:"hasPassword"`
+	Providers   []LinkedIdentity `json:"providers"`
+}
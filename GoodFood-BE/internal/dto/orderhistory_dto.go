@@ -1,6 +1,9 @@
 package dto
 
-import "GoodFood-BE/models"
+import (
+	"GoodFood-BE/internal/money"
+	"GoodFood-BE/models"
+)
 
 //InvoiceList struct represents the response body returned to front-end.
 type InvoiceList struct{
@@ -8,7 +11,7 @@ type InvoiceList struct{
 	TotalProducts int `boil:"total_products" json:"totalProducts"`
 	Address string `boil:"address" json:"address"`
 	Status bool `boil:"status" json:"status"`
-	TotalMoney float64 `boil:"total_money" json:"totalMoney"`
+	TotalMoney money.Money `boil:"total_money" json:"totalMoney"`
 	CancelReason string `boil:"cancel_reason" json:"cancelReason"`
 }
 
@@ -18,7 +21,7 @@ type InvoiceDetailStruct struct{
 	Image string `boil:"image" json:"image"`
 	Product models.Product `boil:"product" json:"product"`
 	Quantity int `boil:"quantity" json:"quantity"`
-	TotalMoney float64 `boil:"total_money" json:"totalMoney"`
-	ShippingFee float64 `boil:"shipping_fee" json:"shippingFee"`
+	TotalMoney money.Money `boil:"total_money" json:"totalMoney"`
+	ShippingFee money.Money `boil:"shipping_fee" json:"shippingFee"`
 	ReviewCheck bool `json:"reviewCheck"`
 }
\ No newline at end of file
@@ -0,0 +1,18 @@
+package dto
+
+import "GoodFood-BE/internal/money"
+
+// RefundInvoiceRequest is an admin refund of an online payment. Amount is in VND; 0 refunds everything
+// not refunded yet. TransactionID 0 refunds the latest successful payment of the invoice.
+type RefundInvoiceRequest struct {
+	TransactionID int         `json:"transactionID"`
+	Amount        money.Money `json:"amount"`
+}
+
+// ResolveRefundRequest settles a pending refund the payment provider never answered for. Status is "succeeded"
+// or "failed", as the provider reports it; Message is kept with the refund.
+type ResolveRefundRequest struct {
+	RefundID int    `json:"refundID"`
+	Status   string `json:"status"`
+	Message  string `json:"message"`
+}
@@ -0,0 +1,9 @@
+package dto
+
+// DeleteAccountRequest re-authenticates the owner before their account is deleted.
+// Password is required for accounts with a local password and MFACode when two-factor is enabled.
+type DeleteAccountRequest struct {
+	Password string `json:"password"`
+	MFACode  string `json:"mfaCode"`
+	Confirm  string `json:"confirm"`
+}
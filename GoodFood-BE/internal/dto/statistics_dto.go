@@ -1,8 +1,10 @@
 package dto
 
+import "GoodFood-BE/internal/money"
+
 // StatisticsResponse represents the aggregated sales and revenue grouped by product type.
 type StatisticsResponse struct{
 	ProductType string `boil:"product_type" json:"productType"`
 	TotalSale int64 `boil:"total_sale" json:"totalSale"`
-	TotalRevenue float64 `boil:"total_revenue" json:"totalRevenue"`
+	TotalRevenue money.Money `boil:"total_revenue" json:"totalRevenue"`
 }
\ No newline at end of file
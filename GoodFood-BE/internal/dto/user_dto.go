@@ -17,12 +17,34 @@ type UserError struct {
 	ErrPassword string `json:"errPassword"`
 }
 
+// OAuthLoginStruct is the body of the social login and link endpoints.
+// OpenID Connect providers need IDToken, AccessToken is optional and used to read the userinfo endpoint.
+// For compatibility an ID token sent alone in AccessToken is accepted too.
 type OAuthLoginStruct struct {
 	AccessToken string `json:"accessToken"`
+	IDToken     string `json:"idToken"`
 }
 
+// Password reset methods and the channels a one-time code can be delivered through.
+const (
+	ResetMethodLink = "link"
+	ResetMethodOTP  = "otp"
+	OTPChannelEmail = "email"
+	OTPChannelSMS   = "sms"
+)
+
+// ForgotPassStruct is the body of the forgot password endpoints.
+// Method defaults to "link" and Channel to "email"; the SMS channel identifies the account by PhoneNumber.
 type ForgotPassStruct struct {
-	Email   string `json:"email"`
+	Email       string `json:"email"`
+	PhoneNumber string `json:"phoneNumber"`
+	Method      string `json:"method"`
+	Channel     string `json:"channel"`
+	CodeOTP     string `json:"codeOTP"`
+}
+
+// PhoneVerifyRequest is the body used to confirm a pending phone number change.
+type PhoneVerifyRequest struct {
 	CodeOTP string `json:"codeOTP"`
 }
 
@@ -30,7 +52,6 @@ type ResetPass struct {
 	Token       string `json:"token"`
 	NewPass     string `json:"newPass"`
 	ConfirmPass string `json:"confirmPass"`
-	Email       string `json:"email"`
 }
 
 type ContactResponse struct {
@@ -40,10 +61,25 @@ type ContactResponse struct {
 }
 
 // RefreshRecord struct represents the refresh token caching structure.
+// SessionID and CreatedAt stay the same across token rotations, LastUsedAt is bumped on every rotation.
 type RefreshRecord struct {
-	Username  string    `json:"username"`
-	TokenHash string    `json:"token_hash"`
-	ExpiresAt time.Time `json:"expires_at"`
-	UserAgent string    `json:"user_agent,omitempty"`
-	IP        string    `json:"ip,omitempty"`
+	Username   string    `json:"username"`
+	SessionID  string    `json:"session_id,omitempty"`
+	TokenHash  string    `json:"token_hash"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+	IP         string    `json:"ip,omitempty"`
+}
+
+// SessionResponse struct represents one active session (device) of a user.
+type SessionResponse struct {
+	SessionID  string    `json:"sessionID"`
+	UserAgent  string    `json:"userAgent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastUsedAt time.Time `json:"lastUsedAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+	Current    bool      `json:"current"`
 }
@@ -0,0 +1,135 @@
+// Package idempotency lets clients retry unsafe requests without repeating their effect. A request carrying an
+// Idempotency-Key header is processed once per caller and key; retries with the same method, URL and body get the
+// first response again, and a key reused for a different request is refused.
+package idempotency
+
+import (
+	"GoodFood-BE/internal/auth"
+	"GoodFood-BE/internal/service"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Headers of idempotent requests and replayed responses.
+const (
+	HeaderKey      = "Idempotency-Key"
+	HeaderReplayed = "Idempotent-Replayed"
+)
+
+// MaxKeyLength bounds the Idempotency-Key header. A UUID is the expected value.
+const MaxKeyLength = 255
+
+// DefaultTTLHours is how long keys are remembered when IDEMPOTENCY_TTL_HOURS is not set.
+const DefaultTTLHours = 24
+
+// LockTTL is how long a key is held while its first request runs. The key is kept for the whole TTL once
+// the request completes, so a request that dies midway only blocks retries for LockTTL.
+const LockTTL = time.Minute
+
+// TTL returns how long keys are remembered, from IDEMPOTENCY_TTL_HOURS.
+func TTL() time.Duration {
+	hours := DefaultTTLHours
+	if v := os.Getenv("IDEMPOTENCY_TTL_HOURS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			hours = n
+		}
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+// Middleware makes the routes it guards idempotent, with keys kept in Redis for TTL.
+// It goes after the authentication middleware so keys are scoped to the caller.
+func Middleware(c *fiber.Ctx) error {
+	return handle(c, RedisStore{}, TTL())
+}
+
+// New returns the middleware backed by store, remembering keys for ttl.
+func New(store Store, ttl time.Duration) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return handle(c, store, ttl)
+	}
+}
+
+// scope identifies the caller, so two callers choosing the same key do not see each other's responses.
+func scope(c *fiber.Ctx) string {
+	if account := auth.GetAccount(c); account != nil {
+		return fmt.Sprintf("account=%d", account.AccountID)
+	}
+	if apiKey := auth.GetAPIKey(c); apiKey != nil {
+		return "apikey=" + apiKey.DisplayPrefix()
+	}
+	return "ip=" + c.IP()
+}
+
+// fingerprint hashes what makes two requests the same: method, URL with its query string, and body.
+func fingerprint(c *fiber.Ctx) string {
+	h := sha256.New()
+	h.Write([]byte(c.Method() + "\n" + c.OriginalURL() + "\n"))
+	h.Write(c.Body())
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func handle(c *fiber.Ctx, store Store, ttl time.Duration) error {
+	key := strings.TrimSpace(c.Get(HeaderKey))
+	if key == "" {
+		return c.Next()
+	}
+	if len(key) > MaxKeyLength {
+		return service.SendError(c, 400, fmt.Sprintf("%s can be at most %d characters", HeaderKey, MaxKeyLength))
+	}
+	storeKey := "idempotency:" + scope(c) + ":" + key
+	fp := fingerprint(c)
+
+	existing, err := store.Reserve(c.Context(), storeKey, &Record{Fingerprint: fp}, LockTTL)
+	if err != nil {
+		return service.SendError(c, 500, err.Error())
+	}
+	if existing != nil {
+		if existing.Fingerprint != fp {
+			return service.SendError(c, 409, HeaderKey+" was already used with a different request")
+		}
+		if !existing.Completed {
+			return service.SendError(c, 409, "A request with this "+HeaderKey+" is still being processed")
+		}
+		c.Set(HeaderReplayed, "true")
+		if existing.ContentType != "" {
+			c.Set(fiber.HeaderContentType, existing.ContentType)
+		}
+		return c.Status(existing.Status).Send(existing.Body)
+	}
+
+	if err := c.Next(); err != nil {
+		//The error handler writes the response later, nothing to replay
+		if releaseErr := store.Release(c.Context(), storeKey); releaseErr != nil {
+			log.Printf("idempotency: release %s: %v", storeKey, releaseErr)
+		}
+		return err
+	}
+	status := c.Response().StatusCode()
+	if status >= 500 {
+		//Server errors are not final, the client may retry with the same key
+		if err := store.Release(c.Context(), storeKey); err != nil {
+			log.Printf("idempotency: release %s: %v", storeKey, err)
+		}
+		return nil
+	}
+	record := &Record{
+		Fingerprint: fp,
+		Completed:   true,
+		Status:      status,
+		ContentType: string(c.Response().Header.ContentType()),
+		Body:        append([]byte(nil), c.Response().Body()...),
+	}
+	if err := store.Complete(c.Context(), storeKey, record, ttl); err != nil {
+		log.Printf("idempotency: save %s: %v", storeKey, err)
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore is a Store without expiry. ttls holds the expiry each key was last given.
+type memoryStore struct {
+	mu      sync.Mutex
+	records map[string]Record
+	ttls    map[string]time.Duration
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{records: map[string]Record{}, ttls: map[string]time.Duration{}}
+}
+
+func (s *memoryStore) Reserve(_ context.Context, key string, r *Record, ttl time.Duration) (*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if existing, ok := s.records[key]; ok {
+		return &existing, nil
+	}
+	s.records[key] = *r
+	s.ttls[key] = ttl
+	return nil, nil
+}
+
+func (s *memoryStore) Complete(_ context.Context, key string, r *Record, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[key] = *r
+	s.ttls[key] = ttl
+	return nil
+}
+
+func (s *memoryStore) Release(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, key)
+	return nil
+}
+
+// newApp returns an app whose POST /orders creates a numbered order, or fails with the status in ?fail=.
+func newApp(store Store) (*fiber.App, *int) {
+	calls := 0
+	app := fiber.New()
+	app.Post("/orders", New(store, time.Hour), func(c *fiber.Ctx) error {
+		calls++
+		if status := c.QueryInt("fail", 0); status != 0 {
+			return c.Status(status).JSON(fiber.Map{"status": "error"})
+		}
+		return c.JSON(fiber.Map{"status": "Success", "data": calls})
+	})
+	return app, &calls
+}
+
+type response struct {
+	status   int
+	replayed string
+	body     map[string]interface{}
+}
+
+func send(t *testing.T, app *fiber.App, url, key, body string) response {
+	req := httptest.NewRequest("POST", url, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set(HeaderKey, key)
+	}
+	resp, err := app.Test(req, -1)
+	require.NoError(t, err)
+	raw, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	r := response{status: resp.StatusCode, replayed: resp.Header.Get(HeaderReplayed)}
+	require.NoError(t, json.Unmarshal(raw, &r.body))
+	return r
+}
+
+func TestReplaysFirstResponse(t *testing.T) {
+	store := newMemoryStore()
+	app, calls := newApp(store)
+
+	first := send(t, app, "/orders", "key-1", `{"items":[1]}`)
+	retry := send(t, app, "/orders", "key-1", `{"items":[1]}`)
+
+	assert.Equal(t, 200, retry.status)
+	assert.Equal(t, first.body, retry.body)
+	assert.Equal(t, "", first.replayed)
+	assert.Equal(t, "true", retry.replayed)
+	assert.Equal(t, 1, *calls, "the handler runs once")
+	assert.Equal(t, time.Hour, store.ttls["idempotency:ip=0.0.0.0:key-1"], "a completed request keeps its key for the whole window")
+
+	other := send(t, app, "/orders", "key-2", `{"items":[1]}`)
+	assert.Equal(t, float64(2), other.body["data"], "another key is another request")
+}
+
+func TestRequestsWithoutKeyAreNotDeduplicated(t *testing.T) {
+	app, calls := newApp(newMemoryStore())
+	send(t, app, "/orders", "", `{}`)
+	send(t, app, "/orders", "", `{}`)
+	assert.Equal(t, 2, *calls)
+}
+
+func TestKeyReusedForAnotherRequest(t *testing.T) {
+	app, calls := newApp(newMemoryStore())
+	send(t, app, "/orders", "key-1", `{"items":[1]}`)
+
+	r := send(t, app, "/orders", "key-1", `{"items":[2]}`)
+	assert.Equal(t, 409, r.status)
+	assert.Equal(t, "Idempotency-Key was already used with a different request", r.body["message"])
+
+	r = send(t, app, "/orders?fail=0&x=1", "key-1", `{"items":[1]}`)
+	assert.Equal(t, 409, r.status, "the query string is part of the request")
+	assert.Equal(t, 1, *calls)
+}
+
+// stuckStore never completes a request, as if the first one were still running.
+type stuckStore struct{ *memoryStore }
+
+func (stuckStore) Complete(context.Context, string, *Record, time.Duration) error { return nil }
+
+func TestRequestInProgress(t *testing.T) {
+	store := newMemoryStore()
+	app, calls := newApp(stuckStore{store})
+	send(t, app, "/orders", "key-1", `{}`)
+	assert.Equal(t, LockTTL, store.ttls["idempotency:ip=0.0.0.0:key-1"], "a running request only holds its key briefly")
+
+	r := send(t, app, "/orders", "key-1", `{}`)
+	assert.Equal(t, 409, r.status)
+	assert.Equal(t, "A request with this Idempotency-Key is still being processed", r.body["message"])
+	assert.Equal(t, 1, *calls)
+}
+
+func TestServerErrorsAreNotStored(t *testing.T) {
+	app, calls := newApp(newMemoryStore())
+
+	r := send(t, app, "/orders?fail=503", "key-1", `{}`)
+	assert.Equal(t, 503, r.status)
+	r = send(t, app, "/orders?fail=503", "key-1", `{}`)
+	assert.Equal(t, "", r.replayed, "the retry runs again")
+	assert.Equal(t, 2, *calls)
+
+	send(t, app, "/orders?fail=409", "key-2", `{}`)
+	r = send(t, app, "/orders?fail=409", "key-2", `{}`)
+	assert.Equal(t, 409, r.status)
+	assert.Equal(t, "true", r.replayed, "client errors are final")
+	assert.Equal(t, 3, *calls)
+}
+
+func TestKeyTooLong(t *testing.T) {
+	app, calls := newApp(newMemoryStore())
+	r := send(t, app, "/orders", strings.Repeat("k", MaxKeyLength+1), `{}`)
+	assert.Equal(t, 400, r.status)
+	assert.Equal(t, 0, *calls)
+}
+
+func TestTTL(t *testing.T) {
+	t.Setenv("IDEMPOTENCY_TTL_HOURS", "")
+	assert.Equal(t, DefaultTTLHours*time.Hour, TTL())
+	t.Setenv("IDEMPOTENCY_TTL_HOURS", "2")
+	assert.Equal(t, 2*time.Hour, TTL())
+	t.Setenv("IDEMPOTENCY_TTL_HOURS", "0")
+	assert.Equal(t, DefaultTTLHours*time.Hour, TTL())
+}
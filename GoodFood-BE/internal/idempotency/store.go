@@ -0,0 +1,78 @@
+package idempotency
+
+import (
+	redisdatabase "GoodFood-BE/internal/redis-database"
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Record is what is stored under an idempotency key: the fingerprint of the first request and, once it completed,
+// its response.
+type Record struct {
+	Fingerprint string `json:"fingerprint"`
+	Completed   bool   `json:"completed"`
+	Status      int    `json:"status"`
+	ContentType string `json:"contentType"`
+	Body        []byte `json:"body"`
+}
+
+// Store keeps records for the length of the idempotency window.
+type Store interface {
+	// Reserve stores r under key for ttl unless the key is taken, in which case it returns the stored record.
+	Reserve(ctx context.Context, key string, r *Record, ttl time.Duration) (*Record, error)
+	// Complete replaces the record of a reserved key and keeps it for ttl.
+	Complete(ctx context.Context, key string, r *Record, ttl time.Duration) error
+	// Release frees a reserved key so the request can be retried.
+	Release(ctx context.Context, key string) error
+}
+
+// RedisStore keeps records in the shared Redis client.
+type RedisStore struct{}
+
+// reserveAttempts bounds Reserve when the stored record expires between SETNX and GET.
+const reserveAttempts = 3
+
+func (RedisStore) Reserve(ctx context.Context, key string, r *Record, ttl time.Duration) (*Record, error) {
+	payload, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < reserveAttempts; i++ {
+		ok, err := redisdatabase.Client.SetNX(ctx, key, payload, ttl).Result()
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return nil, nil
+		}
+		stored, err := redisdatabase.Client.Get(ctx, key).Bytes()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		existing := &Record{}
+		if err := json.Unmarshal(stored, existing); err != nil {
+			return nil, err
+		}
+		return existing, nil
+	}
+	return nil, errors.New("idempotency key keeps expiring")
+}
+
+func (RedisStore) Complete(ctx context.Context, key string, r *Record, ttl time.Duration) error {
+	payload, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return redisdatabase.Client.Set(ctx, key, payload, ttl).Err()
+}
+
+func (RedisStore) Release(ctx context.Context, key string) error {
+	return redisdatabase.Client.Del(ctx, key).Err()
+}
@@ -1,10 +1,16 @@
 package jobs
 
 import (
+	"GoodFood-BE/internal/privacy"
+	"GoodFood-BE/internal/sms"
 	"GoodFood-BE/internal/utils"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
+
+	"cloud.google.com/go/storage"
 	"github.com/hibiken/asynq"
 )
 
@@ -24,7 +30,7 @@ func HandleResetPasswordEmailTask(ctx context.Context, t *asynq.Task) error{
 }
 
 //This function handles the execution of a "customer contact message" job.
-//Unmarshals the task payload into CustomerContactPayload, then sends the message to customer support (my email: williamdang0404@gmail.com)
+//Unmarshals the task payload into CustomerContactPayload, then sends the message to customer support (the SMTP_FROM mailbox)
 func HandleContactCustomerSent(ctx context.Context, t *asynq.Task) error{
 	var payload CustomerSentContactPayload
 	if err := json.Unmarshal(t.Payload(),&payload); err != nil{
@@ -36,4 +42,118 @@ func HandleContactCustomerSent(ctx context.Context, t *asynq.Task) error{
 		return fmt.Errorf("failed to send email: %v",err);
 	}
 	return nil
-}
\ No newline at end of file
+}
+
+//This function handles the execution of the "verify email" job.
+//Unmarshals the task payload into VerifyEmailPayload, then sends an email with the verification link
+func HandleVerifyEmailTask(ctx context.Context, t *asynq.Task) error{
+	var payload VerifyEmailPayload
+	if err := json.Unmarshal(t.Payload(),&payload); err != nil{
+		return fmt.Errorf("failed to unmarshal payload: %v",err);
+	}
+
+	//Attempt to send verification email
+	if err := utils.SendVerifyEmail(payload.ToEmail,payload.VerifyLink);err != nil{
+		return fmt.Errorf("failed to send email: %v",err);
+	}
+	return nil
+}
+
+//This function handles the execution of the "password changed" notification job.
+//Unmarshals the task payload into PasswordChangedPayload, then warns the account owner by email
+func HandlePasswordChangedEmailTask(ctx context.Context, t *asynq.Task) error{
+	var payload PasswordChangedPayload
+	if err := json.Unmarshal(t.Payload(),&payload); err != nil{
+		return fmt.Errorf("failed to unmarshal payload: %v",err);
+	}
+
+	//Attempt to send notification email
+	if err := utils.SendPasswordChangedEmail(payload.ToEmail);err != nil{
+		return fmt.Errorf("failed to send email: %v",err);
+	}
+	return nil
+}
+
+//This function handles the execution of the "one-time code" email job.
+//Unmarshals the task payload into OTPEmailPayload, then emails the code
+func HandleOTPEmailTask(ctx context.Context, t *asynq.Task) error{
+	var payload OTPEmailPayload
+	if err := json.Unmarshal(t.Payload(),&payload); err != nil{
+		return fmt.Errorf("failed to unmarshal payload: %v",err);
+	}
+
+	//Attempt to send the code
+	if err := utils.SendOTPEmail(payload.ToEmail,payload.Code);err != nil{
+		return fmt.Errorf("failed to send email: %v",err);
+	}
+	return nil
+}
+
+//This function handles the execution of a text message job.
+//Unmarshals the task payload into SendSMSPayload, then hands it to the provider selected by SMS_PROVIDER
+func HandleSendSMSTask(ctx context.Context, t *asynq.Task) error{
+	var payload SendSMSPayload
+	if err := json.Unmarshal(t.Payload(),&payload); err != nil{
+		return fmt.Errorf("failed to unmarshal payload: %v",err);
+	}
+
+	provider, err := sms.NewProviderFromEnv()
+	if err != nil{
+		return err
+	}
+	//Attempt to send the message
+	if err := provider.Send(ctx,payload.To,payload.Message);err != nil{
+		return fmt.Errorf("failed to send sms: %v",err);
+	}
+	return nil
+}
+
+//This function handles the execution of a data export job.
+//Unmarshals the task payload into DataExportPayload, then writes the archive the account owner downloads
+func HandleDataExportTask(ctx context.Context, t *asynq.Task) error{
+	var payload DataExportPayload
+	if err := json.Unmarshal(t.Payload(),&payload); err != nil{
+		return fmt.Errorf("failed to unmarshal payload: %v",err);
+	}
+
+	//A failed build is recorded on the export, the retry may still succeed
+	if err := privacy.BuildExport(ctx,payload.ExportID,payload.AccountID); err != nil{
+		return fmt.Errorf("failed to build data export %s: %v",payload.ExportID,err);
+	}
+	return nil
+}
+
+//This function handles the expiry of a data export.
+//Unmarshals the task payload into DataExportPayload, then deletes the archive from disk
+func HandleDataExportExpireTask(ctx context.Context, t *asynq.Task) error{
+	var payload DataExportPayload
+	if err := json.Unmarshal(t.Payload(),&payload); err != nil{
+		return fmt.Errorf("failed to unmarshal payload: %v",err);
+	}
+	return privacy.ExpireExport(ctx,payload.ExportID)
+}
+
+//This function handles the deletion of uploaded images.
+//Unmarshals the task payload into DeleteImagesPayload, then removes every image from Firebase storage.
+//Images that are already gone count as deleted, so a retry only redoes the ones that failed
+func HandleDeleteImagesTask(ctx context.Context, t *asynq.Task) error{
+	var payload DeleteImagesPayload
+	if err := json.Unmarshal(t.Payload(),&payload); err != nil{
+		return fmt.Errorf("failed to unmarshal payload: %v",err);
+	}
+
+	//InitializeFirebaseApp exits the process without credentials, keep the task for when they are configured
+	if os.Getenv("FIREBASE_SERVICE_ACCOUNT") == ""{
+		return errors.New("FIREBASE_SERVICE_ACCOUNT is not set")
+	}
+	var failed []error
+	for _,path := range payload.Paths{
+		if err := utils.DeleteFirebaseImage(path,ctx); err != nil && !errors.Is(err,storage.ErrObjectNotExist){
+			failed = append(failed,fmt.Errorf("%s: %w",path,err))
+		}
+	}
+	if len(failed) > 0{
+		return fmt.Errorf("failed to delete images: %w",errors.Join(failed...));
+	}
+	return nil
+}
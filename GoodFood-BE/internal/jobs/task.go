@@ -9,6 +9,13 @@ import (
 //Task type constants used to identify job categories in Asynq
 const TypeResetPasswordEmail = "email:reset_password"
 const TypeSendContactMessage = "contact:customer_sent"
+const TypeVerifyEmail = "email:verify_email"
+const TypePasswordChangedEmail = "email:password_changed"
+const TypeOTPEmail = "email:otp"
+const TypeSendSMS = "sms:send"
+const TypeDataExport = "account:data_export"
+const TypeDataExportExpire = "account:data_export_expire"
+const TypeDeleteImages = "storage:delete_images"
 
 //This struct defines the payload for reset password email tasks
 type ResetPasswordPayload struct{
@@ -16,6 +23,40 @@ type ResetPasswordPayload struct{
 	ResetLink string
 }
 
+//This struct defines the payload for email verification tasks
+type VerifyEmailPayload struct{
+	ToEmail string
+	VerifyLink string
+}
+
+//This struct defines the payload for "your password was changed" notification tasks
+type PasswordChangedPayload struct{
+	ToEmail string
+}
+
+//This struct defines the payload for one-time code email tasks
+type OTPEmailPayload struct{
+	ToEmail string
+	Code string
+}
+
+//This struct defines the payload for text message tasks
+type SendSMSPayload struct{
+	To string
+	Message string
+}
+
+//This struct defines the payload for data export tasks
+type DataExportPayload struct{
+	ExportID string
+	AccountID int
+}
+
+//This struct defines the payload for deleting uploaded images from storage
+type DeleteImagesPayload struct{
+	Paths []string
+}
+
 //This one defines the payload for customer contact message task
 type CustomerSentContactPayload struct{
 	Fullname string
@@ -49,4 +90,92 @@ func NewCustomerSentContactTask(fullname,fromEmail, message string) (*asynq.Task
 		return nil,err
 	}
 	return asynq.NewTask(TypeSendContactMessage,payload),nil
-}
\ No newline at end of file
+}
+
+//This function creates a new task for sending an email verification link.
+//It marshals the payload and returns an *asynq.Task that can be enqueued
+func NewVerifyEmailTask(toEmail, verifyLink string) (*asynq.Task, error){
+	payload, err := json.Marshal(VerifyEmailPayload{
+		ToEmail: toEmail,
+		VerifyLink: verifyLink,
+	})
+	if err != nil{
+		return nil,err
+	}
+	return asynq.NewTask(TypeVerifyEmail,payload),nil
+}
+
+//This function creates a new task notifying a user that their password was changed.
+//It marshals the payload and returns an *asynq.Task that can be enqueued
+func NewPasswordChangedEmailTask(toEmail string) (*asynq.Task, error){
+	payload, err := json.Marshal(PasswordChangedPayload{
+		ToEmail: toEmail,
+	})
+	if err != nil{
+		return nil,err
+	}
+	return asynq.NewTask(TypePasswordChangedEmail,payload),nil
+}
+
+//This function creates a new task for emailing a one-time code.
+//It marshals the payload and returns an *asynq.Task that can be enqueued
+func NewOTPEmailTask(toEmail, code string) (*asynq.Task, error){
+	payload, err := json.Marshal(OTPEmailPayload{
+		ToEmail: toEmail,
+		Code: code,
+	})
+	if err != nil{
+		return nil,err
+	}
+	return asynq.NewTask(TypeOTPEmail,payload),nil
+}
+
+//This function creates a new task for sending a text message through the configured SMS provider.
+//It marshals the payload and returns an *asynq.Task that can be enqueued
+func NewSendSMSTask(to, message string) (*asynq.Task, error){
+	payload, err := json.Marshal(SendSMSPayload{
+		To: to,
+		Message: message,
+	})
+	if err != nil{
+		return nil,err
+	}
+	return asynq.NewTask(TypeSendSMS,payload),nil
+}
+
+//This function creates a new task building the data export archive of an account.
+//It marshals the payload and returns an *asynq.Task that can be enqueued
+func NewDataExportTask(exportID string, accountID int) (*asynq.Task, error){
+	payload, err := json.Marshal(DataExportPayload{
+		ExportID: exportID,
+		AccountID: accountID,
+	})
+	if err != nil{
+		return nil,err
+	}
+	return asynq.NewTask(TypeDataExport,payload),nil
+}
+
+//This function creates a new task removing a data export archive once it expires.
+//Enqueue it with asynq.ProcessIn so it runs after the download window
+func NewDataExportExpireTask(exportID string) (*asynq.Task, error){
+	payload, err := json.Marshal(DataExportPayload{
+		ExportID: exportID,
+	})
+	if err != nil{
+		return nil,err
+	}
+	return asynq.NewTask(TypeDataExportExpire,payload),nil
+}
+
+//This function creates a new task deleting uploaded images from Firebase storage.
+//It marshals the payload and returns an *asynq.Task that can be enqueued
+func NewDeleteImagesTask(paths []string) (*asynq.Task, error){
+	payload, err := json.Marshal(DeleteImagesPayload{
+		Paths: paths,
+	})
+	if err != nil{
+		return nil,err
+	}
+	return asynq.NewTask(TypeDeleteImages,payload),nil
+}
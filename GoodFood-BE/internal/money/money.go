@@ -0,0 +1,192 @@
+// Package money represents amounts as whole Vietnamese dong. VND has no minor unit in practice, so an amount
+// is an int64 count of dong: sums and products are exact, and the arithmetic helpers report overflow instead
+// of wrapping around. Amounts are stored as bigint and sent to clients as JSON integers.
+package money
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Money is an amount in VND.
+type Money int64
+
+var (
+	ErrOverflow   = errors.New("amount out of range")
+	ErrFractional = errors.New("amounts must be whole VND")
+)
+
+// VND returns n dong.
+func VND(n int64) Money {
+	return Money(n)
+}
+
+// Int64 returns m as a number of dong.
+func (m Money) Int64() int64 {
+	return int64(m)
+}
+
+// Add returns m + o.
+func (m Money) Add(o Money) (Money, error) {
+	if (o > 0 && m > math.MaxInt64-o) || (o < 0 && m < math.MinInt64-o) {
+		return 0, ErrOverflow
+	}
+	return m + o, nil
+}
+
+// Sub returns m - o.
+func (m Money) Sub(o Money) (Money, error) {
+	if (o < 0 && m > math.MaxInt64+o) || (o > 0 && m < math.MinInt64+o) {
+		return 0, ErrOverflow
+	}
+	return m - o, nil
+}
+
+// Mul returns m * n, the price of n units costing m each.
+func (m Money) Mul(n int64) (Money, error) {
+	if m == 0 || n == 0 {
+		return 0, nil
+	}
+	result := m * Money(n)
+	if result/Money(n) != m || (m == -1 && n == math.MinInt64) || (n == -1 && m == math.MinInt64) {
+		return 0, ErrOverflow
+	}
+	return result, nil
+}
+
+// Percent returns percent % of m, rounded to the nearest dong.
+func (m Money) Percent(percent int64) (Money, error) {
+	scaled, err := m.Mul(percent)
+	if err != nil {
+		return 0, err
+	}
+	rounded, err := scaled.Add(50)
+	if scaled < 0 {
+		rounded, err = scaled.Sub(50)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return rounded / 100, nil
+}
+
+// Sum returns the total of amounts.
+func Sum(amounts ...Money) (Money, error) {
+	var total Money
+	for _, amount := range amounts {
+		var err error
+		if total, err = total.Add(amount); err != nil {
+			return 0, err
+		}
+	}
+	return total, nil
+}
+
+// String formats m the way amounts are printed to customers, e.g. "1.250.000 ₫".
+func (m Money) String() string {
+	digits := strconv.FormatInt(int64(m), 10)
+	sign := ""
+	if strings.HasPrefix(digits, "-") {
+		sign, digits = "-", digits[1:]
+	}
+	var b strings.Builder
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte('.')
+		}
+		b.WriteRune(d)
+	}
+	return sign + b.String() + " ₫"
+}
+
+// Parse reads an amount written as an integer. Integral decimals such as "75000.00", which Postgres returns
+// for numeric values, are accepted; amounts with a fraction of a dong are not.
+func Parse(s string) (Money, error) {
+	s = strings.TrimSpace(s)
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return Money(n), nil
+	} else if errors.Is(err, strconv.ErrRange) {
+		return 0, ErrOverflow
+	}
+	whole, fraction, ok := strings.Cut(s, ".")
+	if !ok || strings.Trim(fraction, "0") != "" || (fraction == "" && whole == "") {
+		return 0, fmt.Errorf("invalid amount %q: %w", s, ErrFractional)
+	}
+	if whole == "" || whole == "-" {
+		whole += "0"
+	}
+	n, err := strconv.ParseInt(whole, 10, 64)
+	if errors.Is(err, strconv.ErrRange) {
+		return 0, ErrOverflow
+	}
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	return Money(n), nil
+}
+
+// MarshalJSON writes m as a JSON integer.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.FormatInt(int64(m), 10)), nil
+}
+
+// UnmarshalJSON reads an integer, or a string holding one, so clients sending "75000" keep working.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		s = string(data)
+	}
+	parsed, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// Scan implements sql.Scanner for bigint and numeric columns, and for real columns not migrated yet.
+func (m *Money) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case int64:
+		*m = Money(v)
+	case float64:
+		if v != math.Trunc(v) {
+			return fmt.Errorf("invalid amount %v: %w", v, ErrFractional)
+		}
+		if v >= math.MaxInt64 || v < math.MinInt64 {
+			return ErrOverflow
+		}
+		*m = Money(v)
+	case []byte:
+		return m.scanString(string(v))
+	case string:
+		return m.scanString(v)
+	case nil:
+		*m = 0
+	default:
+		return fmt.Errorf("money: cannot scan %T", src)
+	}
+	return nil
+}
+
+func (m *Money) scanString(s string) error {
+	parsed, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// Value implements driver.Valuer.
+func (m Money) Value() (driver.Value, error) {
+	return int64(m), nil
+}
@@ -0,0 +1,111 @@
+package money
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArithmetic(t *testing.T) {
+	sum, err := VND(150000).Add(30000)
+	assert.NoError(t, err)
+	assert.Equal(t, VND(180000), sum)
+
+	diff, err := VND(30000).Sub(150000)
+	assert.NoError(t, err)
+	assert.Equal(t, VND(-120000), diff)
+
+	product, err := VND(75000).Mul(99)
+	assert.NoError(t, err)
+	assert.Equal(t, VND(7425000), product)
+
+	scaled, err := VND(30000).Percent(150)
+	assert.NoError(t, err)
+	assert.Equal(t, VND(45000), scaled)
+	scaled, err = VND(12345).Percent(110)
+	assert.NoError(t, err)
+	assert.Equal(t, VND(13580), scaled, "13579.5 rounds up")
+	scaled, err = VND(-12345).Percent(110)
+	assert.NoError(t, err)
+	assert.Equal(t, VND(-13580), scaled)
+
+	total, err := Sum(1, 2, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, VND(6), total)
+}
+
+func TestArithmeticOverflow(t *testing.T) {
+	_, err := VND(math.MaxInt64).Add(1)
+	assert.ErrorIs(t, err, ErrOverflow)
+	_, err = VND(math.MinInt64).Sub(1)
+	assert.ErrorIs(t, err, ErrOverflow)
+	_, err = VND(math.MaxInt64 / 2).Mul(3)
+	assert.ErrorIs(t, err, ErrOverflow)
+	_, err = VND(math.MinInt64).Mul(-1)
+	assert.ErrorIs(t, err, ErrOverflow)
+	_, err = Sum(math.MaxInt64, 1)
+	assert.ErrorIs(t, err, ErrOverflow)
+}
+
+func TestFloat32LosesLargeTotals(t *testing.T) {
+	//The reason amounts are integers: real columns could not hold this total
+	total := VND(123456789)
+	assert.NotEqual(t, int64(total), int64(float32(total)))
+	product, err := VND(12345679).Mul(10)
+	assert.NoError(t, err)
+	assert.Equal(t, VND(123456790), product)
+}
+
+func TestString(t *testing.T) {
+	assert.Equal(t, "0 ₫", VND(0).String())
+	assert.Equal(t, "500 ₫", VND(500).String())
+	assert.Equal(t, "1.250.000 ₫", VND(1250000).String())
+	assert.Equal(t, "-30.000 ₫", VND(-30000).String())
+}
+
+func TestJSON(t *testing.T) {
+	var body struct {
+		Amount Money `json:"amount"`
+	}
+	data, err := json.Marshal(struct {
+		Amount Money `json:"amount"`
+	}{VND(123456789)})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"amount":123456789}`, string(data))
+
+	for input, want := range map[string]Money{
+		`{"amount":75000}`:    75000,
+		`{"amount":"75000"}`:  75000,
+		`{"amount":75000.00}`: 75000,
+		`{"amount":-5}`:       -5,
+		`{"amount":null}`:     0,
+	} {
+		body.Amount = 0
+		assert.NoError(t, json.Unmarshal([]byte(input), &body), input)
+		assert.Equal(t, want, body.Amount, input)
+	}
+
+	assert.ErrorIs(t, json.Unmarshal([]byte(`{"amount":75000.5}`), &body), ErrFractional)
+	assert.ErrorIs(t, json.Unmarshal([]byte(`{"amount":99999999999999999999}`), &body), ErrOverflow)
+	assert.Error(t, json.Unmarshal([]byte(`{"amount":"abc"}`), &body))
+}
+
+func TestScan(t *testing.T) {
+	var m Money
+	assert.NoError(t, m.Scan(int64(180000)))
+	assert.Equal(t, VND(180000), m)
+	assert.NoError(t, m.Scan([]byte("123456789012")), "SUM of bigint is numeric")
+	assert.Equal(t, VND(123456789012), m)
+	assert.NoError(t, m.Scan([]byte("42.000")))
+	assert.Equal(t, VND(42), m)
+	assert.NoError(t, m.Scan(float64(30000)))
+	assert.Equal(t, VND(30000), m)
+	assert.ErrorIs(t, m.Scan(float64(0.5)), ErrFractional)
+	assert.Error(t, m.Scan(true))
+
+	v, err := VND(180000).Value()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(180000), v)
+}
@@ -0,0 +1,108 @@
+package oidc
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Google is registered automatically when GOOGLE_AUDIENCE is set and no provider of that name is configured.
+const (
+	GoogleProvider = "google"
+	googleIssuer   = "https://accounts.google.com"
+	googleJWKSURL  = "https://www.googleapis.com/oauth2/v3/certs"
+)
+
+// ClaimMapping names the claims holding each profile field. Empty fields use the standard OIDC claim,
+// and dotted names reach into nested objects, e.g. "picture.data.url".
+type ClaimMapping struct {
+	Subject       string `json:"subject,omitempty"`
+	Email         string `json:"email,omitempty"`
+	EmailVerified string `json:"emailVerified,omitempty"`
+	Name          string `json:"name,omitempty"`
+	Picture       string `json:"picture,omitempty"`
+}
+
+// ProviderConfig configures one OpenID Connect issuer.
+// JWKSURL and UserInfoURL are discovered from the issuer when left empty.
+type ProviderConfig struct {
+	Name             string       `json:"name"`
+	Issuer           string       `json:"issuer"`
+	AlternateIssuers []string     `json:"alternateIssuers,omitempty"`
+	ClientID         string       `json:"clientID"`
+	JWKSURL          string       `json:"jwksURL,omitempty"`
+	UserInfoURL      string       `json:"userInfoURL,omitempty"`
+	Claims           ClaimMapping `json:"claims"`
+	// TrustEmailVerified lets the email_verified claim link the identity to an existing account with the same email.
+	// Leave it off for issuers that let users assert arbitrary addresses.
+	TrustEmailVerified bool `json:"trustEmailVerified"`
+}
+
+func (m ClaimMapping) withDefaults() ClaimMapping {
+	def := func(value, fallback string) string {
+		if value == "" {
+			return fallback
+		}
+		return value
+	}
+	return ClaimMapping{
+		Subject:       def(m.Subject, "sub"),
+		Email:         def(m.Email, "email"),
+		EmailVerified: def(m.EmailVerified, "email_verified"),
+		Name:          def(m.Name, "name"),
+		Picture:       def(m.Picture, "picture"),
+	}
+}
+
+func (cfg ProviderConfig) validate() error {
+	switch {
+	case cfg.Name == "":
+		return fmt.Errorf("oidc: provider without a name")
+	case cfg.Issuer == "":
+		return fmt.Errorf("oidc: provider %s has no issuer", cfg.Name)
+	case cfg.ClientID == "":
+		return fmt.Errorf("oidc: provider %s has no clientID", cfg.Name)
+	}
+	return nil
+}
+
+// LoadConfigs reads the providers from the JSON array in the file OIDC_PROVIDERS_FILE, or inline in OIDC_PROVIDERS,
+// and adds Google from GOOGLE_AUDIENCE.
+func LoadConfigs() ([]ProviderConfig, error) {
+	var raw []byte
+	if path := os.Getenv("OIDC_PROVIDERS_FILE"); path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		raw = b
+	} else if inline := strings.TrimSpace(os.Getenv("OIDC_PROVIDERS")); inline != "" {
+		raw = []byte(inline)
+	}
+
+	var configs []ProviderConfig
+	if raw != nil {
+		if err := json.Unmarshal(raw, &configs); err != nil {
+			return nil, fmt.Errorf("oidc: invalid provider settings: %w", err)
+		}
+	}
+
+	audience := os.Getenv("GOOGLE_AUDIENCE")
+	if audience == "" {
+		return configs, nil
+	}
+	for _, cfg := range configs {
+		if cfg.Name == GoogleProvider {
+			return configs, nil
+		}
+	}
+	return append(configs, ProviderConfig{
+		Name:               GoogleProvider,
+		Issuer:             googleIssuer,
+		AlternateIssuers:   []string{"accounts.google.com"},
+		ClientID:           audience,
+		JWKSURL:            googleJWKSURL,
+		TrustEmailVerified: true,
+	}), nil
+}
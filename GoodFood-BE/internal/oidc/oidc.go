@@ -0,0 +1,280 @@
+// Package oidc verifies ID tokens of the OpenID Connect issuers configured for social login.
+package oidc
+
+import (
+	"GoodFood-BE/internal/dto"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrUnknownProvider = errors.New("oidc: unknown provider")
+	ErrInvalidToken    = errors.New("oidc: invalid ID token")
+)
+
+// validMethods are the signing algorithms accepted from issuers, never "none" or HMAC.
+var validMethods = []string{"RS256", "RS384", "RS512", "ES256", "ES384", "EdDSA"}
+
+// Registry holds the configured providers by name.
+type Registry struct {
+	providers map[string]*Provider
+}
+
+// Provider verifies the tokens of one issuer and caches its keys.
+type Provider struct {
+	cfg    ProviderConfig
+	claims ClaimMapping
+	client *http.Client
+
+	discoverMu  sync.Mutex
+	discovered  bool
+	jwksURL     string
+	userInfoURL string
+
+	mu          sync.Mutex
+	keys        map[string]interface{}
+	keysFetched time.Time
+}
+
+// NewRegistry builds a registry from configs. client is used for discovery, JWKS and userinfo requests,
+// nil uses a client with a 10 second timeout.
+func NewRegistry(configs []ProviderConfig, client *http.Client) (*Registry, error) {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	r := &Registry{providers: make(map[string]*Provider, len(configs))}
+	for _, cfg := range configs {
+		if err := cfg.validate(); err != nil {
+			return nil, err
+		}
+		if _, dup := r.providers[cfg.Name]; dup {
+			return nil, fmt.Errorf("oidc: provider %s is configured twice", cfg.Name)
+		}
+		cfg.Issuer = strings.TrimRight(cfg.Issuer, "/")
+		r.providers[cfg.Name] = &Provider{
+			cfg:         cfg,
+			claims:      cfg.Claims.withDefaults(),
+			client:      client,
+			jwksURL:     cfg.JWKSURL,
+			userInfoURL: cfg.UserInfoURL,
+		}
+	}
+	return r, nil
+}
+
+var (
+	defaultOnce     sync.Once
+	defaultRegistry *Registry
+	defaultErr      error
+)
+
+// Default returns the registry configured from the environment, see LoadConfigs.
+func Default() (*Registry, error) {
+	defaultOnce.Do(func() {
+		configs, err := LoadConfigs()
+		if err != nil {
+			defaultErr = err
+			return
+		}
+		defaultRegistry, defaultErr = NewRegistry(configs, nil)
+	})
+	return defaultRegistry, defaultErr
+}
+
+// Provider returns the provider called name.
+func (r *Registry) Provider(name string) (*Provider, error) {
+	p, ok := r.providers[name]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	return p, nil
+}
+
+// Providers returns the configured providers sorted by name.
+func (r *Registry) Providers() []*Provider {
+	list := make([]*Provider, 0, len(r.providers))
+	for _, p := range r.providers {
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].cfg.Name < list[j].cfg.Name })
+	return list
+}
+
+// Name returns the provider name, stored in oauth_account.provider.
+func (p *Provider) Name() string { return p.cfg.Name }
+
+// Issuer returns the issuer URL of the provider.
+func (p *Provider) Issuer() string { return p.cfg.Issuer }
+
+// ClientID returns the client ID tokens must be issued for.
+func (p *Provider) ClientID() string { return p.cfg.ClientID }
+
+// Verify checks the signature, issuer, audience and lifetime of idToken and maps its claims to a profile.
+// When accessToken is given and the provider has a userinfo endpoint, claims missing from the ID token
+// are completed from it.
+func (p *Provider) Verify(ctx context.Context, idToken, accessToken string) (dto.OAuthProfile, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(idToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.signingKey(ctx, kid)
+	},
+		jwt.WithValidMethods(validMethods),
+		jwt.WithAudience(p.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return dto.OAuthProfile{}, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if iss, _ := claims["iss"].(string); !p.issuerAllowed(iss) {
+		return dto.OAuthProfile{}, fmt.Errorf("%w: unexpected issuer %q", ErrInvalidToken, iss)
+	}
+	subject := stringClaim(claims, p.claims.Subject)
+	if subject == "" {
+		return dto.OAuthProfile{}, fmt.Errorf("%w: missing subject", ErrInvalidToken)
+	}
+
+	if accessToken != "" {
+		if err := p.mergeUserInfo(ctx, accessToken, subject, claims); err != nil {
+			return dto.OAuthProfile{}, err
+		}
+	}
+
+	return dto.OAuthProfile{
+		Provider:       p.cfg.Name,
+		ProviderUserID: subject,
+		Email:          stringClaim(claims, p.claims.Email),
+		EmailVerified:  p.cfg.TrustEmailVerified && boolClaim(claims, p.claims.EmailVerified),
+		FullName:       stringClaim(claims, p.claims.Name),
+		AvatarURL:      stringClaim(claims, p.claims.Picture),
+	}, nil
+}
+
+func (p *Provider) issuerAllowed(iss string) bool {
+	if iss == p.cfg.Issuer {
+		return true
+	}
+	for _, alt := range p.cfg.AlternateIssuers {
+		if iss == alt {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeUserInfo adds the userinfo claims the ID token does not have. The userinfo response must be
+// about the same subject, otherwise the access token belongs to someone else.
+func (p *Provider) mergeUserInfo(ctx context.Context, accessToken, subject string, claims jwt.MapClaims) error {
+	_, userInfoURL, err := p.endpoints(ctx)
+	if err != nil || userInfoURL == "" {
+		return err
+	}
+	info := map[string]interface{}{}
+	if err := p.getJSON(ctx, userInfoURL, accessToken, &info); err != nil {
+		return err
+	}
+	if stringClaim(info, p.claims.Subject) != subject {
+		return fmt.Errorf("%w: userinfo subject does not match", ErrInvalidToken)
+	}
+	for name, value := range info {
+		if _, ok := claims[name]; !ok {
+			claims[name] = value
+		}
+	}
+	return nil
+}
+
+// endpoints returns the JWKS and userinfo URLs, discovering them from the issuer when no JWKS URL is configured.
+func (p *Provider) endpoints(ctx context.Context) (string, string, error) {
+	p.discoverMu.Lock()
+	defer p.discoverMu.Unlock()
+	if p.jwksURL != "" || p.discovered {
+		return p.jwksURL, p.userInfoURL, nil
+	}
+	var doc struct {
+		Issuer           string `json:"issuer"`
+		JWKSURI          string `json:"jwks_uri"`
+		UserInfoEndpoint string `json:"userinfo_endpoint"`
+	}
+	if err := p.getJSON(ctx, p.cfg.Issuer+"/.well-known/openid-configuration", "", &doc); err != nil {
+		return "", "", err
+	}
+	if strings.TrimRight(doc.Issuer, "/") != p.cfg.Issuer {
+		return "", "", fmt.Errorf("oidc: discovery document of %s is for issuer %q", p.cfg.Name, doc.Issuer)
+	}
+	if doc.JWKSURI == "" {
+		return "", "", fmt.Errorf("oidc: discovery document of %s has no jwks_uri", p.cfg.Name)
+	}
+	p.jwksURL = doc.JWKSURI
+	if p.userInfoURL == "" {
+		p.userInfoURL = doc.UserInfoEndpoint
+	}
+	p.discovered = true
+	return p.jwksURL, p.userInfoURL, nil
+}
+
+func (p *Provider) getJSON(ctx context.Context, url, bearer string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+bearer)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("oidc: %s returned %s", url, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// claimValue follows a dotted claim name into nested objects.
+func claimValue(claims map[string]interface{}, name string) interface{} {
+	var value interface{} = claims
+	for _, part := range strings.Split(name, ".") {
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = obj[part]
+	}
+	return value
+}
+
+func stringClaim(claims map[string]interface{}, name string) string {
+	switch v := claimValue(claims, name).(type) {
+	case string:
+		return v
+	case float64:
+		//Some issuers send numeric subjects
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return ""
+}
+
+// boolClaim also accepts "true", which some issuers send for email_verified.
+func boolClaim(claims map[string]interface{}, name string) bool {
+	switch v := claimValue(claims, name).(type) {
+	case bool:
+		return v
+	case string:
+		return v == "true"
+	}
+	return false
+}
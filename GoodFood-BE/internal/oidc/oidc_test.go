@@ -0,0 +1,200 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeIssuer serves discovery, JWKS and userinfo like a real OpenID Connect provider.
+type fakeIssuer struct {
+	server    *httptest.Server
+	key       *rsa.PrivateKey
+	kid       string
+	jwksCalls int
+	userInfo  map[string]interface{}
+}
+
+func newFakeIssuer(t *testing.T) *fakeIssuer {
+	f := &fakeIssuer{kid: "key-1"}
+	f.rotate(t, "key-1")
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":            f.server.URL,
+			"jwks_uri":          f.server.URL + "/jwks",
+			"userinfo_endpoint": f.server.URL + "/userinfo",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		f.jwksCalls++
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": f.kid,
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(f.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(f.key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer good-access-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(f.userInfo)
+	})
+	f.server = httptest.NewServer(mux)
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+func (f *fakeIssuer) rotate(t *testing.T, kid string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	f.key, f.kid = key, kid
+}
+
+func (f *fakeIssuer) sign(t *testing.T, claims jwt.MapClaims) string {
+	base := jwt.MapClaims{
+		"iss": f.server.URL,
+		"aud": "goodfood-web",
+		"sub": "user-42",
+		"iat": time.Now().Unix(),
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+	for k, v := range claims {
+		base[k] = v
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, base)
+	token.Header["kid"] = f.kid
+	signed, err := token.SignedString(f.key)
+	assert.NoError(t, err)
+	return signed
+}
+
+func (f *fakeIssuer) provider(t *testing.T, cfg ProviderConfig) *Provider {
+	cfg.Name = "acme"
+	cfg.Issuer = f.server.URL
+	cfg.ClientID = "goodfood-web"
+	registry, err := NewRegistry([]ProviderConfig{cfg}, f.server.Client())
+	assert.NoError(t, err)
+	p, err := registry.Provider("acme")
+	assert.NoError(t, err)
+	return p
+}
+
+func TestVerifyMapsClaims(t *testing.T) {
+	f := newFakeIssuer(t)
+	p := f.provider(t, ProviderConfig{TrustEmailVerified: true})
+
+	profile, err := p.Verify(context.Background(), f.sign(t, jwt.MapClaims{
+		"email":          "alice@example.com",
+		"email_verified": true,
+		"name":           "Alice",
+	}), "")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "acme", profile.Provider)
+	assert.Equal(t, "user-42", profile.ProviderUserID)
+	assert.Equal(t, "alice@example.com", profile.Email)
+	assert.True(t, profile.EmailVerified)
+	assert.Equal(t, "Alice", profile.FullName)
+}
+
+func TestVerifyCustomClaimMappingAndUserInfo(t *testing.T) {
+	f := newFakeIssuer(t)
+	f.userInfo = map[string]interface{}{
+		"sub":     "user-42",
+		"mail":    "bob@example.com",
+		"profile": map[string]interface{}{"avatar": "https://cdn.example.com/bob.png"},
+	}
+	p := f.provider(t, ProviderConfig{Claims: ClaimMapping{Email: "mail", Picture: "profile.avatar"}})
+
+	profile, err := p.Verify(context.Background(), f.sign(t, jwt.MapClaims{"email_verified": true}), "good-access-token")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "bob@example.com", profile.Email)
+	assert.Equal(t, "https://cdn.example.com/bob.png", profile.AvatarURL)
+	assert.False(t, profile.EmailVerified, "email_verified is ignored unless the provider is trusted")
+
+	f.userInfo["sub"] = "someone-else"
+	_, err = p.Verify(context.Background(), f.sign(t, nil), "good-access-token")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestVerifyRejectsInvalidTokens(t *testing.T) {
+	f := newFakeIssuer(t)
+	p := f.provider(t, ProviderConfig{})
+
+	tests := []struct {
+		name   string
+		claims jwt.MapClaims
+	}{
+		{"wrong audience", jwt.MapClaims{"aud": "another-app"}},
+		{"wrong issuer", jwt.MapClaims{"iss": "https://evil.example.com"}},
+		{"expired", jwt.MapClaims{"exp": time.Now().Add(-time.Hour).Unix()}},
+		{"no expiry", jwt.MapClaims{"exp": nil}},
+		{"no subject", jwt.MapClaims{"sub": ""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := p.Verify(context.Background(), f.sign(t, tt.claims), "")
+			assert.ErrorIs(t, err, ErrInvalidToken)
+		})
+	}
+
+	hmac, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"iss": f.server.URL, "aud": "goodfood-web", "sub": "user-42", "exp": time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte("secret"))
+	assert.NoError(t, err)
+	_, err = p.Verify(context.Background(), hmac, "")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestVerifyRefreshesKeysOnRotation(t *testing.T) {
+	f := newFakeIssuer(t)
+	p := f.provider(t, ProviderConfig{})
+
+	_, err := p.Verify(context.Background(), f.sign(t, nil), "")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, f.jwksCalls)
+
+	f.rotate(t, "key-2")
+	_, err = p.Verify(context.Background(), f.sign(t, nil), "")
+	assert.ErrorIs(t, err, ErrInvalidToken, "unknown kids do not refetch more than once per jwksMinRefresh")
+	assert.Equal(t, 1, f.jwksCalls)
+
+	p.keysFetched = time.Now().Add(-jwksMinRefresh)
+	_, err = p.Verify(context.Background(), f.sign(t, nil), "")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, f.jwksCalls)
+}
+
+func TestLoadConfigs(t *testing.T) {
+	t.Setenv("OIDC_PROVIDERS_FILE", "")
+	t.Setenv("OIDC_PROVIDERS", `[{"name":"acme","issuer":"https://id.acme.test","clientID":"web"}]`)
+	t.Setenv("GOOGLE_AUDIENCE", "google-client")
+
+	configs, err := LoadConfigs()
+	if !assert.NoError(t, err) || !assert.Len(t, configs, 2) {
+		return
+	}
+	assert.Equal(t, "acme", configs[0].Name)
+	assert.Equal(t, GoogleProvider, configs[1].Name)
+	assert.Equal(t, "google-client", configs[1].ClientID)
+
+	_, err = NewRegistry(append(configs, configs[0]), nil)
+	assert.Error(t, err)
+}
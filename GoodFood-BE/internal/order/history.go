@@ -0,0 +1,72 @@
+package order
+
+import (
+	"GoodFood-BE/models"
+	"context"
+	"sort"
+	"time"
+
+	"github.com/aarondl/null/v8"
+	"github.com/aarondl/sqlboiler/v4/boil"
+)
+
+// HistoryEntry is one status change of an order. FromStatus is empty for its placement.
+type HistoryEntry struct {
+	HistoryID     int64       `json:"historyID"`
+	FromStatusID  null.Int    `json:"fromStatusID"`
+	FromStatus    string      `json:"fromStatus"`
+	ToStatusID    int         `json:"toStatusID"`
+	ToStatus      string      `json:"toStatus"`
+	ToStatusName  string      `json:"toStatusName"`
+	ActorRole     string      `json:"actorRole"`
+	ActorID       null.Int    `json:"actorID"`
+	ActorUsername null.String `json:"actorUsername"`
+	Reason        null.String `json:"reason"`
+	CreatedAt     time.Time   `json:"createdAt"`
+}
+
+// Timeline returns the status changes of an invoice, oldest first.
+func Timeline(ctx context.Context, invoiceID int) ([]*HistoryEntry, error) {
+	rows, err := boil.GetContextDB().QueryContext(ctx, `
+		SELECT h."historyID", h."fromStatusID", COALESCE(f.code, ''), h."toStatusID", COALESCE(s.code, ''),
+			COALESCE(s."statusName", ''), h."actorRole", h."actorID", h."actorUsername", h.reason, h."createdAt"
+		FROM invoice_status_history h
+		LEFT JOIN invoice_status f ON f."invoiceStatusID" = h."fromStatusID"
+		LEFT JOIN invoice_status s ON s."invoiceStatusID" = h."toStatusID"
+		WHERE h."invoiceID" = $1
+		ORDER BY h."createdAt", h."historyID"`, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	entries := []*HistoryEntry{}
+	for rows.Next() {
+		e := &HistoryEntry{}
+		if err := rows.Scan(&e.HistoryID, &e.FromStatusID, &e.FromStatus, &e.ToStatusID, &e.ToStatus, &e.ToStatusName,
+			&e.ActorRole, &e.ActorID, &e.ActorUsername, &e.Reason, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// Options returns the statuses role can pick for an order in status current: current itself, then the next ones.
+func Options(ctx context.Context, role Role, current Status) (models.InvoiceStatusSlice, error) {
+	options := append([]Status{current}, Next(role, current)...)
+	codes := make([]string, len(options))
+	for i, s := range options {
+		codes[i] = string(s)
+	}
+	rows, err := models.InvoiceStatuses(models.InvoiceStatusWhere.Code.IN(codes)).All(ctx, boil.GetContextDB())
+	if err != nil {
+		return nil, err
+	}
+	//In the order of the state machine, whatever order the rows were created in
+	position := map[string]int{}
+	for i, code := range codes {
+		position[code] = i
+	}
+	sort.Slice(rows, func(i, j int) bool { return position[rows[i].Code] < position[rows[j].Code] })
+	return rows, nil
+}
@@ -0,0 +1,92 @@
+package order
+
+import (
+	"GoodFood-BE/internal/payment"
+	"GoodFood-BE/internal/shipping"
+	"GoodFood-BE/internal/utils"
+	"GoodFood-BE/models"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aarondl/sqlboiler/v4/boil"
+)
+
+func init() {
+	for _, s := range statuses {
+		OnEnter(s, clearOrderHistory)
+	}
+	OnEnter(StatusProcessing, shipWithCarrier)
+	OnEnter(StatusCancelled, refundOnlinePayment)
+	OnEnter(StatusCancelled, sendCancelEmail)
+	OnEnter(StatusCancelled, cancelCarrierShipment)
+}
+
+// clearOrderHistory renews the cached order history tabs the order left and entered.
+func clearOrderHistory(ctx context.Context, e Event) error {
+	rows, err := models.InvoiceStatuses(models.InvoiceStatusWhere.Code.IN([]string{string(e.From), string(e.To)})).
+		All(ctx, boil.GetContextDB())
+	if err != nil {
+		return err
+	}
+	for _, s := range rows {
+		utils.ClearCache(fmt.Sprintf("orderhistory:accountID=%d:tab:%s", e.Invoice.AccountID, s.StatusName))
+	}
+	return nil
+}
+
+// refundOnlinePayment gives back what was paid online for a cancelled order. When the provider does not confirm it,
+// the refund stays in the refund list of the invoice, where an admin can follow it up.
+func refundOnlinePayment(ctx context.Context, e Event) error {
+	if !e.Invoice.Status {
+		return nil
+	}
+	createdBy := string(e.Actor.Role)
+	if e.Actor.Username.Valid {
+		createdBy = e.Actor.Username.String
+	}
+	_, err := payment.RefundInvoice(ctx, payment.RefundRequest{
+		InvoiceID:     e.Invoice.InvoiceID,
+		CreatedBy:     e.Actor.AccountID,
+		CreatedByName: createdBy,
+		IP:            e.Actor.IP,
+	})
+	if errors.Is(err, payment.ErrPaymentNotFound) || errors.Is(err, payment.ErrRefundAmount) {
+		//Nothing was paid online, or it was refunded already
+		return nil
+	}
+	return err
+}
+
+// sendCancelEmail tells the customer their order was cancelled by the shop. Customers cancelling their own
+// order already know.
+func sendCancelEmail(ctx context.Context, e Event) error {
+	if e.Actor.Role == RoleCustomer {
+		return nil
+	}
+	account, err := models.FindAccount(ctx, boil.GetContextDB(), e.Invoice.AccountID)
+	if err != nil {
+		return err
+	}
+	return utils.SendOrderCancelEmail(account.Email, e.Reason, e.Invoice.Status)
+}
+
+// shipWithCarrier hands an order being processed over to the active carrier, if there is one. When it fails,
+// an admin creates the shipment from the order page.
+func shipWithCarrier(ctx context.Context, e Event) error {
+	if _, ok, err := shipping.ActiveCarrier(); err != nil || !ok {
+		return err
+	}
+	_, err := CreateShipment(ctx, e.Invoice.InvoiceID)
+	return err
+}
+
+// cancelCarrierShipment calls off the shipment of a cancelled order. Parcels the carrier brought back have
+// nothing left to cancel.
+func cancelCarrierShipment(ctx context.Context, e Event) error {
+	s, err := shipmentOf(ctx, boil.GetContextDB(), e.Invoice.InvoiceID)
+	if err != nil || s.TrackingCode == "" || s.Status.String == string(shipping.ShipmentReturned) {
+		return err
+	}
+	return CancelShipment(ctx, e.Invoice.InvoiceID)
+}
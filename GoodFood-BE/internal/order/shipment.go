@@ -0,0 +1,264 @@
+package order
+
+import (
+	"GoodFood-BE/internal/shipping"
+	"GoodFood-BE/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/aarondl/null/v8"
+	"github.com/aarondl/sqlboiler/v4/boil"
+	"github.com/aarondl/sqlboiler/v4/queries/qm"
+)
+
+var (
+	ErrNoCarrier        = errors.New("no shipping carrier is configured")
+	ErrNotReadyToShip   = errors.New("only orders being processed can be handed over to a carrier")
+	ErrNoDestination    = errors.New("the order was placed without the district and ward codes carriers need")
+	ErrShipmentExists   = errors.New("the order already has a shipment")
+	ErrShipmentNotFound = errors.New("shipment not found")
+	ErrCarrierManaged   = errors.New("the carrier moves this order along, cancel its shipment first")
+)
+
+// ShipmentInfo is the shipment of an invoice as stored, with its tracking at the carrier when asked for.
+type ShipmentInfo struct {
+	InvoiceID    int                `json:"invoiceID"`
+	Carrier      string             `json:"carrier"`
+	TrackingCode string             `json:"trackingCode"`
+	Status       null.String        `json:"status"`
+	Tracking     *shipping.Tracking `json:"tracking,omitempty"`
+}
+
+// RecordDestination stores where an invoice is delivered in the codes carriers use, inside the transaction
+// inserting it.
+func RecordDestination(ctx context.Context, exec boil.ContextExecutor, invoiceID int, address *models.Address) error {
+	_, err := exec.ExecContext(ctx, `
+		UPDATE invoice
+		SET "receiveDistrictCode" = (SELECT "districtCode" FROM district WHERE "districtID" = $2), "receiveWardCode" = $3
+		WHERE "invoiceID" = $1`, invoiceID, address.DistrictID, null.NewString(address.WardCode, address.WardCode != ""))
+	return err
+}
+
+// shipmentOf returns the shipment columns of an invoice. The carrier and tracking code are empty when it has none.
+func shipmentOf(ctx context.Context, exec boil.ContextExecutor, invoiceID int) (*ShipmentInfo, error) {
+	s := &ShipmentInfo{InvoiceID: invoiceID}
+	var carrier, trackingCode null.String
+	err := exec.QueryRowContext(ctx, `
+		SELECT "carrierCode", "trackingCode", "shipmentStatus" FROM invoice WHERE "invoiceID" = $1`, invoiceID).
+		Scan(&carrier, &trackingCode, &s.Status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvoiceNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.Carrier, s.TrackingCode = carrier.String, trackingCode.String
+	return s, nil
+}
+
+// GetShipment returns the shipment of an invoice and asks the carrier where the parcel is. When the carrier
+// cannot be reached the stored status is returned without tracking.
+func GetShipment(ctx context.Context, invoiceID int) (*ShipmentInfo, error) {
+	s, err := shipmentOf(ctx, boil.GetContextDB(), invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	if s.TrackingCode == "" {
+		return nil, ErrShipmentNotFound
+	}
+	carrier, err := shipping.GetCarrier(s.Carrier)
+	if err != nil {
+		return nil, err
+	}
+	if tracking, err := carrier.Track(ctx, s.TrackingCode); err == nil {
+		s.Tracking = tracking
+	}
+	return s, nil
+}
+
+// shipmentRequest describes the parcel of an invoice. The carrier collects the total on delivery when the
+// order is not paid yet.
+func shipmentRequest(ctx context.Context, exec boil.ContextExecutor, invoice *models.Invoice) (shipping.ShipmentRequest, error) {
+	req := shipping.ShipmentRequest{
+		Reference: fmt.Sprintf("GF-%d", invoice.InvoiceID),
+		ToName:    invoice.ReceiveName,
+		ToPhone:   invoice.ReceivePhone,
+		ToAddress: invoice.ReceiveAddress,
+		Note:      invoice.Note.String,
+	}
+	var districtCode null.Int
+	var wardCode null.String
+	err := exec.QueryRowContext(ctx, `
+		SELECT "receiveDistrictCode", "receiveWardCode" FROM invoice WHERE "invoiceID" = $1`, invoice.InvoiceID).
+		Scan(&districtCode, &wardCode)
+	if err != nil {
+		return req, err
+	}
+	if !districtCode.Valid || strings.TrimSpace(wardCode.String) == "" {
+		return req, ErrNoDestination
+	}
+	req.ToDistrictCode, req.ToWardCode = districtCode.Int, wardCode.String
+
+	details, err := models.InvoiceDetails(
+		models.InvoiceDetailWhere.InvoiceID.EQ(invoice.InvoiceID),
+		qm.Load(models.InvoiceDetailRels.ProductIDProduct),
+	).All(ctx, exec)
+	if err != nil {
+		return req, err
+	}
+	for _, d := range details {
+		grams := int(math.Round(float64(d.R.ProductIDProduct.Weight) * float64(d.Quantity)))
+		req.Items = append(req.Items, shipping.ShipmentItem{Name: d.R.ProductIDProduct.ProductName, Quantity: d.Quantity, WeightGrams: grams})
+		req.WeightGrams += grams
+	}
+	//The products are insured, not the shipping fee
+	if req.InsuranceValue, err = invoice.TotalPrice.Sub(invoice.ShippingFee); err != nil {
+		return req, err
+	}
+	if !invoice.Status {
+		req.CODAmount = invoice.TotalPrice
+	}
+	return req, nil
+}
+
+// CreateShipment hands an order being processed over to the active carrier and stores its tracking code.
+func CreateShipment(ctx context.Context, invoiceID int) (*shipping.Shipment, error) {
+	carrier, ok, err := shipping.ActiveCarrier()
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrNoCarrier
+	}
+	db := boil.GetContextDB()
+	invoice, err := models.FindInvoice(ctx, db, invoiceID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvoiceNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if status, err := StatusOf(ctx, db, invoice.InvoiceStatusID); err != nil {
+		return nil, err
+	} else if status != StatusProcessing {
+		return nil, ErrNotReadyToShip
+	}
+	if existing, err := shipmentOf(ctx, db, invoiceID); err != nil {
+		return nil, err
+	} else if existing.TrackingCode != "" {
+		return nil, ErrShipmentExists
+	}
+	req, err := shipmentRequest(ctx, db, invoice)
+	if err != nil {
+		return nil, err
+	}
+
+	shipment, err := carrier.CreateShipment(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	res, err := db.ExecContext(ctx, `
+		UPDATE invoice SET "carrierCode" = $2, "trackingCode" = $3, "shipmentStatus" = $4
+		WHERE "invoiceID" = $1 AND "trackingCode" IS NULL`,
+		invoiceID, carrier.Code(), shipment.TrackingCode, string(shipping.ShipmentPending))
+	if err != nil {
+		return nil, err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return nil, err
+	} else if n == 0 {
+		//Another request shipped the order meanwhile, call this parcel off
+		_ = carrier.CancelShipment(ctx, shipment.TrackingCode)
+		return nil, ErrShipmentExists
+	}
+	return shipment, nil
+}
+
+// CancelShipment calls off the shipment of an invoice at its carrier. The order keeps its status and can be
+// shipped again.
+func CancelShipment(ctx context.Context, invoiceID int) error {
+	db := boil.GetContextDB()
+	s, err := shipmentOf(ctx, db, invoiceID)
+	if err != nil {
+		return err
+	}
+	if s.TrackingCode == "" {
+		return ErrShipmentNotFound
+	}
+	carrier, err := shipping.GetCarrier(s.Carrier)
+	if err != nil {
+		return err
+	}
+	if err := carrier.CancelShipment(ctx, s.TrackingCode); err != nil {
+		return err
+	}
+	return clearShipment(ctx, db, invoiceID)
+}
+
+// clearShipment forgets the tracking code of an invoice whose shipment was called off.
+func clearShipment(ctx context.Context, exec boil.ContextExecutor, invoiceID int) error {
+	_, err := exec.ExecContext(ctx, `
+		UPDATE invoice SET "carrierCode" = NULL, "trackingCode" = NULL, "shipmentStatus" = $2 WHERE "invoiceID" = $1`,
+		invoiceID, string(shipping.ShipmentCancelled))
+	return err
+}
+
+// shipmentTargets are the statuses an order goes through, in order, when its parcel reaches a shipment status.
+var shipmentTargets = map[shipping.ShipmentStatus][]Status{
+	shipping.ShipmentInTransit: {StatusShipping},
+	shipping.ShipmentDelivered: {StatusShipping, StatusDelivered}, //notifications can be missed
+	shipping.ShipmentReturned:  {StatusCancelled},
+}
+
+// ApplyTrackingUpdate stores the status of a parcel reported by a carrier and moves its order along. Updates
+// the order is past, such as a late "picked" after "delivered", change nothing.
+func ApplyTrackingUpdate(ctx context.Context, carrierCode string, u *shipping.TrackingUpdate) (*models.Invoice, error) {
+	db := boil.GetContextDB()
+	var invoiceID int
+	err := db.QueryRowContext(ctx, `
+		UPDATE invoice SET "shipmentStatus" = $3 WHERE "carrierCode" = $1 AND "trackingCode" = $2
+		RETURNING "invoiceID"`, carrierCode, u.TrackingCode, string(u.Status)).Scan(&invoiceID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrShipmentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if u.Status == shipping.ShipmentCancelled {
+		//Called off at the carrier, an admin ships the order again
+		if err := clearShipment(ctx, db, invoiceID); err != nil {
+			return nil, err
+		}
+	}
+
+	reason := carrierCode + ": " + u.CarrierStatus
+	if u.Status == shipping.ShipmentReturned {
+		reason = "Returned by " + carrierCode
+		if u.Reason != "" {
+			reason += ": " + u.Reason
+		}
+	}
+	for _, to := range shipmentTargets[u.Status] {
+		_, err := Transition(ctx, invoiceID, to, Actor{Role: RoleSystem}, reason)
+		if errors.Is(err, ErrInvalidTransition) || errors.Is(err, ErrForbidden) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return models.FindInvoice(ctx, db, invoiceID)
+}
+
+// carrierManaged reports whether an invoice has a shipment, whose carrier moves it to shipping and delivered.
+func carrierManaged(ctx context.Context, exec boil.ContextExecutor, invoiceID int) (bool, error) {
+	s, err := shipmentOf(ctx, exec, invoiceID)
+	if err != nil {
+		return false, err
+	}
+	return s.TrackingCode != "", nil
+}
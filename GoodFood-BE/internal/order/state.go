@@ -0,0 +1,100 @@
+// Package order moves orders through their lifecycle. The allowed status changes, who may make them and what
+// happens when they are made are declared here once; every change goes through Transition, which records it
+// in invoice_status_history.
+package order
+
+import "strings"
+
+// Status is the code of an invoice_status row. The codes are stable; the ids and display names of the rows are
+// not, so rows are always looked up by code (see StatusID and StatusOf).
+type Status string
+
+const (
+	StatusPlaced     Status = "placed"
+	StatusConfirmed  Status = "confirmed"
+	StatusProcessing Status = "processing"
+	StatusShipping   Status = "shipping"
+	StatusDelivered  Status = "delivered"
+	StatusCancelled  Status = "cancelled"
+)
+
+// statuses are the known statuses in lifecycle order.
+var statuses = []Status{StatusPlaced, StatusConfirmed, StatusProcessing, StatusShipping, StatusDelivered, StatusCancelled}
+
+// ParseStatus returns the status of an API name.
+func ParseStatus(code string) (Status, bool) {
+	code = strings.ToLower(strings.TrimSpace(code))
+	for _, s := range statuses {
+		if string(s) == code {
+			return s, true
+		}
+	}
+	return "", false
+}
+
+// Terminal reports whether an order in status s can no longer change.
+func (s Status) Terminal() bool {
+	return s == StatusDelivered || s == StatusCancelled
+}
+
+// Role is the kind of actor changing an order, stored in invoice_status_history."actorRole".
+type Role string
+
+const (
+	RoleCustomer Role = "customer"
+	RoleAdmin    Role = "admin"
+	RoleSystem   Role = "system" //background jobs and carrier notifications
+)
+
+// rule allows roles to move an order from one status to another.
+type rule struct {
+	from, to Status
+	roles    []Role
+}
+
+// rules is the state machine. Customers may only cancel an order nobody has started working on.
+var rules = []rule{
+	{StatusPlaced, StatusConfirmed, []Role{RoleAdmin}},
+	{StatusPlaced, StatusCancelled, []Role{RoleAdmin, RoleCustomer}},
+	{StatusConfirmed, StatusProcessing, []Role{RoleAdmin}},
+	{StatusConfirmed, StatusCancelled, []Role{RoleAdmin}},
+	{StatusProcessing, StatusShipping, []Role{RoleAdmin, RoleSystem}},
+	{StatusProcessing, StatusCancelled, []Role{RoleAdmin}},
+	{StatusShipping, StatusDelivered, []Role{RoleAdmin, RoleSystem}},
+	{StatusShipping, StatusCancelled, []Role{RoleAdmin, RoleSystem}}, //delivery failed, the order went back to the shop
+}
+
+// findRule returns the rule from one status to another, if the change exists at all.
+func findRule(from, to Status) (rule, bool) {
+	for _, r := range rules {
+		if r.from == from && r.to == to {
+			return r, true
+		}
+	}
+	return rule{}, false
+}
+
+// Can reports whether role may move an order from one status to another.
+func Can(role Role, from, to Status) bool {
+	r, ok := findRule(from, to)
+	if !ok {
+		return false
+	}
+	for _, allowed := range r.roles {
+		if allowed == role {
+			return true
+		}
+	}
+	return false
+}
+
+// Next returns the statuses role may move an order in status from to, in lifecycle order.
+func Next(role Role, from Status) []Status {
+	next := []Status{}
+	for _, r := range rules {
+		if r.from == from && Can(role, r.from, r.to) {
+			next = append(next, r.to)
+		}
+	}
+	return next
+}
@@ -0,0 +1,61 @@
+package order
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCan(t *testing.T) {
+	tests := []struct {
+		name     string
+		role     Role
+		from, to Status
+		want     bool
+	}{
+		{"admin confirms", RoleAdmin, StatusPlaced, StatusConfirmed, true},
+		{"customer cancels a new order", RoleCustomer, StatusPlaced, StatusCancelled, true},
+		{"customer cannot cancel a confirmed order", RoleCustomer, StatusConfirmed, StatusCancelled, false},
+		{"customer cannot confirm", RoleCustomer, StatusPlaced, StatusConfirmed, false},
+		{"admin cancels while processing", RoleAdmin, StatusProcessing, StatusCancelled, true},
+		{"no skipping to delivered", RoleAdmin, StatusPlaced, StatusDelivered, false},
+		{"no going back", RoleAdmin, StatusShipping, StatusProcessing, false},
+		{"delivered is final", RoleAdmin, StatusDelivered, StatusCancelled, false},
+		{"cancelled is final", RoleAdmin, StatusCancelled, StatusPlaced, false},
+		{"carrier delivers", RoleSystem, StatusShipping, StatusDelivered, true},
+		{"system cannot confirm", RoleSystem, StatusPlaced, StatusConfirmed, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Can(tt.role, tt.from, tt.to))
+		})
+	}
+}
+
+func TestNext(t *testing.T) {
+	assert.Equal(t, []Status{StatusConfirmed, StatusCancelled}, Next(RoleAdmin, StatusPlaced))
+	assert.Equal(t, []Status{StatusCancelled}, Next(RoleCustomer, StatusPlaced))
+	assert.Equal(t, []Status{StatusDelivered, StatusCancelled}, Next(RoleAdmin, StatusShipping))
+	assert.Empty(t, Next(RoleCustomer, StatusConfirmed))
+	assert.Empty(t, Next(RoleAdmin, StatusDelivered))
+}
+
+func TestRulesEndInTerminalStatuses(t *testing.T) {
+	for _, r := range rules {
+		assert.Contains(t, statuses, r.from, "rule from unknown status")
+		assert.Contains(t, statuses, r.to, "rule to unknown status")
+		assert.False(t, r.from.Terminal(), "%s cannot change", r.from)
+	}
+	for _, s := range statuses {
+		assert.Equal(t, s.Terminal(), len(Next(RoleAdmin, s)) == 0, string(s))
+	}
+}
+
+func TestParseStatus(t *testing.T) {
+	s, ok := ParseStatus(" Cancelled ")
+	assert.True(t, ok)
+	assert.Equal(t, StatusCancelled, s)
+
+	_, ok = ParseStatus("lost")
+	assert.False(t, ok)
+}
@@ -0,0 +1,49 @@
+package order
+
+import (
+	"GoodFood-BE/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/aarondl/sqlboiler/v4/boil"
+)
+
+// ErrUnknownStatus is returned when invoice_status has no row for a status, or a row with a code the state
+// machine does not know.
+var ErrUnknownStatus = errors.New("unknown invoice status")
+
+// FindStatus returns the invoice_status row of s.
+func FindStatus(ctx context.Context, exec boil.ContextExecutor, s Status) (*models.InvoiceStatus, error) {
+	row, err := models.InvoiceStatuses(models.InvoiceStatusWhere.Code.EQ(string(s))).One(ctx, exec)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownStatus, s)
+	}
+	return row, err
+}
+
+// StatusID returns the invoice_status id of s.
+func StatusID(ctx context.Context, exec boil.ContextExecutor, s Status) (int, error) {
+	row, err := FindStatus(ctx, exec, s)
+	if err != nil {
+		return 0, err
+	}
+	return row.InvoiceStatusID, nil
+}
+
+// StatusOf returns the status of an invoice_status id.
+func StatusOf(ctx context.Context, exec boil.ContextExecutor, id int) (Status, error) {
+	row, err := models.FindInvoiceStatus(ctx, exec, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("%w: id %d", ErrUnknownStatus, id)
+	}
+	if err != nil {
+		return "", err
+	}
+	s, ok := ParseStatus(row.Code)
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownStatus, row.Code)
+	}
+	return s, nil
+}
@@ -0,0 +1,146 @@
+package order
+
+import (
+	"GoodFood-BE/models"
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"strings"
+
+	"github.com/aarondl/null/v8"
+	"github.com/aarondl/sqlboiler/v4/boil"
+	"github.com/aarondl/sqlboiler/v4/queries/qm"
+)
+
+var (
+	ErrInvoiceNotFound   = errors.New("invoice not found")
+	ErrNotOwner          = errors.New("invoice belongs to another account")
+	ErrInvalidTransition = errors.New("the order cannot move to this status from its current one")
+	ErrForbidden         = errors.New("not allowed to move the order to this status")
+	ErrReasonRequired    = errors.New("a reason is required to cancel an order")
+)
+
+// Actor is who changes an order. AccountID and Username stay empty for the system; API keys only have a Username.
+// IP is the address of the request, passed on to payment providers by the refund hook.
+type Actor struct {
+	Role      Role
+	AccountID null.Int
+	Username  null.String
+	IP        string
+}
+
+// Event is a committed status change, handed to the hooks of its target status.
+type Event struct {
+	Invoice *models.Invoice
+	From    Status
+	To      Status
+	Actor   Actor
+	Reason  string
+}
+
+// Hook is a side effect of entering a status, such as an email or a refund. It runs after the change is
+// committed; an error is logged and does not undo the change.
+type Hook func(ctx context.Context, e Event) error
+
+var hooks = map[Status][]Hook{}
+
+// OnEnter runs h after every change to status s. Hooks are registered at init, in the order they run.
+func OnEnter(s Status, h Hook) {
+	hooks[s] = append(hooks[s], h)
+}
+
+// Transition moves an invoice to status to on behalf of actor and records the change. reason is required
+// for cancellations and stored as the cancel reason of the invoice. Moving an order to the status it already
+// has changes nothing.
+func Transition(ctx context.Context, invoiceID int, to Status, actor Actor, reason string) (*models.Invoice, error) {
+	reason = strings.TrimSpace(reason)
+	tx, err := boil.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	invoice, err := models.Invoices(models.InvoiceWhere.InvoiceID.EQ(invoiceID), qm.For("UPDATE")).One(ctx, tx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvoiceNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if actor.Role == RoleCustomer && (!actor.AccountID.Valid || actor.AccountID.Int != invoice.AccountID) {
+		return nil, ErrNotOwner
+	}
+	from, err := StatusOf(ctx, tx, invoice.InvoiceStatusID)
+	if err != nil {
+		return nil, err
+	}
+	if from == to {
+		return invoice, nil
+	}
+	if _, ok := findRule(from, to); !ok {
+		return nil, ErrInvalidTransition
+	}
+	if !Can(actor.Role, from, to) {
+		return nil, ErrForbidden
+	}
+	if to == StatusCancelled && reason == "" {
+		return nil, ErrReasonRequired
+	}
+	if actor.Role == RoleAdmin && (to == StatusShipping || to == StatusDelivered) {
+		if managed, err := carrierManaged(ctx, tx, invoiceID); err != nil {
+			return nil, err
+		} else if managed {
+			return nil, ErrCarrierManaged
+		}
+	}
+
+	toID, err := StatusID(ctx, tx, to)
+	if err != nil {
+		return nil, err
+	}
+	fromID := invoice.InvoiceStatusID
+	invoice.InvoiceStatusID = toID
+	switch to {
+	case StatusDelivered:
+		//Cash on delivery is collected with the order
+		invoice.Status = true
+	case StatusCancelled:
+		invoice.CancelReason = null.StringFrom(reason)
+	}
+	if _, err := invoice.Update(ctx, tx, boil.Infer()); err != nil {
+		return nil, err
+	}
+	if err := record(ctx, tx, invoice.InvoiceID, null.IntFrom(fromID), toID, actor, reason); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	event := Event{Invoice: invoice, From: from, To: to, Actor: actor, Reason: reason}
+	for _, h := range hooks[to] {
+		if err := h(ctx, event); err != nil {
+			log.Printf("order %d %s -> %s hook: %v", invoice.InvoiceID, from, to, err)
+		}
+	}
+	return invoice, nil
+}
+
+// RecordPlaced records the placement of a new invoice, inside the transaction inserting it.
+func RecordPlaced(ctx context.Context, exec boil.ContextExecutor, invoice *models.Invoice, actor Actor) error {
+	return record(ctx, exec, invoice.InvoiceID, null.Int{}, invoice.InvoiceStatusID, actor, "")
+}
+
+// record inserts one row of invoice_status_history, between the invoice_status ids from and to.
+func record(ctx context.Context, exec boil.ContextExecutor, invoiceID int, from null.Int, to int, actor Actor, reason string) error {
+	var r null.String
+	if reason != "" {
+		r = null.StringFrom(reason)
+	}
+	_, err := exec.ExecContext(ctx, `
+		INSERT INTO invoice_status_history ("invoiceID", "fromStatusID", "toStatusID", "actorRole", "actorID", "actorUsername", reason)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		invoiceID, from, to, string(actor.Role), actor.AccountID, actor.Username, r)
+	return err
+}
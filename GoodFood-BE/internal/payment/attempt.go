@@ -0,0 +1,174 @@
+package payment
+
+import (
+	"GoodFood-BE/internal/money"
+	"GoodFood-BE/models"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/aarondl/null/v8"
+	"github.com/aarondl/sqlboiler/v4/boil"
+	"github.com/gofrs/uuid"
+)
+
+// Attempt statuses stored in payment_attempt.status.
+const (
+	AttemptPending   = "pending"
+	AttemptSucceeded = "succeeded"
+	AttemptFailed    = "failed"
+	AttemptExpired   = "expired"
+)
+
+// AttemptTTL is how long the customer has to complete an attempt, the vnp_ExpireDate of the payment URL.
+const AttemptTTL = 15 * time.Minute
+
+// expiryInterval is how often StartExpiry marks abandoned attempts expired.
+const expiryInterval = 5 * time.Minute
+
+// ErrAttemptNotFound is returned for a merchant reference that matches no attempt.
+var ErrAttemptNotFound = errors.New("payment reference not found")
+
+// Attempt is one row of payment_attempt.
+type Attempt struct {
+	AttemptID     string      `json:"attemptID"`
+	InvoiceID     int         `json:"invoiceID"`
+	Provider      string      `json:"provider"` //method code of the provider
+	TxnRef        string      `json:"txnRef"`
+	Amount        money.Money `json:"amount"`
+	Status        string      `json:"status"`
+	ResponseCode  null.String `json:"responseCode"`
+	TransactionNo null.String `json:"transactionNo"`
+	CreatedAt     time.Time   `json:"createdAt"`
+	ExpiresAt     time.Time   `json:"expiresAt"`
+	CompletedAt   null.Time   `json:"completedAt"`
+}
+
+// attemptColumns lists the columns scanned by scanAttempt, in order.
+const attemptColumns = `"attemptID", "invoiceID", provider, "txnRef", amount, status, "responseCode", "transactionNo", "createdAt", "expiresAt", "completedAt"`
+
+func scanAttempt(row interface{ Scan(...interface{}) error }) (*Attempt, error) {
+	a := &Attempt{}
+	err := row.Scan(&a.AttemptID, &a.InvoiceID, &a.Provider, &a.TxnRef, &a.Amount, &a.Status,
+		&a.ResponseCode, &a.TransactionNo, &a.CreatedAt, &a.ExpiresAt, &a.CompletedAt)
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// newTxnRef returns a random merchant reference: 32 hex characters, which every provider accepts.
+func newTxnRef() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// CreateAttempt starts a payment attempt of the total of invoice with provider.
+// Earlier attempts are left as they are: one of them may still be completed by the customer.
+func CreateAttempt(ctx context.Context, invoice *models.Invoice, provider string) (*Attempt, error) {
+	id, err := uuid.NewV4()
+	if err != nil {
+		return nil, err
+	}
+	txnRef, err := newTxnRef()
+	if err != nil {
+		return nil, err
+	}
+	a := &Attempt{
+		AttemptID: id.String(),
+		InvoiceID: invoice.InvoiceID,
+		Provider:  provider,
+		TxnRef:    txnRef,
+		Amount:    invoice.TotalPrice,
+		Status:    AttemptPending,
+	}
+	err = boil.GetContextDB().QueryRowContext(ctx, `
+		INSERT INTO payment_attempt ("attemptID", "invoiceID", provider, "txnRef", amount, status, "expiresAt")
+		VALUES ($1, $2, $3, $4, $5, $6, now() + $7 * interval '1 second')
+		RETURNING "createdAt", "expiresAt"`,
+		a.AttemptID, a.InvoiceID, a.Provider, a.TxnRef, a.Amount, a.Status, int64(AttemptTTL/time.Second)).
+		Scan(&a.CreatedAt, &a.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// ListAttempts returns the payment attempts of an invoice, newest first.
+func ListAttempts(ctx context.Context, invoiceID int) ([]*Attempt, error) {
+	rows, err := boil.GetContextDB().QueryContext(ctx, `
+		SELECT `+attemptColumns+` FROM payment_attempt
+		WHERE "invoiceID" = $1 ORDER BY "createdAt" DESC`, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	attempts := []*Attempt{}
+	for rows.Next() {
+		a, err := scanAttempt(rows)
+		if err != nil {
+			return nil, err
+		}
+		attempts = append(attempts, a)
+	}
+	return attempts, rows.Err()
+}
+
+// lockAttempt loads the attempt of a provider reference and locks it until the end of tx.
+func lockAttempt(ctx context.Context, tx boil.ContextTransactor, provider, txnRef string) (*Attempt, error) {
+	a, err := scanAttempt(tx.QueryRowContext(ctx, `
+		SELECT `+attemptColumns+` FROM payment_attempt
+		WHERE provider = $1 AND "txnRef" = $2 FOR UPDATE`, provider, txnRef))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAttemptNotFound
+	}
+	return a, err
+}
+
+// completeAttempt stores the outcome reported by the provider.
+func completeAttempt(ctx context.Context, tx boil.ContextTransactor, a *Attempt, status, responseCode, transactionNo string) error {
+	a.Status = status
+	a.ResponseCode = null.StringFrom(responseCode)
+	a.TransactionNo = null.NewString(transactionNo, transactionNo != "")
+	a.CompletedAt = null.TimeFrom(time.Now())
+	_, err := tx.ExecContext(ctx, `
+		UPDATE payment_attempt SET status = $2, "responseCode" = $3, "transactionNo" = $4, "completedAt" = $5
+		WHERE "attemptID" = $1`, a.AttemptID, a.Status, a.ResponseCode, a.TransactionNo, a.CompletedAt)
+	return err
+}
+
+// ExpireAttempts marks the pending attempts past their expiry expired and returns how many were.
+func ExpireAttempts(ctx context.Context, now time.Time) (int64, error) {
+	res, err := boil.GetContextDB().ExecContext(ctx, `
+		UPDATE payment_attempt SET status = $1
+		WHERE status = $2 AND "expiresAt" < $3`, AttemptExpired, AttemptPending, now)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// StartExpiry expires abandoned attempts now and then every few minutes until ctx is done.
+func StartExpiry(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(expiryInterval)
+		defer ticker.Stop()
+		for {
+			if _, err := ExpireAttempts(ctx, time.Now()); err != nil {
+				log.Printf("payment: expiring attempts failed: %v", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
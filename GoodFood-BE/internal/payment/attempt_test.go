@@ -0,0 +1,20 @@
+package payment
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTxnRef(t *testing.T) {
+	seen := map[string]bool{}
+	for i := 0; i < 100; i++ {
+		ref, err := newTxnRef()
+		require.NoError(t, err)
+		assert.Regexp(t, regexp.MustCompile(`^[0-9a-f]{32}$`), ref)
+		assert.False(t, seen[ref], "references must not repeat")
+		seen[ref] = true
+	}
+}
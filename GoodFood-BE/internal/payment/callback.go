@@ -0,0 +1,133 @@
+package payment
+
+import (
+	"GoodFood-BE/models"
+	"context"
+	"errors"
+	"log"
+
+	"github.com/aarondl/sqlboiler/v4/boil"
+	"github.com/aarondl/sqlboiler/v4/queries/qm"
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid payment notification signature")
+	ErrInvalidAmount    = errors.New("amount does not match the payment attempt")
+	ErrAlreadyConfirmed = errors.New("payment already recorded")
+)
+
+// StartPayment creates a payment attempt of invoice with the provider of its payment method and returns
+// the URL where the customer pays it. Methods paid on delivery return ErrNotSupported.
+func StartPayment(ctx context.Context, invoice *models.Invoice, ip string) (*Attempt, string, error) {
+	p, err := InvoiceMethod(ctx, boil.GetContextDB(), invoice.InvoiceID)
+	if err != nil {
+		return nil, "", err
+	}
+	if !p.Online() {
+		return nil, "", ErrNotSupported
+	}
+	attempt, err := CreateAttempt(ctx, invoice, p.Method())
+	if err != nil {
+		return nil, "", err
+	}
+	url, err := p.CreatePayment(ctx, attempt, invoice, ip)
+	if err != nil {
+		return nil, "", err
+	}
+	return attempt, url, nil
+}
+
+// HandleCallback verifies a notification of the provider of method and applies it with ApplyCallback.
+// The result is returned with ErrAlreadyConfirmed too.
+func HandleCallback(ctx context.Context, method string, params map[string]string) (*CallbackResult, *models.Invoice, error) {
+	p, err := Get(method)
+	if err != nil {
+		return nil, nil, err
+	}
+	result, err := p.VerifyCallback(params)
+	if err != nil {
+		return nil, nil, err
+	}
+	_, invoice, err := ApplyCallback(ctx, p.Method(), result)
+	return result, invoice, err
+}
+
+// Acknowledge returns the answer the provider of method expects to a notification processed with err.
+func Acknowledge(method string, err error) interface{} {
+	if p, getErr := Get(method); getErr == nil {
+		if a, ok := p.(CallbackAcknowledger); ok {
+			return a.Acknowledge(err)
+		}
+	}
+	if err != nil {
+		return map[string]string{"status": "error", "message": err.Error()}
+	}
+	return map[string]string{"status": "Success"}
+}
+
+// ApplyCallback completes the attempt of a payment made with method, records it as a transaction of its invoice
+// and marks the invoice paid when it succeeded. Notifications for an attempt that is already completed return
+// ErrAlreadyConfirmed with the invoice.
+func ApplyCallback(ctx context.Context, method string, r *CallbackResult) (*models.Transaction, *models.Invoice, error) {
+	tx, err := boil.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	//The lock serializes the notifications of one attempt, such as the VNPay return URL and IPN
+	attempt, err := lockAttempt(ctx, tx, method, r.TxnRef)
+	if err != nil {
+		return nil, nil, err
+	}
+	invoice, err := models.Invoices(
+		models.InvoiceWhere.InvoiceID.EQ(attempt.InvoiceID),
+		qm.For("UPDATE"),
+	).One(ctx, tx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if attempt.Amount != r.Amount {
+		return nil, invoice, ErrInvalidAmount
+	}
+	//An expired attempt can still succeed: the customer paid right before the deadline
+	if attempt.Status == AttemptSucceeded || attempt.Status == AttemptFailed {
+		return nil, invoice, ErrAlreadyConfirmed
+	}
+
+	transaction := &models.Transaction{
+		TransactionCode: r.TransactionNo,
+		CardNumber:      r.CardNumber,
+		FullName:        invoice.ReceiveName,
+		TransactionDate: r.PaidAt,
+		Status:          r.Success,
+		Amount:          r.Amount,
+		AccountID:       invoice.AccountID,
+		InvoiceID:       invoice.InvoiceID,
+	}
+	if err := transaction.Insert(ctx, tx, boil.Infer()); err != nil {
+		return nil, nil, err
+	}
+	status := AttemptFailed
+	if transaction.Status {
+		status = AttemptSucceeded
+	}
+	if err := completeAttempt(ctx, tx, attempt, status, r.ResponseCode, r.TransactionNo); err != nil {
+		return nil, nil, err
+	}
+	if transaction.Status {
+		if invoice.Status {
+			//Paid twice through two attempts, kept for reconciliation
+			log.Printf("payment: invoice %d already paid, attempt %s charged again", invoice.InvoiceID, attempt.AttemptID)
+		} else {
+			invoice.Status = true
+			if _, err := invoice.Update(ctx, tx, boil.Whitelist(models.InvoiceColumns.Status)); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, nil, err
+	}
+	return transaction, invoice, nil
+}
@@ -0,0 +1,30 @@
+package payment
+
+import (
+	"GoodFood-BE/models"
+	"context"
+)
+
+// CODProvider is cash on delivery: the order is paid to the courier, nothing goes through an online provider.
+type CODProvider struct{}
+
+func (CODProvider) Method() string { return MethodCOD }
+
+func (CODProvider) Online() bool { return false }
+
+func (CODProvider) CreatePayment(context.Context, *Attempt, *models.Invoice, string) (string, error) {
+	return "", ErrNotSupported
+}
+
+func (CODProvider) VerifyCallback(map[string]string) (*CallbackResult, error) {
+	return nil, ErrNotSupported
+}
+
+func (CODProvider) Query(context.Context, *Attempt, string) (*QueryResult, error) {
+	return nil, ErrNotSupported
+}
+
+// Refund is not supported: cash is given back by the shop, outside of the application.
+func (CODProvider) Refund(context.Context, ProviderRefund) (*RefundResult, error) {
+	return nil, ErrNotSupported
+}
@@ -0,0 +1,152 @@
+package payment
+
+import (
+	"GoodFood-BE/internal/money"
+	"GoodFood-BE/models"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// MethodFake is the method code of FakeProvider.
+const MethodFake = "FAKE"
+
+// fakeSecret signs the callbacks of FakeProvider.
+const fakeSecret = "goodfood-fake-provider"
+
+// FakeProvider is an in-memory online provider for tests and local development. Nothing is registered by default:
+// call Register(NewFakeProvider()) and enable the FAKE payment method. Customers never see a real payment page;
+// the test drives the outcome with Callback.
+type FakeProvider struct {
+	mu       sync.Mutex
+	attempts map[string]*fakePayment
+}
+
+type fakePayment struct {
+	amount   money.Money
+	paid     bool
+	refunded money.Money
+}
+
+func NewFakeProvider() *FakeProvider {
+	return &FakeProvider{attempts: map[string]*fakePayment{}}
+}
+
+func (f *FakeProvider) Method() string { return MethodFake }
+
+func (f *FakeProvider) Online() bool { return true }
+
+// CreatePayment records the attempt and returns a URL that cannot be opened.
+func (f *FakeProvider) CreatePayment(_ context.Context, attempt *Attempt, _ *models.Invoice, _ string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.attempts[attempt.TxnRef] = &fakePayment{amount: attempt.Amount}
+	return "https://pay.fake.invalid/" + attempt.TxnRef, nil
+}
+
+// fakeTransactionNo derives the provider transaction number of an attempt.
+func fakeTransactionNo(txnRef string) string {
+	return "FAKE" + txnRef[:min(len(txnRef), 12)]
+}
+
+func fakeSign(txnRef, amount, status string) string {
+	mac := hmac.New(sha256.New, []byte(fakeSecret))
+	mac.Write([]byte(txnRef + "|" + amount + "|" + status))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Callback returns the signed parameters of the notification sent when the customer completes the attempt txnRef,
+// and records the payment when success is true. It returns nil for an unknown attempt.
+func (f *FakeProvider) Callback(txnRef string, success bool) map[string]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	p, ok := f.attempts[txnRef]
+	if !ok {
+		return nil
+	}
+	status := "failed"
+	if success {
+		status = "paid"
+		p.paid = true
+	}
+	amount := strconv.FormatInt(p.amount.Int64(), 10)
+	return map[string]string{
+		"txnRef":        txnRef,
+		"amount":        amount,
+		"status":        status,
+		"transactionNo": fakeTransactionNo(txnRef),
+		"signature":     fakeSign(txnRef, amount, status),
+	}
+}
+
+func (f *FakeProvider) VerifyCallback(params map[string]string) (*CallbackResult, error) {
+	expected := fakeSign(params["txnRef"], params["amount"], params["status"])
+	if !hmac.Equal([]byte(expected), []byte(params["signature"])) {
+		return nil, ErrInvalidSignature
+	}
+	if params["txnRef"] == "" {
+		return nil, ErrAttemptNotFound
+	}
+	amount, err := money.Parse(params["amount"])
+	if err != nil || amount < 0 {
+		return nil, ErrInvalidAmount
+	}
+	success := params["status"] == "paid"
+	message := "Payment failed"
+	if success {
+		message = "Payment successful"
+	}
+	return &CallbackResult{
+		TxnRef:        params["txnRef"],
+		Amount:        amount,
+		Success:       success,
+		ResponseCode:  params["status"],
+		Message:       message,
+		TransactionNo: params["transactionNo"],
+		CardNumber:    MethodFake,
+		PaidAt:        time.Now(),
+	}, nil
+}
+
+func (f *FakeProvider) Query(_ context.Context, attempt *Attempt, _ string) (*QueryResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	p, ok := f.attempts[attempt.TxnRef]
+	if !ok {
+		return nil, ErrAttemptNotFound
+	}
+	r := &QueryResult{Method: MethodFake, TxnRef: attempt.TxnRef, Paid: p.paid, Amount: p.amount, ResponseCode: "failed"}
+	if p.paid {
+		r.TransactionNo = fakeTransactionNo(attempt.TxnRef)
+		r.ResponseCode = "paid"
+	}
+	return r, nil
+}
+
+func (f *FakeProvider) Refund(_ context.Context, r ProviderRefund) (*RefundResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	p, ok := f.attempts[r.Attempt.TxnRef]
+	if !ok || !p.paid {
+		return &RefundResult{ResponseCode: "not_paid", Message: "the payment was not made"}, nil
+	}
+	if r.Amount <= 0 || p.refunded+r.Amount > p.amount {
+		return &RefundResult{ResponseCode: "amount", Message: "the refund exceeds the payment"}, nil
+	}
+	p.refunded += r.Amount
+	return &RefundResult{Success: true, ResponseCode: "refunded", Message: "Refunded"}, nil
+}
+
+// Refunded returns the amount refunded of the attempt txnRef.
+func (f *FakeProvider) Refunded(txnRef string) money.Money {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if p, ok := f.attempts[txnRef]; ok {
+		return p.refunded
+	}
+	return 0
+}
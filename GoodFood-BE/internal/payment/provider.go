@@ -0,0 +1,201 @@
+// Package payment takes the payment of orders through pluggable providers. Every try to pay an invoice online is
+// a payment attempt with its own merchant reference. Providers report the outcome of an attempt with signed
+// callbacks, possibly several times (VNPay redirects the customer to the return URL and calls the IPN endpoint);
+// all of them go through ApplyCallback, which is idempotent, so whichever arrives first completes the attempt
+// and marks the invoice paid.
+package payment
+
+import (
+	"GoodFood-BE/internal/money"
+	"GoodFood-BE/models"
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aarondl/sqlboiler/v4/boil"
+)
+
+// Codes of the built-in payment methods, stored in payment_method.code and invoice."paymentMethodCode".
+const (
+	MethodCOD   = "COD"
+	MethodVNPay = "VNPAY"
+)
+
+// methodAliases maps names used by older clients to method codes.
+var methodAliases = map[string]string{
+	"ONLINE": MethodVNPay,
+}
+
+var (
+	ErrUnknownMethod = errors.New("unknown payment method")
+	ErrNotSupported  = errors.New("not supported by the payment method")
+)
+
+// Provider is a way to pay for an order. Online providers take the payment before delivery: the customer pays
+// each attempt at the URL returned by CreatePayment, and the provider reports the outcome with a signed callback.
+type Provider interface {
+	// Method is the code of the payment method, the key of the registry and of payment_method.
+	Method() string
+	// Online reports whether the customer pays through the provider before delivery.
+	Online() bool
+	// CreatePayment returns the URL where the customer pays attempt.
+	CreatePayment(ctx context.Context, attempt *Attempt, invoice *models.Invoice, ip string) (string, error)
+	// VerifyCallback checks the signature of a notification of the provider and reads the outcome of the payment.
+	VerifyCallback(params map[string]string) (*CallbackResult, error)
+	// Query asks the provider for the current status of attempt.
+	Query(ctx context.Context, attempt *Attempt, ip string) (*QueryResult, error)
+	// Refund asks the provider to give back part or all of a payment.
+	Refund(ctx context.Context, r ProviderRefund) (*RefundResult, error)
+}
+
+// CallbackAcknowledger is implemented by providers expecting a specific answer to their server to server notifications.
+type CallbackAcknowledger interface {
+	Acknowledge(err error) interface{}
+}
+
+// CallbackResult is the signed outcome of a payment attempt.
+type CallbackResult struct {
+	TxnRef        string      `json:"txnRef"`
+	Amount        money.Money `json:"amount"`
+	Success       bool        `json:"success"`
+	ResponseCode  string      `json:"responseCode"`
+	Message       string      `json:"message"`
+	TransactionNo string      `json:"transactionNo"`
+	CardNumber    string      `json:"-"` //what the provider tells about the card or account, stored in transaction."cardNumber"
+	PaidAt        time.Time   `json:"paidAt"`
+}
+
+// QueryResult is the status of a payment attempt according to its provider.
+type QueryResult struct {
+	Method        string      `json:"method"`
+	TxnRef        string      `json:"txnRef"`
+	Paid          bool        `json:"paid"`
+	Amount        money.Money `json:"amount"`
+	TransactionNo string      `json:"transactionNo"`
+	ResponseCode  string      `json:"responseCode"`
+	Message       string      `json:"message"`
+}
+
+// ProviderRefund is a refund of Amount of the payment made through Attempt. Full is true when it gives back
+// the whole payment at once. CreatedBy names the admin and IP is their address.
+type ProviderRefund struct {
+	RequestID string
+	Attempt   *Attempt
+	Amount    money.Money
+	Full      bool
+	CreatedBy string
+	IP        string
+}
+
+// RefundResult is the answer of a provider to a refund.
+type RefundResult struct {
+	Success      bool
+	ResponseCode string
+	Message      string
+}
+
+var (
+	providersMu sync.RWMutex
+	providers   = map[string]Provider{}
+)
+
+func init() {
+	Register(CODProvider{})
+	Register(VNPayProvider{})
+}
+
+// Register makes p available under its method code, replacing a provider registered with the same code.
+// The method is offered to customers once it is enabled in payment_method.
+func Register(p Provider) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	providers[p.Method()] = p
+}
+
+// normalizeMethod returns the method code of a name sent by a client.
+func normalizeMethod(method string) string {
+	method = strings.ToUpper(strings.TrimSpace(method))
+	if code, ok := methodAliases[method]; ok {
+		return code
+	}
+	return method
+}
+
+// Get returns the registered provider of a payment method.
+func Get(method string) (Provider, error) {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+	p, ok := providers[normalizeMethod(method)]
+	if !ok {
+		return nil, ErrUnknownMethod
+	}
+	return p, nil
+}
+
+// Method is a payment method offered to customers.
+type Method struct {
+	Code   string `json:"code"`
+	Name   string `json:"name"`
+	Online bool   `json:"online"`
+}
+
+// EnabledMethods returns the enabled payment methods that have a registered provider, in display order.
+func EnabledMethods(ctx context.Context) ([]Method, error) {
+	rows, err := boil.GetContextDB().QueryContext(ctx, `
+		SELECT code, name FROM payment_method WHERE enabled ORDER BY "sortOrder", code`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	methods := []Method{}
+	for rows.Next() {
+		m := Method{}
+		if err := rows.Scan(&m.Code, &m.Name); err != nil {
+			return nil, err
+		}
+		if p, err := Get(m.Code); err == nil {
+			m.Online = p.Online()
+			methods = append(methods, m)
+		}
+	}
+	return methods, rows.Err()
+}
+
+// ResolveMethod returns the provider of an enabled payment method.
+func ResolveMethod(ctx context.Context, exec boil.ContextExecutor, method string) (Provider, error) {
+	p, err := Get(method)
+	if err != nil {
+		return nil, err
+	}
+	var enabled bool
+	err = exec.QueryRowContext(ctx, `SELECT enabled FROM payment_method WHERE code = $1`, p.Method()).Scan(&enabled)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && !enabled) {
+		return nil, ErrUnknownMethod
+	}
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// SetInvoiceMethod stores the payment method of an invoice.
+// invoice."paymentMethod" is kept for older readers: true means the order is paid on delivery.
+func SetInvoiceMethod(ctx context.Context, exec boil.ContextExecutor, invoiceID int, p Provider) error {
+	_, err := exec.ExecContext(ctx, `
+		UPDATE invoice SET "paymentMethodCode" = $2, "paymentMethod" = $3 WHERE "invoiceID" = $1`,
+		invoiceID, p.Method(), !p.Online())
+	return err
+}
+
+// InvoiceMethod returns the provider of the payment method of an invoice.
+func InvoiceMethod(ctx context.Context, exec boil.ContextExecutor, invoiceID int) (Provider, error) {
+	var method string
+	err := exec.QueryRowContext(ctx, `SELECT "paymentMethodCode" FROM invoice WHERE "invoiceID" = $1`, invoiceID).Scan(&method)
+	if err != nil {
+		return nil, err
+	}
+	return Get(method)
+}
@@ -0,0 +1,119 @@
+package payment
+
+import (
+	"GoodFood-BE/internal/money"
+	"GoodFood-BE/models"
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGet(t *testing.T) {
+	tests := []struct {
+		method string
+		want   string
+	}{
+		{"COD", MethodCOD},
+		{"cod", MethodCOD},
+		{"VNPAY", MethodVNPay},
+		{" vnpay ", MethodVNPay},
+		{"ONLINE", MethodVNPay}, //older clients
+	}
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			p, err := Get(tt.method)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, p.Method())
+		})
+	}
+
+	_, err := Get("FREE")
+	assert.ErrorIs(t, err, ErrUnknownMethod)
+	_, err = Get(MethodFake)
+	assert.ErrorIs(t, err, ErrUnknownMethod, "the fake provider is not registered by default")
+}
+
+func TestCODProviderIsOffline(t *testing.T) {
+	p := CODProvider{}
+	assert.False(t, p.Online())
+
+	_, err := p.CreatePayment(context.Background(), &Attempt{}, &models.Invoice{}, "127.0.0.1")
+	assert.ErrorIs(t, err, ErrNotSupported)
+	_, err = p.VerifyCallback(map[string]string{})
+	assert.ErrorIs(t, err, ErrNotSupported)
+	_, err = p.Query(context.Background(), &Attempt{}, "127.0.0.1")
+	assert.ErrorIs(t, err, ErrNotSupported)
+	_, err = p.Refund(context.Background(), ProviderRefund{Attempt: &Attempt{}})
+	assert.ErrorIs(t, err, ErrNotSupported)
+}
+
+func TestVNPayProviderCreatePayment(t *testing.T) {
+	t.Setenv("VNPAY_SECRET", testSecret)
+	t.Setenv("VNPAY_TMN", "GOODFOOD")
+	t.Setenv("VNPAY_RETURN_URL", "https://goodfood.example/payment/return")
+	created := time.Date(2025, 10, 20, 1, 0, 0, 0, time.UTC)
+	attempt := &Attempt{TxnRef: testTxnRef, Amount: 180000, CreatedAt: created, ExpiresAt: created.Add(AttemptTTL)}
+
+	payURL, err := VNPayProvider{}.CreatePayment(context.Background(), attempt, &models.Invoice{InvoiceID: 42}, "127.0.0.1")
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(payURL, vnpSandboxPayURL+"?"))
+
+	u, err := url.Parse(payURL)
+	require.NoError(t, err)
+	params := map[string]string{}
+	for k, v := range u.Query() {
+		params[k] = v[0]
+	}
+	assert.True(t, VerifySecureHash(params), "the payment URL must carry a valid signature")
+	assert.Equal(t, "18000000", params["vnp_Amount"])
+	assert.Equal(t, testTxnRef, params["vnp_TxnRef"])
+	assert.Equal(t, "20251020080000", params["vnp_CreateDate"])
+	assert.Equal(t, "20251020081500", params["vnp_ExpireDate"])
+	assert.Equal(t, "Paying for invoice: 42", params["vnp_OrderInfo"])
+}
+
+func TestAcknowledge(t *testing.T) {
+	assert.Equal(t, IPNResponseFor(ErrInvalidAmount), Acknowledge(MethodVNPay, ErrInvalidAmount))
+	assert.Equal(t, map[string]string{"status": "Success"}, Acknowledge("UNKNOWN", nil))
+	assert.Equal(t, map[string]string{"status": "error", "message": ErrInvalidSignature.Error()},
+		Acknowledge("UNKNOWN", ErrInvalidSignature))
+}
+
+func TestFakeProvider(t *testing.T) {
+	f := NewFakeProvider()
+	attempt := &Attempt{TxnRef: testTxnRef, Amount: 50000}
+	payURL, err := f.CreatePayment(context.Background(), attempt, &models.Invoice{}, "127.0.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, "https://pay.fake.invalid/"+testTxnRef, payURL)
+	assert.Nil(t, f.Callback("unknown", true))
+
+	params := f.Callback(testTxnRef, true)
+	result, err := f.VerifyCallback(params)
+	require.NoError(t, err)
+	assert.True(t, result.Success)
+	assert.Equal(t, money.VND(50000), result.Amount)
+	assert.Equal(t, params["transactionNo"], result.TransactionNo)
+
+	tampered := f.Callback(testTxnRef, true)
+	tampered["amount"] = "1"
+	_, err = f.VerifyCallback(tampered)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+
+	status, err := f.Query(context.Background(), attempt, "127.0.0.1")
+	require.NoError(t, err)
+	assert.True(t, status.Paid)
+	assert.Equal(t, MethodFake, status.Method)
+
+	refund, err := f.Refund(context.Background(), ProviderRefund{Attempt: attempt, Amount: 30000})
+	require.NoError(t, err)
+	assert.True(t, refund.Success)
+	refund, err = f.Refund(context.Background(), ProviderRefund{Attempt: attempt, Amount: 30000})
+	require.NoError(t, err)
+	assert.False(t, refund.Success, "only 20000 is left to refund")
+	assert.Equal(t, money.VND(30000), f.Refunded(testTxnRef))
+}
@@ -0,0 +1,273 @@
+package payment
+
+import (
+	"GoodFood-BE/internal/money"
+	"GoodFood-BE/models"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/aarondl/null/v8"
+	"github.com/aarondl/sqlboiler/v4/boil"
+	"github.com/aarondl/sqlboiler/v4/queries/qm"
+)
+
+// Refund statuses stored in refund.status.
+const (
+	RefundPending   = "pending"
+	RefundSucceeded = "succeeded"
+	RefundFailed    = "failed"
+)
+
+// invoiceStatusCancelled is the invoice_status code of cancelled orders, the only ones that can be refunded.
+const invoiceStatusCancelled = "cancelled"
+
+var (
+	ErrNotRefundable   = errors.New("only cancelled orders paid online can be refunded")
+	ErrPaymentNotFound = errors.New("no successful online payment found for the invoice")
+	ErrRefundAmount    = errors.New("the refund amount must be positive and at most the amount not refunded yet")
+	ErrRefundNotFound  = errors.New("refund not found")
+	ErrRefundResolved  = errors.New("the refund is no longer pending")
+)
+
+// Refund is one row of refund.
+type Refund struct {
+	RefundID      int         `json:"refundID"`
+	TransactionID int         `json:"transactionID"`
+	InvoiceID     int         `json:"invoiceID"`
+	Amount        money.Money `json:"amount"`
+	Status        string      `json:"status"`
+	RequestID     string      `json:"requestID"`
+	ResponseCode  null.String `json:"responseCode"`
+	Message       null.String `json:"message"`
+	CreatedBy     null.Int    `json:"createdBy"`
+	CreatedAt     time.Time   `json:"createdAt"`
+	CompletedAt   null.Time   `json:"completedAt"`
+}
+
+// RefundRequest asks to give back Amount of a payment of an invoice. Amount 0 refunds everything not refunded yet.
+// TransactionID 0 picks the latest successful payment. CreatedBy and CreatedByName identify the admin, IP their address.
+type RefundRequest struct {
+	InvoiceID     int
+	TransactionID int
+	Amount        money.Money
+	CreatedBy     null.Int
+	CreatedByName string
+	IP            string
+}
+
+// paidTransaction is a successful transaction with the attempt its provider knows it by.
+type paidTransaction struct {
+	TransactionID int
+	Amount        money.Money
+	Attempt       *Attempt
+}
+
+// lockPaidTransaction loads a successful online transaction of an invoice and locks it until the end of tx,
+// so two refunds of one payment cannot both pass the amount check.
+func lockPaidTransaction(ctx context.Context, tx boil.ContextTransactor, invoiceID, transactionID int) (*paidTransaction, error) {
+	t := &paidTransaction{Attempt: &Attempt{}}
+	a := t.Attempt
+	err := tx.QueryRowContext(ctx, `
+		SELECT t."transactionID", t.amount, a."attemptID", a."invoiceID", a.provider, a."txnRef",
+			a.amount, a.status, a."responseCode", a."transactionNo", a."createdAt", a."expiresAt", a."completedAt"
+		FROM transaction t
+		JOIN payment_attempt a ON a."invoiceID" = t."invoiceID" AND a."transactionNo" = t."transactionCode"
+		WHERE t."invoiceID" = $1 AND t.status AND a.status = $2
+			AND ($3 = 0 OR t."transactionID" = $3)
+		ORDER BY t."transactionID" DESC
+		LIMIT 1
+		FOR UPDATE OF t`, invoiceID, AttemptSucceeded, transactionID).
+		Scan(&t.TransactionID, &t.Amount, &a.AttemptID, &a.InvoiceID, &a.Provider, &a.TxnRef, &a.Amount, &a.Status,
+			&a.ResponseCode, &a.TransactionNo, &a.CreatedAt, &a.ExpiresAt, &a.CompletedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrPaymentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// startRefund checks req and inserts a pending refund, returning it with the transaction it refunds.
+// full reports whether it gives back the whole payment at once, which some providers tell apart from partial refunds.
+func startRefund(ctx context.Context, req RefundRequest) (r *Refund, paid *paidTransaction, full bool, err error) {
+	tx, err := boil.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	defer tx.Rollback()
+
+	invoice, err := models.Invoices(models.InvoiceWhere.InvoiceID.EQ(req.InvoiceID), qm.For("UPDATE")).One(ctx, tx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, false, ErrPaymentNotFound
+	}
+	if err != nil {
+		return nil, nil, false, err
+	}
+	status, err := models.FindInvoiceStatus(ctx, tx, invoice.InvoiceStatusID)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	if status.Code != invoiceStatusCancelled || !invoice.Status {
+		return nil, nil, false, ErrNotRefundable
+	}
+	paid, err = lockPaidTransaction(ctx, tx, req.InvoiceID, req.TransactionID)
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	var refunded money.Money
+	err = tx.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(amount), 0) FROM refund WHERE "transactionID" = $1 AND status IN ($2, $3)`,
+		paid.TransactionID, RefundPending, RefundSucceeded).Scan(&refunded)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	amount := req.Amount
+	if amount == 0 {
+		amount = paid.Amount - refunded
+	}
+	if amount <= 0 || amount > paid.Amount-refunded {
+		return nil, nil, false, ErrRefundAmount
+	}
+
+	requestID, err := newRequestID()
+	if err != nil {
+		return nil, nil, false, err
+	}
+	r = &Refund{
+		TransactionID: paid.TransactionID,
+		InvoiceID:     req.InvoiceID,
+		Amount:        amount,
+		Status:        RefundPending,
+		RequestID:     requestID,
+		CreatedBy:     req.CreatedBy,
+	}
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO refund ("transactionID", "invoiceID", amount, status, "requestID", "createdBy")
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING "refundID", "createdAt"`,
+		r.TransactionID, r.InvoiceID, r.Amount, r.Status, r.RequestID, r.CreatedBy).Scan(&r.RefundID, &r.CreatedAt)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, nil, false, err
+	}
+	return r, paid, refunded == 0 && amount == paid.Amount, nil
+}
+
+// RefundInvoice refunds an online payment of a cancelled invoice through the provider that took it and stores the outcome.
+// When the provider cannot be reached the refund is returned pending along with the error: it may have been processed,
+// so its amount cannot be refunded again until an admin checks the payment with QueryAttempt and settles it with ResolveRefund.
+func RefundInvoice(ctx context.Context, req RefundRequest) (*Refund, error) {
+	r, paid, full, err := startRefund(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	p, err := Get(paid.Attempt.Provider)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.Refund(ctx, ProviderRefund{
+		RequestID: r.RequestID,
+		Attempt:   paid.Attempt,
+		Amount:    r.Amount,
+		Full:      full,
+		CreatedBy: req.CreatedByName,
+		IP:        req.IP,
+	})
+	if err != nil {
+		r.Message = null.StringFrom(err.Error())
+		if _, dbErr := boil.GetContextDB().ExecContext(ctx, `
+			UPDATE refund SET message = $2 WHERE "refundID" = $1`, r.RefundID, r.Message); dbErr != nil {
+			return r, dbErr
+		}
+		return r, err
+	}
+
+	r.Status = RefundFailed
+	if resp.Success {
+		r.Status = RefundSucceeded
+	}
+	r.ResponseCode = null.StringFrom(resp.ResponseCode)
+	r.Message = null.StringFrom(resp.Message)
+	r.CompletedAt = null.TimeFrom(time.Now())
+	_, err = boil.GetContextDB().ExecContext(ctx, `
+		UPDATE refund SET status = $2, "responseCode" = $3, message = $4, "completedAt" = $5
+		WHERE "refundID" = $1`, r.RefundID, r.Status, r.ResponseCode, r.Message, r.CompletedAt)
+	return r, err
+}
+
+// ResolveRefund settles a pending refund of an invoice the provider never answered for, once the outcome is known
+// from the provider. A failed refund gives its amount back to the refundable amount of the payment.
+func ResolveRefund(ctx context.Context, invoiceID, refundID int, succeeded bool, message string) (*Refund, error) {
+	status := RefundFailed
+	if succeeded {
+		status = RefundSucceeded
+	}
+	r := &Refund{}
+	//Only a pending refund moves, so two admins cannot settle it twice
+	err := boil.GetContextDB().QueryRowContext(ctx, `
+		UPDATE refund SET status = $3, message = COALESCE(NULLIF($4, ''), message), "completedAt" = now()
+		WHERE "refundID" = $1 AND "invoiceID" = $2 AND status = $5
+		RETURNING "refundID", "transactionID", "invoiceID", amount, status, "requestID", "responseCode", message,
+			"createdBy", "createdAt", "completedAt"`, refundID, invoiceID, status, message, RefundPending).
+		Scan(&r.RefundID, &r.TransactionID, &r.InvoiceID, &r.Amount, &r.Status, &r.RequestID,
+			&r.ResponseCode, &r.Message, &r.CreatedBy, &r.CreatedAt, &r.CompletedAt)
+	if !errors.Is(err, sql.ErrNoRows) {
+		return r, err
+	}
+	var exists bool
+	if err := boil.GetContextDB().QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM refund WHERE "refundID" = $1 AND "invoiceID" = $2)`, refundID, invoiceID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrRefundNotFound
+	}
+	return nil, ErrRefundResolved
+}
+
+// ListRefunds returns the refunds of an invoice, newest first.
+func ListRefunds(ctx context.Context, invoiceID int) ([]*Refund, error) {
+	rows, err := boil.GetContextDB().QueryContext(ctx, `
+		SELECT "refundID", "transactionID", "invoiceID", amount, status, "requestID", "responseCode", message,
+			"createdBy", "createdAt", "completedAt"
+		FROM refund WHERE "invoiceID" = $1 ORDER BY "createdAt" DESC`, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	refunds := []*Refund{}
+	for rows.Next() {
+		r := &Refund{}
+		if err := rows.Scan(&r.RefundID, &r.TransactionID, &r.InvoiceID, &r.Amount, &r.Status, &r.RequestID,
+			&r.ResponseCode, &r.Message, &r.CreatedBy, &r.CreatedAt, &r.CompletedAt); err != nil {
+			return nil, err
+		}
+		refunds = append(refunds, r)
+	}
+	return refunds, rows.Err()
+}
+
+// QueryAttempt asks the provider of a payment attempt of an invoice for its status, the latest attempt when txnRef is empty.
+func QueryAttempt(ctx context.Context, invoiceID int, txnRef, ip string) (*QueryResult, error) {
+	a, err := scanAttempt(boil.GetContextDB().QueryRowContext(ctx, `
+		SELECT `+attemptColumns+` FROM payment_attempt
+		WHERE "invoiceID" = $1 AND ($2 = '' OR "txnRef" = $2)
+		ORDER BY "createdAt" DESC LIMIT 1`, invoiceID, txnRef))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAttemptNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	p, err := Get(a.Provider)
+	if err != nil {
+		return nil, err
+	}
+	return p.Query(ctx, a, ip)
+}
@@ -0,0 +1,231 @@
+package payment
+
+import (
+	"GoodFood-BE/internal/money"
+	"GoodFood-BE/models"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Codes answered to a VNPay IPN call. Any other answer makes VNPay retry the notification.
+const (
+	IPNConfirmSuccess   = "00"
+	IPNOrderNotFound    = "01"
+	IPNAlreadyConfirmed = "02"
+	IPNInvalidAmount    = "04"
+	IPNInvalidSignature = "97"
+	IPNUnknownError     = "99"
+)
+
+// vnpSuccess is the vnp_ResponseCode and vnp_TransactionStatus of a successful payment.
+const vnpSuccess = "00"
+
+// vnpDateLayout is the layout of VNPay timestamps, in Asia/Ho_Chi_Minh time.
+const vnpDateLayout = "20060102150405"
+
+// Fixed fields of the VNPay payment URL.
+const (
+	vnpCommandPay = "pay"
+	vnpCurrCode   = "VND"
+	vnpBankCode   = "NCB"
+	vnpLocale     = "vn"
+)
+
+// vnpSandboxPayURL is the payment page of the VNPay sandbox.
+const vnpSandboxPayURL = "https://sandbox.vnpayment.vn/paymentv2/vpcpay.html"
+
+// IPNResponse is the body VNPay expects in answer to an IPN call.
+type IPNResponse struct {
+	RspCode string `json:"RspCode"`
+	Message string `json:"Message"`
+}
+
+// IPNResponseFor maps the error returned by HandleCallback to the IPN answer.
+func IPNResponseFor(err error) IPNResponse {
+	switch {
+	case err == nil:
+		return IPNResponse{RspCode: IPNConfirmSuccess, Message: "Confirm Success"}
+	case errors.Is(err, ErrInvalidSignature):
+		return IPNResponse{RspCode: IPNInvalidSignature, Message: "Invalid signature"}
+	case errors.Is(err, ErrAttemptNotFound):
+		return IPNResponse{RspCode: IPNOrderNotFound, Message: "Order not found"}
+	case errors.Is(err, ErrInvalidAmount):
+		return IPNResponse{RspCode: IPNInvalidAmount, Message: "Invalid amount"}
+	case errors.Is(err, ErrAlreadyConfirmed):
+		return IPNResponse{RspCode: IPNAlreadyConfirmed, Message: "Order already confirmed"}
+	default:
+		return IPNResponse{RspCode: IPNUnknownError, Message: "Unknown error"}
+	}
+}
+
+// responseMessages describes the vnp_ResponseCode of failed payments.
+var responseMessages = map[string]string{
+	"07": "The payment is suspected of fraud",
+	"09": "The card or account is not registered for internet banking",
+	"10": "The card or account could not be verified",
+	"11": "The payment has expired",
+	"12": "The card or account is locked",
+	"13": "Wrong one-time password",
+	"24": "The payment was cancelled",
+	"51": "Insufficient balance",
+	"65": "The daily transaction limit has been exceeded",
+	"75": "The bank is under maintenance",
+	"79": "Too many wrong payment passwords",
+}
+
+// vnpayMessage describes the outcome of a payment for the customer.
+func vnpayMessage(success bool, responseCode string) string {
+	if success {
+		return "Payment successful"
+	}
+	if msg, ok := responseMessages[responseCode]; ok {
+		return msg
+	}
+	return "Payment failed"
+}
+
+// vnpAmount formats amount as a vnp_Amount, in hundredths of a dong.
+func vnpAmount(amount money.Money) (string, error) {
+	hundredths, err := amount.Mul(100)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(hundredths.Int64(), 10), nil
+}
+
+// parseVNPAmount reads a vnp_Amount. VNPay only takes whole dong.
+func parseVNPAmount(s string) (money.Money, error) {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 || n%100 != 0 {
+		return 0, ErrInvalidAmount
+	}
+	return money.VND(n / 100), nil
+}
+
+// ParseVNPayResult verifies the signature of the parameters VNPay sent to the return URL or the IPN endpoint
+// and reads the payment outcome.
+func ParseVNPayResult(params map[string]string) (*CallbackResult, error) {
+	if !VerifySecureHash(params) {
+		return nil, ErrInvalidSignature
+	}
+	txnRef := params["vnp_TxnRef"]
+	if txnRef == "" {
+		return nil, ErrAttemptNotFound
+	}
+	amount, err := parseVNPAmount(params["vnp_Amount"])
+	if err != nil {
+		return nil, err
+	}
+
+	paidAt := time.Now()
+	if loc, err := time.LoadLocation("Asia/Ho_Chi_Minh"); err == nil {
+		if t, err := time.ParseInLocation(vnpDateLayout, params["vnp_PayDate"], loc); err == nil {
+			paidAt = t
+		}
+	}
+	cardNumber := params["vnp_BankTranNo"]
+	if cardNumber == "" {
+		cardNumber = params["vnp_BankCode"]
+	}
+	success := params["vnp_ResponseCode"] == vnpSuccess && params["vnp_TransactionStatus"] == vnpSuccess
+	return &CallbackResult{
+		TxnRef:        txnRef,
+		Amount:        amount,
+		Success:       success,
+		ResponseCode:  params["vnp_ResponseCode"],
+		Message:       vnpayMessage(success, params["vnp_ResponseCode"]),
+		TransactionNo: params["vnp_TransactionNo"],
+		CardNumber:    cardNumber,
+		PaidAt:        paidAt,
+	}, nil
+}
+
+// VNPayProvider takes online payments through the VNPay gateway. It is configured by VNPAY_TMN, VNPAY_SECRET,
+// VNPAY_RETURN_URL and VNPAY_API_URL.
+type VNPayProvider struct{}
+
+func (VNPayProvider) Method() string { return MethodVNPay }
+
+func (VNPayProvider) Online() bool { return true }
+
+// CreatePayment builds the signed VNPay payment URL of attempt. It expires with the attempt.
+func (VNPayProvider) CreatePayment(_ context.Context, attempt *Attempt, invoice *models.Invoice, ip string) (string, error) {
+	amount, err := vnpAmount(attempt.Amount)
+	if err != nil {
+		return "", err
+	}
+	params := map[string]string{
+		"vnp_Version":    vnpVersion,
+		"vnp_Command":    vnpCommandPay,
+		"vnp_TmnCode":    os.Getenv("VNPAY_TMN"),
+		"vnp_Amount":     amount,
+		"vnp_CurrCode":   vnpCurrCode,
+		"vnp_BankCode":   vnpBankCode,
+		"vnp_TxnRef":     attempt.TxnRef,
+		"vnp_OrderInfo":  fmt.Sprintf("Paying for invoice: %d", invoice.InvoiceID),
+		"vnp_Locale":     vnpLocale,
+		"vnp_OrderType":  "other",
+		"vnp_ReturnUrl":  os.Getenv("VNPAY_RETURN_URL"),
+		"vnp_IpAddr":     ip,
+		"vnp_CreateDate": vnpDate(attempt.CreatedAt),
+		"vnp_ExpireDate": vnpDate(attempt.ExpiresAt),
+	}
+	query := url.Values{}
+	for k, v := range params {
+		if v != "" {
+			query.Set(k, v)
+		}
+	}
+	//The hash is appended as is, after the signed fields
+	return fmt.Sprintf("%s?%s&vnp_SecureHash=%s", vnpSandboxPayURL, query.Encode(), HashAllFields(params)), nil
+}
+
+func (VNPayProvider) VerifyCallback(params map[string]string) (*CallbackResult, error) {
+	return ParseVNPayResult(params)
+}
+
+func (VNPayProvider) Query(ctx context.Context, attempt *Attempt, ip string) (*QueryResult, error) {
+	resp, err := NewVNPayClient().QueryDR(ctx, vnpayTransaction(attempt), ip)
+	if err != nil {
+		return nil, err
+	}
+	return &QueryResult{
+		Method:        MethodVNPay,
+		TxnRef:        attempt.TxnRef,
+		Paid:          resp.Successful() && resp.TransactionStatus == vnpSuccess,
+		Amount:        resp.Amount(),
+		TransactionNo: resp.TransactionNo,
+		ResponseCode:  resp.ResponseCode,
+		Message:       resp.Message,
+	}, nil
+}
+
+func (VNPayProvider) Refund(ctx context.Context, r ProviderRefund) (*RefundResult, error) {
+	resp, err := NewVNPayClient().Refund(ctx, VNPayRefundRequest{
+		RequestID:   r.RequestID,
+		Transaction: vnpayTransaction(r.Attempt),
+		Amount:      r.Amount,
+		Full:        r.Full,
+		CreatedBy:   r.CreatedBy,
+		IP:          r.IP,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &RefundResult{Success: resp.Successful(), ResponseCode: resp.ResponseCode, Message: resp.Message}, nil
+}
+
+// Acknowledge answers IPN calls in the format VNPay expects.
+func (VNPayProvider) Acknowledge(err error) interface{} {
+	return IPNResponseFor(err)
+}
+
+// vnpayTransaction returns how VNPay identifies the payment of attempt.
+func vnpayTransaction(attempt *Attempt) VNPayTransaction {
+	return VNPayTransaction{TxnRef: attempt.TxnRef, TransactionNo: attempt.TransactionNo.String, CreatedAt: attempt.CreatedAt}
+}
@@ -0,0 +1,156 @@
+package payment
+
+import (
+	"GoodFood-BE/internal/money"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSecret = "TESTSECRET"
+
+const testTxnRef = "9f86d081884c7d659a2feaa0c55ad015"
+
+// signed returns VNPay callback parameters of attempt testTxnRef signed with testSecret, after applying edit.
+func signed(t *testing.T, edit func(map[string]string)) map[string]string {
+	t.Setenv("VNPAY_SECRET", testSecret)
+	params := map[string]string{
+		"vnp_Amount":            "18000000",
+		"vnp_BankCode":          "NCB",
+		"vnp_BankTranNo":        "VNP14226112",
+		"vnp_CardType":          "ATM",
+		"vnp_OrderInfo":         "Paying for invoice: 42",
+		"vnp_PayDate":           "20251020153000",
+		"vnp_ResponseCode":      "00",
+		"vnp_TmnCode":           "GOODFOOD",
+		"vnp_TransactionNo":     "14226112",
+		"vnp_TransactionStatus": "00",
+		"vnp_TxnRef":            testTxnRef,
+	}
+	if edit != nil {
+		edit(params)
+	}
+	params["vnp_SecureHash"] = HashAllFields(params)
+	params["vnp_SecureHashType"] = "HmacSHA512"
+	return params
+}
+
+func TestParseVNPayResult(t *testing.T) {
+	result, err := ParseVNPayResult(signed(t, nil))
+	require.NoError(t, err)
+
+	assert.Equal(t, testTxnRef, result.TxnRef)
+	assert.Equal(t, money.VND(180000), result.Amount)
+	assert.Equal(t, "14226112", result.TransactionNo)
+	assert.Equal(t, "VNP14226112", result.CardNumber)
+	assert.True(t, result.Success)
+	assert.Equal(t, "Payment successful", result.Message)
+	assert.Equal(t, time.Date(2025, 10, 20, 8, 30, 0, 0, time.UTC), result.PaidAt.UTC())
+}
+
+func TestParseVNPayResultRejectsTampering(t *testing.T) {
+	tests := []struct {
+		name   string
+		params func() map[string]string
+		want   error
+	}{
+		{
+			name: "amount changed after signing",
+			params: func() map[string]string {
+				p := signed(t, nil)
+				p["vnp_Amount"] = "100"
+				return p
+			},
+			want: ErrInvalidSignature,
+		},
+		{
+			name: "missing hash",
+			params: func() map[string]string {
+				p := signed(t, nil)
+				delete(p, "vnp_SecureHash")
+				return p
+			},
+			want: ErrInvalidSignature,
+		},
+		{
+			name: "signed with another secret",
+			params: func() map[string]string {
+				t.Setenv("VNPAY_SECRET", "OTHER")
+				p := signed(t, nil)
+				p["vnp_SecureHash"] = HashAllFields(map[string]string{"vnp_TxnRef": "42"})
+				return p
+			},
+			want: ErrInvalidSignature,
+		},
+		{
+			name:   "missing reference",
+			params: func() map[string]string { return signed(t, func(p map[string]string) { delete(p, "vnp_TxnRef") }) },
+			want:   ErrAttemptNotFound,
+		},
+		{
+			name:   "amount not in hundredths",
+			params: func() map[string]string { return signed(t, func(p map[string]string) { p["vnp_Amount"] = "18000050" }) },
+			want:   ErrInvalidAmount,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseVNPayResult(tt.params())
+			assert.ErrorIs(t, err, tt.want)
+		})
+	}
+}
+
+func TestParseVNPayResultAcceptsUppercaseHash(t *testing.T) {
+	params := signed(t, func(p map[string]string) { p["vnp_OrderInfo"] = "Thanh toan don hang #42" })
+	params["vnp_SecureHash"] = strings.ToUpper(params["vnp_SecureHash"])
+
+	_, err := ParseVNPayResult(params)
+	assert.NoError(t, err)
+}
+
+func TestParseVNPayResultFailure(t *testing.T) {
+	result, err := ParseVNPayResult(signed(t, func(p map[string]string) {
+		p["vnp_ResponseCode"] = "24"
+		p["vnp_TransactionStatus"] = "02"
+		p["vnp_TransactionNo"] = "0"
+	}))
+	require.NoError(t, err)
+
+	assert.False(t, result.Success)
+	assert.Equal(t, "The payment was cancelled", result.Message)
+}
+
+func TestIPNResponseFor(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{nil, IPNConfirmSuccess},
+		{ErrInvalidSignature, IPNInvalidSignature},
+		{ErrAttemptNotFound, IPNOrderNotFound},
+		{ErrInvalidAmount, IPNInvalidAmount},
+		{ErrAlreadyConfirmed, IPNAlreadyConfirmed},
+		{errors.New("connection refused"), IPNUnknownError},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, IPNResponseFor(tt.err).RspCode)
+	}
+}
+
+func TestHashAllFields(t *testing.T) {
+	t.Setenv("VNPAY_SECRET", testSecret)
+	fields := map[string]string{
+		"vnp_TxnRef":    "42",
+		"vnp_Amount":    "18000000",
+		"vnp_BankCode":  "",
+		"vnp_OrderInfo": "Paying for invoice: 42",
+	}
+	//Sorted by name, the empty bank code skipped and the values query-escaped
+	want := HmacSHA512(testSecret, "vnp_Amount=18000000&vnp_OrderInfo=Paying+for+invoice%3A+42&vnp_TxnRef=42")
+	assert.Equal(t, want, HashAllFields(fields))
+}
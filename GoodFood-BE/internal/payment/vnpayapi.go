@@ -0,0 +1,225 @@
+package payment
+
+import (
+	"GoodFood-BE/internal/money"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// vnpVersion is the VNPay API version of every request.
+const vnpVersion = "2.1.0"
+
+// vnpSandboxAPIURL is the merchant API of the VNPay sandbox, used when VNPAY_API_URL is not set.
+const vnpSandboxAPIURL = "https://sandbox.vnpayment.vn/merchant_webapi/api/transaction"
+
+// Merchant API commands.
+const (
+	vnpCommandQueryDR = "querydr"
+	vnpCommandRefund  = "refund"
+)
+
+// vnp_TransactionType of refund requests.
+const (
+	VNPayRefundFull    = "02"
+	VNPayRefundPartial = "03"
+)
+
+// ErrInvalidResponse is returned when a merchant API response is not signed by VNPay.
+var ErrInvalidResponse = errors.New("invalid VNPay response signature")
+
+// VNPayClient calls the VNPay merchant API, which answers transaction queries and refunds.
+type VNPayClient struct {
+	URL     string
+	TmnCode string
+	HTTP    *http.Client
+}
+
+// NewVNPayClient returns a client of the API at VNPAY_API_URL, or the sandbox, for the merchant VNPAY_TMN.
+// Requests and responses are signed with VNPAY_SECRET.
+func NewVNPayClient() *VNPayClient {
+	url := os.Getenv("VNPAY_API_URL")
+	if url == "" {
+		url = vnpSandboxAPIURL
+	}
+	return &VNPayClient{
+		URL:     url,
+		TmnCode: os.Getenv("VNPAY_TMN"),
+		HTTP:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// VNPayTransaction identifies a payment at VNPay.
+type VNPayTransaction struct {
+	TxnRef        string
+	TransactionNo string
+	CreatedAt     time.Time //vnp_CreateDate of the payment URL
+}
+
+// VNPayAPIResponse is the answer of the merchant API.
+type VNPayAPIResponse struct {
+	ResponseID        string `json:"vnp_ResponseId"`
+	Command           string `json:"vnp_Command"`
+	ResponseCode      string `json:"vnp_ResponseCode"`
+	Message           string `json:"vnp_Message"`
+	TmnCode           string `json:"vnp_TmnCode"`
+	TxnRef            string `json:"vnp_TxnRef"`
+	RawAmount         string `json:"vnp_Amount"`
+	BankCode          string `json:"vnp_BankCode"`
+	PayDate           string `json:"vnp_PayDate"`
+	TransactionNo     string `json:"vnp_TransactionNo"`
+	TransactionType   string `json:"vnp_TransactionType"`
+	TransactionStatus string `json:"vnp_TransactionStatus"`
+	OrderInfo         string `json:"vnp_OrderInfo"`
+	PromotionCode     string `json:"vnp_PromotionCode"`
+	PromotionAmount   string `json:"vnp_PromotionAmount"`
+	SecureHash        string `json:"vnp_SecureHash"`
+}
+
+// Successful reports whether the API accepted the request.
+func (r *VNPayAPIResponse) Successful() bool {
+	return r.ResponseCode == vnpSuccess
+}
+
+// Amount returns vnp_Amount.
+func (r *VNPayAPIResponse) Amount() money.Money {
+	n, _ := strconv.ParseInt(r.RawAmount, 10, 64)
+	return money.VND(n / 100)
+}
+
+// signedValues returns the values covered by vnp_SecureHash, which differ between commands.
+func (r *VNPayAPIResponse) signedValues() []string {
+	values := []string{r.ResponseID, r.Command, r.ResponseCode, r.Message, r.TmnCode, r.TxnRef, r.RawAmount,
+		r.BankCode, r.PayDate, r.TransactionNo, r.TransactionType, r.TransactionStatus, r.OrderInfo}
+	if r.Command == vnpCommandQueryDR {
+		values = append(values, r.PromotionCode, r.PromotionAmount)
+	}
+	return values
+}
+
+// Sign sets vnp_SecureHash. Only a fake VNPay needs it.
+func (r *VNPayAPIResponse) Sign() {
+	r.SecureHash = HashPipeFields(r.signedValues()...)
+}
+
+// verify checks vnp_SecureHash.
+func (r *VNPayAPIResponse) verify() bool {
+	expected := HashPipeFields(r.signedValues()...)
+	return hmac.Equal([]byte(expected), []byte(strings.ToLower(r.SecureHash)))
+}
+
+// newRequestID returns a vnp_RequestId, unique per merchant and day.
+func newRequestID() (string, error) {
+	return newTxnRef()
+}
+
+// vnpDate formats t the way the merchant API expects it.
+func vnpDate(t time.Time) string {
+	if loc, err := time.LoadLocation("Asia/Ho_Chi_Minh"); err == nil {
+		t = t.In(loc)
+	}
+	return t.Format(vnpDateLayout)
+}
+
+// QueryDR asks VNPay for the current status of a payment. ip is the address of the caller.
+func (c *VNPayClient) QueryDR(ctx context.Context, t VNPayTransaction, ip string) (*VNPayAPIResponse, error) {
+	requestID, err := newRequestID()
+	if err != nil {
+		return nil, err
+	}
+	req := map[string]string{
+		"vnp_RequestId":       requestID,
+		"vnp_Version":         vnpVersion,
+		"vnp_Command":         vnpCommandQueryDR,
+		"vnp_TmnCode":         c.TmnCode,
+		"vnp_TxnRef":          t.TxnRef,
+		"vnp_OrderInfo":       "Query transaction " + t.TxnRef,
+		"vnp_TransactionNo":   t.TransactionNo,
+		"vnp_TransactionDate": vnpDate(t.CreatedAt),
+		"vnp_CreateDate":      vnpDate(time.Now()),
+		"vnp_IpAddr":          ip,
+	}
+	req["vnp_SecureHash"] = HashPipeFields(req["vnp_RequestId"], req["vnp_Version"], req["vnp_Command"],
+		req["vnp_TmnCode"], req["vnp_TxnRef"], req["vnp_TransactionDate"], req["vnp_CreateDate"], req["vnp_IpAddr"],
+		req["vnp_OrderInfo"])
+	return c.call(ctx, req)
+}
+
+// VNPayRefundRequest is a refund of Amount of a payment, requested by CreatedBy.
+type VNPayRefundRequest struct {
+	RequestID   string //sent as vnp_RequestId, kept to match the refund with VNPay records
+	Transaction VNPayTransaction
+	Amount      money.Money
+	Full        bool
+	CreatedBy   string
+	IP          string
+}
+
+// Refund asks VNPay to give amount back to the customer.
+func (c *VNPayClient) Refund(ctx context.Context, r VNPayRefundRequest) (*VNPayAPIResponse, error) {
+	amount, err := vnpAmount(r.Amount)
+	if err != nil {
+		return nil, err
+	}
+	transactionType := VNPayRefundPartial
+	if r.Full {
+		transactionType = VNPayRefundFull
+	}
+	req := map[string]string{
+		"vnp_RequestId":       r.RequestID,
+		"vnp_Version":         vnpVersion,
+		"vnp_Command":         vnpCommandRefund,
+		"vnp_TmnCode":         c.TmnCode,
+		"vnp_TransactionType": transactionType,
+		"vnp_TxnRef":          r.Transaction.TxnRef,
+		"vnp_Amount":          amount,
+		"vnp_OrderInfo":       "Refund transaction " + r.Transaction.TxnRef,
+		"vnp_TransactionNo":   r.Transaction.TransactionNo,
+		"vnp_TransactionDate": vnpDate(r.Transaction.CreatedAt),
+		"vnp_CreateBy":        r.CreatedBy,
+		"vnp_CreateDate":      vnpDate(time.Now()),
+		"vnp_IpAddr":          r.IP,
+	}
+	req["vnp_SecureHash"] = HashPipeFields(req["vnp_RequestId"], req["vnp_Version"], req["vnp_Command"],
+		req["vnp_TmnCode"], req["vnp_TransactionType"], req["vnp_TxnRef"], req["vnp_Amount"], req["vnp_TransactionNo"],
+		req["vnp_TransactionDate"], req["vnp_CreateBy"], req["vnp_CreateDate"], req["vnp_IpAddr"], req["vnp_OrderInfo"])
+	return c.call(ctx, req)
+}
+
+// call posts a signed request and verifies the signature of the answer.
+func (c *VNPayClient) call(ctx context.Context, body map[string]string) (*VNPayAPIResponse, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("vnpay %s: unexpected status %s", body["vnp_Command"], resp.Status)
+	}
+
+	result := &VNPayAPIResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return nil, fmt.Errorf("vnpay %s: %w", body["vnp_Command"], err)
+	}
+	if !result.verify() {
+		return nil, ErrInvalidResponse
+	}
+	return result, nil
+}
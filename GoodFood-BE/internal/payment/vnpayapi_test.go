@@ -0,0 +1,104 @@
+package payment_test
+
+import (
+	"GoodFood-BE/internal/money"
+	"GoodFood-BE/internal/payment"
+	"GoodFood-BE/internal/payment/vnpayfake"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const txnRef = "9f86d081884c7d659a2feaa0c55ad015"
+
+// newFake starts a fake VNPay knowing one payment of 180000 VND and returns a client of it.
+func newFake(t *testing.T) (*vnpayfake.Server, *payment.VNPayClient) {
+	t.Setenv("VNPAY_SECRET", "TESTSECRET")
+	fake := vnpayfake.New()
+	t.Cleanup(fake.Close)
+	fake.AddTransaction(vnpayfake.Transaction{TxnRef: txnRef, TransactionNo: "14226112", Amount: 180000})
+
+	t.Setenv("VNPAY_API_URL", fake.URL)
+	t.Setenv("VNPAY_TMN", "GOODFOOD")
+	return fake, payment.NewVNPayClient()
+}
+
+var transaction = payment.VNPayTransaction{
+	TxnRef:        txnRef,
+	TransactionNo: "14226112",
+	CreatedAt:     time.Date(2025, 10, 20, 8, 15, 0, 0, time.UTC),
+}
+
+func TestQueryDR(t *testing.T) {
+	fake, client := newFake(t)
+
+	resp, err := client.QueryDR(context.Background(), transaction, "127.0.0.1")
+	require.NoError(t, err)
+	assert.True(t, resp.Successful())
+	assert.Equal(t, money.VND(180000), resp.Amount())
+	assert.Equal(t, "14226112", resp.TransactionNo)
+
+	require.Len(t, fake.Requests, 1)
+	assert.Equal(t, "querydr", fake.Requests[0]["vnp_Command"])
+	assert.Equal(t, "GOODFOOD", fake.Requests[0]["vnp_TmnCode"])
+	assert.Equal(t, "20251020151500", fake.Requests[0]["vnp_TransactionDate"], "dates are sent in Vietnam time")
+}
+
+func TestQueryDRUnknownTransaction(t *testing.T) {
+	_, client := newFake(t)
+
+	resp, err := client.QueryDR(context.Background(), payment.VNPayTransaction{TxnRef: "unknown"}, "127.0.0.1")
+	require.NoError(t, err)
+	assert.False(t, resp.Successful())
+	assert.Equal(t, vnpayfake.CodeNotFound, resp.ResponseCode)
+}
+
+func TestRefund(t *testing.T) {
+	fake, client := newFake(t)
+	refund := func(requestID string, amount money.Money, full bool) *payment.VNPayAPIResponse {
+		resp, err := client.Refund(context.Background(), payment.VNPayRefundRequest{
+			RequestID: requestID, Transaction: transaction, Amount: amount, Full: full, CreatedBy: "admin", IP: "127.0.0.1",
+		})
+		require.NoError(t, err)
+		return resp
+	}
+
+	resp := refund("req1", 50000, false)
+	assert.True(t, resp.Successful())
+	assert.Equal(t, payment.VNPayRefundPartial, resp.TransactionType)
+	assert.Equal(t, "5000000", fake.Requests[0]["vnp_Amount"])
+
+	assert.Equal(t, vnpayfake.CodeDuplicateRequest, refund("req1", 50000, false).ResponseCode)
+	assert.Equal(t, vnpayfake.CodeInvalidData, refund("req2", 180000, true).ResponseCode, "no full refund after a partial one")
+	assert.Equal(t, vnpayfake.CodeInvalidData, refund("req3", 130001, false).ResponseCode)
+	assert.True(t, refund("req4", 130000, false).Successful())
+
+	state, _ := fake.Transaction(txnRef)
+	assert.Equal(t, int64(180000), state.Refunded)
+}
+
+func TestResponseSignatureIsVerified(t *testing.T) {
+	t.Setenv("VNPAY_SECRET", "TESTSECRET")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp := &payment.VNPayAPIResponse{Command: "querydr", ResponseCode: "00", TxnRef: txnRef, RawAmount: "18000000"}
+		resp.Sign()
+		resp.RawAmount = "100" //tampered after signing
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	client := &payment.VNPayClient{URL: server.URL, HTTP: server.Client()}
+	_, err := client.QueryDR(context.Background(), transaction, "127.0.0.1")
+	assert.ErrorIs(t, err, payment.ErrInvalidResponse)
+}
+
+func TestHashPipeFields(t *testing.T) {
+	t.Setenv("VNPAY_SECRET", "TESTSECRET")
+	assert.Equal(t, payment.HmacSHA512("TESTSECRET", "a|b||c"), payment.HashPipeFields("a", "b", "", "c"))
+}
@@ -0,0 +1,165 @@
+// Package vnpayfake is an in-memory VNPay merchant API for tests. It checks request signatures
+// the way VNPay does and answers signed querydr and refund responses.
+package vnpayfake
+
+import (
+	"GoodFood-BE/internal/payment"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+)
+
+// Response codes of the merchant API used by the fake.
+const (
+	CodeSuccess          = "00"
+	CodeInvalidData      = "03"
+	CodeNotFound         = "91"
+	CodeDuplicateRequest = "94"
+	CodeInvalidChecksum  = "97"
+)
+
+// Transaction is a payment known to the fake. Amounts are in VND.
+type Transaction struct {
+	TxnRef        string
+	TransactionNo string
+	Amount        int64
+	Refunded      int64
+}
+
+// Server is a running fake VNPay. Requests holds every request received, in order.
+type Server struct {
+	*httptest.Server
+
+	mu           sync.Mutex
+	transactions map[string]*Transaction
+	requestIDs   map[string]bool
+	Requests     []map[string]string
+}
+
+// New starts a fake VNPay. Close it when done.
+func New() *Server {
+	s := &Server{transactions: map[string]*Transaction{}, requestIDs: map[string]bool{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// AddTransaction registers a successful payment.
+func (s *Server) AddTransaction(t Transaction) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.transactions[t.TxnRef] = &t
+}
+
+// Transaction returns the state of a payment.
+func (s *Server) Transaction(txnRef string) (Transaction, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.transactions[txnRef]
+	if !ok {
+		return Transaction{}, false
+	}
+	return *t, true
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	req := map[string]string{}
+	if r.Method != http.MethodPost || json.NewDecoder(r.Body).Decode(&req) != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Requests = append(s.Requests, req)
+
+	resp := &payment.VNPayAPIResponse{
+		ResponseID: req["vnp_RequestId"],
+		Command:    req["vnp_Command"],
+		TmnCode:    req["vnp_TmnCode"],
+		TxnRef:     req["vnp_TxnRef"],
+		OrderInfo:  req["vnp_OrderInfo"],
+	}
+	switch req["vnp_Command"] {
+	case "querydr":
+		s.queryDR(req, resp)
+	case "refund":
+		s.refund(req, resp)
+	default:
+		resp.ResponseCode = CodeInvalidData
+	}
+	resp.Sign()
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// check verifies the signature and request ID, setting the response code when they are wrong.
+func (s *Server) check(req map[string]string, resp *payment.VNPayAPIResponse, signed ...string) bool {
+	values := make([]string, len(signed))
+	for i, k := range signed {
+		values[i] = req[k]
+	}
+	switch {
+	case payment.HashPipeFields(values...) != req["vnp_SecureHash"]:
+		resp.ResponseCode = CodeInvalidChecksum
+	case s.requestIDs[req["vnp_RequestId"]]:
+		resp.ResponseCode = CodeDuplicateRequest
+	default:
+		s.requestIDs[req["vnp_RequestId"]] = true
+		return true
+	}
+	return false
+}
+
+func (s *Server) queryDR(req map[string]string, resp *payment.VNPayAPIResponse) {
+	if !s.check(req, resp, "vnp_RequestId", "vnp_Version", "vnp_Command", "vnp_TmnCode", "vnp_TxnRef",
+		"vnp_TransactionDate", "vnp_CreateDate", "vnp_IpAddr", "vnp_OrderInfo") {
+		return
+	}
+	t, ok := s.transactions[req["vnp_TxnRef"]]
+	if !ok {
+		resp.ResponseCode = CodeNotFound
+		return
+	}
+	resp.ResponseCode = CodeSuccess
+	resp.Message = "QueryDR Success"
+	resp.RawAmount = strconv.FormatInt(t.Amount*100, 10)
+	resp.BankCode = "NCB"
+	resp.TransactionNo = t.TransactionNo
+	resp.TransactionStatus = "00"
+	resp.TransactionType = "01"
+	switch {
+	case t.Refunded == t.Amount:
+		resp.TransactionType = payment.VNPayRefundFull
+	case t.Refunded > 0:
+		resp.TransactionType = payment.VNPayRefundPartial
+	}
+}
+
+func (s *Server) refund(req map[string]string, resp *payment.VNPayAPIResponse) {
+	if !s.check(req, resp, "vnp_RequestId", "vnp_Version", "vnp_Command", "vnp_TmnCode", "vnp_TransactionType",
+		"vnp_TxnRef", "vnp_Amount", "vnp_TransactionNo", "vnp_TransactionDate", "vnp_CreateBy", "vnp_CreateDate",
+		"vnp_IpAddr", "vnp_OrderInfo") {
+		return
+	}
+	t, ok := s.transactions[req["vnp_TxnRef"]]
+	if !ok || t.TransactionNo != req["vnp_TransactionNo"] {
+		resp.ResponseCode = CodeNotFound
+		return
+	}
+	amount, err := strconv.ParseInt(req["vnp_Amount"], 10, 64)
+	amount /= 100
+	full := req["vnp_TransactionType"] == payment.VNPayRefundFull
+	if err != nil || amount <= 0 || t.Refunded+amount > t.Amount || (full && (t.Refunded > 0 || amount != t.Amount)) {
+		resp.ResponseCode = CodeInvalidData
+		return
+	}
+	t.Refunded += amount
+	resp.ResponseCode = CodeSuccess
+	resp.Message = "Refund success"
+	resp.RawAmount = req["vnp_Amount"]
+	resp.BankCode = "NCB"
+	resp.TransactionNo = t.TransactionNo
+	resp.TransactionType = req["vnp_TransactionType"]
+	resp.TransactionStatus = "05"
+}
@@ -0,0 +1,60 @@
+package payment
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/hex"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+)
+
+// HmacSHA512 returns the hex encoded HMAC-SHA512 of data, the signature VNPay uses for every request and response.
+func HmacSHA512(key, data string) string {
+	h := hmac.New(sha512.New, []byte(key))
+	h.Write([]byte(data))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// HashAllFields signs fields with VNPAY_SECRET the way VNPay signs payment URLs and callbacks:
+// fields sorted by name, empty fields skipped and values query-escaped.
+func HashAllFields(fields map[string]string) string {
+	var keys []string
+	for k, v := range fields {
+		if v != "" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	for i, k := range keys {
+		if i > 0 {
+			sb.WriteString("&")
+		}
+		sb.WriteString(k + "=" + url.QueryEscape(fields[k]))
+	}
+	return HmacSHA512(os.Getenv("VNPAY_SECRET"), sb.String())
+}
+
+// VerifySecureHash checks the vnp_SecureHash of parameters sent by VNPay (return URL and IPN).
+// Only vnp_ fields are signed, vnp_SecureHash and vnp_SecureHashType themselves excluded.
+func VerifySecureHash(params map[string]string) bool {
+	received := params["vnp_SecureHash"]
+	if received == "" || os.Getenv("VNPAY_SECRET") == "" {
+		return false
+	}
+	fields := make(map[string]string, len(params))
+	for k, v := range params {
+		if strings.HasPrefix(k, "vnp_") && k != "vnp_SecureHash" && k != "vnp_SecureHashType" {
+			fields[k] = v
+		}
+	}
+	return hmac.Equal([]byte(HashAllFields(fields)), []byte(strings.ToLower(received)))
+}
+
+// HashPipeFields signs values joined by "|", the format of the merchant API (querydr, refund) requests and responses.
+func HashPipeFields(values ...string) string {
+	return HmacSHA512(os.Getenv("VNPAY_SECRET"), strings.Join(values, "|"))
+}
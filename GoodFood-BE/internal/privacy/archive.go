@@ -0,0 +1,238 @@
+package privacy
+
+import (
+	"GoodFood-BE/internal/auth"
+	"GoodFood-BE/models"
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"time"
+
+	"github.com/aarondl/null/v8"
+	"github.com/aarondl/sqlboiler/v4/boil"
+	"github.com/aarondl/sqlboiler/v4/queries/qm"
+)
+
+// maxImageSize bounds each review image copied into an archive.
+const maxImageSize = 10 << 20
+
+// imageClient downloads the review images of an archive.
+var imageClient = &http.Client{Timeout: 30 * time.Second}
+
+// chatHistoryNote explains the missing chat section of the archive.
+const chatHistoryNote = "Live chat messages are relayed in real time and never stored, so there is no chat history to export."
+
+// exportManifest is export.json, the first file of the archive.
+type exportManifest struct {
+	AccountID     int       `json:"accountID"`
+	ExportedAt    time.Time `json:"exportedAt"`
+	Files         []string  `json:"files"`
+	MissingImages []string  `json:"missingImages,omitempty"`
+	Notes         []string  `json:"notes"`
+}
+
+// exportedAccount is account.json. The password hash is never exported.
+type exportedAccount struct {
+	AccountID        int         `json:"accountID"`
+	Username         string      `json:"username"`
+	Email            string      `json:"email"`
+	EmailVerified    bool        `json:"emailVerified"`
+	FullName         string      `json:"fullName"`
+	PhoneNumber      null.String `json:"phoneNumber"`
+	Gender           bool        `json:"gender"`
+	Avatar           null.String `json:"avatar"`
+	Role             string      `json:"role"`
+	LinkedProviders  []string    `json:"linkedProviders"`
+	TwoFactorEnabled bool        `json:"twoFactorEnabled"`
+}
+
+type exportedInvoice struct {
+	*models.Invoice
+	Details      models.InvoiceDetailSlice `json:"details"`
+	Transactions models.TransactionSlice   `json:"transactions"`
+}
+
+type exportedReview struct {
+	*models.Review
+	Images  []exportedImage   `json:"images"`
+	Replies models.ReplySlice `json:"replies"`
+}
+
+// exportedImage links a review image to its copy inside the archive, empty if it could not be downloaded.
+type exportedImage struct {
+	URL         string `json:"url"`
+	ArchivePath string `json:"archivePath,omitempty"`
+}
+
+// writeArchive writes the ZIP archive with every piece of personal data held about accountID.
+func writeArchive(ctx context.Context, w io.Writer, accountID int) error {
+	db := boil.GetContextDB()
+	account, err := models.FindAccount(ctx, db, accountID)
+	if err != nil {
+		return err
+	}
+	identities, err := auth.ListIdentities(ctx, db, accountID)
+	if err != nil {
+		return err
+	}
+	mfaEnabled, err := auth.MFAEnabled(ctx, accountID)
+	if err != nil {
+		return err
+	}
+	addresses, err := models.Addresses(models.AddressWhere.AccountID.EQ(accountID), qm.OrderBy("\"addressID\"")).All(ctx, db)
+	if err != nil {
+		return err
+	}
+	invoices, err := models.Invoices(
+		models.InvoiceWhere.AccountID.EQ(accountID),
+		qm.Load(models.InvoiceRels.InvoiceIDInvoiceDetails),
+		qm.Load(models.InvoiceRels.InvoiceIDTransactions),
+		qm.OrderBy("\"invoiceID\""),
+	).All(ctx, db)
+	if err != nil {
+		return err
+	}
+	reviews, err := models.Reviews(
+		models.ReviewWhere.AccountID.EQ(accountID),
+		qm.Load(models.ReviewRels.ReviewIDReviewImages),
+		qm.Load(models.ReviewRels.ReviewIDReplies),
+		qm.OrderBy("\"reviewID\""),
+	).All(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	zw := zip.NewWriter(w)
+	manifest := exportManifest{AccountID: accountID, ExportedAt: time.Now().UTC(), Notes: []string{chatHistoryNote}}
+
+	acc := exportedAccount{
+		AccountID:        account.AccountID,
+		Username:         account.Username,
+		Email:            account.Email,
+		EmailVerified:    account.EmailVerified,
+		FullName:         account.FullName,
+		PhoneNumber:      account.PhoneNumber,
+		Gender:           account.Gender,
+		Avatar:           account.Avatar,
+		Role:             account.Role,
+		LinkedProviders:  []string{},
+		TwoFactorEnabled: mfaEnabled,
+	}
+	for _, identity := range identities {
+		acc.LinkedProviders = append(acc.LinkedProviders, identity.Provider)
+	}
+
+	exportedInvoices := make([]exportedInvoice, 0, len(invoices))
+	for _, invoice := range invoices {
+		item := exportedInvoice{Invoice: invoice, Details: models.InvoiceDetailSlice{}, Transactions: models.TransactionSlice{}}
+		if invoice.R != nil {
+			if invoice.R.InvoiceIDInvoiceDetails != nil {
+				item.Details = invoice.R.InvoiceIDInvoiceDetails
+			}
+			if invoice.R.InvoiceIDTransactions != nil {
+				item.Transactions = invoice.R.InvoiceIDTransactions
+			}
+		}
+		exportedInvoices = append(exportedInvoices, item)
+	}
+
+	exportedReviews := make([]exportedReview, 0, len(reviews))
+	for _, review := range reviews {
+		item := exportedReview{Review: review, Images: []exportedImage{}, Replies: models.ReplySlice{}}
+		if review.R != nil {
+			if review.R.ReviewIDReplies != nil {
+				item.Replies = review.R.ReviewIDReplies
+			}
+			for _, img := range review.R.ReviewIDReviewImages {
+				image := exportedImage{URL: img.ImageName}
+				name := fmt.Sprintf("review-images/%d%s", img.ReviewImageID, imageExt(img.ImageName))
+				if err := copyImage(ctx, zw, name, img.ImageName); err != nil {
+					manifest.MissingImages = append(manifest.MissingImages, img.ImageName)
+				} else {
+					image.ArchivePath = name
+					manifest.Files = append(manifest.Files, name)
+				}
+				item.Images = append(item.Images, image)
+			}
+		}
+		exportedReviews = append(exportedReviews, item)
+	}
+
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"account.json", acc},
+		{"addresses.json", nonNil(addresses)},
+		{"invoices.json", exportedInvoices},
+		{"reviews.json", exportedReviews},
+	}
+	for _, f := range files {
+		if err := writeJSON(zw, f.name, f.data); err != nil {
+			return err
+		}
+		manifest.Files = append(manifest.Files, f.name)
+	}
+	if err := writeJSON(zw, "export.json", manifest); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func nonNil(addresses models.AddressSlice) models.AddressSlice {
+	if addresses == nil {
+		return models.AddressSlice{}
+	}
+	return addresses
+}
+
+func writeJSON(zw *zip.Writer, name string, data interface{}) error {
+	f, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	return enc.Encode(data)
+}
+
+// copyImage downloads an uploaded image into the archive.
+func copyImage(ctx context.Context, zw *zip.Writer, name, imageURL string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, imageURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := imageClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %s", imageURL, resp.Status)
+	}
+	f, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, io.LimitReader(resp.Body, maxImageSize))
+	return err
+}
+
+// imageExt returns the extension of the object an image URL points to.
+// Firebase URLs escape the object path, e.g. .../o/AnhDanhGia%2Fphoto_uuid.jpg?alt=media.
+func imageExt(imageURL string) string {
+	u, err := url.Parse(imageURL)
+	if err != nil {
+		return ""
+	}
+	p := u.Path
+	if unescaped, err := url.PathUnescape(p); err == nil {
+		p = unescaped
+	}
+	return path.Ext(p)
+}
@@ -0,0 +1,56 @@
+package privacy
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImageExt(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://firebasestorage.googleapis.com/v0/b/goodfood/o/AnhDanhGia%2Fphoto_1234.jpg?alt=media&token=abc", ".jpg"},
+		{"https://cdn.example.com/images/avatar.png", ".png"},
+		{"https://cdn.example.com/images/avatar", ""},
+		{"::not a url", ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, imageExt(tt.url), tt.url)
+	}
+}
+
+func TestCopyImage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing.jpg" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte("image-bytes"))
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	assert.NoError(t, copyImage(context.Background(), zw, "review-images/1.jpg", server.URL+"/photo.jpg"))
+	assert.Error(t, copyImage(context.Background(), zw, "review-images/2.jpg", server.URL+"/missing.jpg"))
+	assert.NoError(t, zw.Close())
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if !assert.NoError(t, err) || !assert.Len(t, zr.File, 1, "a failed download leaves no entry behind") {
+		return
+	}
+	assert.Equal(t, "review-images/1.jpg", zr.File[0].Name)
+	f, err := zr.File[0].Open()
+	assert.NoError(t, err)
+	data, err := io.ReadAll(f)
+	assert.NoError(t, err)
+	assert.Equal(t, "image-bytes", string(data))
+}
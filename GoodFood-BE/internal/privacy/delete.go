@@ -0,0 +1,158 @@
+package privacy
+
+import (
+	"GoodFood-BE/internal/audit"
+	"GoodFood-BE/internal/auth"
+	"GoodFood-BE/internal/order"
+	"GoodFood-BE/models"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/aarondl/null/v8"
+	"github.com/aarondl/sqlboiler/v4/boil"
+	"github.com/aarondl/sqlboiler/v4/queries/qm"
+)
+
+// DeletedFullName replaces the name of deleted accounts everywhere it was copied.
+const DeletedFullName = "Deleted user"
+
+// firebaseStorageHost identifies uploaded images, as opposed to avatars hosted by an OAuth provider.
+const firebaseStorageHost = "firebasestorage.googleapis.com"
+
+// ErrOrdersInProgress is returned by DeleteAccount while the account has orders that are neither delivered nor cancelled.
+var ErrOrdersInProgress = errors.New("the account has orders in progress")
+
+// DeletedUsername returns the username a deleted account is renamed to, unique since it embeds the account id.
+func DeletedUsername(accountID int) string {
+	return fmt.Sprintf("deleted-%d", accountID)
+}
+
+// DeleteAccount anonymizes the personal data of acc in place. Orders, reviews and transactions are kept
+// for bookkeeping but no longer point to anyone; the account can never log in again.
+// It returns the uploaded images to delete from storage once the transaction is committed.
+func DeleteAccount(ctx context.Context, acc *models.Account) ([]string, error) {
+	tx, err := boil.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	//Lock the account so an order cannot be placed while it is being deleted
+	if _, err := models.Accounts(models.AccountWhere.AccountID.EQ(acc.AccountID), qm.For("UPDATE")).One(ctx, tx); err != nil {
+		return nil, err
+	}
+	//Delivered and cancelled orders are finished, every other one is still in progress
+	inProgress, err := models.Invoices(
+		models.InvoiceWhere.AccountID.EQ(acc.AccountID),
+		qm.Where(`"invoiceStatusID" NOT IN (SELECT "invoiceStatusID" FROM invoice_status WHERE code IN (?, ?))`,
+			string(order.StatusDelivered), string(order.StatusCancelled)),
+	).Exists(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	if inProgress {
+		return nil, ErrOrdersInProgress
+	}
+
+	var images []string
+	if acc.Avatar.Valid && strings.Contains(acc.Avatar.String, firebaseStorageHost) {
+		images = append(images, acc.Avatar.String)
+	}
+	reviewImages, err := models.ReviewImages(
+		qm.InnerJoin("review r ON r.\"reviewID\" = review_images.\"reviewID\""),
+		qm.Where("r.\"accountID\" = ?", acc.AccountID),
+	).All(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	for _, img := range reviewImages {
+		images = append(images, img.ImageName)
+	}
+	if _, err := reviewImages.DeleteAll(ctx, tx); err != nil {
+		return nil, err
+	}
+
+	statements := []string{
+		`UPDATE address SET "fullName" = '', "phoneNumber" = '', address = '', "specificAddress" = '',
+			status = false, "deleteStatus" = true WHERE "accountID" = $1`,
+		`UPDATE invoice SET "receiveName" = '', "receivePhone" = '', "receiveAddress" = '', note = NULL WHERE "accountID" = $1`,
+		`DELETE FROM cart_detail WHERE "accountID" = $1`,
+		`DELETE FROM oauth_account WHERE "accountID" = $1`,
+		`DELETE FROM mfa_recovery_code WHERE "accountID" = $1`,
+		`DELETE FROM account_mfa WHERE "accountID" = $1`,
+	}
+	for _, stmt := range statements {
+		if _, err := tx.ExecContext(ctx, stmt, acc.AccountID); err != nil {
+			return nil, err
+		}
+	}
+	exportIDs, err := deleteExports(ctx, tx, acc.AccountID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE "transaction" SET "fullName" = $2 WHERE "accountID" = $1`, acc.AccountID, DeletedFullName); err != nil {
+		return nil, err
+	}
+	if err := scrubAuditLog(ctx, tx, acc); err != nil {
+		return nil, err
+	}
+
+	acc.Username = DeletedUsername(acc.AccountID)
+	acc.Email = acc.Username + "@deleted.invalid"
+	acc.FullName = DeletedFullName
+	acc.PhoneNumber = null.String{}
+	acc.Avatar = null.String{}
+	acc.Password = auth.NoPasswordHash
+	acc.EmailVerified = false
+	acc.Status = false
+	if _, err := acc.Update(ctx, tx, boil.Infer()); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	for _, id := range exportIDs {
+		if err := os.Remove(ArchivePath(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Printf("privacy: could not remove archive %s: %v", id, err)
+		}
+	}
+	return images, nil
+}
+
+// scrubAuditLog removes the username and snapshots of an account from the audit log. The entries stay, so what
+// was done to the account and by it can still be told by its id.
+func scrubAuditLog(ctx context.Context, tx boil.ContextExecutor, acc *models.Account) error {
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE audit_log SET "actorUsername" = $3 WHERE "actorID" = $1 OR "actorUsername" = $2`,
+		acc.AccountID, acc.Username, DeletedUsername(acc.AccountID)); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx, `
+		UPDATE audit_log SET metadata = metadata - 'username', before = NULL, after = NULL, changes = NULL
+		WHERE "entityType" = $1 AND "entityID" = $2`,
+		audit.EntityAccount, strconv.Itoa(acc.AccountID))
+	return err
+}
+
+// deleteExports deletes the data exports of an account and returns their ids.
+func deleteExports(ctx context.Context, tx boil.ContextExecutor, accountID int) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, `DELETE FROM data_export WHERE "accountID" = $1 RETURNING "exportID"`, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
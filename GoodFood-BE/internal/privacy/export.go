@@ -0,0 +1,161 @@
+// Package privacy implements the customer self-service data export and account deletion.
+package privacy
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aarondl/null/v8"
+	"github.com/aarondl/sqlboiler/v4/boil"
+	"github.com/gofrs/uuid"
+)
+
+// Export statuses stored in data_export.status.
+const (
+	ExportPending = "pending"
+	ExportReady   = "ready"
+	ExportFailed  = "failed"
+	ExportExpired = "expired"
+)
+
+// ExportTTL is how long a finished archive can be downloaded.
+const ExportTTL = 7 * 24 * time.Hour
+
+// DefaultExportDir is used when EXPORT_DIR is not set. The API and the worker must share it.
+const DefaultExportDir = "data/exports"
+
+var (
+	ErrExportNotFound   = errors.New("data export not found")
+	ErrExportInProgress = errors.New("a data export is already being prepared")
+)
+
+// Export is one row of data_export.
+type Export struct {
+	ExportID    string    `json:"exportID"`
+	AccountID   int       `json:"-"`
+	Status      string    `json:"status"`
+	Error       string    `json:"error,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
+	CompletedAt null.Time `json:"completedAt"`
+	ExpiresAt   null.Time `json:"expiresAt"`
+}
+
+// ExportDir returns the directory holding the archives, from EXPORT_DIR.
+func ExportDir() string {
+	if dir := os.Getenv("EXPORT_DIR"); dir != "" {
+		return dir
+	}
+	return DefaultExportDir
+}
+
+// ArchivePath returns where the archive of exportID is stored.
+func ArchivePath(exportID string) string {
+	return filepath.Join(ExportDir(), exportID+".zip")
+}
+
+// RequestExport records a pending export for accountID, refusing while another one is still pending.
+func RequestExport(ctx context.Context, accountID int) (*Export, error) {
+	id, err := uuid.NewV4()
+	if err != nil {
+		return nil, err
+	}
+	export := &Export{ExportID: id.String(), AccountID: accountID, Status: ExportPending}
+	//The NOT EXISTS guard keeps two concurrent requests from both being accepted
+	err = boil.GetContextDB().QueryRowContext(ctx, `
+		INSERT INTO data_export ("exportID", "accountID", status)
+		SELECT $1, $2, $3
+		WHERE NOT EXISTS (SELECT 1 FROM data_export WHERE "accountID" = $2 AND status = $3)
+		RETURNING "createdAt"`, export.ExportID, accountID, ExportPending).Scan(&export.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrExportInProgress
+	}
+	if err != nil {
+		return nil, err
+	}
+	return export, nil
+}
+
+// FindExport returns an export of accountID.
+func FindExport(ctx context.Context, accountID int, exportID string) (*Export, error) {
+	if _, err := uuid.FromString(exportID); err != nil {
+		return nil, ErrExportNotFound
+	}
+	export := &Export{}
+	var errText null.String
+	err := boil.GetContextDB().QueryRowContext(ctx, `
+		SELECT "exportID", "accountID", status, error, "createdAt", "completedAt", "expiresAt"
+		FROM data_export WHERE "exportID" = $1 AND "accountID" = $2`, exportID, accountID).
+		Scan(&export.ExportID, &export.AccountID, &export.Status, &errText, &export.CreatedAt, &export.CompletedAt, &export.ExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrExportNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	export.Error = errText.String
+	//The expiry task may not have run yet
+	if export.Status == ExportReady && export.ExpiresAt.Valid && time.Now().After(export.ExpiresAt.Time) {
+		export.Status = ExportExpired
+	}
+	return export, nil
+}
+
+// BuildExport writes the archive of a pending export and marks it ready, or failed if it cannot be built.
+func BuildExport(ctx context.Context, exportID string, accountID int) error {
+	if err := os.MkdirAll(ExportDir(), 0o700); err != nil {
+		return err
+	}
+	//Write to a temporary file so a download never sees a partial archive
+	tmp, err := os.CreateTemp(ExportDir(), exportID+"-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	buildErr := writeArchive(ctx, tmp, accountID)
+	if err := tmp.Close(); err != nil && buildErr == nil {
+		buildErr = err
+	}
+	if buildErr == nil {
+		buildErr = os.Rename(tmp.Name(), ArchivePath(exportID))
+	}
+	if buildErr != nil {
+		_, err := boil.GetContextDB().ExecContext(ctx, `
+			UPDATE data_export SET status = $2, error = $3, "completedAt" = now() WHERE "exportID" = $1`,
+			exportID, ExportFailed, buildErr.Error())
+		if err != nil {
+			return err
+		}
+		return buildErr
+	}
+	res, err := boil.GetContextDB().ExecContext(ctx, `
+		UPDATE data_export SET status = $2, error = NULL, "completedAt" = now(), "expiresAt" = $3 WHERE "exportID" = $1`,
+		exportID, ExportReady, time.Now().Add(ExportTTL))
+	if err != nil {
+		return err
+	}
+	//The account was deleted while the archive was being built
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return os.Remove(ArchivePath(exportID))
+	}
+	return nil
+}
+
+// ExpireExport removes the archive of an export once it is no longer downloadable.
+func ExpireExport(ctx context.Context, exportID string) error {
+	if err := os.Remove(ArchivePath(exportID)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	_, err := boil.GetContextDB().ExecContext(ctx, `
+		UPDATE data_export SET status = $2 WHERE "exportID" = $1 AND status = $3`,
+		exportID, ExportExpired, ExportReady)
+	if err != nil {
+		return fmt.Errorf("expire export %s: %w", exportID, err)
+	}
+	return nil
+}
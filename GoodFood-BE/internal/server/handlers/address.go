@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"GoodFood-BE/internal/auth"
 	"GoodFood-BE/internal/service"
 	"GoodFood-BE/internal/utils"
 	"GoodFood-BE/models"
@@ -16,10 +17,11 @@ func FetchAddress(c *fiber.Ctx) error {
 	const pageSize = 6 //fixed pageSize due to UI design constraint.
 
 	//Parse query params.
-	accountID := c.QueryInt("accountID", 0)
-	if accountID == 0 {
-		return service.SendError(c, 400, "Did not receive accountID")
+	account := auth.GetAccount(c)
+	if account == nil {
+		return service.SendError(c, 401, "Unauthenticated")
 	}
+	accountID := account.AccountID
 	page := c.QueryInt("page", 0)
 	if page == 0 {
 		return service.SendError(c, 400, "Did not receive pageNum")
@@ -64,11 +66,18 @@ func FetchAddress(c *fiber.Ctx) error {
 // Returns the insert information
 func AddressInsert(c *fiber.Ctx) error {
 
+	account := auth.GetAccount(c)
+	if account == nil {
+		return service.SendError(c, 401, "Unauthenticated")
+	}
+
 	//Parse data into struct Address
 	var addressDetails models.Address
 	if err := c.BodyParser(&addressDetails); err != nil {
 		return service.SendError(c, 400, "Invalid request body")
 	}
+	//New addresses always belong to the caller
+	addressDetails.AccountID = account.AccountID
 
 	//Insert
 	if err := addressDetails.Insert(c.Context(), boil.GetContextDB(), boil.Infer()); err != nil {
@@ -93,10 +102,11 @@ func AddressDetail(c *fiber.Ctx) error {
 	if addressID == 0 {
 		return service.SendError(c, 400, "Did not receive addressID")
 	}
-	accountID := c.QueryInt("accountID", 0)
-	if accountID == 0 {
-		return service.SendError(c, 400, "Did not receive accountID")
+	account := auth.GetAccount(c)
+	if account == nil {
+		return service.SendError(c, 401, "Unauthenticated")
 	}
+	accountID := account.AccountID
 
 	addressDetail, err := models.Addresses(qm.Where("\"addressID\" = ?",addressID)).One(c.Context(),boil.GetContextDB());
 	if err != nil{
@@ -121,10 +131,11 @@ func AddressDetail(c *fiber.Ctx) error {
 func AddressUpdate(c *fiber.Ctx) error {
 
 	//Parse params and body
-	accountID := c.QueryInt("accountID", 0)
-	if accountID == 0 {
-		return service.SendError(c, 400, "Did not receive accountID")
+	account := auth.GetAccount(c)
+	if account == nil {
+		return service.SendError(c, 401, "Unauthenticated")
 	}
+	accountID := account.AccountID
 	addressID := c.QueryInt("addressID", 0)
 	if addressID == 0 {
 		return service.SendError(c, 400, "Did not receive addressID")
@@ -174,10 +185,11 @@ func AddressUpdate(c *fiber.Ctx) error {
 func AddressDelete(c *fiber.Ctx) error {
 
 	//Parse query params
-	accountID := c.QueryInt("accountID", 0)
-	if accountID == 0 {
-		return service.SendError(c, 400, "Did not receive accountID")
+	account := auth.GetAccount(c)
+	if account == nil {
+		return service.SendError(c, 401, "Unauthenticated")
 	}
+	accountID := account.AccountID
 	addressID := c.QueryInt("addressID", 0)
 	if addressID == 0 {
 		return service.SendError(c, 400, "Did not receive addressID")
@@ -212,10 +224,11 @@ func AddressDelete(c *fiber.Ctx) error {
 // This function checks if the authenticated user has his default address set.
 // Returns the default address if found.
 func AddressFill(c *fiber.Ctx) error {
-	accountID := c.QueryInt("accountID", 0)
-	if accountID == 0 {
-		return service.SendError(c, 400, "Did not receive accountID")
+	account := auth.GetAccount(c)
+	if account == nil {
+		return service.SendError(c, 401, "Unauthenticated")
 	}
+	accountID := account.AccountID
 
 	address, err := models.Addresses(
 		qm.Where("\"accountID\" = ? AND status = TRUE", accountID),
@@ -232,10 +245,11 @@ func AddressFill(c *fiber.Ctx) error {
 func AddressQuickChange(c *fiber.Ctx) error {
 
 	//Parse query params
-	accountID := c.QueryInt("accountID", 0)
-	if accountID == 0 {
-		return service.SendError(c, 400, "Did not receive accountID")
+	account := auth.GetAccount(c)
+	if account == nil {
+		return service.SendError(c, 401, "Unauthenticated")
 	}
+	accountID := account.AccountID
 	addressID := c.QueryInt("addressID", 0)
 	if addressID == 0 {
 		return service.SendError(c, 400, "Did not receive addressID")
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"GoodFood-BE/internal/audit"
+	"GoodFood-BE/internal/auth"
+	"GoodFood-BE/internal/dto"
+	"GoodFood-BE/internal/service"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aarondl/null/v8"
+	"github.com/gofiber/fiber/v2"
+)
+
+// maxAPIKeyUsageDays caps the days query parameter of GetAdminAPIKeyUsage.
+const maxAPIKeyUsageDays = 365
+
+// GetAdminAPIKeys lists every API key with its usage counters. Hashes are never returned.
+func GetAdminAPIKeys(c *fiber.Ctx) error {
+	keys, err := auth.ListAPIKeys(c.Context())
+	if err != nil {
+		return service.SendError(c, 500, err.Error())
+	}
+	return c.JSON(fiber.Map{
+		"status": "Success",
+		"data":   keys,
+	})
+}
+
+// AdminAPIKeyCreate creates an API key. The plaintext key is only part of this response.
+// An admin can only give a key the scopes their own role holds.
+func AdminAPIKeyCreate(c *fiber.Ctx) error {
+	account := auth.GetAccount(c)
+	if account == nil {
+		return service.SendError(c, 401, "Unauthenticated")
+	}
+	body := dto.CreateAPIKeyRequest{}
+	if err := c.BodyParser(&body); err != nil {
+		return service.SendError(c, 400, "Invalid request body")
+	}
+	body.Name = strings.TrimSpace(body.Name)
+	if body.Name == "" || len(body.Name) > 100 {
+		return service.SendError(c, 400, "Name is required and must be at most 100 characters")
+	}
+	for _, scope := range body.Scopes {
+		if auth.IsValidAPIKeyScope(scope) && !auth.HasPermission(account.Role, auth.Permission(scope)) {
+			return service.SendError(c, 403, "You cannot grant the scope "+scope)
+		}
+	}
+	params := auth.NewAPIKey{
+		Name:       body.Name,
+		Scopes:     body.Scopes,
+		AllowedIPs: body.AllowedIPs,
+		CreatedBy:  account.AccountID,
+	}
+	if body.ExpiresAt != nil {
+		if !body.ExpiresAt.After(time.Now()) {
+			return service.SendError(c, 400, "expiresAt must be in the future")
+		}
+		params.ExpiresAt = null.TimeFrom(*body.ExpiresAt)
+	}
+
+	apiKey, key, err := auth.CreateAPIKey(c.Context(), params)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidAPIKeyScope) || errors.Is(err, auth.ErrInvalidAPIKeyIPRule) {
+			return service.SendError(c, 400, err.Error())
+		}
+		return service.SendError(c, 500, err.Error())
+	}
+	entry := audit.FromRequest(c, audit.ActionAPIKeyCreate, audit.EntityAPIKey, strconv.Itoa(apiKey.APIKeyID))
+	entry.After = apiKey
+	audit.RecordBestEffort(c.Context(), entry)
+
+	return c.JSON(fiber.Map{
+		"status":  "Success",
+		"data":    fiber.Map{"apiKey": apiKey, "key": key},
+		"message": "API key created. Copy it now, it will not be shown again",
+	})
+}
+
+// AdminAPIKeyRevoke revokes an API key immediately.
+func AdminAPIKeyRevoke(c *fiber.Ctx) error {
+	apiKeyID := c.QueryInt("apiKeyID", 0)
+	if apiKeyID == 0 {
+		return service.SendError(c, 400, "Did not receive apiKeyID")
+	}
+	before, err := auth.FindAPIKey(c.Context(), apiKeyID)
+	if err != nil {
+		if errors.Is(err, auth.ErrAPIKeyNotFound) {
+			return service.SendError(c, 404, "API key not found")
+		}
+		return service.SendError(c, 500, err.Error())
+	}
+	if err := auth.RevokeAPIKey(c.Context(), apiKeyID); err != nil {
+		return service.SendError(c, 500, err.Error())
+	}
+	after, err := auth.FindAPIKey(c.Context(), apiKeyID)
+	if err != nil {
+		return service.SendError(c, 500, err.Error())
+	}
+	recordAdminChange(c, audit.ActionAPIKeyRevoke, audit.EntityAPIKey, apiKeyID, before, after)
+
+	return c.JSON(fiber.Map{
+		"status":  "Success",
+		"data":    after,
+		"message": "API key revoked",
+	})
+}
+
+// GetAdminAPIKeyUsage returns the daily request counts of an API key over the last days (30 by default).
+func GetAdminAPIKeyUsage(c *fiber.Ctx) error {
+	apiKeyID := c.QueryInt("apiKeyID", 0)
+	if apiKeyID == 0 {
+		return service.SendError(c, 400, "Did not receive apiKeyID")
+	}
+	days := c.QueryInt("days", 30)
+	if days < 1 || days > maxAPIKeyUsageDays {
+		return service.SendError(c, 400, "days must be between 1 and "+strconv.Itoa(maxAPIKeyUsageDays))
+	}
+	apiKey, err := auth.FindAPIKey(c.Context(), apiKeyID)
+	if err != nil {
+		if errors.Is(err, auth.ErrAPIKeyNotFound) {
+			return service.SendError(c, 404, "API key not found")
+		}
+		return service.SendError(c, 500, err.Error())
+	}
+	usage, err := auth.APIKeyUsage(c.Context(), apiKeyID, days)
+	if err != nil {
+		return service.SendError(c, 500, err.Error())
+	}
+	return c.JSON(fiber.Map{
+		"status": "Success",
+		"data": fiber.Map{
+			"apiKey": apiKey,
+			"usage":  usage,
+		},
+	})
+}
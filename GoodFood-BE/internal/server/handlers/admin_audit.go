@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"GoodFood-BE/internal/audit"
+	"GoodFood-BE/internal/service"
+	"GoodFood-BE/internal/utils"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// maxAuditPageSize caps the pageSize query parameter of GetAdminAuditLog.
+const maxAuditPageSize = 100
+
+// parseAuditTime accepts a date (2006-01-02) or an RFC 3339 timestamp.
+func parseAuditTime(value string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// GetAdminAuditLog returns a page of the audit log, newest first.
+// Filters: actorID, action (a trailing dot matches a prefix, e.g. "account."), entityType, entityID, from and to.
+func GetAdminAuditLog(c *fiber.Ctx) error {
+	filter := audit.Filter{
+		ActorID:    c.QueryInt("actorID", 0),
+		Action:     c.Query("action", ""),
+		EntityType: c.Query("entityType", ""),
+		EntityID:   c.Query("entityID", ""),
+	}
+	if from := c.Query("from", ""); from != "" {
+		t, err := parseAuditTime(from)
+		if err != nil {
+			return service.SendError(c, 400, "Invalid from date")
+		}
+		filter.From = t
+	}
+	if to := c.Query("to", ""); to != "" {
+		t, err := parseAuditTime(to)
+		if err != nil {
+			return service.SendError(c, 400, "Invalid to date")
+		}
+		//A plain date includes the whole day
+		if len(to) == len("2006-01-02") {
+			t = t.AddDate(0, 0, 1)
+		}
+		filter.To = t
+	}
+
+	page := c.QueryInt("page", 1)
+	pageSize := c.QueryInt("pageSize", utils.PageSize)
+	if pageSize <= 0 || pageSize > maxAuditPageSize {
+		pageSize = maxAuditPageSize
+	}
+
+	total, err := audit.Count(c.Context(), filter)
+	if err != nil {
+		return service.SendError(c, 500, err.Error())
+	}
+	offset, totalPage := utils.Paginate(page, pageSize, total)
+	entries, err := audit.List(c.Context(), filter, pageSize, offset)
+	if err != nil {
+		return service.SendError(c, 500, err.Error())
+	}
+
+	return c.JSON(fiber.Map{
+		"status":    "Success",
+		"data":      entries,
+		"total":     total,
+		"totalPage": totalPage,
+		"message":   "Successfully fetched audit log",
+	})
+}
+
+// recordAdminChange audits a mutation of an entity by the current admin, with its state before and after.
+func recordAdminChange(c *fiber.Ctx, action, entityType string, entityID int, before, after interface{}) {
+	entry := audit.FromRequest(c, action, entityType, strconv.Itoa(entityID))
+	entry.Before = before
+	entry.After = after
+	audit.RecordBestEffort(c.Context(), entry)
+}
@@ -1,12 +1,19 @@
 package handlers
 
 import (
+	"GoodFood-BE/internal/audit"
+	"GoodFood-BE/internal/auth"
 	"GoodFood-BE/internal/dto"
+	"GoodFood-BE/internal/order"
+	"GoodFood-BE/internal/payment"
 	"GoodFood-BE/internal/service"
 	"GoodFood-BE/internal/utils"
 	"GoodFood-BE/models"
+	"database/sql"
+	"errors"
 	"time"
 
+	"github.com/aarondl/null/v8"
 	"github.com/aarondl/sqlboiler/v4/boil"
 	"github.com/aarondl/sqlboiler/v4/queries/qm"
 	"github.com/gofiber/fiber/v2"
@@ -17,7 +24,7 @@ import (
 func GetAdminInvoice(c *fiber.Ctx) error{
 	//Fetch metrics for InvoiceCards
 	query := `SELECT COALESCE(COUNT("invoiceID"),0) AS total,
-		COUNT(CASE WHEN "invoiceStatusID" = 6 THEN 1 END) AS canceled
+		COUNT(CASE WHEN "invoiceStatusID" = (SELECT "invoiceStatusID" FROM invoice_status WHERE code = 'cancelled') THEN 1 END) AS canceled
 		FROM invoice`
 	cards, err := utils.FetchCards(c,query,&dto.InvoiceCards{});
 	if err != nil{
@@ -94,7 +101,7 @@ func GetAdminInvoiceDetail(c *fiber.Ctx) error{
 	}
 
 	//Determine possible status progression
-	statusList, err := utils.FetchStatusProgression(c,status);
+	statusList, err := order.Options(c.Context(),order.RoleAdmin,order.Status(status.Code));
 	if err != nil {
 		return service.SendError(c, 500, err.Error())
 	}
@@ -115,6 +122,157 @@ func GetAdminInvoiceDetail(c *fiber.Ctx) error{
 	return c.JSON(resp);
 }
 
+// GetAdminInvoicePaymentStatus asks the payment provider for the status of a payment attempt of an invoice, the latest one unless txnRef is given.
+func GetAdminInvoicePaymentStatus(c *fiber.Ctx) error{
+	invoiceID := c.QueryInt("invoiceID",0);
+	if invoiceID == 0{
+		return service.SendError(c,400,"Did not receive invoiceID");
+	}
+
+	status, err := payment.QueryAttempt(c.Context(),invoiceID,c.Query("txnRef",""),c.IP())
+	if err != nil{
+		if errors.Is(err,payment.ErrAttemptNotFound){
+			return service.SendError(c,404,"Payment not found");
+		}
+		if errors.Is(err,payment.ErrNotSupported){
+			return service.SendError(c,400,"The payment method cannot be queried");
+		}
+		return service.SendError(c,502,"Could not query the payment provider: " + err.Error())
+	}
+
+	resp := fiber.Map{
+		"status": "Success",
+		"data": status,
+		"message": "Successfully queried the payment status",
+	}
+
+	return c.JSON(resp);
+}
+
+// AdminInvoiceRefund refunds all or part of the online payment of a cancelled order through the provider that took it.
+func AdminInvoiceRefund(c *fiber.Ctx) error{
+	invoiceID := c.QueryInt("invoiceID",0);
+	if invoiceID == 0{
+		return service.SendError(c,400,"Did not receive invoiceID");
+	}
+	var body dto.RefundInvoiceRequest
+	if err := c.BodyParser(&body); err != nil{
+		return service.SendError(c,400,"Invalid body!");
+	}
+
+	req := payment.RefundRequest{
+		InvoiceID: invoiceID,
+		TransactionID: body.TransactionID,
+		Amount: body.Amount,
+		IP: c.IP(),
+	}
+	if account := auth.GetAccount(c); account != nil{
+		req.CreatedBy = null.IntFrom(account.AccountID)
+		req.CreatedByName = account.Username
+	} else if apiKey := auth.GetAPIKey(c); apiKey != nil{
+		req.CreatedByName = "api-key:" + apiKey.DisplayPrefix()
+	}
+
+	refund, err := payment.RefundInvoice(c.Context(),req)
+	switch {
+	case errors.Is(err,payment.ErrNotRefundable):
+		return service.SendError(c,409,"Only cancelled orders paid online can be refunded")
+	case errors.Is(err,payment.ErrPaymentNotFound):
+		return service.SendError(c,404,"No successful online payment found for this invoice")
+	case errors.Is(err,payment.ErrRefundAmount):
+		return service.SendError(c,400,"The refund amount must be positive and at most the amount not refunded yet")
+	case err != nil && refund != nil:
+		//The provider may still process it, the refund stays pending
+		recordAdminChange(c,audit.ActionInvoiceRefund,audit.EntityInvoice,invoiceID,nil,refund)
+		return service.SendError(c,502,"The payment provider did not confirm the refund, it stays pending: " + err.Error())
+	case err != nil:
+		return service.SendError(c,500,err.Error())
+	}
+	recordAdminChange(c,audit.ActionInvoiceRefund,audit.EntityInvoice,invoiceID,nil,refund)
+	if refund.Status != payment.RefundSucceeded{
+		return service.SendError(c,502,"The payment provider refused the refund: " + refund.Message.String)
+	}
+
+	resp := fiber.Map{
+		"status": "Success",
+		"data": refund,
+		"message": "Successfully refunded the payment",
+	}
+
+	return c.JSON(resp);
+}
+
+// AdminInvoiceRefundResolve marks a refund left pending by an unreachable payment provider as succeeded or failed,
+// after the admin checked its outcome with the provider. A failed refund can be requested again.
+func AdminInvoiceRefundResolve(c *fiber.Ctx) error{
+	invoiceID := c.QueryInt("invoiceID",0);
+	if invoiceID == 0{
+		return service.SendError(c,400,"Did not receive invoiceID");
+	}
+	var body dto.ResolveRefundRequest
+	if err := c.BodyParser(&body); err != nil || body.RefundID == 0{
+		return service.SendError(c,400,"Invalid body!");
+	}
+	if body.Status != payment.RefundSucceeded && body.Status != payment.RefundFailed{
+		return service.SendError(c,400,"The status must be succeeded or failed")
+	}
+
+	refund, err := payment.ResolveRefund(c.Context(),invoiceID,body.RefundID,body.Status == payment.RefundSucceeded,body.Message)
+	switch {
+	case errors.Is(err,payment.ErrRefundNotFound):
+		return service.SendError(c,404,"Refund not found")
+	case errors.Is(err,payment.ErrRefundResolved):
+		return service.SendError(c,409,"The refund is no longer pending")
+	case err != nil:
+		return service.SendError(c,500,err.Error())
+	}
+	recordAdminChange(c,audit.ActionRefundResolve,audit.EntityInvoice,invoiceID,nil,refund)
+
+	resp := fiber.Map{
+		"status": "Success",
+		"data": refund,
+		"message": "Successfully resolved the refund",
+	}
+
+	return c.JSON(resp);
+}
+
+// GetAdminInvoicePayments lists the online payment attempts of an invoice and the transactions they produced, for reconciliation.
+func GetAdminInvoicePayments(c *fiber.Ctx) error{
+	invoiceID := c.QueryInt("invoiceID",0);
+	if invoiceID == 0{
+		return service.SendError(c,400,"Did not receive invoiceID");
+	}
+
+	attempts, err := payment.ListAttempts(c.Context(),invoiceID)
+	if err != nil{
+		return service.SendError(c,500,err.Error())
+	}
+	transactions, err := models.Transactions(
+		models.TransactionWhere.InvoiceID.EQ(invoiceID),
+		qm.OrderBy("\"transactionID\" DESC"),
+	).All(c.Context(),boil.GetContextDB())
+	if err != nil{
+		return service.SendError(c,500,err.Error())
+	}
+	refunds, err := payment.ListRefunds(c.Context(),invoiceID)
+	if err != nil{
+		return service.SendError(c,500,err.Error())
+	}
+
+	resp := fiber.Map{
+		"status": "Success",
+		"data": fiber.Map{
+			"attempts": attempts,
+			"transactions": transactions,
+			"refunds": refunds,
+		},
+		"message": "Successfully fetched invoice payments",
+	}
+
+	return c.JSON(resp);
+}
+
 // UpdateInvoice updates an invoice's status and returns the updateđ invocie.
 func UpdateInvoice(c *fiber.Ctx) error{
 	var status dto.UpdateInvoiceStruct
@@ -127,10 +285,43 @@ func UpdateInvoice(c *fiber.Ctx) error{
 		return service.SendError(c,400,"Invalid body!");
 	}
 
-	getInvoice, err := utils.UpdateInvoiceStatus(c,invoiceID,status);
-	if err != nil{
+	//The target is a status code, or the exact display name older clients send
+	statusMod := models.InvoiceStatusWhere.StatusName.EQ(status.StatusName)
+	if status.Status != ""{
+		code, ok := order.ParseStatus(status.Status)
+		if !ok{
+			return service.SendError(c,400,"Invalid status code!")
+		}
+		statusMod = models.InvoiceStatusWhere.Code.EQ(string(code))
+	}
+	invoiceStatus, err := models.InvoiceStatuses(statusMod).One(c.Context(), boil.GetContextDB())
+	if errors.Is(err,sql.ErrNoRows){
+		return service.SendError(c,400,"Invoice status not found!")
+	}
+	if err != nil {
 		return service.SendError(c,500,err.Error())
 	}
+	to, ok := order.ParseStatus(invoiceStatus.Code)
+	if !ok{
+		return service.SendError(c,400,"Invoice status not found!")
+	}
+
+	//Keep the current state for the audit log, Transition reports a missing invoice itself
+	before, _ := models.FindInvoice(c.Context(),boil.GetContextDB(),invoiceID)
+	getInvoice, err := order.Transition(c.Context(),invoiceID,to,orderActor(c,order.RoleAdmin),status.CancelReason.String);
+	switch {
+	case errors.Is(err,order.ErrInvoiceNotFound):
+		return service.SendError(c,404,"Invoice not found!")
+	case errors.Is(err,order.ErrReasonRequired):
+		return service.SendError(c,400,"Please provide a cancel reason!")
+	case errors.Is(err,order.ErrInvalidTransition), errors.Is(err,order.ErrForbidden):
+		return service.SendError(c,409,"The order cannot be moved to " + invoiceStatus.StatusName + " from its current status")
+	case errors.Is(err,order.ErrCarrierManaged):
+		return service.SendError(c,409,"The shipping carrier moves this order to " + invoiceStatus.StatusName + ", cancel its shipment first")
+	case err != nil:
+		return service.SendError(c,500,err.Error())
+	}
+	recordAdminChange(c,audit.ActionInvoiceUpdate,audit.EntityInvoice,invoiceID,before,getInvoice)
 
 	resp := fiber.Map{
 		"status": "Success",
@@ -139,4 +330,25 @@ func UpdateInvoice(c *fiber.Ctx) error{
 	}
 
 	return c.JSON(resp);
-}
\ No newline at end of file
+}
+
+// GetAdminInvoiceTimeline returns every status change of an order with who made it.
+func GetAdminInvoiceTimeline(c *fiber.Ctx) error{
+	invoiceID := c.QueryInt("invoiceID",0);
+	if invoiceID == 0{
+		return service.SendError(c,400,"Did not receive invoiceID");
+	}
+
+	timeline, err := order.Timeline(c.Context(),invoiceID)
+	if err != nil{
+		return service.SendError(c,500,err.Error())
+	}
+
+	resp := fiber.Map{
+		"status": "Success",
+		"data": timeline,
+		"message": "Successfully fetched the order timeline",
+	}
+
+	return c.JSON(resp);
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"GoodFood-BE/internal/audit"
 	"GoodFood-BE/internal/dto"
 	"GoodFood-BE/internal/service"
 	"GoodFood-BE/internal/utils"
@@ -126,11 +127,13 @@ func AdminProductTypeUpdate(c *fiber.Ctx) error {
 	}
 
 	//Update
+	before := *toUpdate
 	toUpdate.Status = pt.Status
 	toUpdate.TypeName = pt.TypeName
 	if _, err := toUpdate.Update(c.Context(), boil.GetContextDB(), boil.Infer()); err != nil {
 		return service.SendError(c, 500, err.Error())
 	}
+	recordAdminChange(c, audit.ActionProductTypeUpdate, audit.EntityProductType, toUpdate.ProductTypeID, before, toUpdate)
 
 	resp := fiber.Map{
 		"status":  "Success",
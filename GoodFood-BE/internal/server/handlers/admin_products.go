@@ -1,12 +1,14 @@
 package handlers
 
 import (
+	"GoodFood-BE/internal/audit"
 	"GoodFood-BE/internal/dto"
 	"GoodFood-BE/internal/service"
 	"GoodFood-BE/internal/utils"
 	"GoodFood-BE/models"
 	"database/sql"
 	"fmt"
+	"strconv"
 
 	"github.com/aarondl/sqlboiler/v4/boil"
 	"github.com/aarondl/sqlboiler/v4/queries/qm"
@@ -203,6 +205,14 @@ func AdminProductUpdate(c *fiber.Ctx) error {
 		return service.SendError(c, 500, err.Error())
 	}
 
+	entry := audit.FromRequest(c, audit.ActionProductUpdate, audit.EntityProduct, strconv.Itoa(productID))
+	entry.Before = product
+	entry.After = update.Product
+	if len(update.ProductImages) > 0 {
+		entry.Metadata = fiber.Map{"imagesReplaced": len(update.ProductImages)}
+	}
+	audit.RecordBestEffort(c.Context(), entry)
+
 	//Clear all related redis cache keys related to products
 	utils.ClearCache("products:page=*:type=*:search=*:minPrice=*:maxPrice=*:orderByPrice=*")
 	productDetailKey := fmt.Sprintf("product:detail:%d:filter=*:page=*", update.ProductID)
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"GoodFood-BE/internal/audit"
 	"GoodFood-BE/internal/dto"
 	"GoodFood-BE/internal/service"
 	"GoodFood-BE/internal/utils"
@@ -250,6 +251,7 @@ func InsertReviewReply(c *fiber.Ctx) error {
 	if err := reply.Insert(c.Context(), boil.GetContextDB(), boil.Infer()); err != nil {
 		return service.SendError(c, 500, err.Error())
 	}
+	recordAdminChange(c, audit.ActionReviewReplyCreate, audit.EntityReviewReply, reply.ReplyID, nil, reply)
 
 	resp := fiber.Map{
 		"status":  "Success",
@@ -273,9 +275,15 @@ func UpdateReviewReply(c *fiber.Ctx) error {
 		return service.SendError(c, 400, "Did not receive replyID")
 	}
 
+	before, err := models.FindReply(c.Context(), boil.GetContextDB(), replyID)
+	if err != nil {
+		return service.SendError(c, 404, "Reply not found!")
+	}
+	reply.ReplyID = replyID
 	if _, err := reply.Update(c.Context(), boil.GetContextDB(), boil.Infer()); err != nil {
 		return service.SendError(c, 500, err.Error())
 	}
+	recordAdminChange(c, audit.ActionReviewReplyUpdate, audit.EntityReviewReply, replyID, before, reply)
 
 	resp := fiber.Map{
 		"status":  "Success",
@@ -1,12 +1,15 @@
 package handlers
 
 import (
+	"GoodFood-BE/internal/audit"
+	"GoodFood-BE/internal/auth"
 	"GoodFood-BE/internal/dto"
 	"GoodFood-BE/internal/service"
 	"GoodFood-BE/internal/utils"
 	"GoodFood-BE/models"
 	"context"
 	"fmt"
+	"strconv"
 
 	"github.com/aarondl/sqlboiler/v4/boil"
 	"github.com/aarondl/sqlboiler/v4/queries/qm"
@@ -91,15 +94,51 @@ func GetAdminUserDetail(c *fiber.Ctx) error{
 		return service.SendError(c,500,err.Error());
 	}
 
+	//Remaining login lockout, 0 when the account is not locked
+	lockedFor, err := auth.LoginLockRemaining(account.Username)
+	if err != nil{
+		return service.SendError(c,500,err.Error());
+	}
+
 	resp := fiber.Map{
 		"status": "Success",
 		"data": account,
+		"lockedForSeconds": int(lockedFor.Seconds()),
 		"message": "Successfully fetched user values",
 	}
 
 	return c.JSON(resp);
 }
 
+// AdminUserUnlock lifts a login lockout of a user and clears their failed attempts.
+func AdminUserUnlock(c *fiber.Ctx) error{
+	accountID := c.QueryInt("accountID",0);
+	if accountID == 0{
+		return service.SendError(c,400,"Did not receive accountID");
+	}
+
+	account, err := models.Accounts(qm.Where("\"accountID\" = ?",accountID)).One(c.Context(),boil.GetContextDB())
+	if err != nil{
+		return service.SendError(c,500,err.Error());
+	}
+
+	wasLocked, err := auth.UnlockLogin(account.Username)
+	if err != nil{
+		return service.SendError(c,500,err.Error());
+	}
+	entry := audit.FromRequest(c,audit.ActionAccountUnlock,audit.EntityAccount,strconv.Itoa(account.AccountID))
+	entry.Metadata = fiber.Map{"username": account.Username, "wasLocked": wasLocked}
+	audit.RecordBestEffort(c.Context(),entry)
+
+	resp := fiber.Map{
+		"status": "Success",
+		"data": fiber.Map{"wasLocked": wasLocked},
+		"message": "Successfully unlocked user",
+	}
+
+	return c.JSON(resp);
+}
+
 // AdminUserCreate creates a new user after validating input.
 func AdminUserCreate(c *fiber.Ctx) error{
 	var(
@@ -117,6 +156,12 @@ func AdminUserCreate(c *fiber.Ctx) error{
 	if valid, errObj := validationUser(&user,hasPassword,hasUsername,hasEmail,hasPhone); !valid{
 		return service.SendErrorStruct(c,400,errObj);
 	}
+	if user.Role == ""{
+		user.Role = auth.RoleCustomer
+	}
+	if !auth.CanAssignRole(actorRole(c),user.Role){
+		return service.SendError(c,403,"You cannot assign this role");
+	}
 
 	//Insert
 	if err := user.Insert(c.Context(),boil.GetContextDB(),boil.Infer()); err != nil{
@@ -148,15 +193,31 @@ func AdminUserUpdate(c *fiber.Ctx) error{
 	if err != nil{
 		return service.SendError(c,500, err.Error());
 	}
+	before := *user
+
+	//Role changes are limited to roles at or below the caller's own
+	if userBody.Role != "" && userBody.Role != user.Role{
+		if !auth.CanAssignRole(actorRole(c),userBody.Role) || !auth.CanAssignRole(actorRole(c),user.Role){
+			return service.SendError(c,403,"You cannot assign this role");
+		}
+		user.Role = userBody.Role
+	}
 
 	// Only update specific fields
+	disabling := user.Status && !userBody.Status
 	user.FullName = userBody.FullName
-	user.Role = userBody.Role
 	user.Gender = userBody.Gender
 	user.Status = userBody.Status
 	if _,err = user.Update(c.Context(),boil.GetContextDB(),boil.Infer()); err != nil{
 		return service.SendError(c,500,err.Error());
 	}
+	recordAdminChange(c,audit.ActionAccountUpdate,audit.EntityAccount,user.AccountID,before,user)
+	//A disabled account loses its outstanding tokens right away
+	if disabling{
+		if err := auth.RevokeUserTokens(user.Username); err != nil{
+			return service.SendError(c,500,err.Error());
+		}
+	}
 
 	resp := fiber.Map{
 		"status": "Success",
@@ -168,6 +229,14 @@ func AdminUserUpdate(c *fiber.Ctx) error{
 
 }
 
+// actorRole returns the role of the admin performing the request, as loaded by auth.RequirePermission.
+func actorRole(c *fiber.Ctx) string{
+	if account := auth.GetAccount(c); account != nil{
+		return account.Role
+	}
+	return auth.GetAuthenticatedRole(c)
+}
+
 // validateUser checks input fields depending on required flags.
 func validationUser(user *models.Account, hasPassword bool,hasUsername bool, hasEmail bool,hasPhone bool) (bool,dto.UserError){
 	var error dto.UserError
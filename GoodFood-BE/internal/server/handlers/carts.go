@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"GoodFood-BE/internal/auth"
 	"GoodFood-BE/internal/dto"
 	"GoodFood-BE/internal/service"
 	"GoodFood-BE/internal/utils"
@@ -17,11 +18,12 @@ import (
 
 //GetCartDetail returns cart details (fetch from redis cache or db) and save cart details into cache afterward.
 func GetCartDetail(c *fiber.Ctx) error{
-	//Fetch query param
-	accountID := c.QueryInt("accountID",0);
-	if accountID == 0{
-		return service.SendError(c,400,"Did not receive accountID");
+	//Fetch authenticated account
+	account := auth.GetAccount(c)
+	if account == nil{
+		return service.SendError(c,401,"Unauthenticated");
 	}
+	accountID := account.AccountID
 
 	//Create redis key after accountID
 	redisKey := fmt.Sprintf("cart:accountID=%d",accountID);
@@ -63,10 +65,11 @@ func Cart_ModifyQuantity(c *fiber.Ctx) error{
 	if (cartID == 0){
 		return service.SendError(c,401,"Did not receive cartID");
 	}
-	accountID := c.QueryInt("accountID",0);
-	if (accountID == 0){
-		return service.SendError(c,401,"Did not receive accountID");
+	account := auth.GetAccount(c)
+	if account == nil{
+		return service.SendError(c,401,"Unauthenticated");
 	}
+	accountID := account.AccountID
 
 	//Parse request body into struct
 	var cartDetail models.CartDetail
@@ -78,6 +81,9 @@ func Cart_ModifyQuantity(c *fiber.Ctx) error{
 	if err != nil{
 		return service.SendError(c,500,"Cart detail not found");
 	}
+	if update.AccountID != accountID{
+		return service.SendError(c,403,"Cart item belongs to another account!");
+	}
 
 	//If found, update the quantity
 	update.Quantity = cartDetail.Quantity;
@@ -104,16 +110,20 @@ func DeleteCartItem(c *fiber.Ctx) error{
 	if cartID == 0{
 		return service.SendError(c,401,"Did not receive cartID");
 	}
-	accountID := c.QueryInt("accountID",0);
-	if accountID == 0{
-		return service.SendError(c,401,"Did not receive accountID");
+	account := auth.GetAccount(c)
+	if account == nil{
+		return service.SendError(c,401,"Unauthenticated");
 	}
+	accountID := account.AccountID
 
 	//Fetch db
 	cartItem,err := models.FindCartDetail(c.Context(),boil.GetContextDB(),cartID);
 	if err != nil {
 		return service.SendError(c,500,"No cart item found");
 	}
+	if cartItem.AccountID != accountID{
+		return service.SendError(c,403,"Cart item belongs to another account!");
+	}
 
 	//If found, delete cart item
 	if _,err = cartItem.Delete(c.Context(),boil.GetContextDB()); err != nil {
@@ -134,10 +144,16 @@ func DeleteCartItem(c *fiber.Ctx) error{
 
 //AddToCart adds a product into the customer's cart
 func AddToCart(c *fiber.Ctx) error{
+	account := auth.GetAccount(c)
+	if account == nil{
+		return service.SendError(c,401,"Unauthenticated");
+	}
 	var cartDetail models.CartDetail
 	if err := c.BodyParser(&cartDetail); err != nil{
 		return service.SendError(c,400,"Invalid body");
 	}
+	//Items always go into the caller's own cart
+	cartDetail.AccountID = account.AccountID
 
 	//Clear cache after mutation
 	redisKey := fmt.Sprintf("cart:accountID=%d",cartDetail.AccountID)
@@ -196,11 +212,12 @@ func AddToCart(c *fiber.Ctx) error{
 
 //FetchCart returns all cart items to the requested user.
 func FetchCart(c *fiber.Ctx) error{
-	//Fetch query param
-	accountID := c.QueryInt("accountID",0);
-	if accountID == 0{
-		return service.SendError(c,401,"Did not receive accountID");
+	//Fetch authenticated account
+	account := auth.GetAccount(c)
+	if account == nil{
+		return service.SendError(c,401,"Unauthenticated");
 	}
+	accountID := account.AccountID
 
 	//Fetch db
 	cartDetail,err := models.CartDetails(
@@ -224,15 +241,19 @@ func FetchCart(c *fiber.Ctx) error{
 
 //DeleteAllItems deletes the entire cart of the user.
 func DeleteAllItems(c *fiber.Ctx) error{
-	//Fetch query param
-	accountID := c.QueryInt("accountID",0);
+	//Fetch authenticated account
+	account := auth.GetAccount(c)
+	if account == nil{
+		return service.SendError(c,401,"Unauthenticated");
+	}
+	accountID := account.AccountID
 
 	//Fetch all cart details for deletion
 	cartToDelete,err := models.CartDetails(
 		qm.Where("\"accountID\" = ?",accountID),
 	).All(c.Context(),boil.GetContextDB());
 	if err != nil{
-		return service.SendError(c,401,"Did not receive accountID");
+		return service.SendError(c,500,"Cart detail not found");
 	}
 
 	//Delete
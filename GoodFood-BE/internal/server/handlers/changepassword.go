@@ -1,9 +1,13 @@
 package handlers
 
 import (
+	"GoodFood-BE/internal/audit"
+	"GoodFood-BE/internal/auth"
 	"GoodFood-BE/internal/dto"
 	"GoodFood-BE/internal/service"
 	"GoodFood-BE/models"
+	"strconv"
+
 	"github.com/aarondl/sqlboiler/v4/boil"
 	"github.com/gofiber/fiber/v2"
 	"golang.org/x/crypto/bcrypt"
@@ -16,10 +20,10 @@ func ChangePasswordSubmit(c *fiber.Ctx) error{
 		return service.SendError(c,400,"Invalid body");
 	}
 
-	//Fetch user
-	user, err := models.FindAccount(c.Context(),boil.GetContextDB(),body.AccountID)
-	if err != nil{
-		return service.SendError(c,404,err.Error());
+	//Fetch authenticated user
+	user := auth.GetAccount(c)
+	if user == nil{
+		return service.SendError(c,401,"Unauthenticated");
 	}
 
 	//Validate input
@@ -38,6 +42,11 @@ func ChangePasswordSubmit(c *fiber.Ctx) error{
 	if err != nil{
 		return service.SendError(c,500,err.Error());
 	}
+	//Invalidate every access and refresh token issued with the old password
+	if err := auth.RevokeUserTokens(user.Username); err != nil{
+		return service.SendError(c,500,err.Error());
+	}
+	_ = enqueuePasswordChangedEmail(user.Email)
 
 	//Clear refresh cookie if exists
 	refreshToken := c.Cookies("refreshToken","");
@@ -62,6 +71,54 @@ func ChangePasswordSubmit(c *fiber.Ctx) error{
 	return c.JSON(resp);
 }
 
+//SetPassword adds a password to an account created through a social login, which has no old password to confirm.
+func SetPassword(c *fiber.Ctx) error{
+	body := dto.SetPasswordRequest{}
+	if err := c.BodyParser(&body); err != nil{
+		return service.SendError(c,400,"Invalid body");
+	}
+
+	user := auth.GetAccount(c)
+	if user == nil{
+		return service.SendError(c,401,"Unauthenticated");
+	}
+	identities, err := auth.ListIdentities(c.Context(),boil.GetContextDB(),user.AccountID)
+	if err != nil{
+		return service.SendError(c,500,err.Error());
+	}
+	if auth.HasLocalPassword(user,identities){
+		return service.SendError(c,400,"Password is already set, use change password instead");
+	}
+
+	errObj := dto.ChangePassErr{}
+	if len(body.NewPassword) <= 7{
+		errObj.ErrNewPassword = "New password needs to be at least 8 characters!";
+		return service.SendErrorStruct(c,400,errObj);
+	}
+	if body.ConfirmPassword != body.NewPassword{
+		errObj.ErrConfirmPassword = "Password does not match!"
+		return service.SendErrorStruct(c,400,errObj);
+	}
+
+	hashedPass, err := bcrypt.GenerateFromPassword([]byte(body.NewPassword),bcrypt.DefaultCost);
+	if err != nil{
+		return service.SendError(c,500,err.Error());
+	}
+	user.Password = string(hashedPass);
+	if _, err := user.Update(c.Context(),boil.GetContextDB(),boil.Whitelist(models.AccountColumns.Password)); err != nil{
+		return service.SendError(c,500,err.Error());
+	}
+	entry := audit.FromRequest(c,audit.ActionPasswordSet,audit.EntityAccount,strconv.Itoa(user.AccountID))
+	entry.Metadata = fiber.Map{"username": user.Username}
+	audit.RecordBestEffort(c.Context(),entry)
+	_ = enqueuePasswordChangedEmail(user.Email)
+
+	return c.JSON(fiber.Map{
+		"status": "Success",
+		"message": "Password has been set, you can now sign in with your username and password",
+	});
+}
+
 //validateChangePass validates input fields when changing password
 func validateChangePass(body *dto.ChangePassRequest, user models.Account) (dto.ChangePassErr, bool){
 	valid := true;
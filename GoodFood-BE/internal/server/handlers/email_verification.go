@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"GoodFood-BE/internal/auth"
+	"GoodFood-BE/internal/jobs"
+	redisdatabase "GoodFood-BE/internal/redis-database"
+	"GoodFood-BE/internal/service"
+	"GoodFood-BE/internal/utils"
+	"GoodFood-BE/models"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/aarondl/sqlboiler/v4/boil"
+	"github.com/gofiber/fiber/v2"
+)
+
+// verifyEmailResendCooldown is the minimum delay between two verification emails for one account.
+const verifyEmailResendCooldown = time.Minute
+
+// enqueueVerificationEmail signs a verification token for the current email of account and queues the email.
+func enqueueVerificationEmail(account *models.Account) error {
+	token, err := auth.CreateEmailVerificationToken(account.AccountID, account.Email)
+	if err != nil {
+		return err
+	}
+	verifyLink := fmt.Sprintf("%s/verify-email?token=%s", utils.FrontendURL(), url.QueryEscape(token))
+
+	task, err := jobs.NewVerifyEmailTask(account.Email, verifyLink)
+	if err != nil {
+		return err
+	}
+	_, err = asynqClient.Enqueue(task)
+	return err
+}
+
+// VerifyEmail marks the email of an account as verified using the token from the verification link.
+func VerifyEmail(c *fiber.Ctx) error {
+	token := c.Query("token", "")
+	if token == "" {
+		return service.SendError(c, 400, "Did not receive token!")
+	}
+
+	claims, err := auth.VerifyEmailVerificationToken(token)
+	if err != nil {
+		return service.SendError(c, 400, "Invalid or expired token")
+	}
+
+	account, err := models.FindAccount(c.Context(), boil.GetContextDB(), claims.AccountID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return service.SendError(c, 400, "Invalid or expired token")
+		}
+		return service.SendError(c, 500, err.Error())
+	}
+	//The email changed since the link was sent
+	if account.Email != claims.Email {
+		return service.SendError(c, 400, "Invalid or expired token")
+	}
+
+	if !account.EmailVerified {
+		account.EmailVerified = true
+		if _, err := account.Update(c.Context(), boil.GetContextDB(), boil.Whitelist(models.AccountColumns.EmailVerified)); err != nil {
+			return service.SendError(c, 500, err.Error())
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"status":  "Success",
+		"message": "Email has been verified",
+	})
+}
+
+// ResendVerificationEmail sends a new verification link to the current account, at most once per cooldown.
+func ResendVerificationEmail(c *fiber.Ctx) error {
+	account := auth.GetAccount(c)
+	if account == nil {
+		return service.SendError(c, 401, "Unauthenticated")
+	}
+	if account.EmailVerified {
+		return service.SendError(c, 400, "Email is already verified")
+	}
+
+	redisKey := fmt.Sprintf("verifyEmail:resend=%d", account.AccountID)
+	ok, err := redisdatabase.Client.SetNX(redisdatabase.Ctx, redisKey, 1, verifyEmailResendCooldown).Result()
+	if err != nil {
+		return service.SendError(c, 500, err.Error())
+	}
+	if !ok {
+		return service.SendError(c, 429, "Please wait before requesting another verification email")
+	}
+
+	if err := enqueueVerificationEmail(account); err != nil {
+		_ = redisdatabase.Client.Del(redisdatabase.Ctx, redisKey).Err()
+		return service.SendError(c, 500, err.Error())
+	}
+
+	return c.JSON(fiber.Map{
+		"status":  "Success",
+		"message": "A verification link has been sent to your email",
+	})
+}
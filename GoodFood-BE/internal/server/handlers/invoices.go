@@ -1,141 +1,147 @@
 package handlers
 
 import (
-	"GoodFood-BE/config"
+	"GoodFood-BE/internal/auth"
+	"GoodFood-BE/internal/checkout"
 	"GoodFood-BE/internal/dto"
+	"GoodFood-BE/internal/order"
+	"GoodFood-BE/internal/payment"
 	"GoodFood-BE/internal/service"
+	"GoodFood-BE/internal/utils"
 	"GoodFood-BE/models"
+	"database/sql"
+	"errors"
 	"fmt"
-	"net/url"
-	"os"
-	"sort"
-	"strconv"
-	"strings"
-	"time"
 
 	"github.com/aarondl/sqlboiler/v4/boil"
 	"github.com/gofiber/fiber/v2"
 )
 
-//InvoicePay creates an invoice for the purchased products and insert data into related tables
-func InvoicePay(c *fiber.Ctx) error{
-	var payload dto.InvoicePayload
-	if err := c.BodyParser(&payload); err != nil{
-		return service.SendError(c,401,"Invalid body details: " + err.Error());
+//checkoutError writes the response for errors returned by the checkout service.
+func checkoutError(c *fiber.Ctx, err error) error{
+	switch {
+	case errors.Is(err,checkout.ErrProductUnavailable):
+		return service.SendError(c,409,"Some products are no longer available: "+err.Error())
+	case errors.Is(err,checkout.ErrAddressNotFound):
+		return service.SendError(c,404,"Delivery address not found")
+	case errors.Is(err,checkout.ErrEmptyOrder), errors.Is(err,checkout.ErrTooManyLines),
+		errors.Is(err,checkout.ErrInvalidQuantity), errors.Is(err,checkout.ErrInvalidPaymentMethod),
+		errors.Is(err,checkout.ErrNoteTooLong):
+		return service.SendError(c,400,err.Error())
+	default:
+		return service.SendError(c,500,err.Error())
 	}
+}
 
-	//Open transaction
-	tx, err := boil.BeginTx(c.Context(),nil);
+//InvoiceQuote prices the products of a checkout from the catalog and the shipping rates, so the client can show the totals it will be charged.
+//Shipping is priced for the address of the body, or for the default zone until the customer picks one
+func InvoiceQuote(c *fiber.Ctx) error{
+	var body dto.CheckoutRequest
+	if err := c.BodyParser(&body); err != nil{
+		return service.SendError(c,400,"Invalid body details: " + err.Error());
+	}
+	account := auth.GetAccount(c)
+	if account == nil{
+		return service.SendError(c,401,"Unauthenticated");
+	}
+	quote, err := checkout.Quote(c.Context(),account.AccountID,body)
 	if err != nil{
-		return service.SendError(c,500,err.Error())
+		return checkoutError(c,err)
 	}
-	defer tx.Rollback() //rollback data if something went wrong
+	return c.JSON(fiber.Map{
+		"status": "Success",
+		"data": quote,
+		"message": "Successfully priced the order",
+	})
+}
 
-	//Insert invoice first
-	if err := payload.Invoice.Insert(c.Context(),tx,boil.Infer()); err != nil{
-		return service.SendError(c,500,err.Error());
+//InvoicePay places an order for the caller. The client only sends products, quantities, the address and the payment method:
+//prices, shipping and totals are computed from the catalog, and the ordered products are removed from the cart
+func InvoicePay(c *fiber.Ctx) error{
+	account := auth.GetAccount(c)
+	if account == nil{
+		return service.SendError(c,401,"Unauthenticated");
+	}
+	var body dto.CheckoutRequest
+	if err := c.BodyParser(&body); err != nil{
+		return service.SendError(c,400,"Invalid body details: " + err.Error());
 	}
 
-	//Insert invoice details
-	for _,detail := range payload.InvoiceDetails{
-		detail.InvoiceID = payload.Invoice.InvoiceID
-		if err := detail.Insert(c.Context(),tx,boil.Infer()); err != nil{
-			return service.SendError(c, 500, err.Error())
-		}
-	}
-	
-	//Commit transaction
-	if err := tx.Commit(); err != nil{
-		return service.SendError(c,500,err.Error())
+	placed, err := checkout.PlaceOrder(c.Context(),account.AccountID,body)
+	if err != nil{
+		return checkoutError(c,err)
 	}
-	
+
+	//Caches that need to be renewed
+	cartKey := fmt.Sprintf("cart:accountID=%d",account.AccountID)
+	orderHistoryKey := fmt.Sprintf("orderhistory:accountID=%d:tab:%s",account.AccountID,utils.StatusOrderPlaced)
+	utils.ClearCache(cartKey,orderHistoryKey)
+
 	resp := fiber.Map{
 		"status": "Success",
-		"data": payload,
+		"data": placed,
 		"message": "Successfully created new invoice!",
 	}
 
 	return c.JSON(resp);
 }
 
-//InvoicePayVNPAY receives invoice details from front-end, then construct a payment url to VNPAY
-func InvoicePayVNPAY(c *fiber.Ctx) error{
+//InvoicePayVNPAYRemoved answers the old VNPay route, which priced the payment from the client before the order existed.
+//Orders are now placed first and paid by their invoiceID, so old clients are told where to go instead.
+func InvoicePayVNPAYRemoved(c *fiber.Ctx) error{
+	return service.SendError(c,410,"This endpoint was removed: place the order with POST /api/invoice/pay, then pay it with POST /api/invoice/pay/online and its invoiceID");
+}
+
+//InvoicePayOnline starts a payment attempt of an unpaid online order of the caller with the provider of its
+//payment method and returns the URL where the customer pays it.
+//The amount comes from the invoice stored by InvoicePay, never from the client
+func InvoicePayOnline(c *fiber.Ctx) error{
+	account := auth.GetAccount(c)
+	if account == nil{
+		return service.SendError(c,401,"Unauthenticated");
+	}
 	body := models.Invoice{}
 	if err := c.BodyParser(&body); err != nil{
 		return service.SendError(c,400,err.Error());
 	}
-
-	//amount * 100 (vnpay requirement)
-	amount := int64(body.TotalPrice) * 100
-	// Fetch latest invoiceID from db
-	var latestID int
-	err := boil.GetContextDB().QueryRowContext(c.Context(), `
-		SELECT COALESCE(MAX("invoiceID"), 0) FROM invoice
-	`).Scan(&latestID)
-	if err != nil {
-		return service.SendError(c, 500, "Failed to fetch latest invoiceID: "+err.Error())
-	}
-	orderId := strconv.Itoa(latestID + 1) //unique orderID for vnpay payment
-
-	//query params
-	vnpParams := map[string]string{
-		"vnp_Version": 	 config.VnpVersion,
-		"vnp_Command":   config.VnpCommand,
-		"vnp_TmnCode":   os.Getenv("VNPAY_TMN"),
-		"vnp_Amount":    strconv.FormatInt(amount, 10),
-		"vnp_CurrCode":  "VND",
-		"vnp_BankCode":  "NCB",
-		"vnp_TxnRef":    orderId,
-		"vnp_OrderInfo": fmt.Sprintf("Paying for invoice: %d", body.InvoiceID),
-		"vnp_Locale":    "vn",
-		"vnp_OrderType": "other",
-		"vnp_ReturnUrl": os.Getenv("VNPAY_RETURN_URL"),
-		"vnp_IpAddr":    c.IP(),
+	if body.InvoiceID == 0{
+		return service.SendError(c,400,"Did not receive invoiceID");
 	}
 
-	//Time zone in Asia/HCM
-	loc, _ := time.LoadLocation("Asia/Ho_Chi_Minh")
-	now := time.Now().In(loc)
-	vnpParams["vnp_CreateDate"] = now.Format("20060102150405")
-	vnpParams["vnp_ExpireDate"] = now.Add(15 * time.Minute).Format("20060102150405")
-
-	// Sort keys before encoding
-	var keys []string
-	for k := range vnpParams {
-		keys = append(keys, k)
+	invoice, err := models.Invoices(
+		models.InvoiceWhere.InvoiceID.EQ(body.InvoiceID),
+		models.InvoiceWhere.AccountID.EQ(account.AccountID),
+	).One(c.Context(),boil.GetContextDB())
+	if err == sql.ErrNoRows{
+		return service.SendError(c,404,"Invoice not found");
 	}
-	sort.Strings(keys)
-
-	//Build rawData and query string
-	var rawData strings.Builder
-	var query strings.Builder
-	for i, k := range keys {
-		v := vnpParams[k]
-		if v != "" {
-			if i > 0 {
-				rawData.WriteString("&")
-				query.WriteString("&")
-			}
-			// phải encode value theo chuẩn VNPay
-			encodedVal := url.QueryEscape(v)
-			rawData.WriteString(k + "=" + encodedVal)
-			query.WriteString(k + "=" + encodedVal)
-		}
+	if err != nil{
+		return service.SendError(c,500,err.Error());
+	}
+	if invoice.Status{
+		return service.SendError(c,409,"This order has already been paid");
+	}
+	status, err := order.StatusOf(c.Context(),boil.GetContextDB(),invoice.InvoiceStatusID)
+	if err != nil{
+		return service.SendError(c,500,err.Error());
+	}
+	if status == order.StatusCancelled{
+		return service.SendError(c,409,"This order has been cancelled");
 	}
 
-	// Hash HMAC SHA512
-	secureHash := config.HmacSHA512(os.Getenv("VNPAY_SECRET"), rawData.String())
-
-	// Thêm SecureHash vào cuối query (KHÔNG encode lại chuỗi hash)
-	query.WriteString("&vnp_SecureHash=" + secureHash)
-
-	// Build final URL
-	paymentUrl := fmt.Sprintf("%s?%s", config.VnpPayURL, query.String())
+	//Every payment url is a new attempt with its own merchant reference, so the order can be paid again if it fails
+	_, paymentUrl, err := payment.StartPayment(c.Context(),invoice,c.IP())
+	if errors.Is(err,payment.ErrNotSupported){
+		return service.SendError(c,400,"This order is paid on delivery");
+	}
+	if err != nil{
+		return service.SendError(c,500,err.Error());
+	}
 
 	resp := fiber.Map{
 		"status": "Success",
 		"data": paymentUrl,
-		"message": "Successfully redirected to VNPay gateway!",
+		"message": "Successfully redirected to the payment gateway!",
 	}
 
 	return c.JSON(resp);
@@ -0,0 +1,18 @@
+package handlers
+
+import (
+	"GoodFood-BE/internal/auth"
+	"GoodFood-BE/internal/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetJWKS publishes the public keys verifying access tokens so other services can validate them.
+func GetJWKS(c *fiber.Ctx) error {
+	keys, err := auth.JWKS()
+	if err != nil {
+		return service.SendError(c, 500, err.Error())
+	}
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return c.JSON(fiber.Map{"keys": keys})
+}
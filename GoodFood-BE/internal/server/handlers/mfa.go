@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"GoodFood-BE/internal/audit"
+	"GoodFood-BE/internal/auth"
+	"GoodFood-BE/internal/dto"
+	"GoodFood-BE/internal/service"
+	"GoodFood-BE/internal/utils"
+	"GoodFood-BE/models"
+	"errors"
+	"strconv"
+
+	"github.com/aarondl/sqlboiler/v4/boil"
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// mfaError writes the response for errors returned by the auth two-factor functions.
+func mfaError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, auth.ErrInvalidMFACode):
+		return service.SendError(c, 400, "Invalid two-factor code")
+	case errors.Is(err, auth.ErrMFANotEnrolled):
+		return service.SendError(c, 400, "Two-factor authentication is not set up")
+	case errors.Is(err, auth.ErrMFAAlreadyEnabled):
+		return service.SendError(c, 409, "Two-factor authentication is already enabled")
+	case errors.Is(err, auth.ErrInvalidMFAChallenge):
+		return service.SendError(c, 401, "Invalid or expired two-factor challenge")
+	default:
+		return service.SendError(c, 500, err.Error())
+	}
+}
+
+// GetMFAStatus returns whether two-factor is enabled for the current account and if its role requires it.
+func GetMFAStatus(c *fiber.Ctx) error {
+	account := auth.GetAccount(c)
+	if account == nil {
+		return service.SendError(c, 401, "Unauthenticated")
+	}
+	enabled, err := auth.MFAEnabled(c.Context(), account.AccountID)
+	if err != nil {
+		return service.SendError(c, 500, err.Error())
+	}
+	status := dto.MFAStatusResponse{
+		Enabled:  enabled,
+		Required: auth.AdminMFARequired() && auth.HasPermission(account.Role, auth.PermAdminAccess),
+	}
+	if enabled {
+		if status.RemainingRecoveryCodes, err = auth.RemainingRecoveryCodes(c.Context(), account.AccountID); err != nil {
+			return service.SendError(c, 500, err.Error())
+		}
+	}
+	return c.JSON(fiber.Map{
+		"status": "Success",
+		"data":   status,
+	})
+}
+
+// SetupMFA starts enrollment and returns the secret with its provisioning URI.
+func SetupMFA(c *fiber.Ctx) error {
+	account := auth.GetAccount(c)
+	if account == nil {
+		return service.SendError(c, 401, "Unauthenticated")
+	}
+	secret, err := auth.BeginMFAEnrollment(c.Context(), account.AccountID)
+	if err != nil {
+		return mfaError(c, err)
+	}
+	return c.JSON(fiber.Map{
+		"status": "Success",
+		"data": dto.MFASetupResponse{
+			Secret:          secret,
+			ProvisioningURI: auth.TOTPProvisioningURI(account.Email, secret),
+		},
+		"message": "Scan the QR code with your authenticator app, then confirm with a code",
+	})
+}
+
+// ConfirmMFA enables two-factor with a first valid code and returns the recovery codes.
+func ConfirmMFA(c *fiber.Ctx) error {
+	account := auth.GetAccount(c)
+	if account == nil {
+		return service.SendError(c, 401, "Unauthenticated")
+	}
+	body := dto.MFACodeRequest{}
+	if err := c.BodyParser(&body); err != nil {
+		return service.SendError(c, 400, "Invalid request body")
+	}
+
+	codes, err := auth.ConfirmMFAEnrollment(c.Context(), account.AccountID, body.Code)
+	if err != nil {
+		return mfaError(c, err)
+	}
+	audit.RecordBestEffort(c.Context(), audit.FromRequest(c, audit.ActionMFAEnabled, audit.EntityAccount, strconv.Itoa(account.AccountID)))
+
+	return c.JSON(fiber.Map{
+		"status":  "Success",
+		"data":    fiber.Map{"recoveryCodes": codes},
+		"message": "Two-factor authentication enabled. Store these recovery codes somewhere safe",
+	})
+}
+
+// DisableMFA turns two-factor off after checking the password and a current code.
+func DisableMFA(c *fiber.Ctx) error {
+	account := auth.GetAccount(c)
+	if account == nil {
+		return service.SendError(c, 401, "Unauthenticated")
+	}
+	body := dto.MFADisableRequest{}
+	if err := c.BodyParser(&body); err != nil {
+		return service.SendError(c, 400, "Invalid request body")
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(account.Password), []byte(body.Password)); err != nil {
+		return service.SendError(c, 400, "Wrong password!")
+	}
+	if err := auth.VerifyMFACode(c.Context(), account.AccountID, body.Code); err != nil {
+		return mfaError(c, err)
+	}
+
+	if err := auth.DisableMFA(c.Context(), account.AccountID); err != nil {
+		return service.SendError(c, 500, err.Error())
+	}
+	audit.RecordBestEffort(c.Context(), audit.FromRequest(c, audit.ActionMFADisabled, audit.EntityAccount, strconv.Itoa(account.AccountID)))
+
+	return c.JSON(fiber.Map{
+		"status":  "Success",
+		"message": "Two-factor authentication disabled",
+	})
+}
+
+// RegenerateMFARecoveryCodes replaces the recovery codes after checking a current code.
+func RegenerateMFARecoveryCodes(c *fiber.Ctx) error {
+	account := auth.GetAccount(c)
+	if account == nil {
+		return service.SendError(c, 401, "Unauthenticated")
+	}
+	body := dto.MFACodeRequest{}
+	if err := c.BodyParser(&body); err != nil {
+		return service.SendError(c, 400, "Invalid request body")
+	}
+	if err := auth.VerifyMFACode(c.Context(), account.AccountID, body.Code); err != nil {
+		return mfaError(c, err)
+	}
+
+	codes, err := auth.RegenerateRecoveryCodes(c.Context(), account.AccountID)
+	if err != nil {
+		return service.SendError(c, 500, err.Error())
+	}
+	return c.JSON(fiber.Map{
+		"status":  "Success",
+		"data":    fiber.Map{"recoveryCodes": codes},
+		"message": "New recovery codes generated, the old ones no longer work",
+	})
+}
+
+// HandleLoginMFA completes a login with the challenge token and a TOTP or recovery code.
+func HandleLoginMFA(c *fiber.Ctx) error {
+	body := dto.MFALoginRequest{}
+	if err := c.BodyParser(&body); err != nil {
+		return service.SendError(c, 400, "Invalid request body")
+	}
+
+	accountID, err := auth.ResolveMFAChallenge(body.MFAToken)
+	if err != nil {
+		return mfaError(c, err)
+	}
+	account, err := models.FindAccount(c.Context(), boil.GetContextDB(), accountID)
+	if err != nil {
+		return mfaError(c, auth.ErrInvalidMFAChallenge)
+	}
+
+	//Wrong codes count towards the login lockout, so new challenges can't be used to brute-force the code
+	ip := c.IP()
+	if err := auth.CheckLoginAllowed(account.Username, ip); err != nil {
+		return loginBlocked(c, err)
+	}
+	if err := auth.VerifyMFACode(c.Context(), accountID, body.Code); err != nil {
+		if errors.Is(err, auth.ErrInvalidMFACode) {
+			if locked, _ := auth.RegisterLoginFailure(account.Username, ip); locked {
+				entry := audit.FromRequest(c, audit.ActionAccountLockout, audit.EntityAccount, strconv.Itoa(account.AccountID))
+				entry.Metadata = fiber.Map{"username": account.Username, "reason": "too many failed two-factor codes", "duration": auth.LoginLockoutDuration.String()}
+				audit.RecordBestEffort(c.Context(), entry)
+			}
+		}
+		return mfaError(c, err)
+	}
+	//The challenge is single-use
+	if ok, err := auth.ConsumeMFAChallenge(body.MFAToken); err != nil {
+		return service.SendError(c, 500, err.Error())
+	} else if !ok {
+		return mfaError(c, auth.ErrInvalidMFAChallenge)
+	}
+	_ = auth.ResetLoginFailures(account.Username)
+
+	if !account.Status {
+		return service.SendError(c, 403, "Account is disabled")
+	}
+	return utils.IssueSession(c, account, true)
+}
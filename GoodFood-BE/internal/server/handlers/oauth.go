@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"GoodFood-BE/internal/audit"
+	"GoodFood-BE/internal/auth"
+	"GoodFood-BE/internal/dto"
+	"GoodFood-BE/internal/oidc"
+	"GoodFood-BE/internal/service"
+	"GoodFood-BE/internal/utils"
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/aarondl/sqlboiler/v4/boil"
+	"github.com/gofiber/fiber/v2"
+)
+
+// providerFacebook is stored in oauth_account.provider for Facebook logins, which do not use OpenID Connect.
+const providerFacebook = "facebook"
+
+// oauthProfileFetchers verify the access token of providers that are not OpenID Connect issuers.
+// Every other provider name is looked up in the OIDC registry.
+var oauthProfileFetchers = map[string]func(ctx context.Context, token string) (dto.OAuthProfile, error){
+	providerFacebook: facebookProfile,
+}
+
+// facebookProfile resolves a Facebook access token through the Graph API.
+// Facebook does not say whether the email was verified, so it is never trusted for linking by email.
+func facebookProfile(ctx context.Context, token string) (dto.OAuthProfile, error) {
+	fbUser, err := utils.GetFacebookUserInfo(token)
+	if err != nil {
+		return dto.OAuthProfile{}, err
+	}
+	return dto.OAuthProfile{
+		Provider:       providerFacebook,
+		ProviderUserID: fbUser.ID,
+		Email:          fbUser.Email,
+		FullName:       fbUser.Name,
+		AvatarURL:      fbUser.Picture.Data.URL,
+	}, nil
+}
+
+// verifyOAuthToken resolves the provider token of the request body to a profile.
+// On failure it returns the status and message to answer with.
+func verifyOAuthToken(c *fiber.Ctx, provider string) (dto.OAuthProfile, int, string) {
+	body := dto.OAuthLoginStruct{}
+	if err := c.BodyParser(&body); err != nil || (body.AccessToken == "" && body.IDToken == "") {
+		return dto.OAuthProfile{}, 400, "Invalid request body"
+	}
+
+	if fetch, ok := oauthProfileFetchers[provider]; ok {
+		profile, err := fetch(c.Context(), body.AccessToken)
+		if err != nil || profile.ProviderUserID == "" {
+			return dto.OAuthProfile{}, 401, "Invalid provider token"
+		}
+		return profile, 0, ""
+	}
+
+	registry, err := oidc.Default()
+	if err != nil {
+		return dto.OAuthProfile{}, 500, err.Error()
+	}
+	issuer, err := registry.Provider(provider)
+	if err != nil {
+		return dto.OAuthProfile{}, 400, "Unsupported provider"
+	}
+	//Clients that only send one token send the ID token in accessToken
+	idToken, accessToken := body.IDToken, body.AccessToken
+	if idToken == "" {
+		idToken, accessToken = accessToken, ""
+	}
+	profile, err := issuer.Verify(c.Context(), idToken, accessToken)
+	if err != nil {
+		if errors.Is(err, oidc.ErrInvalidToken) {
+			return dto.OAuthProfile{}, 401, "Invalid provider token"
+		}
+		return dto.OAuthProfile{}, 502, "Could not reach the identity provider"
+	}
+	return profile, 0, ""
+}
+
+// handleOAuthLogin signs in with the token of provider.
+func handleOAuthLogin(c *fiber.Ctx, provider string) error {
+	profile, status, msg := verifyOAuthToken(c, provider)
+	if status != 0 {
+		return service.SendError(c, status, msg)
+	}
+	return utils.HandleOAuthLogin(c, profile)
+}
+
+// HandleLoginOIDC signs in with an ID token of any configured OpenID Connect provider, named by the provider query parameter.
+func HandleLoginOIDC(c *fiber.Ctx) error {
+	return handleOAuthLogin(c, c.Query("provider", ""))
+}
+
+// GetOIDCProviders lists the configured OpenID Connect providers and the client ID the web client must request tokens for.
+func GetOIDCProviders(c *fiber.Ctx) error {
+	registry, err := oidc.Default()
+	if err != nil {
+		return service.SendError(c, 500, err.Error())
+	}
+	providers := []dto.OIDCProviderInfo{}
+	for _, p := range registry.Providers() {
+		providers = append(providers, dto.OIDCProviderInfo{Name: p.Name(), Issuer: p.Issuer(), ClientID: p.ClientID()})
+	}
+	return c.JSON(fiber.Map{
+		"status": "Success",
+		"data":   providers,
+	})
+}
+
+// loginMethods lists the password and linked providers of the current account.
+func loginMethods(c *fiber.Ctx) (dto.LoginMethodsResponse, error) {
+	account := auth.GetAccount(c)
+	identities, err := auth.ListIdentities(c.Context(), boil.GetContextDB(), account.AccountID)
+	if err != nil {
+		return dto.LoginMethodsResponse{}, err
+	}
+	res := dto.LoginMethodsResponse{
+		HasPassword: auth.HasLocalPassword(account, identities),
+		Providers:   make([]dto.LinkedIdentity, 0, len(identities)),
+	}
+	for _, identity := range identities {
+		res.Providers = append(res.Providers, dto.LinkedIdentity{Provider: identity.Provider, LinkedAt: identity.CreatedAt.Time})
+	}
+	return res, nil
+}
+
+// GetLoginMethods returns whether the current account has a password and which providers are linked to it.
+func GetLoginMethods(c *fiber.Ctx) error {
+	if auth.GetAccount(c) == nil {
+		return service.SendError(c, 401, "Unauthenticated")
+	}
+	methods, err := loginMethods(c)
+	if err != nil {
+		return service.SendError(c, 500, err.Error())
+	}
+	return c.JSON(fiber.Map{
+		"status": "Success",
+		"data":   methods,
+	})
+}
+
+// LinkOAuthProvider links the provider account behind the body token to the current account.
+func LinkOAuthProvider(c *fiber.Ctx) error {
+	account := auth.GetAccount(c)
+	if account == nil {
+		return service.SendError(c, 401, "Unauthenticated")
+	}
+	provider := c.Query("provider", "")
+	profile, status, msg := verifyOAuthToken(c, provider)
+	if status != 0 {
+		return service.SendError(c, status, msg)
+	}
+	if err := auth.LinkIdentity(c.Context(), boil.GetContextDB(), account.AccountID, provider, profile.ProviderUserID); err != nil {
+		return utils.IdentityError(c, err)
+	}
+
+	entry := audit.FromRequest(c, audit.ActionIdentityLinked, audit.EntityAccount, strconv.Itoa(account.AccountID))
+	entry.Metadata = fiber.Map{"username": account.Username, "provider": provider}
+	audit.RecordBestEffort(c.Context(), entry)
+
+	methods, err := loginMethods(c)
+	if err != nil {
+		return service.SendError(c, 500, err.Error())
+	}
+	return c.JSON(fiber.Map{
+		"status":  "Success",
+		"data":    methods,
+		"message": "Provider has been linked",
+	})
+}
+
+// UnlinkOAuthProvider removes a linked provider, unless it is the last way to sign in to the account.
+func UnlinkOAuthProvider(c *fiber.Ctx) error {
+	account := auth.GetAccount(c)
+	if account == nil {
+		return service.SendError(c, 401, "Unauthenticated")
+	}
+	provider := c.Query("provider", "")
+	if provider == "" {
+		return service.SendError(c, 400, "Did not receive provider!")
+	}
+	if err := auth.UnlinkIdentity(c.Context(), account, provider); err != nil {
+		return utils.IdentityError(c, err)
+	}
+
+	entry := audit.FromRequest(c, audit.ActionIdentityUnlinked, audit.EntityAccount, strconv.Itoa(account.AccountID))
+	entry.Metadata = fiber.Map{"username": account.Username, "provider": provider}
+	audit.RecordBestEffort(c.Context(), entry)
+
+	methods, err := loginMethods(c)
+	if err != nil {
+		return service.SendError(c, 500, err.Error())
+	}
+	return c.JSON(fiber.Map{
+		"status":  "Success",
+		"data":    methods,
+		"message": "Provider has been unlinked",
+	})
+}
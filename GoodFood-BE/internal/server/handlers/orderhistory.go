@@ -1,10 +1,13 @@
 package handlers
 
 import (
+	"GoodFood-BE/internal/auth"
 	"GoodFood-BE/internal/dto"
+	"GoodFood-BE/internal/order"
 	"GoodFood-BE/internal/service"
 	"GoodFood-BE/internal/utils"
 	"GoodFood-BE/models"
+	"errors"
 	"fmt"
 	"time"
 
@@ -19,10 +22,11 @@ import (
 func GetOrderHistory(c *fiber.Ctx) error{
 	//Fetch query params
 	tab := c.Query("tab","Order Placed");
-	accountID := c.QueryInt("accountID",0);
-	if accountID == 0{
-		return service.SendError(c,400,"Did not receive accountID!")
+	account := auth.GetAccount(c)
+	if account == nil{
+		return service.SendError(c,401,"Unauthenticated")
 	}
+	accountID := account.AccountID
 	page := c.QueryInt("page",0);
 	if page == 0{
 		return service.SendError(c,400,"Did not receive pageNum");
@@ -93,27 +97,30 @@ func CancelOrder(c *fiber.Ctx) error{
 	if invoiceID == 0{
 		return service.SendError(c,400, "Did not receive invoiceID!");
 	}
+	account := auth.GetAccount(c)
+	if account == nil{
+		return service.SendError(c,401,"Unauthenticated")
+	}
 
 	var invoice dto.InvoiceList
 	if err := c.BodyParser(&invoice); err != nil{
 		return service.SendError(c,400,err.Error());
 	}
 
-	//Update
-	toUpdate, err := models.FindInvoice(c.Context(),boil.GetContextDB(),invoiceID)
-	if err != nil {
+	//Customers may only cancel their own orders, and only before the shop confirmed them
+	toUpdate, err := order.Transition(c.Context(),invoiceID,order.StatusCancelled,orderActor(c,order.RoleCustomer),invoice.CancelReason)
+	switch {
+	case errors.Is(err,order.ErrInvoiceNotFound):
+		return service.SendError(c,404,"Invoice not found!");
+	case errors.Is(err,order.ErrNotOwner):
+		return service.SendError(c,403,"Invoice belongs to another account!");
+	case errors.Is(err,order.ErrReasonRequired):
+		return service.SendError(c,400,"Please provide a cancel reason!");
+	case errors.Is(err,order.ErrInvalidTransition), errors.Is(err,order.ErrForbidden):
+		return service.SendError(c,409,"Only orders that have just been placed can be cancelled");
+	case err != nil:
 		return service.SendError(c,500,err.Error());
 	}
-	toUpdate.InvoiceStatusID = 6
-	toUpdate.CancelReason = null.StringFrom(invoice.CancelReason)
-	if _ ,err = toUpdate.Update(c.Context(),boil.GetContextDB(),boil.Infer()); err != nil{
-		return service.SendError(c,500,err.Error());
-	}
-
-	//Caches that need to be renewed
-	tab1 := fmt.Sprintf("orderhistory:accountID=%d:tab=%s",toUpdate.AccountID,utils.StatusOrderPlaced)
-	tab2 := fmt.Sprintf("orderhistory:accountID=%d:tab=%s",toUpdate.AccountID,utils.StatusCancelled)
-	utils.ClearCache(tab1,tab2); 
 
 	resp := fiber.Map{
 		"status": "Success",
@@ -124,6 +131,56 @@ func CancelOrder(c *fiber.Ctx) error{
 	return c.JSON(resp);
 }
 
+//GetOrderHistoryTimeline returns the status changes of an order of the caller.
+func GetOrderHistoryTimeline(c *fiber.Ctx) error{
+	invoiceID := c.QueryInt("invoiceID",0);
+	if invoiceID == 0{
+		return service.SendError(c,400,"Did not receive invoiceID!");
+	}
+	account := auth.GetAccount(c)
+	if account == nil{
+		return service.SendError(c,401,"Unauthenticated")
+	}
+	owned, err := ownsInvoice(c,invoiceID,account.AccountID)
+	if err != nil{
+		return service.SendError(c,500,err.Error())
+	}
+	if !owned{
+		return service.SendError(c,403,"Invoice belongs to another account!")
+	}
+
+	timeline, err := order.Timeline(c.Context(),invoiceID)
+	if err != nil{
+		return service.SendError(c,500,err.Error())
+	}
+	//Customers see that the shop changed their order, not which staff member did
+	for _, entry := range timeline{
+		entry.ActorID = null.Int{}
+		entry.ActorUsername = null.String{}
+	}
+
+	resp := fiber.Map{
+		"status": "Success",
+		"data": timeline,
+		"message": "Successfully fetched the order timeline",
+	}
+
+	return c.JSON(resp);
+}
+
+//orderActor returns who changes an order through the current request: the account resolved by the auth
+//middlewares, or the API key of an integration.
+func orderActor(c *fiber.Ctx, role order.Role) order.Actor{
+	actor := order.Actor{Role: role, IP: c.IP()}
+	if account := auth.GetAccount(c); account != nil{
+		actor.AccountID = null.IntFrom(account.AccountID)
+		actor.Username = null.StringFrom(account.Username)
+	} else if apiKey := auth.GetAPIKey(c); apiKey != nil{
+		actor.Username = null.StringFrom("api-key:" + apiKey.DisplayPrefix())
+	}
+	return actor
+}
+
 //GetOrderHistoryDetails returns the details of an invoice when clicked on.
 func GetOrderHistoryDetail(c *fiber.Ctx) error{
 	//Fetch query param
@@ -131,6 +188,19 @@ func GetOrderHistoryDetail(c *fiber.Ctx) error{
 	if invoiceID == 0{
 		return service.SendError(c,400,"Did not receive invoiceID!");
 	}
+	account := auth.GetAccount(c)
+	if account == nil{
+		return service.SendError(c,401,"Unauthenticated")
+	}
+
+	//Only the owner of the invoice may see its details
+	owned, err := ownsInvoice(c,invoiceID,account.AccountID)
+	if err != nil{
+		return service.SendError(c,500,err.Error());
+	}
+	if !owned{
+		return service.SendError(c,403,"Invoice belongs to another account!");
+	}
 
 	invoiceDetails, err := models.InvoiceDetails(
 		qm.Where("\"invoiceID\" = ?",invoiceID),
@@ -153,8 +223,8 @@ func GetOrderHistoryDetail(c *fiber.Ctx) error{
 			Image: detail.R.ProductIDProduct.CoverImage,
 			Product: *detail.R.ProductIDProduct,
 			Quantity: detail.Quantity,
-			TotalMoney: float64(detail.Price),
-			ShippingFee: float64(detail.R.InvoiceIDInvoice.ShippingFee),
+			TotalMoney: detail.Price,
+			ShippingFee: detail.R.InvoiceIDInvoice.ShippingFee,
 			ReviewCheck: reviewExists,
 		}
 	}
@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"GoodFood-BE/internal/auth"
+	"GoodFood-BE/internal/dto"
+	"GoodFood-BE/internal/jobs"
+	"GoodFood-BE/internal/service"
+	"GoodFood-BE/internal/utils"
+	"GoodFood-BE/models"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aarondl/null/v8"
+	"github.com/aarondl/sqlboiler/v4/boil"
+	"github.com/aarondl/sqlboiler/v4/queries/qm"
+	"github.com/gofiber/fiber/v2"
+	"github.com/hibiken/asynq"
+)
+
+// Limits for sending phone verification codes, counted per account.
+const (
+	phoneOTPWindow = 15 * time.Minute
+	phoneOTPLimit  = 3
+)
+
+var errPhoneOTPLimit = errors.New("Too many verification codes requested, please try again later")
+
+// resetIdentifier normalizes the email or phone number of a forgot password body and returns it as a rate limit key.
+func resetIdentifier(body *dto.ForgotPassStruct) (string, error) {
+	if body.Channel == dto.OTPChannelSMS {
+		body.PhoneNumber = strings.TrimSpace(body.PhoneNumber)
+		if body.PhoneNumber == "" {
+			return "", errors.New("Did not receive phone number!")
+		}
+		return "phone=" + body.PhoneNumber, nil
+	}
+	if body.Channel != dto.OTPChannelEmail {
+		return "", errors.New("Invalid channel!")
+	}
+	body.Email = strings.ToLower(strings.TrimSpace(body.Email))
+	if body.Email == "" {
+		return "", errors.New("Did not receive email!")
+	}
+	return "email=" + body.Email, nil
+}
+
+// findResetAccount looks up the account a forgot password body refers to, by phone number for SMS and by email otherwise.
+func findResetAccount(c *fiber.Ctx, body *dto.ForgotPassStruct) (*models.Account, error) {
+	if body.Channel == dto.OTPChannelSMS {
+		return models.Accounts(qm.Where("\"phoneNumber\" = ?", body.PhoneNumber)).One(c.Context(), boil.GetContextDB())
+	}
+	return models.Accounts(qm.Where("LOWER(email) = ?", body.Email)).One(c.Context(), boil.GetContextDB())
+}
+
+// enqueueOTP queues the delivery of a one-time code by email or SMS.
+func enqueueOTP(channel, to, code string) error {
+	var (
+		task *asynq.Task
+		err  error
+	)
+	if channel == dto.OTPChannelSMS {
+		message := fmt.Sprintf("Your GoodFood24h verification code is %s. It expires in %d minutes.", code, int(auth.OTPTTL.Minutes()))
+		task, err = jobs.NewSendSMSTask(to, message)
+	} else {
+		task, err = jobs.NewOTPEmailTask(to, code)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = asynqClient.Enqueue(task)
+	return err
+}
+
+// otpError writes the response for a failed auth.VerifyOTP call.
+func otpError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, auth.ErrInvalidOTP):
+		return service.SendError(c, 400, "Invalid or expired code")
+	case errors.Is(err, auth.ErrOTPTooManyAttempts):
+		return service.SendError(c, 429, "Too many attempts, please request a new code")
+	default:
+		return service.SendError(c, 500, err.Error())
+	}
+}
+
+// HandleVerifyResetOTP exchanges a valid password reset code for a reset token usable with HandleResetPassword.
+func HandleVerifyResetOTP(c *fiber.Ctx) error {
+	body := dto.ForgotPassStruct{}
+	if err := c.BodyParser(&body); err != nil {
+		return service.SendError(c, 400, "Invalid request body")
+	}
+	if body.Channel == "" {
+		body.Channel = dto.OTPChannelEmail
+	}
+	if _, err := resetIdentifier(&body); err != nil {
+		return service.SendError(c, 400, err.Error())
+	}
+	if body.CodeOTP == "" {
+		return service.SendError(c, 400, "Did not receive code!")
+	}
+	if ok, err := utils.AllowRequest("resetPass:ip="+c.IP(), resetSubmitLimitIP, resetWindow); err != nil {
+		return service.SendError(c, 500, err.Error())
+	} else if !ok {
+		return service.SendError(c, 429, "Too many requests, please try again later")
+	}
+
+	acc, err := findResetAccount(c, &body)
+	if err != nil {
+		return service.SendError(c, 400, "Invalid or expired code")
+	}
+	if _, err := auth.VerifyOTP(auth.OTPPurposePasswordReset, strconv.Itoa(acc.AccountID), body.CodeOTP); err != nil {
+		return otpError(c, err)
+	}
+
+	token, err := auth.CreatePasswordResetToken(acc.AccountID)
+	if err != nil {
+		return service.SendError(c, 500, err.Error())
+	}
+	return c.JSON(fiber.Map{
+		"status":  "Success",
+		"data":    fiber.Map{"token": token},
+		"message": "Code verified, you can now reset your password",
+	})
+}
+
+// startPhoneVerification sends a code to phone; the number is only saved once HandleVerifyPhone confirms it.
+func startPhoneVerification(account *models.Account, phone string) error {
+	ok, err := utils.AllowRequest("phoneOTP:account="+strconv.Itoa(account.AccountID), phoneOTPLimit, phoneOTPWindow)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errPhoneOTPLimit
+	}
+	code, err := auth.GenerateOTP(auth.OTPPurposePhone, strconv.Itoa(account.AccountID), phone)
+	if err != nil {
+		return err
+	}
+	return enqueueOTP(dto.OTPChannelSMS, phone, code)
+}
+
+// HandleVerifyPhone confirms the pending phone number of the current account with the code sent to it.
+func HandleVerifyPhone(c *fiber.Ctx) error {
+	account := auth.GetAccount(c)
+	if account == nil {
+		return service.SendError(c, 401, "Unauthenticated")
+	}
+	body := dto.PhoneVerifyRequest{}
+	if err := c.BodyParser(&body); err != nil {
+		return service.SendError(c, 400, "Invalid request body")
+	}
+	if body.CodeOTP == "" {
+		return service.SendError(c, 400, "Did not receive code!")
+	}
+
+	phone, err := auth.VerifyOTP(auth.OTPPurposePhone, strconv.Itoa(account.AccountID), body.CodeOTP)
+	if err != nil {
+		return otpError(c, err)
+	}
+
+	//The number may have been taken while the code was pending
+	taken, err := models.Accounts(qm.Where("\"phoneNumber\" = ? AND \"accountID\" <> ?", phone, account.AccountID)).Exists(c.Context(), boil.GetContextDB())
+	if err != nil {
+		return service.SendError(c, 500, err.Error())
+	}
+	if taken {
+		return service.SendErrorStruct(c, 400, dto.UserError{ErrPhone: "Phone number already exists!"})
+	}
+
+	account.PhoneNumber = null.StringFrom(phone)
+	if _, err := account.Update(c.Context(), boil.GetContextDB(), boil.Whitelist(models.AccountColumns.PhoneNumber)); err != nil {
+		return service.SendError(c, 500, err.Error())
+	}
+	return c.JSON(fiber.Map{
+		"status":  "Success",
+		"data":    account,
+		"message": "Phone number has been verified",
+	})
+}
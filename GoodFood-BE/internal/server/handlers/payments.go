@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"GoodFood-BE/internal/payment"
+	"GoodFood-BE/internal/service"
+	"GoodFood-BE/internal/utils"
+	"GoodFood-BE/models"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/aarondl/sqlboiler/v4/boil"
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetPaymentMethods lists the payment methods customers can choose at checkout.
+func GetPaymentMethods(c *fiber.Ctx) error {
+	methods, err := payment.EnabledMethods(c.Context())
+	if err != nil {
+		return service.SendError(c, 500, err.Error())
+	}
+	return c.JSON(fiber.Map{
+		"status":  "Success",
+		"data":    methods,
+		"message": "Successfully retrieved payment methods",
+	})
+}
+
+// callbackParams returns the parameters of a provider notification: the query string, and the form body of POST requests.
+func callbackParams(c *fiber.Ctx) map[string]string {
+	params := c.Queries()
+	c.Request().PostArgs().VisitAll(func(key, value []byte) {
+		params[string(key)] = string(value)
+	})
+	return params
+}
+
+// PaymentCallback receives the server to server payment notification of the provider of :method, such as the VNPay IPN.
+// It always answers 200 with the body the provider expects; providers retry until the notification is acknowledged.
+func PaymentCallback(c *fiber.Ctx) error {
+	method := c.Params("method")
+	_, _, err := handleCallback(c, method)
+	switch {
+	case err == nil, errors.Is(err, payment.ErrAlreadyConfirmed), errors.Is(err, payment.ErrInvalidSignature),
+		errors.Is(err, payment.ErrAttemptNotFound), errors.Is(err, payment.ErrInvalidAmount):
+	default:
+		log.Printf("%s callback: %v", method, err)
+	}
+	return c.JSON(payment.Acknowledge(method, err))
+}
+
+// PaymentReturn checks the parameters the provider of :method appended to the return URL, which the client forwards
+// as is, and reports the outcome of the payment. The payment is recorded here too in case the callback has not arrived yet.
+func PaymentReturn(c *fiber.Ctx) error {
+	result, invoice, err := handleCallback(c, c.Params("method"))
+	if result == nil && err != nil {
+		//The notification itself was rejected before looking up the payment
+		switch {
+		case errors.Is(err, payment.ErrUnknownMethod):
+			return service.SendError(c, 404, "Unknown payment method")
+		case errors.Is(err, payment.ErrInvalidSignature):
+			return service.SendError(c, 400, "Invalid payment signature")
+		default:
+			return service.SendError(c, 400, "Invalid payment details")
+		}
+	}
+	switch {
+	case err == nil, errors.Is(err, payment.ErrAlreadyConfirmed):
+	case errors.Is(err, payment.ErrAttemptNotFound):
+		return service.SendError(c, 404, "Payment not found")
+	case errors.Is(err, payment.ErrInvalidAmount):
+		return service.SendError(c, 400, "The paid amount does not match the invoice")
+	default:
+		return service.SendError(c, 500, err.Error())
+	}
+
+	return c.JSON(fiber.Map{
+		"status": "Success",
+		"data": fiber.Map{
+			"invoiceID":    invoice.InvoiceID,
+			"paid":         result.Success,
+			"responseCode": result.ResponseCode,
+			"amount":       result.Amount,
+		},
+		"message": result.Message,
+	})
+}
+
+// handleCallback records a payment notification and renews the order history of the account of the invoice.
+// The result and invoice are returned with ErrAlreadyConfirmed too.
+func handleCallback(c *fiber.Ctx, method string) (*payment.CallbackResult, *models.Invoice, error) {
+	result, invoice, err := payment.HandleCallback(c.Context(), method, callbackParams(c))
+	if err != nil {
+		return result, invoice, err
+	}
+	if status, err := models.FindInvoiceStatus(c.Context(), boil.GetContextDB(), invoice.InvoiceStatusID); err == nil {
+		utils.ClearCache(fmt.Sprintf("orderhistory:accountID=%d:tab:%s", invoice.AccountID, status.StatusName))
+	}
+	return result, invoice, nil
+}
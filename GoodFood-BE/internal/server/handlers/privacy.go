@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"GoodFood-BE/internal/audit"
+	"GoodFood-BE/internal/auth"
+	"GoodFood-BE/internal/dto"
+	"GoodFood-BE/internal/jobs"
+	"GoodFood-BE/internal/privacy"
+	redisdatabase "GoodFood-BE/internal/redis-database"
+	"GoodFood-BE/internal/service"
+	"GoodFood-BE/internal/utils"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/aarondl/sqlboiler/v4/boil"
+	"github.com/gofiber/fiber/v2"
+	"github.com/hibiken/asynq"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// dataExportCooldown is the minimum delay between two data export requests of one account.
+const dataExportCooldown = 24 * time.Hour
+
+// deleteAccountConfirmation must be typed by the user to delete their account.
+const deleteAccountConfirmation = "DELETE"
+
+// RequestDataExport queues the archive of every piece of personal data held about the current account.
+func RequestDataExport(c *fiber.Ctx) error {
+	account := auth.GetAccount(c)
+	if account == nil {
+		return service.SendError(c, 401, "Unauthenticated")
+	}
+
+	redisKey := fmt.Sprintf("dataExport:request=%d", account.AccountID)
+	ok, err := redisdatabase.Client.SetNX(redisdatabase.Ctx, redisKey, 1, dataExportCooldown).Result()
+	if err != nil {
+		return service.SendError(c, 500, err.Error())
+	}
+	if !ok {
+		return service.SendError(c, 429, "You can request one data export per day")
+	}
+
+	export, err := privacy.RequestExport(c.Context(), account.AccountID)
+	if err != nil {
+		_ = redisdatabase.Client.Del(redisdatabase.Ctx, redisKey).Err()
+		if errors.Is(err, privacy.ErrExportInProgress) {
+			return service.SendError(c, 409, "A data export is already being prepared")
+		}
+		return service.SendError(c, 500, err.Error())
+	}
+	if err := enqueueDataExport(export.ExportID, account.AccountID); err != nil {
+		_ = redisdatabase.Client.Del(redisdatabase.Ctx, redisKey).Err()
+		return service.SendError(c, 500, err.Error())
+	}
+	audit.RecordBestEffort(c.Context(), audit.FromRequest(c, audit.ActionDataExport, audit.EntityAccount, strconv.Itoa(account.AccountID)))
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"status":  "Success",
+		"data":    export,
+		"message": "Your data export is being prepared",
+	})
+}
+
+// enqueueDataExport queues the build of an export and the removal of its archive once it expires.
+func enqueueDataExport(exportID string, accountID int) error {
+	task, err := jobs.NewDataExportTask(exportID, accountID)
+	if err != nil {
+		return err
+	}
+	if _, err := asynqClient.Enqueue(task); err != nil {
+		return err
+	}
+	//The archive is ready a little after the request, give it the full TTL
+	expireTask, err := jobs.NewDataExportExpireTask(exportID)
+	if err != nil {
+		return err
+	}
+	_, err = asynqClient.Enqueue(expireTask, asynq.ProcessIn(privacy.ExportTTL+time.Hour))
+	return err
+}
+
+// GetDataExport returns the status of a data export of the current account.
+func GetDataExport(c *fiber.Ctx) error {
+	account := auth.GetAccount(c)
+	if account == nil {
+		return service.SendError(c, 401, "Unauthenticated")
+	}
+	exportID := c.Query("exportID", "")
+	if exportID == "" {
+		return service.SendError(c, 400, "Did not receive exportID")
+	}
+
+	export, err := privacy.FindExport(c.Context(), account.AccountID, exportID)
+	if err != nil {
+		if errors.Is(err, privacy.ErrExportNotFound) {
+			return service.SendError(c, 404, "Data export not found")
+		}
+		return service.SendError(c, 500, err.Error())
+	}
+	return c.JSON(fiber.Map{
+		"status": "Success",
+		"data":   export,
+	})
+}
+
+// DownloadDataExport sends the archive of a ready data export of the current account.
+func DownloadDataExport(c *fiber.Ctx) error {
+	account := auth.GetAccount(c)
+	if account == nil {
+		return service.SendError(c, 401, "Unauthenticated")
+	}
+	exportID := c.Query("exportID", "")
+	if exportID == "" {
+		return service.SendError(c, 400, "Did not receive exportID")
+	}
+
+	export, err := privacy.FindExport(c.Context(), account.AccountID, exportID)
+	if err != nil {
+		if errors.Is(err, privacy.ErrExportNotFound) {
+			return service.SendError(c, 404, "Data export not found")
+		}
+		return service.SendError(c, 500, err.Error())
+	}
+	switch export.Status {
+	case privacy.ExportReady:
+	case privacy.ExportExpired:
+		return service.SendError(c, 410, "This data export has expired, please request a new one")
+	case privacy.ExportFailed:
+		return service.SendError(c, 409, "This data export failed, please request a new one")
+	default:
+		return service.SendError(c, 409, "This data export is not ready yet")
+	}
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.Download(privacy.ArchivePath(export.ExportID), fmt.Sprintf("goodfood-data-%s.zip", export.CreatedAt.Format("2006-01-02")))
+}
+
+// DeleteAccount anonymizes the current account after checking the password and, if enabled, a two-factor code.
+// Orders are kept for bookkeeping without the personal data, every session is revoked and uploaded images are deleted.
+func DeleteAccount(c *fiber.Ctx) error {
+	account := auth.GetAccount(c)
+	if account == nil {
+		return service.SendError(c, 401, "Unauthenticated")
+	}
+	body := dto.DeleteAccountRequest{}
+	if err := c.BodyParser(&body); err != nil {
+		return service.SendError(c, 400, "Invalid request body")
+	}
+	if body.Confirm != deleteAccountConfirmation {
+		return service.SendError(c, 400, fmt.Sprintf("Type %s to confirm the deletion of your account", deleteAccountConfirmation))
+	}
+
+	identities, err := auth.ListIdentities(c.Context(), boil.GetContextDB(), account.AccountID)
+	if err != nil {
+		return service.SendError(c, 500, err.Error())
+	}
+	if auth.HasLocalPassword(account, identities) {
+		if err := bcrypt.CompareHashAndPassword([]byte(account.Password), []byte(body.Password)); err != nil {
+			return service.SendError(c, 400, "Wrong password!")
+		}
+	}
+	mfaEnabled, err := auth.MFAEnabled(c.Context(), account.AccountID)
+	if err != nil {
+		return service.SendError(c, 500, err.Error())
+	}
+	if mfaEnabled {
+		if err := auth.VerifyMFACode(c.Context(), account.AccountID, body.MFACode); err != nil {
+			return mfaError(c, err)
+		}
+	}
+
+	username := account.Username
+	images, err := privacy.DeleteAccount(c.Context(), account)
+	if err != nil {
+		if errors.Is(err, privacy.ErrOrdersInProgress) {
+			return service.SendError(c, 409, "You have orders in progress. Wait for them to be delivered or cancel them first")
+		}
+		return service.SendError(c, 500, err.Error())
+	}
+
+	//The account is already anonymized, failures below must not report the deletion as failed
+	if err := auth.RevokeUserTokens(username); err != nil {
+		log.Printf("delete account %d: revoke tokens: %v", account.AccountID, err)
+	}
+	_ = auth.RevokeAccessToken(auth.GetTokenClaims(c))
+	if len(images) > 0 {
+		if task, err := jobs.NewDeleteImagesTask(images); err != nil {
+			log.Printf("delete account %d: %v", account.AccountID, err)
+		} else if _, err := asynqClient.Enqueue(task); err != nil {
+			log.Printf("delete account %d: enqueue image deletion: %v", account.AccountID, err)
+		}
+	}
+	audit.RecordBestEffort(c.Context(), audit.FromRequest(c, audit.ActionAccountDeleted, audit.EntityAccount, strconv.Itoa(account.AccountID)))
+	utils.SaveCookie("", c)
+
+	return c.JSON(fiber.Map{
+		"status":  "Success",
+		"message": "Your account has been deleted",
+	})
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"GoodFood-BE/internal/auth"
 	"GoodFood-BE/internal/dto"
 	"GoodFood-BE/internal/service"
 	"GoodFood-BE/internal/utils"
@@ -24,6 +25,15 @@ func GetReviewData(c *fiber.Ctx) error{
 	if invoiceID == 0 || productID == 0{
 		return service.SendError(c,400,"Did not receive invoiceID or productID");
 	}
+	account := auth.GetAccount(c)
+	if account == nil{
+		return service.SendError(c,401,"Unauthenticated");
+	}
+	if ok, err := ownsInvoice(c,invoiceID,account.AccountID); err != nil{
+		return service.SendError(c,500,err.Error());
+	}else if !ok{
+		return service.SendError(c,403,"Invoice belongs to another account!");
+	}
 
 	//Build response
 	invoiceDetails, err := models.InvoiceDetails(
@@ -46,8 +56,8 @@ func GetReviewData(c *fiber.Ctx) error{
 			Image: detail.R.ProductIDProduct.CoverImage,
 			Product: *detail.R.ProductIDProduct,
 			Quantity: detail.Quantity,
-			TotalMoney: float64(detail.Price),
-			ShippingFee: float64(detail.R.InvoiceIDInvoice.ShippingFee),
+			TotalMoney: detail.Price,
+			ShippingFee: detail.R.InvoiceIDInvoice.ShippingFee,
 			ReviewCheck: reviewExist,
 		}
 		
@@ -76,6 +86,18 @@ func HandleSubmitReview(c *fiber.Ctx) error{
 		return service.SendError(c, 400, "Invalid review JSON: "+err.Error())
 	}
 
+	//Reviews are always written by the caller, on one of their own invoices
+	account := auth.GetAccount(c)
+	if account == nil {
+		return service.SendError(c, 401, "Unauthenticated")
+	}
+	body.AccountID = account.AccountID
+	if ok, err := ownsInvoice(c, body.InvoiceID, account.AccountID); err != nil {
+		return service.SendError(c, 500, err.Error())
+	} else if !ok {
+		return service.SendError(c, 403, "Invoice belongs to another account!")
+	}
+
 	// 2. Fetch all files from "images"
 	form, err := c.MultipartForm()
 	if err != nil {
@@ -192,6 +214,9 @@ func GetReviewDetail(c *fiber.Ctx) error{
 	if err != nil{
 		return service.SendError(c,500,err.Error());
 	}
+	if account := auth.GetAccount(c); account == nil || review.AccountID != account.AccountID{
+		return service.SendError(c,403,"Review belongs to another account!");
+	}
 	
 	invoiceDetails, err := models.InvoiceDetails(qm.Where("\"invoiceID\" = ? AND \"productID\" = ?",review.InvoiceID,review.ProductID)).One(c.Context(),boil.GetContextDB());
 	if err != nil{
@@ -229,6 +254,15 @@ func HandleUpdateReview(c *fiber.Ctx) error{
 		return service.SendError(c,400,"Did not receive reviewID");
 	}
 
+	//fetching the referred review, only its author may edit it
+	review, err := models.Reviews(qm.Where("\"reviewID\" = ?",reviewID)).One(c.Context(),boil.GetContextDB());
+	if err != nil{
+		return service.SendError(c,500,err.Error());
+	}
+	if account := auth.GetAccount(c); account == nil || review.AccountID != account.AccountID{
+		return service.SendError(c,403,"Review belongs to another account!");
+	}
+
 	// 1. Parse "review" from multipart
 	reviewJson := c.FormValue("review")
 	if reviewJson == "" {
@@ -238,6 +272,7 @@ func HandleUpdateReview(c *fiber.Ctx) error{
 	if err := json.Unmarshal([]byte(reviewJson), &body); err != nil {
 		return service.SendError(c, 400, "Invalid review JSON: "+err.Error())
 	}
+	body.ReviewID = review.ReviewID
 
 	// 2. Fetch all files from "images"
 	form, err := c.MultipartForm()
@@ -305,11 +340,6 @@ func HandleUpdateReview(c *fiber.Ctx) error{
 		}
 	}
 
-	//fetching the referred review
-	review, err := models.Reviews(qm.Where("\"reviewID\" = ?",reviewID)).One(c.Context(),boil.GetContextDB());
-	if err != nil{
-		return service.SendError(c,500,err.Error());
-	}
 	//update the review
 	review.Comment = body.Comment
 	review.Stars = body.Stars
@@ -369,4 +399,9 @@ func HandleUpdateReview(c *fiber.Ctx) error{
 		"message": "Successfully updated product review!",
 	}
 	return c.JSON(resp);
+}
+
+//ownsInvoice reports whether the invoice belongs to the given account.
+func ownsInvoice(c *fiber.Ctx, invoiceID int, accountID int) (bool, error){
+	return models.Invoices(qm.Where("\"invoiceID\" = ? AND \"accountID\" = ?",invoiceID,accountID)).Exists(c.Context(),boil.GetContextDB())
 }
\ No newline at end of file
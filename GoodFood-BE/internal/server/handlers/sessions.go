@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"GoodFood-BE/internal/auth"
+	"GoodFood-BE/internal/service"
+	"GoodFood-BE/models"
+	"database/sql"
+
+	"github.com/aarondl/sqlboiler/v4/boil"
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetSessions lists the active sessions of the current account. The session of this request is flagged as current.
+func GetSessions(c *fiber.Ctx) error {
+	account := auth.GetAccount(c)
+	if account == nil {
+		return service.SendError(c, 401, "Unauthenticated")
+	}
+
+	currentSessionID := auth.SessionIDFromRefreshToken(c.Cookies("refreshToken"))
+	sessions, err := auth.ListUserSessions(account.Username, currentSessionID)
+	if err != nil {
+		return service.SendError(c, 500, err.Error())
+	}
+	return c.JSON(fiber.Map{
+		"status": "Success",
+		"data":   sessions,
+	})
+}
+
+// RevokeSession signs the current account out of one session.
+func RevokeSession(c *fiber.Ctx) error {
+	account := auth.GetAccount(c)
+	if account == nil {
+		return service.SendError(c, 401, "Unauthenticated")
+	}
+	sessionID := c.Query("sessionID", "")
+	if sessionID == "" {
+		return service.SendError(c, 400, "Did not receive sessionID")
+	}
+
+	found, err := auth.RevokeUserSession(account.Username, sessionID)
+	if err != nil {
+		return service.SendError(c, 500, err.Error())
+	}
+	if !found {
+		return service.SendError(c, 404, "Session not found")
+	}
+	return c.JSON(fiber.Map{
+		"status":  "Success",
+		"message": "Session revoked",
+	})
+}
+
+// RevokeOtherSessions signs the current account out everywhere except the session of this request.
+func RevokeOtherSessions(c *fiber.Ctx) error {
+	account := auth.GetAccount(c)
+	if account == nil {
+		return service.SendError(c, 401, "Unauthenticated")
+	}
+	currentSessionID := auth.SessionIDFromRefreshToken(c.Cookies("refreshToken"))
+	if currentSessionID == "" {
+		return service.SendError(c, 401, "No refresh token")
+	}
+
+	if err := auth.RevokeOtherUserSessions(account.Username, currentSessionID); err != nil {
+		return service.SendError(c, 500, err.Error())
+	}
+	return c.JSON(fiber.Map{
+		"status":  "Success",
+		"message": "Logged out from other sessions",
+	})
+}
+
+// GetAdminUserSessions lists the active sessions of any account.
+func GetAdminUserSessions(c *fiber.Ctx) error {
+	account, status, msg := findSessionAccount(c)
+	if account == nil {
+		return service.SendError(c, status, msg)
+	}
+
+	sessions, err := auth.ListUserSessions(account.Username, "")
+	if err != nil {
+		return service.SendError(c, 500, err.Error())
+	}
+	return c.JSON(fiber.Map{
+		"status": "Success",
+		"data":   sessions,
+	})
+}
+
+// AdminRevokeUserSession revokes one session of any account, or all of them when sessionID is omitted.
+func AdminRevokeUserSession(c *fiber.Ctx) error {
+	account, status, msg := findSessionAccount(c)
+	if account == nil {
+		return service.SendError(c, status, msg)
+	}
+
+	sessionID := c.Query("sessionID", "")
+	if sessionID == "" {
+		if err := auth.RevokeAllUserRefreshTokens(account.Username); err != nil {
+			return service.SendError(c, 500, err.Error())
+		}
+		return c.JSON(fiber.Map{
+			"status":  "Success",
+			"message": "All sessions revoked",
+		})
+	}
+
+	found, err := auth.RevokeUserSession(account.Username, sessionID)
+	if err != nil {
+		return service.SendError(c, 500, err.Error())
+	}
+	if !found {
+		return service.SendError(c, 404, "Session not found")
+	}
+	return c.JSON(fiber.Map{
+		"status":  "Success",
+		"message": "Session revoked",
+	})
+}
+
+// findSessionAccount loads the account from the accountID query param.
+// On failure it returns a nil account with the status code and message to respond with.
+func findSessionAccount(c *fiber.Ctx) (*models.Account, int, string) {
+	accountID := c.QueryInt("accountID", 0)
+	if accountID == 0 {
+		return nil, 400, "Did not receive accountID"
+	}
+	account, err := models.FindAccount(c.Context(), boil.GetContextDB(), accountID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, 404, "Account not found"
+		}
+		return nil, 500, err.Error()
+	}
+	return account, 0, ""
+}
@@ -0,0 +1,318 @@
+package handlers
+
+import (
+	"GoodFood-BE/internal/audit"
+	"GoodFood-BE/internal/auth"
+	"GoodFood-BE/internal/checkout"
+	"GoodFood-BE/internal/dto"
+	"GoodFood-BE/internal/order"
+	"GoodFood-BE/internal/service"
+	"GoodFood-BE/internal/shipping"
+	"errors"
+	"log"
+
+	"github.com/aarondl/sqlboiler/v4/boil"
+	"github.com/gofiber/fiber/v2"
+)
+
+// ShippingQuote returns the shipping fee of products delivered to an address of the caller, or to an area,
+// with how it was computed.
+func ShippingQuote(c *fiber.Ctx) error {
+	account := auth.GetAccount(c)
+	if account == nil {
+		return service.SendError(c, 401, "Unauthenticated")
+	}
+	var body dto.ShippingQuoteRequest
+	if err := c.BodyParser(&body); err != nil {
+		return service.SendError(c, 400, "Invalid body details: "+err.Error())
+	}
+	quote, err := checkout.ShippingQuote(c.Context(), account.AccountID, body)
+	if err != nil {
+		return checkoutError(c, err)
+	}
+	return c.JSON(fiber.Map{
+		"status":  "Success",
+		"data":    quote,
+		"message": "Successfully priced the shipping",
+	})
+}
+
+// shippingError writes the response for errors returned by the shipping rate service.
+func shippingError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, shipping.ErrZoneNotFound), errors.Is(err, shipping.ErrAreaNotFound):
+		return service.SendError(c, 404, err.Error())
+	case errors.Is(err, shipping.ErrZoneExists), errors.Is(err, shipping.ErrAreaTaken), errors.Is(err, shipping.ErrDefaultZone):
+		return service.SendError(c, 409, err.Error())
+	case errors.Is(err, shipping.ErrInvalidRates), errors.Is(err, shipping.ErrInvalidZone), errors.Is(err, shipping.ErrInvalidArea):
+		return service.SendError(c, 400, err.Error())
+	default:
+		return service.SendError(c, 500, err.Error())
+	}
+}
+
+// recordZoneChange audits a change of a shipping zone, identified by its code.
+func recordZoneChange(c *fiber.Ctx, action, code string, before, after interface{}) {
+	entry := audit.FromRequest(c, action, audit.EntityShippingZone, code)
+	entry.Before = before
+	entry.After = after
+	audit.RecordBestEffort(c.Context(), entry)
+}
+
+// GetAdminShipping returns the shared shipping rates and every zone with its areas.
+func GetAdminShipping(c *fiber.Ctx) error {
+	rates, err := shipping.LoadRates(c.Context(), boil.GetContextDB())
+	if err != nil {
+		return service.SendError(c, 500, err.Error())
+	}
+	zones, err := shipping.ListZones(c.Context())
+	if err != nil {
+		return service.SendError(c, 500, err.Error())
+	}
+	return c.JSON(fiber.Map{
+		"status":  "Success",
+		"data":    fiber.Map{"rates": rates, "zones": zones},
+		"message": "Successfully fetched shipping rates",
+	})
+}
+
+// AdminShippingRatesUpdate replaces the base fee, the fee per kilogram, the included weight and the global
+// free shipping threshold.
+func AdminShippingRatesUpdate(c *fiber.Ctx) error {
+	var body shipping.Rates
+	if err := c.BodyParser(&body); err != nil {
+		return service.SendError(c, 400, "Invalid body details: "+err.Error())
+	}
+	before, err := shipping.LoadRates(c.Context(), boil.GetContextDB())
+	if err != nil {
+		return service.SendError(c, 500, err.Error())
+	}
+	rates, err := shipping.UpdateRates(c.Context(), body)
+	if err != nil {
+		return shippingError(c, err)
+	}
+	recordAdminChange(c, audit.ActionShippingRateUpdate, audit.EntityShippingRate, 1, before, rates)
+
+	return c.JSON(fiber.Map{
+		"status":  "Success",
+		"data":    rates,
+		"message": "Successfully updated shipping rates!",
+	})
+}
+
+// AdminShippingZoneCreate adds a shipping zone. Areas are added to it with AdminShippingAreaCreate.
+func AdminShippingZoneCreate(c *fiber.Ctx) error {
+	var body shipping.Zone
+	if err := c.BodyParser(&body); err != nil {
+		return service.SendError(c, 400, "Invalid body details: "+err.Error())
+	}
+	zone, err := shipping.CreateZone(c.Context(), body)
+	if err != nil {
+		return shippingError(c, err)
+	}
+	recordZoneChange(c, audit.ActionShippingZoneCreate, zone.Code, nil, zone)
+
+	return c.JSON(fiber.Map{
+		"status":  "Success",
+		"data":    zone,
+		"message": "Successfully created shipping zone!",
+	})
+}
+
+// AdminShippingZoneUpdate changes the name, multiplier and free shipping threshold of the zone named by code.
+func AdminShippingZoneUpdate(c *fiber.Ctx) error {
+	var body shipping.Zone
+	if err := c.BodyParser(&body); err != nil {
+		return service.SendError(c, 400, "Invalid body details: "+err.Error())
+	}
+	before, err := shipping.GetZone(c.Context(), body.Code)
+	if err != nil {
+		return shippingError(c, err)
+	}
+	zone, err := shipping.UpdateZone(c.Context(), body)
+	if err != nil {
+		return shippingError(c, err)
+	}
+	recordZoneChange(c, audit.ActionShippingZoneUpdate, zone.Code, before, zone)
+
+	return c.JSON(fiber.Map{
+		"status":  "Success",
+		"data":    zone,
+		"message": "Successfully updated shipping zone!",
+	})
+}
+
+// AdminShippingZoneDelete removes the zone of the code query param. Its areas fall back to the default zone.
+func AdminShippingZoneDelete(c *fiber.Ctx) error {
+	code := c.Query("code")
+	if code == "" {
+		return service.SendError(c, 400, "Did not receive code")
+	}
+	before, err := shipping.GetZone(c.Context(), code)
+	if err != nil {
+		return shippingError(c, err)
+	}
+	if err := shipping.DeleteZone(c.Context(), code); err != nil {
+		return shippingError(c, err)
+	}
+	recordZoneChange(c, audit.ActionShippingZoneDelete, before.Code, before, nil)
+
+	return c.JSON(fiber.Map{
+		"status":  "Success",
+		"message": "Successfully deleted shipping zone!",
+	})
+}
+
+// AdminShippingAreaCreate puts a province, district or ward in a zone.
+func AdminShippingAreaCreate(c *fiber.Ctx) error {
+	var body shipping.Area
+	if err := c.BodyParser(&body); err != nil {
+		return service.SendError(c, 400, "Invalid body details: "+err.Error())
+	}
+	before, err := shipping.GetZone(c.Context(), body.ZoneCode)
+	if err != nil {
+		return shippingError(c, err)
+	}
+	area, err := shipping.AddArea(c.Context(), body)
+	if err != nil {
+		return shippingError(c, err)
+	}
+	if after, err := shipping.GetZone(c.Context(), area.ZoneCode); err == nil {
+		recordZoneChange(c, audit.ActionShippingZoneUpdate, area.ZoneCode, before, after)
+	}
+
+	return c.JSON(fiber.Map{
+		"status":  "Success",
+		"data":    area,
+		"message": "Successfully added the area to the shipping zone!",
+	})
+}
+
+// AdminShippingAreaDelete takes the area of the areaID query param out of its zone.
+func AdminShippingAreaDelete(c *fiber.Ctx) error {
+	areaID := c.QueryInt("areaID", 0)
+	if areaID == 0 {
+		return service.SendError(c, 400, "Did not receive areaID")
+	}
+	area, err := shipping.RemoveArea(c.Context(), areaID)
+	if err != nil {
+		return shippingError(c, err)
+	}
+	entry := audit.FromRequest(c, audit.ActionShippingZoneUpdate, audit.EntityShippingZone, area.ZoneCode)
+	entry.Metadata = fiber.Map{"removedArea": area}
+	audit.RecordBestEffort(c.Context(), entry)
+
+	return c.JSON(fiber.Map{
+		"status":  "Success",
+		"data":    area,
+		"message": "Successfully removed the area from the shipping zone!",
+	})
+}
+
+// ShippingWebhook reads a status notification of the carrier :carrier and moves the order of the parcel along.
+func ShippingWebhook(c *fiber.Ctx) error {
+	carrier, err := shipping.GetCarrier(c.Params("carrier"))
+	if err != nil {
+		return service.SendError(c, 404, "Unknown shipping carrier")
+	}
+	update, err := carrier.ParseWebhook(c.Queries(), c.Body())
+	if err != nil {
+		return service.SendError(c, 401, "Invalid shipping notification")
+	}
+	invoice, err := order.ApplyTrackingUpdate(c.Context(), carrier.Code(), update)
+	if errors.Is(err, order.ErrShipmentNotFound) {
+		return service.SendError(c, 404, "Shipment not found")
+	}
+	if err != nil {
+		log.Printf("%s webhook %s: %v", carrier.Code(), update.TrackingCode, err)
+		return service.SendError(c, 500, "Could not apply the shipping notification")
+	}
+	orderStatus, err := order.StatusOf(c.Context(), boil.GetContextDB(), invoice.InvoiceStatusID)
+	if err != nil {
+		log.Printf("%s webhook %s: %v", carrier.Code(), update.TrackingCode, err)
+		return service.SendError(c, 500, "Could not apply the shipping notification")
+	}
+	return c.JSON(fiber.Map{
+		"status": "Success",
+		"data": fiber.Map{
+			"invoiceID":      invoice.InvoiceID,
+			"orderStatus":    orderStatus,
+			"shipmentStatus": update.Status,
+		},
+		"message": "Successfully applied the shipping notification",
+	})
+}
+
+// shipmentError writes the response for errors returned when shipping an order with a carrier.
+func shipmentError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, order.ErrInvoiceNotFound), errors.Is(err, order.ErrShipmentNotFound):
+		return service.SendError(c, 404, err.Error())
+	case errors.Is(err, order.ErrNoCarrier), errors.Is(err, order.ErrNotReadyToShip), errors.Is(err, order.ErrNoDestination),
+		errors.Is(err, order.ErrShipmentExists):
+		return service.SendError(c, 409, err.Error())
+	case errors.Is(err, shipping.ErrCarrierRejected):
+		return service.SendError(c, 502, err.Error())
+	default:
+		return service.SendError(c, 500, err.Error())
+	}
+}
+
+// GetAdminInvoiceShipment returns the shipment of an order with where the carrier says the parcel is.
+func GetAdminInvoiceShipment(c *fiber.Ctx) error {
+	invoiceID := c.QueryInt("invoiceID", 0)
+	if invoiceID == 0 {
+		return service.SendError(c, 400, "Did not receive invoiceID")
+	}
+	shipment, err := order.GetShipment(c.Context(), invoiceID)
+	if err != nil {
+		return shipmentError(c, err)
+	}
+	return c.JSON(fiber.Map{
+		"status":  "Success",
+		"data":    shipment,
+		"message": "Successfully fetched the shipment",
+	})
+}
+
+// AdminInvoiceShipmentCreate hands an order being processed over to the carrier. Orders are shipped when they
+// enter processing; this is for the ones whose shipment failed or was cancelled.
+func AdminInvoiceShipmentCreate(c *fiber.Ctx) error {
+	invoiceID := c.QueryInt("invoiceID", 0)
+	if invoiceID == 0 {
+		return service.SendError(c, 400, "Did not receive invoiceID")
+	}
+	shipment, err := order.CreateShipment(c.Context(), invoiceID)
+	if err != nil {
+		return shipmentError(c, err)
+	}
+	recordAdminChange(c, audit.ActionInvoiceShip, audit.EntityInvoice, invoiceID, nil, shipment)
+
+	return c.JSON(fiber.Map{
+		"status":  "Success",
+		"data":    shipment,
+		"message": "Successfully handed the order over to the carrier!",
+	})
+}
+
+// AdminInvoiceShipmentCancel calls off the shipment of an order that was not picked up yet.
+func AdminInvoiceShipmentCancel(c *fiber.Ctx) error {
+	invoiceID := c.QueryInt("invoiceID", 0)
+	if invoiceID == 0 {
+		return service.SendError(c, 400, "Did not receive invoiceID")
+	}
+	before, err := order.GetShipment(c.Context(), invoiceID)
+	if err != nil {
+		return shipmentError(c, err)
+	}
+	before.Tracking = nil
+	if err := order.CancelShipment(c.Context(), invoiceID); err != nil {
+		return shipmentError(c, err)
+	}
+	recordAdminChange(c, audit.ActionInvoiceUnship, audit.EntityInvoice, invoiceID, before, nil)
+
+	return c.JSON(fiber.Map{
+		"status":  "Success",
+		"message": "Successfully cancelled the shipment!",
+	})
+}
@@ -1,24 +1,29 @@
 package handlers
 
 import (
+	"GoodFood-BE/internal/audit"
 	"GoodFood-BE/internal/auth"
 	"GoodFood-BE/internal/dto"
 	"GoodFood-BE/internal/jobs"
+	"GoodFood-BE/internal/oidc"
 	"GoodFood-BE/internal/service"
 	"GoodFood-BE/internal/utils"
 	"GoodFood-BE/models"
+	"errors"
 	"fmt"
+	"math"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/aarondl/null/v8"
 	"github.com/aarondl/sqlboiler/v4/boil"
 	"github.com/aarondl/sqlboiler/v4/queries/qm"
 	"github.com/gofiber/fiber/v2"
-	"github.com/gofrs/uuid"
 	"github.com/hibiken/asynq"
 	"golang.org/x/crypto/bcrypt"
-	"google.golang.org/api/idtoken"
 )
 
 // HandleRegister handles user registration
@@ -39,6 +44,8 @@ func HandleRegister(c *fiber.Ctx) error {
 		return service.SendError(c, 500, err.Error())
 	}
 	user.Password = string(hashedPass)
+	//Self-registered accounts are always customers, whatever the body says
+	user.Role = auth.RoleCustomer
 
 	//Save user
 	err = user.Insert(c.Context(), boil.GetContextDB(), boil.Infer())
@@ -46,6 +53,9 @@ func HandleRegister(c *fiber.Ctx) error {
 		return service.SendError(c, 500, err.Error())
 	}
 
+	//Send the verification link, the user can ask for a new one if this fails
+	_ = enqueueVerificationEmail(&user)
+
 	response := fiber.Map{
 		"status":  "Success",
 		"data":    user,
@@ -64,76 +74,87 @@ func HandleLogin(c *fiber.Ctx) error {
 		return service.SendError(c, 400, "Invalid request body")
 	}
 
-	//comparing login details with users db
-	user, err := models.Accounts(qm.Where("username = ?", body.Username)).One(c.Context(), boil.GetContextDB())
-	if err != nil {
-		return service.SendError(c, 500, "User not found!")
+	//Reject throttled or locked attempts before touching the password
+	ip := c.IP()
+	if err := auth.CheckLoginAllowed(body.Username, ip); err != nil {
+		return loginBlocked(c, err)
 	}
 
-	//comparing 2 hashes
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(body.Password)); err != nil {
-		return service.SendError(c, 401, "Username or password does not match!")
+	//comparing login details with users db. Unknown usernames still pay for a bcrypt comparison
+	//and get the same answer, so responses don't reveal which accounts exist
+	user, err := models.Accounts(qm.Where("username = ?", body.Username)).One(c.Context(), boil.GetContextDB())
+	hash := dummyPasswordHash
+	if err == nil {
+		hash = []byte(user.Password)
+	}
+	valid := bcrypt.CompareHashAndPassword(hash, []byte(body.Password)) == nil && err == nil
+	//Accounts created by a social login used to get their provider user ID as username and password
+	if valid && body.Password == user.Username {
+		cleared, err := auth.ClearLegacyOAuthPassword(c.Context(), boil.GetContextDB(), user)
+		if err != nil {
+			return service.SendError(c, 500, err.Error())
+		}
+		valid = !cleared
+	}
+	if !valid {
+		locked, lockErr := auth.RegisterLoginFailure(body.Username, ip)
+		if lockErr != nil {
+			return service.SendError(c, 500, lockErr.Error())
+		}
+		if locked {
+			//Usernames without an account are locked out too, their entry has no entity
+			entityID := ""
+			if err == nil {
+				entityID = strconv.Itoa(user.AccountID)
+			}
+			entry := audit.FromRequest(c, audit.ActionAccountLockout, audit.EntityAccount, entityID)
+			entry.Metadata = fiber.Map{"username": body.Username, "reason": "too many failed logins", "duration": auth.LoginLockoutDuration.String()}
+			audit.RecordBestEffort(c.Context(), entry)
+		}
+		return service.SendError(c, 401, invalidCredentialsMessage)
 	}
 
+	_ = auth.ResetLoginFailures(body.Username)
 	return utils.LoginWithAccount(c, user)
 }
 
-// HandleLoginGoogle handles login with Google OAuth
-func HandleLoginGoogle(c *fiber.Ctx) error {
-	body := dto.OAuthLoginStruct{}
-	if err := c.BodyParser(&body); err != nil {
-		return service.SendError(c, 400, "Invalid request body")
-	}
+const invalidCredentialsMessage = "Invalid username or password"
 
-	//Validate token
-	audience := os.Getenv("GOOGLE_AUDIENCE")
-	payload, err := idtoken.Validate(c.Context(), body.AccessToken, audience)
-	if err != nil {
+// dummyPasswordHash is compared against when the username does not exist, to keep timing uniform.
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("goodfood-dummy-password"), bcrypt.DefaultCost)
+
+// loginBlocked writes the response for an attempt refused by auth.CheckLoginAllowed.
+func loginBlocked(c *fiber.Ctx, err error) error {
+	var blocked *auth.LoginBlockedError
+	if !errors.As(err, &blocked) {
 		return service.SendError(c, 500, err.Error())
 	}
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(blocked.RetryAfter.Seconds()))))
+	return service.SendError(c, 429, "Too many login attempts, please try again later")
+}
 
-	//Parse payload
-	sub := fmt.Sprintf("%v", payload.Claims["sub"]) //unique google id
-	name := fmt.Sprintf("%v", payload.Claims["name"])
-	email := fmt.Sprintf("%v", payload.Claims["email"])
-	picture := fmt.Sprintf("%v", payload.Claims["picture"])
-
-	return utils.HandleOAuthLogin(c, "google", sub, email, name, picture)
+// HandleLoginGoogle handles login with Google OAuth
+func HandleLoginGoogle(c *fiber.Ctx) error {
+	return handleOAuthLogin(c, oidc.GoogleProvider)
 }
 
 // HandleLoginFacebook handles login with Facebook OAuth
 func HandleLoginFacebook(c *fiber.Ctx) error {
-	body := dto.OAuthLoginStruct{}
-	if err := c.BodyParser(&body); err != nil {
-		return service.SendError(c, 400, "Invalid request body")
-	}
-
-	fbUser, err := utils.GetFacebookUserInfo(body.AccessToken)
-	if err != nil {
-		return service.SendError(c, 500, err.Error())
-	}
-
-	return utils.HandleOAuthLogin(c, "facebook", fbUser.ID, fbUser.Email, fbUser.Name, fbUser.Picture.Data.URL)
+	return handleOAuthLogin(c, providerFacebook)
 }
 
 // HandleUpdateAccount allows user to update their profile (fullname, avatar, phone, gender).
 func HandleUpdateAccount(c *fiber.Ctx) error {
-	//Fetch query param
-	accountID := c.QueryInt("accountID", 0)
-	if accountID == 0 {
-		return service.SendError(c, 400, "Did not receive accountID!")
+	//Fetch authenticated account
+	account := auth.GetAccount(c)
+	if account == nil {
+		return service.SendError(c, 401, "Unauthenticated")
 	}
 	var body models.Account
 	if err := c.BodyParser(&body); err != nil {
 		return service.SendError(c, 400, "Invalid request body")
 	}
 
-	//Fetch account
-	account, err := models.FindAccount(c.Context(), boil.GetContextDB(), accountID)
-	if err != nil {
-		return service.SendError(c, 500, err.Error())
-	}
-
 	//Check logic for hasEmail and hasPhone
 	var (
 		hasEmail = false
@@ -142,7 +163,9 @@ func HandleUpdateAccount(c *fiber.Ctx) error {
 	if body.Email != account.Email {
 		hasEmail = true
 	}
-	if body.PhoneNumber.String != account.PhoneNumber.String {
+	//An empty or unchanged phone number keeps the one on file, no code is sent for it
+	body.PhoneNumber.String = strings.TrimSpace(body.PhoneNumber.String)
+	if body.PhoneNumber.String != "" && body.PhoneNumber.String != account.PhoneNumber.String {
 		hasPhone = true
 	}
 
@@ -151,26 +174,43 @@ func HandleUpdateAccount(c *fiber.Ctx) error {
 		return service.SendErrorStruct(c, 400, errObj)
 	}
 
+	//A new phone number is only saved once the code sent to it is confirmed
+	if hasPhone {
+		if err := startPhoneVerification(account, body.PhoneNumber.String); err != nil {
+			if errors.Is(err, errPhoneOTPLimit) {
+				return service.SendError(c, 429, err.Error())
+			}
+			return service.SendError(c, 500, err.Error())
+		}
+	}
+
 	//start updating account info
 	account.FullName = body.FullName
-	if account.PhoneNumber != null.StringFrom(body.PhoneNumber.String) {
-		account.PhoneNumber = null.StringFrom(body.PhoneNumber.String)
-	}
-	if account.Email != body.Email {
+	emailChanged := account.Email != body.Email
+	if emailChanged {
 		account.Email = body.Email
+		account.EmailVerified = false
 	}
 	if body.Avatar.String != "" {
 		account.Avatar = null.StringFrom(body.Avatar.String)
 	}
 
-	if _, err = account.Update(c.Context(), boil.GetContextDB(), boil.Infer()); err != nil {
+	if _, err := account.Update(c.Context(), boil.GetContextDB(), boil.Infer()); err != nil {
 		return service.SendError(c, 500, err.Error())
 	}
+	//The new address has to be verified again
+	if emailChanged {
+		_ = enqueueVerificationEmail(account)
+	}
 
 	resp := fiber.Map{
-		"status":  "Success",
-		"data":    account,
-		"message": "Successfully updated account information!",
+		"status":                    "Success",
+		"data":                      account,
+		"phoneVerificationRequired": hasPhone,
+		"message":                   "Successfully updated account information!",
+	}
+	if hasPhone {
+		resp["message"] = "Successfully updated account information! Enter the code sent to your new phone number to confirm it."
 	}
 
 	return c.JSON(resp)
@@ -180,33 +220,87 @@ func HandleUpdateAccount(c *fiber.Ctx) error {
 var addr = fmt.Sprintf("%s:%s", os.Getenv("REDIS_HOST"), os.Getenv("REDIS_PORT"))
 var asynqClient = asynq.NewClient(asynq.RedisClientOpt{Addr: addr, Password: "", DB: 0})
 
+// Limits for the password reset endpoints, counted per fixed window.
+const (
+	resetWindow          = 15 * time.Minute
+	resetLimitPerEmail   = 3
+	resetLimitPerIP      = 10
+	resetSubmitLimitIP   = 20
+	resetLinkSentMessage = "If the email is registered, a password reset link will be sent to your inbox."
+	resetCodeSentMessage = "If the account exists, a verification code will be sent to it."
+)
+
 // HandleForgotPassword generates a password reset token and sends email.
 func HandleForgotPassword(c *fiber.Ctx) error {
 	body := dto.ForgotPassStruct{}
 	if err := c.BodyParser(&body); err != nil {
 		return service.SendError(c, 400, err.Error())
 	}
+	//Links are only emailed, codes can also go by SMS
+	if body.Method == "" {
+		body.Method = dto.ResetMethodLink
+	}
+	if body.Channel == "" {
+		body.Channel = dto.OTPChannelEmail
+	}
+	if body.Method != dto.ResetMethodLink && body.Method != dto.ResetMethodOTP {
+		return service.SendError(c, 400, "Invalid reset method!")
+	}
+	if body.Method == dto.ResetMethodLink && body.Channel != dto.OTPChannelEmail {
+		return service.SendError(c, 400, "Reset links can only be sent by email!")
+	}
+	identifier, err := resetIdentifier(&body)
+	if err != nil {
+		return service.SendError(c, 400, err.Error())
+	}
+
+	//Rate limit per IP and per email/phone
+	if ok, err := utils.AllowRequest("forgotPass:ip="+c.IP(), resetLimitPerIP, resetWindow); err != nil {
+		return service.SendError(c, 500, err.Error())
+	} else if !ok {
+		return service.SendError(c, 429, "Too many requests, please try again later")
+	}
+	if ok, err := utils.AllowRequest("forgotPass:"+identifier, resetLimitPerEmail, resetWindow); err != nil {
+		return service.SendError(c, 500, err.Error())
+	} else if !ok {
+		return service.SendError(c, 429, "Too many requests, please try again later")
+	}
+
+	resp := fiber.Map{
+		"status":  "Success",
+		"message": resetLinkSentMessage,
+	}
+	if body.Method == dto.ResetMethodOTP {
+		resp["message"] = resetCodeSentMessage
+	}
 
-	//Find account by email
-	acc, err := models.Accounts(qm.Where("email = ?", body.Email)).One(c.Context(), boil.GetContextDB())
+	//Find account by email or phone, the response is the same whether it exists or not
+	acc, err := findResetAccount(c, &body)
 	if err != nil {
-		resp := fiber.Map{
-			"status":  "Success",
-			"message": "If the email is registered, a password reset link will be sent to your inbox.",
+		return c.JSON(resp)
+	}
+
+	if body.Method == dto.ResetMethodOTP {
+		code, err := auth.GenerateOTP(auth.OTPPurposePasswordReset, strconv.Itoa(acc.AccountID), "")
+		if err != nil {
+			return service.SendError(c, 500, err.Error())
+		}
+		destination := acc.Email
+		if body.Channel == dto.OTPChannelSMS {
+			destination = acc.PhoneNumber.String
+		}
+		if err := enqueueOTP(body.Channel, destination, code); err != nil {
+			return service.SendError(c, 500, err.Error())
 		}
 		return c.JSON(resp)
 	}
 
-	//Create reset token
-	token, err := uuid.NewV4()
+	//Create reset token, only its hash is stored in redis
+	token, err := auth.CreatePasswordResetToken(acc.AccountID)
 	if err != nil {
 		return service.SendError(c, 500, err.Error())
 	}
-	resetLink := fmt.Sprintf("http://localhost:5173/reset-password?token=%s", token.String())
-
-	//Cache token key in redis if user exists
-	redisKey := fmt.Sprintf("resetPass:token=%s", token.String())
-	utils.SetCache(redisKey, acc.Email, 15*time.Minute, "")
+	resetLink := fmt.Sprintf("%s/reset-password?token=%s", utils.FrontendURL(), url.QueryEscape(token))
 
 	//Enqueue email task - asynq
 	task, err := jobs.NewResetPasswordEmailTask(acc.Email, resetLink)
@@ -219,11 +313,6 @@ func HandleForgotPassword(c *fiber.Ctx) error {
 		return service.SendError(c, 500, err.Error())
 	}
 
-	resp := fiber.Map{
-		"status":  "Succes",
-		"data":    token,
-		"message": "If the email is registered, a password reset link will be sent to your inbox.",
-	}
 	return c.JSON(resp)
 }
 
@@ -233,44 +322,68 @@ func ValidateResetToken(c *fiber.Ctx) error {
 	if token == "" {
 		return service.SendError(c, 400, "Did not receive token!")
 	}
+	if ok, err := utils.AllowRequest("resetPass:ip="+c.IP(), resetSubmitLimitIP, resetWindow); err != nil {
+		return service.SendError(c, 500, err.Error())
+	} else if !ok {
+		return service.SendError(c, 429, "Too many requests, please try again later")
+	}
 
-	//Fetch cache
-	redisKey := fmt.Sprintf("resetPass:token=%s", token)
-	cachedToken := fiber.Map{}
-	ok, err := utils.GetCache(redisKey, &cachedToken)
-
-	//Validating cache
+	//The token alone resolves the account
+	accountID, err := auth.PeekPasswordResetToken(token)
 	if err != nil {
+		if errors.Is(err, auth.ErrInvalidResetToken) {
+			return service.SendError(c, 400, "Invalid or expired token")
+		}
 		return service.SendError(c, 500, "Error validating token")
-
 	}
-	if !ok {
-		return service.SendError(c, 500, "Invalid or expired token")
-
+	acc, err := models.FindAccount(c.Context(), boil.GetContextDB(), accountID)
+	if err != nil {
+		return service.SendError(c, 400, "Invalid or expired token")
 	}
 
 	resp := fiber.Map{
 		"status":  "Success",
 		"isValid": true,
-		"email":   cachedToken,
+		"email":   acc.Email,
 		"message": "Successfully validated reset password token!",
 	}
 	return c.JSON(resp)
 }
 
 // HandleResetPassword updates password if token is valid.
+// The account comes from the token, which is used up by the first request that presents it.
 func HandleResetPassword(c *fiber.Ctx) error {
 	body := dto.ResetPass{}
 	err := c.BodyParser(&body)
 	if err != nil {
 		return service.SendError(c, 400, "Invalid request body")
 	}
+	if ok, err := utils.AllowRequest("resetPass:ip="+c.IP(), resetSubmitLimitIP, resetWindow); err != nil {
+		return service.SendError(c, 500, err.Error())
+	} else if !ok {
+		return service.SendError(c, 429, "Too many requests, please try again later")
+	}
+
+	//Validate input before using up the token
+	if len(body.NewPass) <= 7 {
+		return service.SendError(c, 400, "New password needs to be at least 8 characters!")
+	}
+	if body.NewPass != body.ConfirmPass {
+		return service.SendError(c, 400, "Password does not match!")
+	}
 
-	//Find account with email
-	user, err := models.Accounts(qm.Where("email = ?", body.Email)).One(c.Context(), boil.GetContextDB())
+	//Consume token - one-time use
+	accountID, err := auth.ConsumePasswordResetToken(body.Token)
 	if err != nil {
+		if errors.Is(err, auth.ErrInvalidResetToken) {
+			return service.SendError(c, 400, "Invalid or expired token")
+		}
 		return service.SendError(c, 500, err.Error())
 	}
+	user, err := models.FindAccount(c.Context(), boil.GetContextDB(), accountID)
+	if err != nil {
+		return service.SendError(c, 400, "Invalid or expired token")
+	}
 
 	//Hash new pass
 	hashedPass, err := bcrypt.GenerateFromPassword([]byte(body.NewPass), bcrypt.DefaultCost)
@@ -280,13 +393,15 @@ func HandleResetPassword(c *fiber.Ctx) error {
 
 	//Update db
 	user.Password = string(hashedPass)
-	if _, err = user.Update(c.Context(), boil.GetContextDB(), boil.Infer()); err != nil {
+	if _, err = user.Update(c.Context(), boil.GetContextDB(), boil.Whitelist(models.AccountColumns.Password)); err != nil {
 		return service.SendError(c, 500, err.Error())
 	}
 
-	//Delete token from redis - one-time use
-	redisKey := fmt.Sprintf("resetPass:token=%s", body.Token)
-	utils.ClearCache(redisKey)
+	//Sign out every device that still holds a token issued with the old password
+	if err := auth.RevokeUserTokens(user.Username); err != nil {
+		return service.SendError(c, 500, err.Error())
+	}
+	_ = enqueuePasswordChangedEmail(user.Email)
 
 	resp := fiber.Map{
 		"status":  "Success",
@@ -295,6 +410,16 @@ func HandleResetPassword(c *fiber.Ctx) error {
 	return c.JSON(resp)
 }
 
+// enqueuePasswordChangedEmail queues the "your password was changed" notification.
+func enqueuePasswordChangedEmail(email string) error {
+	task, err := jobs.NewPasswordChangedEmailTask(email)
+	if err != nil {
+		return err
+	}
+	_, err = asynqClient.Enqueue(task)
+	return err
+}
+
 // RefreshToken handles refresh token rotation
 func RefreshToken(c *fiber.Ctx) error {
 	refreshToken := c.Cookies("refreshToken")
@@ -313,11 +438,12 @@ func RefreshToken(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{"status": "Success", "accessToken": newAccess})
 }
 
-// HandleLogout revokes current refresh token
+// HandleLogout revokes current refresh token and the access token sent with the request
 func HandleLogout(c *fiber.Ctx) error {
+	_ = auth.RevokeAccessToken(auth.GetTokenClaims(c))
 	refreshToken := c.Cookies("refreshToken")
 	if refreshToken != "" {
-		claims, err := auth.VerifyToken(refreshToken)
+		claims, err := auth.VerifyRefreshToken(refreshToken)
 		if err == nil {
 			_ = auth.RevokeRefreshToken(claims.ID)
 		}
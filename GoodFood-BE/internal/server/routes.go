@@ -3,6 +3,7 @@ package server
 import (
 	"GoodFood-BE/internal/auth"
 	"GoodFood-BE/internal/database"
+	"GoodFood-BE/internal/idempotency"
 	"GoodFood-BE/internal/server/handlers"
 	"context"
 	"fmt"
@@ -19,7 +20,8 @@ func (s *FiberServer) RegisterFiberRoutes(dbService database.Service) {
 	s.App.Use(cors.New(cors.Config{
 		AllowOrigins:     "http://localhost:5173, http://localhost:5000, http://127.0.0.1:5173",
 		AllowMethods:     "GET,POST,PUT,DELETE,OPTIONS,PATCH",
-		AllowHeaders:     "Accept,Authorization,Content-Type",
+		AllowHeaders:     "Accept,Authorization,Content-Type,Idempotency-Key",
+		ExposeHeaders:    "Idempotent-Replayed",
 		AllowCredentials: true, // credentials require explicit origins
 		MaxAge:           300,
 	}))
@@ -36,19 +38,28 @@ func (s *FiberServer) RegisterFiberRoutes(dbService database.Service) {
 	//Routes related to Chat Bot
 	chatbotGroup := s.App.Group("/api/chatbot",auth.OptionalAuthMiddleware)
 	chatbotGroup.Post("/call",handlers.CallVertexAI)
+	//Public keys for verifying access tokens
+	s.App.Get("/.well-known/jwks.json",handlers.GetJWKS)
 	//Routes related to accounts
 	userGroup := s.App.Group("/api/user",auth.OptionalAuthMiddleware)
 	userGroup.Post("/register",handlers.HandleRegister)
 	userGroup.Post("/login",handlers.HandleLogin)
+	userGroup.Post("/login/2fa",handlers.HandleLoginMFA)
 	userGroup.Get("/logout",handlers.HandleLogout)
 	userGroup.Post("/login/google",handlers.HandleLoginGoogle)
 	userGroup.Post("/login/facebook",handlers.HandleLoginFacebook)
+	userGroup.Get("/login/oidc/providers",handlers.GetOIDCProviders)
+	userGroup.Post("/login/oidc",handlers.HandleLoginOIDC)
 	userGroup.Post("/refresh-token",handlers.RefreshToken)
-	userGroup.Put("/update",handlers.HandleUpdateAccount)
+	userGroup.Put("/update",auth.AuthMiddleware,auth.AccountMiddleware,handlers.HandleUpdateAccount)
 	userGroup.Post("/forgot-password/sendOTP",handlers.HandleForgotPassword)
 	userGroup.Get("/forgot-password/validate",handlers.ValidateResetToken)
+	userGroup.Post("/forgot-password/verifyOTP",handlers.HandleVerifyResetOTP)
 	userGroup.Post("/forgot-password/reset",handlers.HandleResetPassword)
+	userGroup.Put("/phone/verify",auth.AuthMiddleware,auth.AccountMiddleware,handlers.HandleVerifyPhone)
 	userGroup.Post("/contact",handlers.HandleContact)
+	userGroup.Get("/verify-email",handlers.VerifyEmail)
+	userGroup.Post("/verify-email/resend",auth.AuthMiddleware,auth.AccountMiddleware,handlers.ResendVerificationEmail)
 	//Routes related to products
 	productGroup := s.App.Group("/api/products")
 	productGroup.Get("/getFeaturings",handlers.GetFour)
@@ -58,7 +69,8 @@ func (s *FiberServer) RegisterFiberRoutes(dbService database.Service) {
 	productGroup.Get("/detail",handlers.GetDetail)
 	productGroup.Get("/similar",handlers.GetSimilar)
 	//Routes related to cart
-	cartGroup := s.App.Group("/api/cart",auth.AuthMiddleware)
+	//Customer groups resolve the caller's account once, handlers scope every query to it
+	cartGroup := s.App.Group("/api/cart",auth.AuthMiddleware,auth.AccountMiddleware)
 	cartGroup.Get("/fetch",handlers.FetchCart)
 	cartGroup.Get("",handlers.GetCartDetail)
 	cartGroup.Post("/modify",handlers.Cart_ModifyQuantity)
@@ -66,7 +78,7 @@ func (s *FiberServer) RegisterFiberRoutes(dbService database.Service) {
 	cartGroup.Post("/add",handlers.AddToCart)
 	cartGroup.Delete("/deleteAll",handlers.DeleteAllItems)
 	//Routes related to address
-	addressGroup := s.App.Group("api/address",auth.AuthMiddleware)
+	addressGroup := s.App.Group("api/address",auth.AuthMiddleware,auth.AccountMiddleware)
 	addressGroup.Get("/fetch",handlers.FetchAddress)
 	addressGroup.Post("/insert",handlers.AddressInsert)
 	addressGroup.Get("/detail",handlers.AddressDetail)
@@ -75,62 +87,132 @@ func (s *FiberServer) RegisterFiberRoutes(dbService database.Service) {
 	addressGroup.Get("/fill",handlers.AddressFill)
 	addressGroup.Put("/quickChange",handlers.AddressQuickChange)
 	//Routes related to invoice
-	invoiceGroup := s.App.Group("api/invoice",auth.AuthMiddleware)
-	invoiceGroup.Post("/pay",handlers.InvoicePay)
-	invoiceGroup.Post("/pay/vnpay",handlers.InvoicePayVNPAY)
+	invoiceGroup := s.App.Group("api/invoice",auth.AuthMiddleware,auth.AccountMiddleware)
+	invoiceGroup.Post("/quote",handlers.InvoiceQuote)
+	//Retries carrying the same Idempotency-Key get the first response instead of a second order or payment
+	invoiceGroup.Post("/pay",auth.RequireVerifiedEmail(auth.ActionCheckout),idempotency.Middleware,handlers.InvoicePay)
+	invoiceGroup.Post("/pay/online",auth.RequireVerifiedEmail(auth.ActionCheckout),idempotency.Middleware,handlers.InvoicePayOnline)
+	invoiceGroup.Post("/pay/vnpay",handlers.InvoicePayVNPAYRemoved)
+	//Routes related to shipping. Carrier notifications are authenticated by the carrier
+	shippingGroup := s.App.Group("api/shipping")
+	shippingGroup.Post("/quote",auth.AuthMiddleware,auth.AccountMiddleware,handlers.ShippingQuote)
+	shippingGroup.Post("/webhook/:carrier",handlers.ShippingWebhook)
+	//Payment methods and provider callbacks, the latter authenticated by their signature
+	paymentGroup := s.App.Group("api/payment")
+	paymentGroup.Get("/methods",handlers.GetPaymentMethods)
+	paymentGroup.Get("/:method/return",handlers.PaymentReturn)
+	paymentGroup.Get("/:method/ipn",handlers.PaymentCallback)
+	paymentGroup.Post("/:method/ipn",handlers.PaymentCallback)
 	//Routes related to order history
-	orderHistoryGroup := s.App.Group("api/order-history",auth.AuthMiddleware)
+	orderHistoryGroup := s.App.Group("api/order-history",auth.AuthMiddleware,auth.AccountMiddleware)
 	orderHistoryGroup.Get("",handlers.GetOrderHistory)
-	orderHistoryGroup.Put("/update",handlers.CancelOrder)
+	orderHistoryGroup.Put("/update",idempotency.Middleware,handlers.CancelOrder)
 	orderHistoryGroup.Get("/details",handlers.GetOrderHistoryDetail)
+	orderHistoryGroup.Get("/timeline",handlers.GetOrderHistoryTimeline)
 	//Routes related to customer review
-	customerReviewGroup := s.App.Group("api/review",auth.AuthMiddleware)
+	customerReviewGroup := s.App.Group("api/review",auth.AuthMiddleware,auth.AccountMiddleware)
 	customerReviewGroup.Get("",handlers.GetReviewData)
-	customerReviewGroup.Post("/create",handlers.HandleSubmitReview)
+	customerReviewGroup.Post("/create",auth.RequireVerifiedEmail(auth.ActionReview),handlers.HandleSubmitReview)
 	customerReviewGroup.Get("/detail",handlers.GetReviewDetail)
-	customerReviewGroup.Put("/update",handlers.HandleUpdateReview)
+	customerReviewGroup.Put("/update",auth.RequireVerifiedEmail(auth.ActionReview),handlers.HandleUpdateReview)
 	//Routes related to change password
-	changePasswordGroup := s.App.Group("api/change-password",auth.AuthMiddleware)
+	changePasswordGroup := s.App.Group("api/change-password",auth.AuthMiddleware,auth.AccountMiddleware)
 	changePasswordGroup.Post("/submit",handlers.ChangePasswordSubmit)
+	changePasswordGroup.Post("/set",handlers.SetPassword)
+	//Routes related to linked social logins
+	oauthGroup := s.App.Group("api/user/oauth",auth.AuthMiddleware,auth.AccountMiddleware)
+	oauthGroup.Get("",handlers.GetLoginMethods)
+	oauthGroup.Post("/link",handlers.LinkOAuthProvider)
+	oauthGroup.Delete("/unlink",handlers.UnlinkOAuthProvider)
+	//Routes related to two-factor authentication
+	mfaGroup := s.App.Group("api/user/2fa",auth.AuthMiddleware,auth.AccountMiddleware)
+	mfaGroup.Get("",handlers.GetMFAStatus)
+	mfaGroup.Post("/setup",handlers.SetupMFA)
+	mfaGroup.Post("/confirm",handlers.ConfirmMFA)
+	mfaGroup.Post("/disable",handlers.DisableMFA)
+	mfaGroup.Post("/recovery-codes",handlers.RegenerateMFARecoveryCodes)
+	//Routes related to personal data export and account deletion
+	dataExportGroup := s.App.Group("api/user/data-export",auth.AuthMiddleware,auth.AccountMiddleware)
+	dataExportGroup.Post("",handlers.RequestDataExport)
+	dataExportGroup.Get("",handlers.GetDataExport)
+	dataExportGroup.Get("/download",handlers.DownloadDataExport)
+	userAccountGroup := s.App.Group("api/user/account",auth.AuthMiddleware,auth.AccountMiddleware)
+	userAccountGroup.Delete("",handlers.DeleteAccount)
+	//Routes related to login sessions
+	sessionGroup := s.App.Group("api/sessions",auth.AuthMiddleware,auth.AccountMiddleware)
+	sessionGroup.Get("",handlers.GetSessions)
+	sessionGroup.Delete("/revoke",handlers.RevokeSession)
+	sessionGroup.Delete("/revoke-others",handlers.RevokeOtherSessions)
 	//Routes related to Admin Dashboard
-	dashboardGroup := s.App.Group("api/admin",auth.AuthMiddleware)
-	dashboardGroup.Get("/dashboard",handlers.GetDashboard)
-	dashboardGroup.Get("/linechart",handlers.GetLineChart)
-	dashboardGroup.Get("/piechart",handlers.GetPieChart)
-	dashboardGroup.Get("/barchart",handlers.GetBarChart)
+	//Every api/admin/* route goes through this group, so non-admin callers are rejected here.
+	//Groups using APIKeyMiddleware also accept X-API-Key requests, limited by the scopes of the key
+	dashboardGroup := s.App.Group("api/admin",auth.APIKeyMiddleware,auth.RequirePermission(auth.PermAdminAccess))
+	dashboardGroup.Get("/dashboard",auth.RequirePermission(auth.PermDashboardRead),handlers.GetDashboard)
+	dashboardGroup.Get("/linechart",auth.RequirePermission(auth.PermDashboardRead),handlers.GetLineChart)
+	dashboardGroup.Get("/piechart",auth.RequirePermission(auth.PermDashboardRead),handlers.GetPieChart)
+	dashboardGroup.Get("/barchart",auth.RequirePermission(auth.PermDashboardRead),handlers.GetBarChart)
 	//Routes related to Admin Invoice
-	adminInvoiceGroup := s.App.Group("api/admin/order",auth.AuthMiddleware)
+	adminInvoiceGroup := s.App.Group("api/admin/order",auth.APIKeyMiddleware,auth.RequirePermission(auth.PermOrdersRead))
 	adminInvoiceGroup.Get("",handlers.GetAdminInvoice)
 	adminInvoiceGroup.Get("/detail",handlers.GetAdminInvoiceDetail)
-	adminInvoiceGroup.Put("/update",handlers.UpdateInvoice)
+	adminInvoiceGroup.Get("/timeline",handlers.GetAdminInvoiceTimeline)
+	adminInvoiceGroup.Get("/payments",handlers.GetAdminInvoicePayments)
+	adminInvoiceGroup.Get("/payments/status",handlers.GetAdminInvoicePaymentStatus)
+	adminInvoiceGroup.Post("/refund",auth.RequirePermission(auth.PermOrdersWrite),idempotency.Middleware,handlers.AdminInvoiceRefund)
+	adminInvoiceGroup.Post("/refund/resolve",auth.RequirePermission(auth.PermOrdersWrite),handlers.AdminInvoiceRefundResolve)
+	adminInvoiceGroup.Put("/update",auth.RequirePermission(auth.PermOrdersWrite),handlers.UpdateInvoice)
+	adminInvoiceGroup.Get("/shipment",handlers.GetAdminInvoiceShipment)
+	adminInvoiceGroup.Post("/shipment/create",auth.RequirePermission(auth.PermOrdersWrite),handlers.AdminInvoiceShipmentCreate)
+	adminInvoiceGroup.Post("/shipment/cancel",auth.RequirePermission(auth.PermOrdersWrite),handlers.AdminInvoiceShipmentCancel)
 	//Routes related to Admin User
-	adminUserGroup := s.App.Group("api/admin/user",auth.AuthMiddleware)
+	adminUserGroup := s.App.Group("api/admin/user",auth.AuthMiddleware,auth.RequirePermission(auth.PermUsersRead))
 	adminUserGroup.Get("",handlers.GetAdminUsers)
 	adminUserGroup.Get("/detail",handlers.GetAdminUserDetail)
-	adminUserGroup.Post("/create",handlers.AdminUserCreate)
-	adminUserGroup.Put("/update",handlers.AdminUserUpdate)
+	adminUserGroup.Post("/create",auth.RequirePermission(auth.PermUsersWrite),handlers.AdminUserCreate)
+	adminUserGroup.Put("/update",auth.RequirePermission(auth.PermUsersWrite),handlers.AdminUserUpdate)
+	adminUserGroup.Post("/unlock",auth.RequirePermission(auth.PermUsersWrite),handlers.AdminUserUnlock)
+	adminUserGroup.Get("/sessions",handlers.GetAdminUserSessions)
+	adminUserGroup.Delete("/sessions/revoke",auth.RequirePermission(auth.PermUsersWrite),handlers.AdminRevokeUserSession)
 	//Routes related to Admin Product Type
-	adminProductTypeGroup := s.App.Group("api/admin/product-type",auth.AuthMiddleware)
+	adminProductTypeGroup := s.App.Group("api/admin/product-type",auth.APIKeyMiddleware,auth.RequirePermission(auth.PermProductsRead))
 	adminProductTypeGroup.Get("",handlers.GetAdminProductTypes)
 	adminProductTypeGroup.Get("/detail",handlers.GetAdminProductTypeDetail)
-	adminProductTypeGroup.Post("/create",handlers.AdminProductTypeCreate)
-	adminProductTypeGroup.Put("/update",handlers.AdminProductTypeUpdate)
+	adminProductTypeGroup.Post("/create",auth.RequirePermission(auth.PermProductsWrite),handlers.AdminProductTypeCreate)
+	adminProductTypeGroup.Put("/update",auth.RequirePermission(auth.PermProductsWrite),handlers.AdminProductTypeUpdate)
 	//Routes related to Admin Product
-	adminProductGroup := s.App.Group("api/admin/product",auth.AuthMiddleware)
+	adminProductGroup := s.App.Group("api/admin/product",auth.APIKeyMiddleware,auth.RequirePermission(auth.PermProductsRead))
 	adminProductGroup.Get("",handlers.GetAdminProducts)
 	adminProductGroup.Get("/detail",handlers.GetAdminProductDetail);
-	adminProductGroup.Post("/create",handlers.AdminProductCreate)
-	adminProductGroup.Put("/update",handlers.AdminProductUpdate)
+	adminProductGroup.Post("/create",auth.RequirePermission(auth.PermProductsWrite),handlers.AdminProductCreate)
+	adminProductGroup.Put("/update",auth.RequirePermission(auth.PermProductsWrite),handlers.AdminProductUpdate)
+	//Routes related to Admin Shipping. Shipping rates are part of pricing, managed with the catalog
+	adminShippingGroup := s.App.Group("api/admin/shipping",auth.APIKeyMiddleware,auth.RequirePermission(auth.PermProductsRead))
+	adminShippingGroup.Get("",handlers.GetAdminShipping)
+	adminShippingGroup.Put("/rates/update",auth.RequirePermission(auth.PermProductsWrite),handlers.AdminShippingRatesUpdate)
+	adminShippingGroup.Post("/zone/create",auth.RequirePermission(auth.PermProductsWrite),handlers.AdminShippingZoneCreate)
+	adminShippingGroup.Put("/zone/update",auth.RequirePermission(auth.PermProductsWrite),handlers.AdminShippingZoneUpdate)
+	adminShippingGroup.Delete("/zone/delete",auth.RequirePermission(auth.PermProductsWrite),handlers.AdminShippingZoneDelete)
+	adminShippingGroup.Post("/area/create",auth.RequirePermission(auth.PermProductsWrite),handlers.AdminShippingAreaCreate)
+	adminShippingGroup.Delete("/area/delete",auth.RequirePermission(auth.PermProductsWrite),handlers.AdminShippingAreaDelete)
 	//Routes related to Admin Statistics
-	adminStatisticGroup := s.App.Group("api/admin/statistic",auth.AuthMiddleware)
+	adminStatisticGroup := s.App.Group("api/admin/statistic",auth.APIKeyMiddleware,auth.RequirePermission(auth.PermStatisticsRead))
 	adminStatisticGroup.Get("",handlers.GetAdminStatistics)
 	//Routes related to Admin Reviews
-	adminReviewGroup := s.App.Group("api/admin/review",auth.AuthMiddleware)
+	adminReviewGroup := s.App.Group("api/admin/review",auth.APIKeyMiddleware,auth.RequirePermission(auth.PermReviewsRead))
 	adminReviewGroup.Get("",handlers.GetAdminReview)
 	adminReviewGroup.Get("/review-analysis",handlers.GetAdminReviewAnalysis)
 	adminReviewGroup.Get("/detail",handlers.GetAdminReviewDetail)
-	adminReviewGroup.Post("/reply",handlers.InsertReviewReply)
-	adminReviewGroup.Put("/update",handlers.UpdateReviewReply)
+	adminReviewGroup.Post("/reply",auth.RequirePermission(auth.PermReviewsWrite),handlers.InsertReviewReply)
+	adminReviewGroup.Put("/update",auth.RequirePermission(auth.PermReviewsWrite),handlers.UpdateReviewReply)
+	//Routes related to Admin Audit Log
+	adminAuditGroup := s.App.Group("api/admin/audit-log",auth.AuthMiddleware,auth.RequirePermission(auth.PermAuditRead))
+	adminAuditGroup.Get("",handlers.GetAdminAuditLog)
+	//Routes related to Admin API Keys
+	adminAPIKeyGroup := s.App.Group("api/admin/api-key",auth.AuthMiddleware,auth.RequirePermission(auth.PermAPIKeysManage))
+	adminAPIKeyGroup.Get("",handlers.GetAdminAPIKeys)
+	adminAPIKeyGroup.Post("/create",handlers.AdminAPIKeyCreate)
+	adminAPIKeyGroup.Delete("/revoke",handlers.AdminAPIKeyRevoke)
+	adminAPIKeyGroup.Get("/usage",handlers.GetAdminAPIKeyUsage)
 }
 
 func (s *FiberServer) websocketHandler(con *websocket.Conn) {
@@ -0,0 +1,143 @@
+package shipping
+
+import (
+	"GoodFood-BE/internal/money"
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrUnknownCarrier  = errors.New("unknown shipping carrier")
+	ErrCarrierRejected = errors.New("the shipping carrier rejected the request")
+	ErrInvalidWebhook  = errors.New("invalid shipping carrier notification")
+)
+
+// ShipmentStatus is the state of a parcel, common to every carrier. Carriers report finer statuses, kept as
+// CarrierStatus.
+type ShipmentStatus string
+
+const (
+	ShipmentPending   ShipmentStatus = "pending"    //created, not picked up yet
+	ShipmentInTransit ShipmentStatus = "in_transit" //picked up, on its way to the customer
+	ShipmentDelivered ShipmentStatus = "delivered"
+	ShipmentReturned  ShipmentStatus = "returned" //could not be delivered and went back to the shop
+	ShipmentCancelled ShipmentStatus = "cancelled"
+	ShipmentException ShipmentStatus = "exception" //lost or damaged, needs an admin
+)
+
+// ShippingCarrier is a delivery company. The shop creates a shipment for every order it hands over; the carrier
+// reports the progress of the parcel with notifications read by ParseWebhook.
+type ShippingCarrier interface {
+	// Code names the carrier, the key of the registry and of invoice."carrierCode".
+	Code() string
+	// Quote returns what the carrier charges the shop for a shipment.
+	Quote(ctx context.Context, req ShipmentRequest) (*CarrierQuote, error)
+	// CreateShipment hands a parcel over to the carrier.
+	CreateShipment(ctx context.Context, req ShipmentRequest) (*Shipment, error)
+	// CancelShipment calls off a shipment that has not been picked up yet.
+	CancelShipment(ctx context.Context, trackingCode string) error
+	// Track asks the carrier where a parcel is.
+	Track(ctx context.Context, trackingCode string) (*Tracking, error)
+	// ParseWebhook authenticates a notification of the carrier and reads the update it carries. params are the
+	// query params of the request.
+	ParseWebhook(params map[string]string, body []byte) (*TrackingUpdate, error)
+}
+
+// ShipmentItem is a line of a parcel.
+type ShipmentItem struct {
+	Name        string `json:"name"`
+	Quantity    int    `json:"quantity"`
+	WeightGrams int    `json:"weightGrams"`
+}
+
+// ShipmentRequest describes a parcel and where it goes. Reference is the shop reference of the order, and
+// CODAmount what the carrier collects from the customer on delivery.
+type ShipmentRequest struct {
+	Reference      string
+	ToName         string
+	ToPhone        string
+	ToAddress      string
+	ToDistrictCode int
+	ToWardCode     string
+	WeightGrams    int
+	CODAmount      money.Money
+	InsuranceValue money.Money
+	Items          []ShipmentItem
+	Note           string
+}
+
+// CarrierQuote is the price of a shipment.
+type CarrierQuote struct {
+	Carrier string      `json:"carrier"`
+	Fee     money.Money `json:"fee"`
+}
+
+// Shipment is a parcel accepted by a carrier.
+type Shipment struct {
+	Carrier          string      `json:"carrier"`
+	TrackingCode     string      `json:"trackingCode"`
+	Fee              money.Money `json:"fee"`
+	ExpectedDelivery time.Time   `json:"expectedDelivery"`
+}
+
+// TrackingUpdate is a change of status of a parcel.
+type TrackingUpdate struct {
+	TrackingCode  string         `json:"trackingCode"`
+	Status        ShipmentStatus `json:"status"`
+	CarrierStatus string         `json:"carrierStatus"`
+	Reason        string         `json:"reason,omitempty"`
+	At            time.Time      `json:"at"`
+}
+
+// Tracking is the current status of a parcel with its history, oldest first.
+type Tracking struct {
+	TrackingCode  string            `json:"trackingCode"`
+	Status        ShipmentStatus    `json:"status"`
+	CarrierStatus string            `json:"carrierStatus"`
+	History       []*TrackingUpdate `json:"history"`
+}
+
+var (
+	carriersMu sync.RWMutex
+	carriers   = map[string]ShippingCarrier{}
+)
+
+func init() {
+	RegisterCarrier(GHNCarrier{})
+}
+
+// RegisterCarrier makes c available under its code, replacing a carrier registered with the same code.
+func RegisterCarrier(c ShippingCarrier) {
+	carriersMu.Lock()
+	defer carriersMu.Unlock()
+	carriers[c.Code()] = c
+}
+
+// GetCarrier returns the registered carrier of a code.
+func GetCarrier(code string) (ShippingCarrier, error) {
+	carriersMu.RLock()
+	defer carriersMu.RUnlock()
+	c, ok := carriers[strings.ToUpper(strings.TrimSpace(code))]
+	if !ok {
+		return nil, ErrUnknownCarrier
+	}
+	return c, nil
+}
+
+// ActiveCarrier returns the carrier named by SHIPPING_CARRIER, which new shipments are handed to. ok is false
+// when it is not set: orders are then delivered by the shop and moved along by admins.
+func ActiveCarrier() (c ShippingCarrier, ok bool, err error) {
+	code := os.Getenv("SHIPPING_CARRIER")
+	if strings.TrimSpace(code) == "" {
+		return nil, false, nil
+	}
+	c, err = GetCarrier(code)
+	if err != nil {
+		return nil, false, err
+	}
+	return c, true, nil
+}
@@ -0,0 +1,152 @@
+package shipping_test
+
+import (
+	"GoodFood-BE/internal/money"
+	"GoodFood-BE/internal/shipping"
+	"GoodFood-BE/internal/shipping/ghnfake"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newGHN starts a fake GHN for the shop 885 and points the GHN carrier at it.
+func newGHN(t *testing.T) (*ghnfake.Server, shipping.ShippingCarrier) {
+	fake := ghnfake.New("TESTTOKEN", "885")
+	t.Cleanup(fake.Close)
+	t.Setenv("GHN_API_URL", fake.URL)
+	t.Setenv("GHN_TOKEN", "TESTTOKEN")
+	t.Setenv("GHN_SHOP_ID", "885")
+	t.Setenv("GHN_WEBHOOK_SECRET", "HOOKSECRET")
+	carrier, err := shipping.GetCarrier("ghn")
+	require.NoError(t, err)
+	return fake, carrier
+}
+
+var parcel = shipping.ShipmentRequest{
+	Reference:      "GF-42",
+	ToName:         "Test User",
+	ToPhone:        "0900000000",
+	ToAddress:      "1 Le Loi, Q1, HCM",
+	ToDistrictCode: 1442,
+	ToWardCode:     "20109",
+	WeightGrams:    2500,
+	CODAmount:      money.VND(255000),
+	Items:          []shipping.ShipmentItem{{Name: "Pho", Quantity: 2, WeightGrams: 1250}},
+}
+
+func TestGHNQuote(t *testing.T) {
+	_, carrier := newGHN(t)
+
+	quote, err := carrier.Quote(context.Background(), parcel)
+	require.NoError(t, err)
+	assert.Equal(t, money.VND(ghnfake.BaseFee+2*ghnfake.PerKgFee), quote.Fee)
+	assert.Equal(t, shipping.CarrierGHN, quote.Carrier)
+}
+
+func TestGHNShipment(t *testing.T) {
+	fake, carrier := newGHN(t)
+	ctx := context.Background()
+
+	shipment, err := carrier.CreateShipment(ctx, parcel)
+	require.NoError(t, err)
+	assert.NotEmpty(t, shipment.TrackingCode)
+	order, ok := fake.Order(shipment.TrackingCode)
+	require.True(t, ok)
+	assert.Equal(t, "GF-42", order.ClientOrderCode)
+	assert.Equal(t, int64(255000), order.CODAmount)
+	assert.Equal(t, "20109", order.ToWardCode)
+
+	_, err = carrier.CreateShipment(ctx, parcel)
+	assert.ErrorIs(t, err, shipping.ErrCarrierRejected, "GHN refuses a second order with the same reference")
+
+	fake.SetStatus(shipment.TrackingCode, "picked")
+	fake.SetStatus(shipment.TrackingCode, "delivering")
+	tracking, err := carrier.Track(ctx, shipment.TrackingCode)
+	require.NoError(t, err)
+	assert.Equal(t, shipping.ShipmentInTransit, tracking.Status)
+	assert.Equal(t, "delivering", tracking.CarrierStatus)
+	require.Len(t, tracking.History, 3)
+	assert.Equal(t, shipping.ShipmentPending, tracking.History[0].Status)
+
+	assert.ErrorIs(t, carrier.CancelShipment(ctx, shipment.TrackingCode), shipping.ErrCarrierRejected,
+		"picked up parcels cannot be cancelled")
+}
+
+func TestGHNCancel(t *testing.T) {
+	fake, carrier := newGHN(t)
+	ctx := context.Background()
+
+	shipment, err := carrier.CreateShipment(ctx, parcel)
+	require.NoError(t, err)
+	require.NoError(t, carrier.CancelShipment(ctx, shipment.TrackingCode))
+	order, _ := fake.Order(shipment.TrackingCode)
+	assert.Equal(t, "cancel", order.Status)
+}
+
+func TestGHNRejectsBadToken(t *testing.T) {
+	_, carrier := newGHN(t)
+	t.Setenv("GHN_TOKEN", "WRONG")
+
+	_, err := carrier.Quote(context.Background(), parcel)
+	assert.ErrorIs(t, err, shipping.ErrCarrierRejected)
+}
+
+func TestGHNWebhook(t *testing.T) {
+	fake, carrier := newGHN(t)
+	shipment, err := carrier.CreateShipment(context.Background(), parcel)
+	require.NoError(t, err)
+
+	body := fake.Webhook(shipment.TrackingCode, "delivered", "")
+	update, err := carrier.ParseWebhook(map[string]string{"token": "HOOKSECRET"}, body)
+	require.NoError(t, err)
+	assert.Equal(t, shipment.TrackingCode, update.TrackingCode)
+	assert.Equal(t, shipping.ShipmentDelivered, update.Status)
+	assert.Equal(t, "delivered", update.CarrierStatus)
+
+	_, err = carrier.ParseWebhook(map[string]string{"token": "GUESS"}, body)
+	assert.ErrorIs(t, err, shipping.ErrInvalidWebhook)
+	_, err = carrier.ParseWebhook(map[string]string{"token": "HOOKSECRET"}, []byte(`{"Status":"delivered"}`))
+	assert.ErrorIs(t, err, shipping.ErrInvalidWebhook)
+
+	t.Setenv("GHN_WEBHOOK_SECRET", "")
+	_, err = carrier.ParseWebhook(map[string]string{"token": ""}, body)
+	assert.ErrorIs(t, err, shipping.ErrInvalidWebhook, "notifications are refused until a secret is set")
+}
+
+func TestGHNStatuses(t *testing.T) {
+	fake, carrier := newGHN(t)
+	shipment, err := carrier.CreateShipment(context.Background(), parcel)
+	require.NoError(t, err)
+
+	for status, want := range map[string]shipping.ShipmentStatus{
+		"ready_to_pick": shipping.ShipmentPending,
+		"transporting":  shipping.ShipmentInTransit,
+		"delivery_fail": shipping.ShipmentInTransit,
+		"returned":      shipping.ShipmentReturned,
+		"lost":          shipping.ShipmentException,
+		"something_new": shipping.ShipmentException,
+	} {
+		update, err := carrier.ParseWebhook(map[string]string{"token": "HOOKSECRET"}, fake.Webhook(shipment.TrackingCode, status, ""))
+		require.NoError(t, err)
+		assert.Equal(t, want, update.Status, status)
+	}
+}
+
+func TestActiveCarrier(t *testing.T) {
+	t.Setenv("SHIPPING_CARRIER", "")
+	_, ok, err := shipping.ActiveCarrier()
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	t.Setenv("SHIPPING_CARRIER", "ghn")
+	c, ok, err := shipping.ActiveCarrier()
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, shipping.CarrierGHN, c.Code())
+
+	t.Setenv("SHIPPING_CARRIER", "PIGEON")
+	_, _, err = shipping.ActiveCarrier()
+	assert.ErrorIs(t, err, shipping.ErrUnknownCarrier)
+}
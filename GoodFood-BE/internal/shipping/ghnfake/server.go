@@ -0,0 +1,235 @@
+// Package ghnfake is an in-memory GHN API for tests. It checks the token and shop of requests the way GHN does,
+// keeps the orders it is given and builds the status notifications GHN would send for them.
+package ghnfake
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+)
+
+// Fees charged by the fake: a base fee and a fee per started kilogram above the first.
+const (
+	BaseFee  = 22000
+	PerKgFee = 5000
+)
+
+// Order is a shipment known to the fake.
+type Order struct {
+	OrderCode       string
+	ClientOrderCode string
+	ToDistrictID    int
+	ToWardCode      string
+	Weight          int
+	CODAmount       int64
+	Status          string
+	Log             []LogEntry
+}
+
+// LogEntry is a past status of an order.
+type LogEntry struct {
+	Status      string    `json:"status"`
+	UpdatedDate time.Time `json:"updated_date"`
+}
+
+// Server is a running fake GHN. Requests holds the path of every request received, in order.
+type Server struct {
+	*httptest.Server
+	Token  string
+	ShopID string
+
+	mu       sync.Mutex
+	orders   map[string]*Order
+	next     int
+	Requests []string
+}
+
+// New starts a fake GHN accepting token for shop. Close it when done.
+func New(token, shopID string) *Server {
+	s := &Server{Token: token, ShopID: shopID, orders: map[string]*Order{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// Order returns the state of an order.
+func (s *Server) Order(code string) (Order, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	o, ok := s.orders[code]
+	if !ok {
+		return Order{}, false
+	}
+	return *o, true
+}
+
+// Orders returns the number of orders created.
+func (s *Server) Orders() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.orders)
+}
+
+// SetStatus moves an order to status, as GHN does when the parcel moves.
+func (s *Server) SetStatus(code, status string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if o, ok := s.orders[code]; ok {
+		o.Status = status
+		o.Log = append(o.Log, LogEntry{Status: status, UpdatedDate: time.Now().UTC()})
+	}
+}
+
+// Webhook moves an order to status and returns the body of the notification GHN sends about it.
+func (s *Server) Webhook(code, status, reason string) []byte {
+	s.SetStatus(code, status)
+	o, _ := s.Order(code)
+	body, _ := json.Marshal(map[string]interface{}{
+		"OrderCode":       code,
+		"ClientOrderCode": o.ClientOrderCode,
+		"Status":          status,
+		"Type":            "switch_status",
+		"Reason":          reason,
+		"Time":            time.Now().UTC(),
+		"ShopID":          s.ShopID,
+	})
+	return body
+}
+
+// respond writes the GHN envelope.
+func respond(w http.ResponseWriter, code int, message string, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"code": code, "message": message, "data": data})
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Requests = append(s.Requests, r.URL.Path)
+
+	if r.Header.Get("Token") != s.Token {
+		respond(w, http.StatusUnauthorized, "Token is not valid", nil)
+		return
+	}
+	if r.Header.Get("ShopId") != s.ShopID {
+		respond(w, http.StatusBadRequest, "ShopId is not valid", nil)
+		return
+	}
+	var req map[string]interface{}
+	if r.Method != http.MethodPost || json.NewDecoder(r.Body).Decode(&req) != nil {
+		respond(w, http.StatusBadRequest, "Invalid request", nil)
+		return
+	}
+
+	switch r.URL.Path {
+	case "/v2/shipping-order/fee":
+		s.fee(w, req)
+	case "/v2/shipping-order/create":
+		s.create(w, req)
+	case "/v2/switch-status/cancel":
+		s.cancel(w, req)
+	case "/v2/shipping-order/detail":
+		s.detail(w, req)
+	default:
+		respond(w, http.StatusNotFound, "Not found", nil)
+	}
+}
+
+func number(req map[string]interface{}, key string) int64 {
+	n, _ := req[key].(float64)
+	return int64(n)
+}
+
+func fee(weight int64) int64 {
+	kg := int64(0)
+	if weight > 1000 {
+		kg = (weight - 1000 + 999) / 1000
+	}
+	return BaseFee + kg*PerKgFee
+}
+
+// valid checks the destination and weight of a fee or create request.
+func valid(req map[string]interface{}) bool {
+	ward, _ := req["to_ward_code"].(string)
+	return number(req, "to_district_id") > 0 && ward != "" && number(req, "weight") > 0
+}
+
+func (s *Server) fee(w http.ResponseWriter, req map[string]interface{}) {
+	if !valid(req) {
+		respond(w, http.StatusBadRequest, "Invalid destination or weight", nil)
+		return
+	}
+	total := fee(number(req, "weight"))
+	respond(w, http.StatusOK, "Success", map[string]interface{}{"total": total, "service_fee": total, "insurance_fee": 0})
+}
+
+func (s *Server) create(w http.ResponseWriter, req map[string]interface{}) {
+	if !valid(req) {
+		respond(w, http.StatusBadRequest, "Invalid destination or weight", nil)
+		return
+	}
+	client, _ := req["client_order_code"].(string)
+	for _, o := range s.orders {
+		if client != "" && o.ClientOrderCode == client && o.Status != "cancel" {
+			respond(w, http.StatusBadRequest, "Duplicate client_order_code", nil)
+			return
+		}
+	}
+	s.next++
+	ward, _ := req["to_ward_code"].(string)
+	o := &Order{
+		OrderCode:       fmt.Sprintf("GHNFAKE%04d", s.next),
+		ClientOrderCode: client,
+		ToDistrictID:    int(number(req, "to_district_id")),
+		ToWardCode:      ward,
+		Weight:          int(number(req, "weight")),
+		CODAmount:       number(req, "cod_amount"),
+		Status:          "ready_to_pick",
+		Log:             []LogEntry{{Status: "ready_to_pick", UpdatedDate: time.Now().UTC()}},
+	}
+	s.orders[o.OrderCode] = o
+	respond(w, http.StatusOK, "Success", map[string]interface{}{
+		"order_code":             o.OrderCode,
+		"total_fee":              fee(int64(o.Weight)),
+		"expected_delivery_time": time.Now().Add(72 * time.Hour).UTC(),
+	})
+}
+
+// cancel calls off orders that were not picked up yet, the way GHN does.
+func (s *Server) cancel(w http.ResponseWriter, req map[string]interface{}) {
+	codes, _ := req["order_codes"].([]interface{})
+	results := []map[string]interface{}{}
+	for _, c := range codes {
+		code, _ := c.(string)
+		o, ok := s.orders[code]
+		switch {
+		case !ok:
+			results = append(results, map[string]interface{}{"order_code": code, "result": false, "message": "Order not found"})
+		case o.Status != "ready_to_pick" && o.Status != "picking":
+			results = append(results, map[string]interface{}{"order_code": code, "result": false, "message": "Order cannot be cancelled"})
+		default:
+			o.Status = "cancel"
+			o.Log = append(o.Log, LogEntry{Status: "cancel", UpdatedDate: time.Now().UTC()})
+			results = append(results, map[string]interface{}{"order_code": code, "result": true, "message": "OK"})
+		}
+	}
+	respond(w, http.StatusOK, "Success", results)
+}
+
+func (s *Server) detail(w http.ResponseWriter, req map[string]interface{}) {
+	code, _ := req["order_code"].(string)
+	o, ok := s.orders[code]
+	if !ok {
+		respond(w, http.StatusBadRequest, "Order not found", nil)
+		return
+	}
+	respond(w, http.StatusOK, "Success", map[string]interface{}{
+		"order_code":        o.OrderCode,
+		"client_order_code": o.ClientOrderCode,
+		"status":            o.Status,
+		"log":               o.Log,
+	})
+}
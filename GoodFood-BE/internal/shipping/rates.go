@@ -0,0 +1,268 @@
+package shipping
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/aarondl/null/v8"
+	"github.com/aarondl/sqlboiler/v4/boil"
+	"github.com/lib/pq"
+)
+
+// MaxMultiplierPercent caps zone multipliers, which catches a typo such as 15000 for 150.
+const MaxMultiplierPercent = 1000
+
+var (
+	ErrInvalidRates = errors.New("fees, thresholds and the included weight cannot be negative")
+	ErrInvalidZone  = errors.New("a zone needs a code of at most 20 characters, a name and a multiplier between 1 and 1000 percent")
+	ErrZoneExists   = errors.New("a shipping zone with this code already exists")
+	ErrZoneNotFound = errors.New("shipping zone not found")
+	ErrDefaultZone  = errors.New("the default shipping zone cannot be deleted")
+	ErrInvalidArea  = errors.New("an area is a province, optionally narrowed to one of its districts and one of its wards")
+	ErrAreaTaken    = errors.New("the area already belongs to a shipping zone")
+	ErrAreaNotFound = errors.New("shipping area not found")
+)
+
+// isUniqueViolation reports whether err is a duplicate key error.
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// UpdateRates replaces the shared rates.
+func UpdateRates(ctx context.Context, r Rates) (*Rates, error) {
+	if r.BaseFee < 0 || r.IncludedGrams < 0 || r.PerKgFee < 0 || r.FreeShippingThreshold < 0 {
+		return nil, ErrInvalidRates
+	}
+	err := boil.GetContextDB().QueryRowContext(ctx, `
+		UPDATE shipping_rate
+		SET "baseFee" = $1, "includedGrams" = $2, "perKgFee" = $3, "freeShippingThreshold" = $4, "updatedAt" = now()
+		WHERE "rateID" = 1
+		RETURNING "updatedAt"`, r.BaseFee, r.IncludedGrams, r.PerKgFee, r.FreeShippingThreshold).Scan(&r.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// validateZone normalizes the code of z and checks its fields.
+func validateZone(z *Zone) error {
+	z.Code = strings.ToUpper(strings.TrimSpace(z.Code))
+	z.Name = strings.TrimSpace(z.Name)
+	if z.Code == "" || len(z.Code) > 20 || z.Name == "" || len(z.Name) > 100 ||
+		z.MultiplierPercent < 1 || z.MultiplierPercent > MaxMultiplierPercent {
+		return ErrInvalidZone
+	}
+	if z.FreeShippingThreshold != nil && *z.FreeShippingThreshold < 0 {
+		return ErrInvalidRates
+	}
+	return nil
+}
+
+// zoneThreshold returns the value stored in shipping_zone."freeShippingThreshold".
+func zoneThreshold(z Zone) null.Int64 {
+	if z.FreeShippingThreshold == nil {
+		return null.Int64{}
+	}
+	return null.Int64From(z.FreeShippingThreshold.Int64())
+}
+
+// ListZones returns every zone with its areas, the default zone first.
+func ListZones(ctx context.Context) ([]*Zone, error) {
+	db := boil.GetContextDB()
+	rows, err := db.QueryContext(ctx, `
+		SELECT `+zoneColumns+` FROM shipping_zone z ORDER BY z.code <> $1, z.code`, DefaultZone)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	zones := []*Zone{}
+	byCode := map[string]*Zone{}
+	for rows.Next() {
+		z, err := scanZone(rows)
+		if err != nil {
+			return nil, err
+		}
+		z.Areas = []*Area{}
+		zones = append(zones, z)
+		byCode[z.Code] = z
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	areas, err := listAreas(ctx, db, "")
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range areas {
+		if z, ok := byCode[a.ZoneCode]; ok {
+			z.Areas = append(z.Areas, a)
+		}
+	}
+	return zones, nil
+}
+
+// GetZone returns a zone with its areas.
+func GetZone(ctx context.Context, code string) (*Zone, error) {
+	db := boil.GetContextDB()
+	z, err := scanZone(db.QueryRowContext(ctx, `
+		SELECT `+zoneColumns+` FROM shipping_zone z WHERE z.code = $1`, strings.ToUpper(strings.TrimSpace(code))))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrZoneNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if z.Areas, err = listAreas(ctx, db, z.Code); err != nil {
+		return nil, err
+	}
+	return z, nil
+}
+
+// CreateZone adds a zone without areas.
+func CreateZone(ctx context.Context, z Zone) (*Zone, error) {
+	if err := validateZone(&z); err != nil {
+		return nil, err
+	}
+	_, err := boil.GetContextDB().ExecContext(ctx, `
+		INSERT INTO shipping_zone (code, name, "multiplierPercent", "freeShippingThreshold") VALUES ($1, $2, $3, $4)`,
+		z.Code, z.Name, z.MultiplierPercent, zoneThreshold(z))
+	if isUniqueViolation(err) {
+		return nil, ErrZoneExists
+	}
+	if err != nil {
+		return nil, err
+	}
+	return GetZone(ctx, z.Code)
+}
+
+// UpdateZone changes the name, multiplier and threshold of a zone. Its areas are kept.
+func UpdateZone(ctx context.Context, z Zone) (*Zone, error) {
+	if err := validateZone(&z); err != nil {
+		return nil, err
+	}
+	res, err := boil.GetContextDB().ExecContext(ctx, `
+		UPDATE shipping_zone SET name = $2, "multiplierPercent" = $3, "freeShippingThreshold" = $4 WHERE code = $1`,
+		z.Code, z.Name, z.MultiplierPercent, zoneThreshold(z))
+	if err != nil {
+		return nil, err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return nil, err
+	} else if n == 0 {
+		return nil, ErrZoneNotFound
+	}
+	return GetZone(ctx, z.Code)
+}
+
+// DeleteZone removes a zone. Its areas fall back to DefaultZone.
+func DeleteZone(ctx context.Context, code string) error {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == DefaultZone {
+		return ErrDefaultZone
+	}
+	res, err := boil.GetContextDB().ExecContext(ctx, `DELETE FROM shipping_zone WHERE code = $1`, code)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrZoneNotFound
+	}
+	return nil
+}
+
+// areaColumns lists the columns scanned by listAreas, in order.
+const areaColumns = `a."areaID", a."zoneCode", a."provinceID", p."provinceName", a."districtID", d."districtName",
+	a."wardID", w."wardName"`
+
+// listAreas returns the areas of a zone, or of every zone when zoneCode is empty, by province then district then ward.
+func listAreas(ctx context.Context, exec boil.ContextExecutor, zoneCode string) ([]*Area, error) {
+	rows, err := exec.QueryContext(ctx, `
+		SELECT `+areaColumns+`
+		FROM shipping_zone_area a
+		JOIN province p ON p."provinceID" = a."provinceID"
+		LEFT JOIN district d ON d."districtID" = a."districtID"
+		LEFT JOIN ward w ON w."wardID" = a."wardID"
+		WHERE $1 = '' OR a."zoneCode" = $1
+		ORDER BY p."provinceName", d."districtName" NULLS FIRST, w."wardName" NULLS FIRST`, zoneCode)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	areas := []*Area{}
+	for rows.Next() {
+		a := &Area{}
+		if err := rows.Scan(&a.AreaID, &a.ZoneCode, &a.ProvinceID, &a.ProvinceName, &a.DistrictID, &a.DistrictName,
+			&a.WardID, &a.WardName); err != nil {
+			return nil, err
+		}
+		areas = append(areas, a)
+	}
+	return areas, rows.Err()
+}
+
+// AddArea puts an area in a zone. The district must be in the province and the ward in the district.
+func AddArea(ctx context.Context, a Area) (*Area, error) {
+	a.ZoneCode = strings.ToUpper(strings.TrimSpace(a.ZoneCode))
+	if a.ProvinceID == 0 || (a.WardID.Valid && !a.DistrictID.Valid) {
+		return nil, ErrInvalidArea
+	}
+	db := boil.GetContextDB()
+	var valid bool
+	err := db.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM province WHERE "provinceID" = $1)
+			AND ($2::integer IS NULL OR EXISTS (SELECT 1 FROM district WHERE "districtID" = $2 AND "provinceID" = $1))
+			AND ($3::integer IS NULL OR EXISTS (SELECT 1 FROM ward WHERE "wardID" = $3 AND "districtID" = $2))`,
+		a.ProvinceID, a.DistrictID, a.WardID).Scan(&valid)
+	if err != nil {
+		return nil, err
+	}
+	if !valid {
+		return nil, ErrInvalidArea
+	}
+
+	err = db.QueryRowContext(ctx, `
+		INSERT INTO shipping_zone_area ("zoneCode", "provinceID", "districtID", "wardID")
+		SELECT code, $2, $3, $4 FROM shipping_zone WHERE code = $1
+		RETURNING "areaID"`, a.ZoneCode, a.ProvinceID, a.DistrictID, a.WardID).Scan(&a.AreaID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrZoneNotFound
+	}
+	if isUniqueViolation(err) {
+		return nil, ErrAreaTaken
+	}
+	if err != nil {
+		return nil, err
+	}
+	areas, err := listAreas(ctx, db, a.ZoneCode)
+	if err != nil {
+		return nil, err
+	}
+	for _, added := range areas {
+		if added.AreaID == a.AreaID {
+			return added, nil
+		}
+	}
+	return &a, nil
+}
+
+// RemoveArea takes an area out of its zone and returns it. Its addresses fall back to a wider area or DefaultZone.
+func RemoveArea(ctx context.Context, areaID int) (*Area, error) {
+	a := &Area{}
+	err := boil.GetContextDB().QueryRowContext(ctx, `
+		DELETE FROM shipping_zone_area WHERE "areaID" = $1
+		RETURNING "areaID", "zoneCode", "provinceID", "districtID", "wardID"`, areaID).
+		Scan(&a.AreaID, &a.ZoneCode, &a.ProvinceID, &a.DistrictID, &a.WardID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAreaNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
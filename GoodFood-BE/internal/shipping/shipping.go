@@ -0,0 +1,162 @@
+// Package shipping prices the delivery of orders from their weight and destination. Rates are stored in
+// shipping_rate, shipping_zone and shipping_zone_area and edited by admins: a base fee and a fee per started
+// kilogram above an included weight, scaled by the multiplier of the zone of the destination. Orders reaching
+// a free shipping threshold ship for free.
+package shipping
+
+import (
+	"GoodFood-BE/internal/money"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/aarondl/null/v8"
+	"github.com/aarondl/sqlboiler/v4/boil"
+)
+
+// DefaultZone is the zone of destinations outside every area. It always exists.
+const DefaultZone = "DEFAULT"
+
+// Rates are the fees shared by every zone. A FreeShippingThreshold of 0 never applies.
+type Rates struct {
+	BaseFee               money.Money `json:"baseFee"`
+	IncludedGrams         int         `json:"includedGrams"`
+	PerKgFee              money.Money `json:"perKgFee"`
+	FreeShippingThreshold money.Money `json:"freeShippingThreshold"`
+	UpdatedAt             time.Time   `json:"updatedAt"`
+}
+
+// Zone is a group of areas sharing a multiplier. A nil FreeShippingThreshold falls back to the one of Rates.
+type Zone struct {
+	Code                  string       `json:"code"`
+	Name                  string       `json:"name"`
+	MultiplierPercent     int          `json:"multiplierPercent"`
+	FreeShippingThreshold *money.Money `json:"freeShippingThreshold"`
+	Areas                 []*Area      `json:"areas,omitempty"`
+}
+
+// Area is a province, a district of it or a ward of that district, belonging to a zone.
+type Area struct {
+	AreaID       int         `json:"areaID"`
+	ZoneCode     string      `json:"zoneCode"`
+	ProvinceID   int         `json:"provinceID"`
+	ProvinceName string      `json:"provinceName"`
+	DistrictID   null.Int    `json:"districtID"`
+	DistrictName null.String `json:"districtName"`
+	WardID       null.Int    `json:"wardID"`
+	WardName     null.String `json:"wardName"`
+}
+
+// Destination is where an order is delivered. Zero IDs are unknown; an empty destination is in DefaultZone.
+type Destination struct {
+	ProvinceID int `json:"provinceID"`
+	DistrictID int `json:"districtID"`
+	WardID     int `json:"wardID"`
+}
+
+// Quote is the shipping fee of an order with how it was computed.
+type Quote struct {
+	Zone              string      `json:"zone"`
+	WeightGrams       int         `json:"weightGrams"`
+	ChargedKg         int         `json:"chargedKg"` //started kilograms above the included weight
+	BaseFee           money.Money `json:"baseFee"`
+	WeightFee         money.Money `json:"weightFee"`
+	MultiplierPercent int         `json:"multiplierPercent"`
+	FreeShipping      bool        `json:"freeShipping"`
+	Fee               money.Money `json:"fee"`
+}
+
+// Calculate prices the delivery in zone of an order weighing weightGrams, whose products cost subtotal.
+func Calculate(rates *Rates, zone *Zone, weightGrams int, subtotal money.Money) (*Quote, error) {
+	q := &Quote{
+		Zone:              zone.Code,
+		WeightGrams:       weightGrams,
+		BaseFee:           rates.BaseFee,
+		MultiplierPercent: zone.MultiplierPercent,
+	}
+	if extra := weightGrams - rates.IncludedGrams; extra > 0 {
+		q.ChargedKg = (extra + 999) / 1000
+	}
+	var err error
+	if q.WeightFee, err = rates.PerKgFee.Mul(int64(q.ChargedKg)); err != nil {
+		return nil, err
+	}
+	fee, err := q.BaseFee.Add(q.WeightFee)
+	if err != nil {
+		return nil, err
+	}
+	if q.Fee, err = fee.Percent(int64(zone.MultiplierPercent)); err != nil {
+		return nil, err
+	}
+
+	threshold := rates.FreeShippingThreshold
+	if zone.FreeShippingThreshold != nil {
+		threshold = *zone.FreeShippingThreshold
+	}
+	if threshold > 0 && subtotal >= threshold {
+		q.FreeShipping = true
+		q.Fee = 0
+	}
+	return q, nil
+}
+
+// LoadRates returns the shared rates.
+func LoadRates(ctx context.Context, exec boil.ContextExecutor) (*Rates, error) {
+	r := &Rates{}
+	err := exec.QueryRowContext(ctx, `
+		SELECT "baseFee", "includedGrams", "perKgFee", "freeShippingThreshold", "updatedAt"
+		FROM shipping_rate WHERE "rateID" = 1`).
+		Scan(&r.BaseFee, &r.IncludedGrams, &r.PerKgFee, &r.FreeShippingThreshold, &r.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// zoneColumns lists the columns scanned by scanZone, in order.
+const zoneColumns = `z.code, z.name, z."multiplierPercent", z."freeShippingThreshold"`
+
+func scanZone(row interface{ Scan(...interface{}) error }) (*Zone, error) {
+	z := &Zone{}
+	var threshold null.Int64
+	if err := row.Scan(&z.Code, &z.Name, &z.MultiplierPercent, &threshold); err != nil {
+		return nil, err
+	}
+	if threshold.Valid {
+		t := money.VND(threshold.Int64)
+		z.FreeShippingThreshold = &t
+	}
+	return z, nil
+}
+
+// ZoneFor returns the zone of dest: the one of its ward, else of its district, else of its province,
+// else DefaultZone.
+func ZoneFor(ctx context.Context, exec boil.ContextExecutor, dest Destination) (*Zone, error) {
+	z, err := scanZone(exec.QueryRowContext(ctx, `
+		SELECT `+zoneColumns+`
+		FROM shipping_zone_area a JOIN shipping_zone z ON z.code = a."zoneCode"
+		WHERE a."provinceID" = $1
+			AND (a."districtID" IS NULL OR a."districtID" = $2)
+			AND (a."wardID" IS NULL OR a."wardID" = $3)
+		ORDER BY a."wardID" IS NULL, a."districtID" IS NULL
+		LIMIT 1`, dest.ProvinceID, dest.DistrictID, dest.WardID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return scanZone(exec.QueryRowContext(ctx, `
+			SELECT `+zoneColumns+` FROM shipping_zone z WHERE z.code = $1`, DefaultZone))
+	}
+	return z, err
+}
+
+// QuoteFor prices the delivery to dest of an order weighing weightGrams, whose products cost subtotal.
+func QuoteFor(ctx context.Context, exec boil.ContextExecutor, dest Destination, weightGrams int, subtotal money.Money) (*Quote, error) {
+	rates, err := LoadRates(ctx, exec)
+	if err != nil {
+		return nil, err
+	}
+	zone, err := ZoneFor(ctx, exec, dest)
+	if err != nil {
+		return nil, err
+	}
+	return Calculate(rates, zone, weightGrams, subtotal)
+}
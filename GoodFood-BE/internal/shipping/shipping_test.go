@@ -0,0 +1,65 @@
+package shipping
+
+import (
+	"GoodFood-BE/internal/money"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalculate(t *testing.T) {
+	rates := &Rates{BaseFee: 20000, IncludedGrams: 1000, PerKgFee: 5000, FreeShippingThreshold: 500000}
+	inner := &Zone{Code: "INNER", MultiplierPercent: 100}
+	outer := &Zone{Code: "OUTER", MultiplierPercent: 150}
+	noFree := money.VND(0)
+	remote := &Zone{Code: "REMOTE", MultiplierPercent: 200, FreeShippingThreshold: &noFree}
+
+	tests := []struct {
+		name     string
+		zone     *Zone
+		grams    int
+		subtotal money.Money
+		wantKg   int
+		wantFee  money.Money
+		wantFree bool
+	}{
+		{name: "Within the included weight", zone: inner, grams: 1000, subtotal: 100000, wantFee: 20000},
+		{name: "Every started kilogram counts", zone: inner, grams: 1001, subtotal: 100000, wantKg: 1, wantFee: 25000},
+		{name: "Heavy order", zone: inner, grams: 4200, subtotal: 100000, wantKg: 4, wantFee: 40000},
+		{name: "Zone multiplier", zone: outer, grams: 2500, subtotal: 100000, wantKg: 2, wantFee: 45000},
+		{name: "Free shipping threshold", zone: outer, grams: 2500, subtotal: 500000, wantKg: 2, wantFee: 0, wantFree: true},
+		{name: "Zone without free shipping", zone: remote, grams: 0, subtotal: 900000, wantFee: 40000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := Calculate(rates, tt.zone, tt.grams, tt.subtotal)
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, tt.zone.Code, q.Zone)
+			assert.Equal(t, tt.wantKg, q.ChargedKg)
+			assert.Equal(t, tt.wantFee, q.Fee)
+			assert.Equal(t, tt.wantFree, q.FreeShipping)
+		})
+	}
+}
+
+func TestCalculateWithoutThreshold(t *testing.T) {
+	zone := &Zone{Code: DefaultZone, MultiplierPercent: 100}
+	q, err := Calculate(&Rates{BaseFee: 30000}, zone, 3600, 10000000)
+	assert.NoError(t, err)
+	assert.Equal(t, money.VND(30000), q.Fee, "a threshold of 0 never applies")
+	assert.False(t, q.FreeShipping)
+}
+
+func TestValidateZone(t *testing.T) {
+	z := &Zone{Code: " inner ", Name: "Inner city", MultiplierPercent: 100}
+	assert.NoError(t, validateZone(z))
+	assert.Equal(t, "INNER", z.Code)
+
+	assert.ErrorIs(t, validateZone(&Zone{Code: "X", Name: "X", MultiplierPercent: 0}), ErrInvalidZone)
+	assert.ErrorIs(t, validateZone(&Zone{Code: "X", Name: "X", MultiplierPercent: MaxMultiplierPercent + 1}), ErrInvalidZone)
+	assert.ErrorIs(t, validateZone(&Zone{Code: "", Name: "X", MultiplierPercent: 100}), ErrInvalidZone)
+	negative := money.VND(-1)
+	assert.ErrorIs(t, validateZone(&Zone{Code: "X", Name: "X", MultiplierPercent: 100, FreeShippingThreshold: &negative}), ErrInvalidRates)
+}
@@ -0,0 +1,51 @@
+package sms
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// Provider sends a text message to a phone number.
+type Provider interface {
+	Send(ctx context.Context, to, message string) error
+}
+
+// NewProviderFromEnv returns the provider selected by SMS_PROVIDER.
+// Only "log" is built in for now, which is also the default so development never sends real messages.
+func NewProviderFromEnv() (Provider, error) {
+	switch name := os.Getenv("SMS_PROVIDER"); name {
+	case "", "log":
+		return &LogProvider{Path: os.Getenv("SMS_LOG_FILE")}, nil
+	default:
+		return nil, fmt.Errorf("unknown SMS provider %q", name)
+	}
+}
+
+// LogProvider is the development sink: it appends every message to Path, or to the standard logger when Path is empty.
+type LogProvider struct {
+	Path string
+	mu   sync.Mutex
+}
+
+// Send writes the message instead of delivering it.
+func (p *LogProvider) Send(ctx context.Context, to, message string) error {
+	line := fmt.Sprintf("%s to=%s message=%q\n", time.Now().Format(time.RFC3339), to, message)
+	if p.Path == "" {
+		log.Print("[sms] " + line)
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	f, err := os.OpenFile(p.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.WriteString(line)
+	return err
+}
@@ -0,0 +1,34 @@
+package sms
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogProviderWritesToFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sms.log")
+	p := &LogProvider{Path: path}
+
+	assert.NoError(t, p.Send(context.Background(), "0901234567", "code 123456"))
+	assert.NoError(t, p.Send(context.Background(), "0907654321", "code 654321"))
+
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "to=0901234567 message=\"code 123456\"")
+	assert.Contains(t, string(content), "to=0907654321 message=\"code 654321\"")
+}
+
+func TestNewProviderFromEnv(t *testing.T) {
+	t.Setenv("SMS_PROVIDER", "")
+	p, err := NewProviderFromEnv()
+	assert.NoError(t, err)
+	assert.IsType(t, &LogProvider{}, p)
+
+	t.Setenv("SMS_PROVIDER", "carrier-pigeon")
+	_, err = NewProviderFromEnv()
+	assert.Error(t, err)
+}
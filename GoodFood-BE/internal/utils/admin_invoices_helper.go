@@ -7,15 +7,13 @@ import (
 	"fmt"
 	"time"
 
-	"github.com/aarondl/null/v8"
 	"github.com/aarondl/sqlboiler/v4/boil"
 	"github.com/aarondl/sqlboiler/v4/queries"
 	"github.com/aarondl/sqlboiler/v4/queries/qm"
 	"github.com/gofiber/fiber/v2"
-	"gopkg.in/gomail.v2"
 )
 
-// These constants define allowed invoice statuses
+// Display names of the invoice statuses, used in order history cache keys
 const (
 	StatusOrderPlaced    = "Order Placed"
 	StatusCancelled      = "Cancelled"
@@ -116,29 +114,6 @@ func FetchInvoiceAndStatus(c *fiber.Ctx, invoiceID int) (*models.Invoice, *model
 	return invoice, status, nil
 }
 
-// FetchStatusProgression builds the next possible statuses based on current status.
-func FetchStatusProgression(c *fiber.Ctx, status *models.InvoiceStatus) ([]*models.InvoiceStatus, error) {
-	var queryMods []qm.QueryMod
-
-	switch status.StatusName {
-	case StatusOrderPlaced:
-		queryMods = append(queryMods, qm.Where("\"statusName\" LIKE ? OR \"statusName\" LIKE ? OR \"statusName\" LIKE ?", status.StatusName, "Cancelled", "Order Confirmed"))
-	case StatusOrderConfirmed:
-		queryMods = append(queryMods, qm.Where("\"statusName\" LIKE ? OR \"statusName\" LIKE ?", status.StatusName, "Order Processing"))
-	case StatusProcessing:
-		queryMods = append(queryMods, qm.Where("\"statusName\" LIKE ? OR \"statusName\" LIKE ?", status.StatusName, "Shipping"))
-	case StatusShipping:
-		queryMods = append(queryMods, qm.Where("\"statusName\" LIKE ? OR \"statusName\" LIKE ?", status.StatusName, "Delivered"))
-	case StatusDelivered:
-		queryMods = append(queryMods, qm.Where("\"statusName\" LIKE ?", status.StatusName))
-	default:
-		// fallback: return only current status
-		queryMods = append(queryMods, qm.Where("\"statusName\" LIKE ?", status.StatusName))
-	}
-
-	return models.InvoiceStatuses(queryMods...).All(c.Context(), boil.GetContextDB())
-}
-
 // FetchInvoiceDetails loads invoice details with joined product and customer info.
 func FetchInvoiceDetails(c *fiber.Ctx, invoiceID int) ([]*dto.InvoiceDetailResponse, error) {
 	details := []*dto.InvoiceDetailResponse{}
@@ -160,66 +135,16 @@ func FetchInvoiceDetails(c *fiber.Ctx, invoiceID int) ([]*dto.InvoiceDetailRespo
 	return details, nil
 }
 
-// UpdateInvoiceStatus updates an invoice's status with business rules applied.
-func UpdateInvoiceStatus(c *fiber.Ctx, invoiceID int, status dto.UpdateInvoiceStruct) (*models.Invoice, error) {
-	//Find status record
-	invoiceStatus, err := models.InvoiceStatuses(qm.Where("\"statusName\" LIKE ?", status.StatusName)).One(c.Context(), boil.GetContextDB())
-	if err != nil {
-		return nil, errors.New("Invoice status not found!")
-	}
-
-	//Find invoice
-	invoice, err := models.Invoices(
-		qm.Where("\"invoiceID\" = ?", invoiceID),
-		qm.Load(models.InvoiceRels.AccountIDAccount),
-	).One(c.Context(), boil.GetContextDB())
-	if err != nil {
-		return nil, errors.New("Invoice not found!")
-	}
-
-	//Apply business logicc
-	if invoice.InvoiceStatusID != invoiceStatus.InvoiceStatusID && invoiceStatus.InvoiceStatusID != 6 {
-		invoice.InvoiceStatusID += 1
-	}
-	//invoiceStatusID reaches 5 meaning the order has been delivered, meaning invoice status is paid.
-	if invoiceStatus.InvoiceStatusID == 5 {
-		invoice.InvoiceStatusID = 5
-		invoice.Status = true
-	}
-	//invoiceStatusID reaches 6 meaning the order has been cancelled, also sends an email clarifying the
-	//cancelation
-	if invoiceStatus.InvoiceStatusID == 6 {
-		invoice.InvoiceStatusID = 6
-		invoice.CancelReason = null.String(status.CancelReason)
-		err := SendOrderCancelEmail(invoice.R.AccountIDAccount.Email, invoice.CancelReason.String, invoice.Status)
-		if err != nil {
-			return nil, err
-		}
-	}
-
-	//Update DB
-	_, err = invoice.Update(c.Context(), boil.GetContextDB(), boil.Infer())
-	if err != nil {
-		return nil, err
-	}
-
-	return invoice, nil
-}
-
 // SendOrderCancelEmail sends an order cancellation email from Admin to customer.
 // If isPaid = true, the message will include refund instructions.
 func SendOrderCancelEmail(toEmail string, reason string, isPaid bool) error {
-	mailer := gomail.NewMessage()
-	mailer.SetHeader("From", "williamdang0404@gmail.com")
-	mailer.SetHeader("To", toEmail)
-	mailer.SetHeader("Subject", "❌ Order Cancellation Notice from GoodFood24h")
+	mailer := newMail(toEmail, "❌ Order Cancellation Notice from GoodFood24h")
 
 	//Build mail content
 	body := BuildCancelEmailBody(reason, isPaid)
 	mailer.SetBody("text/html", body)
 
-	//Gmail SMTP
-	dialer := gomail.NewDialer("smtp.gmail.com", 587, "williamdang0404@gmail.com", "yhjd uzhk hhvp zfiq")
+	dialer := NewMailDialer()
 
 	return dialer.DialAndSend(mailer)
 }
@@ -240,8 +165,10 @@ func BuildCancelEmailBody(reason string, isPaid bool) string {
 	var extra string
 	if isPaid {
 		extra = `
-			<p>Since your order was already paid online, please contact our support hotline 
-			at <strong>0799607411</strong> for detailed instructions regarding your refund.</p>
+			<p>Since your order was already paid online, the amount will be refunded to the card or account
+			you paid with. Refunds usually take 7 to 15 working days to appear, depending on your bank.</p>
+			<p>If you have any question regarding your refund, please contact our support hotline
+			at <strong>0799607411</strong>.</p>
 		`
 	} else {
 		extra = `
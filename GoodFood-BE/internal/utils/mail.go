@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	"gopkg.in/gomail.v2"
+)
+
+// DefaultFrontendURL is used when FRONTEND_URL is not set (local Vite dev server).
+const DefaultFrontendURL = "http://localhost:5173"
+
+// SMTP server used when SMTP_HOST or SMTP_PORT are not set.
+const (
+	DefaultSMTPHost = "smtp.gmail.com"
+	DefaultSMTPPort = 587
+)
+
+// FrontendURL returns the base URL of the web client used to build links sent by email, without a trailing slash.
+func FrontendURL() string {
+	url := strings.TrimRight(os.Getenv("FRONTEND_URL"), "/")
+	if url == "" {
+		return DefaultFrontendURL
+	}
+	return url
+}
+
+// MailSender returns the address emails are sent from and contact messages are delivered to: SMTP_FROM, or SMTP_USER.
+func MailSender() string {
+	if from := os.Getenv("SMTP_FROM"); from != "" {
+		return from
+	}
+	return os.Getenv("SMTP_USER")
+}
+
+// NewMailDialer returns a dialer of the SMTP server at SMTP_HOST:SMTP_PORT signing in as SMTP_USER with SMTP_PASSWORD.
+func NewMailDialer() *gomail.Dialer {
+	host := os.Getenv("SMTP_HOST")
+	if host == "" {
+		host = DefaultSMTPHost
+	}
+	port, err := strconv.Atoi(os.Getenv("SMTP_PORT"))
+	if err != nil || port <= 0 {
+		port = DefaultSMTPPort
+	}
+	return gomail.NewDialer(host, port, os.Getenv("SMTP_USER"), os.Getenv("SMTP_PASSWORD"))
+}
+
+// newMail starts an email from MailSender to toEmail.
+func newMail(toEmail, subject string) *gomail.Message {
+	mailer := gomail.NewMessage()
+	mailer.SetHeader("From", MailSender())
+	mailer.SetHeader("To", toEmail)
+	mailer.SetHeader("Subject", subject)
+	return mailer
+}
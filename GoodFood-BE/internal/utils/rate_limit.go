@@ -0,0 +1,20 @@
+package utils
+
+import (
+	redisdatabase "GoodFood-BE/internal/redis-database"
+	"time"
+)
+
+// AllowRequest counts one request against key and reports whether it is still within limit for the current window.
+// The window starts with the first request, fixed-window style.
+func AllowRequest(key string, limit int64, window time.Duration) (bool, error) {
+	key = "ratelimit:" + key
+	count, err := redisdatabase.Client.Incr(redisdatabase.Ctx, key).Result()
+	if err != nil {
+		return false, err
+	}
+	if count == 1 {
+		_ = redisdatabase.Client.Expire(redisdatabase.Ctx, key, window).Err()
+	}
+	return count <= limit, nil
+}
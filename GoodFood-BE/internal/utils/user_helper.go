@@ -2,80 +2,140 @@ package utils
 
 import (
 	"GoodFood-BE/internal/auth"
+	"GoodFood-BE/internal/dto"
 	"GoodFood-BE/internal/service"
 	"GoodFood-BE/models"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 
 	"github.com/aarondl/null/v8"
 	"github.com/aarondl/sqlboiler/v4/boil"
 	"github.com/aarondl/sqlboiler/v4/queries/qm"
 	"github.com/gofiber/fiber/v2"
-	"golang.org/x/crypto/bcrypt"
 )
 
-//HandleOAuthLogin is a shared function for Google/Facebook login.
-//It checks if OAuth account exists, links account if needed, or creates new account.
-func HandleOAuthLogin(c *fiber.Ctx, provider, providerUserID, email, fullName, avatarURL string) error{
-	//Check if oauth user already exists
-	existingOAuth, err := models.OauthAccounts(qm.Where("\"providerUserID\" = ?",providerUserID),qm.Load(models.OauthAccountRels.AccountIDAccount)).One(c.Context(),boil.GetContextDB())
+// HandleOAuthLogin signs in with an identity verified by a social login provider.
+// A linked identity logs into its account. An unknown identity is linked to the account using the same email
+// only if both the provider and the account verified that email, otherwise the user has to sign in and link it
+// from their profile. Without any matching account a new one is created, with no password.
+func HandleOAuthLogin(c *fiber.Ctx, profile dto.OAuthProfile) error{
+	ctx := c.Context()
+	identity, err := auth.FindIdentity(ctx, boil.GetContextDB(), profile.Provider, profile.ProviderUserID)
 	if err == nil{
-		//OAuth exists => login user
-		return LoginWithAccount(c,existingOAuth.R.AccountIDAccount);
+		acc, err := models.FindAccount(ctx, boil.GetContextDB(), identity.AccountID)
+		if err != nil{
+			return service.SendError(c, 500, err.Error())
+		}
+		if _, err := auth.ClearLegacyOAuthPassword(ctx, boil.GetContextDB(), acc); err != nil{
+			return service.SendError(c, 500, err.Error())
+		}
+		return LoginWithAccount(c, acc)
+	}
+	if !errors.Is(err, sql.ErrNoRows){
+		return service.SendError(c, 500, err.Error())
 	}
 
-	//Check if account exists with same email
-	existingAccount, err := models.Accounts(qm.Where("email = ?",email)).One(c.Context(),boil.GetContextDB())
-	if err != nil{
-		if existingAccount.EmailVerified{
-			//Link OAuth to this account
-			oauth := models.OauthAccount{
-				AccountID: existingAccount.AccountID,
-				Provider: provider,
-				ProviderUserID: providerUserID,
-			}
-			if err := oauth.Insert(c.Context(),boil.GetContextDB(),boil.Infer()); err != nil{
-				return service.SendError(c,500,"Failed to link OAuth account")
-			}
+	email := strings.ToLower(strings.TrimSpace(profile.Email))
+	if email == ""{
+		return service.SendError(c, 400, "The provider did not share an email address")
+	}
+	existingAccount, err := models.Accounts(qm.Where("LOWER(email) = ?", email)).One(ctx, boil.GetContextDB())
+	if err == nil{
+		if !existingAccount.EmailVerified || !profile.EmailVerified{
+			return service.SendError(c, 409, "An account with this email already exists, sign in and link "+profile.Provider+" from your profile")
 		}
-		return service.SendError(c,403,"This email is associated with an unverified account");
+		if err := auth.LinkIdentity(ctx, boil.GetContextDB(), existingAccount.AccountID, profile.Provider, profile.ProviderUserID); err != nil{
+			return IdentityError(c, err)
+		}
+		return LoginWithAccount(c, existingAccount)
+	}
+	if !errors.Is(err, sql.ErrNoRows){
+		return service.SendError(c, 500, err.Error())
 	}
 
 	//Otherwise, create new account and link to oauth
-	hashedPass, _ := bcrypt.GenerateFromPassword([]byte(providerUserID),bcrypt.DefaultCost)
 	newUser := models.Account{
-		Username: providerUserID,
-		Password: string(hashedPass),
+		Username: profile.ProviderUserID,
+		Password: auth.NoPasswordHash,
 		Email: email,
-		FullName: fullName,
-		Avatar: null.StringFrom(avatarURL),
+		EmailVerified: profile.EmailVerified,
+		FullName: profile.FullName,
+		Avatar: null.StringFrom(profile.AvatarURL),
 		Status: true,
-		Role: false,
+		Role: auth.RoleCustomer,
 		PhoneNumber: null.String{},
 		Gender: true,
 	}
-	if err := newUser.Insert(c.Context(),boil.GetContextDB(),boil.Infer()); err != nil{
+	tx, err := boil.BeginTx(ctx, nil)
+	if err != nil{
+		return service.SendError(c, 500, err.Error())
+	}
+	defer tx.Rollback()
+	if err := newUser.Insert(ctx, tx, boil.Infer()); err != nil{
 		return service.SendError(c,500, "Failed to create account");
 	}
-
-	oauthUser := models.OauthAccount{
-		AccountID: newUser.AccountID,
-		Provider: provider,
-		ProviderUserID: providerUserID,
+	if err := auth.LinkIdentity(ctx, tx, newUser.AccountID, profile.Provider, profile.ProviderUserID); err != nil{
+		return IdentityError(c, err)
+	}
+	if err := tx.Commit(); err != nil{
+		return service.SendError(c, 500, err.Error())
 	}
-	if err := oauthUser.Insert(c.Context(), boil.GetContextDB(), boil.Infer()); err != nil {
-        return service.SendError(c, fiber.StatusInternalServerError, "Failed to create OAuth account")
-    }
 
 	return LoginWithAccount(c, &newUser)
 }
 
-// LoginWithAccount issues tokens and saves refresh token to redis
+// IdentityError writes the response for errors returned by the auth identity linking functions.
+func IdentityError(c *fiber.Ctx, err error) error{
+	switch {
+	case errors.Is(err, auth.ErrIdentityLinkedElsewhere):
+		return service.SendError(c, 409, "This account of the provider is already linked to another user")
+	case errors.Is(err, auth.ErrProviderAlreadyLinked):
+		return service.SendError(c, 409, "Another account of this provider is already linked, unlink it first")
+	case errors.Is(err, auth.ErrIdentityNotLinked):
+		return service.SendError(c, 404, "This provider is not linked to your account")
+	case errors.Is(err, auth.ErrLastLoginMethod):
+		return service.SendError(c, 400, "Set a password or link another provider before removing your last login method")
+	default:
+		return service.SendError(c, 500, err.Error())
+	}
+}
+
+// LoginWithAccount finishes a login once the first factor is verified.
+// Accounts with two-factor enabled get a challenge token instead of session tokens.
 func LoginWithAccount(c *fiber.Ctx, acc *models.Account) error{
-	accessToken, refreshToken, jti, err := auth.CreateToken(acc.Username)
+	if !acc.Status{
+		return service.SendError(c, 403, "Account is disabled")
+	}
+	mfaEnabled, err := auth.MFAEnabled(c.Context(), acc.AccountID)
+	if err != nil{
+		return service.SendError(c, 500, err.Error())
+	}
+	if mfaEnabled{
+		challenge, err := auth.CreateMFAChallenge(acc.AccountID)
+		if err != nil{
+			return service.SendError(c, 500, err.Error())
+		}
+		return c.JSON(fiber.Map{
+			"status": "Success",
+			"data": fiber.Map{
+				"mfaRequired": true,
+				"mfaToken": challenge,
+			},
+			"message": "Two-factor authentication required",
+		})
+	}
+	return IssueSession(c, acc, false)
+}
+
+// IssueSession issues tokens and saves refresh token to redis. mfaVerified is true once the second factor was checked.
+func IssueSession(c *fiber.Ctx, acc *models.Account, mfaVerified bool) error{
+	accessToken, refreshToken, jti, err := auth.CreateToken(acc.Username, acc.Role, mfaVerified)
 	if err != nil {
 		return service.SendError(c, 500, "Failed to create tokens")
 	}
@@ -83,7 +143,7 @@ func LoginWithAccount(c *fiber.Ctx, acc *models.Account) error{
 	// Save refresh token into redis (store userAgent and IP as metadata)
 	userAgent := c.Get("User-Agent")
 	ip := c.IP()
-	claims, _ := auth.VerifyToken(refreshToken)
+	claims, _ := auth.VerifyRefreshToken(refreshToken)
 	_ = auth.SaveRefreshToken(jti,refreshToken,acc.Username,userAgent,ip,claims.ExpiresAt.Time)
 
 	//Set refreshToken into httpOnly cookie
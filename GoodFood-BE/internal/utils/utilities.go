@@ -20,7 +20,6 @@ import (
 	"github.com/gofrs/uuid"
 	"google.golang.org/api/option"
 	"google.golang.org/genai"
-	"gopkg.in/gomail.v2"
 )
 
 func Paginate(page, pageSize, totalRecords int) (offset int,totalPage int){
@@ -43,10 +42,7 @@ func Paginate(page, pageSize, totalRecords int) (offset int,totalPage int){
 }
 
 func SendResetPasswordEmail(toEmail string, resetLink string) error{
-	mailer := gomail.NewMessage();
-	mailer.SetHeader("From","williamdang0404@gmail.com")
-	mailer.SetHeader("To",toEmail)
-	mailer.SetHeader("Subject","Reset Your Password")
+	mailer := newMail(toEmail,"Reset Your Password")
 
 	emailBody := fmt.Sprintf(`
 		<div style="font-family: Arial, sans-serif; color: #333; padding: 20px; max-width: 600px; margin: auto; border: 1px solid #ddd; border-radius: 8px;">
@@ -73,16 +69,57 @@ func SendResetPasswordEmail(toEmail string, resetLink string) error{
 	`, resetLink, resetLink, resetLink)
 
 	mailer.SetBody("text/html", emailBody)
-	dialer := gomail.NewDialer("smtp.gmail.com",587,"williamdang0404@gmail.com","yhjd uzhk hhvp zfiq")
+	dialer := NewMailDialer()
+	err := dialer.DialAndSend(mailer);
+	return err;
+}
+
+func SendPasswordChangedEmail(toEmail string) error{
+	mailer := newMail(toEmail,"Your Password Was Changed")
+
+	emailBody := `
+		<div style="font-family: Arial, sans-serif; color: #333; padding: 20px; max-width: 600px; margin: auto; border: 1px solid #ddd; border-radius: 8px;">
+			<h2 style="color: #ff5722;">Xin chào,</h2>
+			<p>Mật khẩu của tài khoản của bạn tại <strong>GoodFood24h</strong> vừa được <strong>thay đổi</strong>. Tất cả các thiết bị đã được đăng xuất.</p>
+			<p>Nếu bạn không thực hiện thay đổi này, hãy đặt lại mật khẩu ngay và liên hệ với chúng tôi.</p>
+
+			<hr style="margin: 30px 0; border: none; border-top: 1px solid #eee;">
+			<p style="font-size: 14px; color: #888;">Email này được gửi từ hệ thống của GoodFood24h. Vui lòng không trả lời lại email này.</p>
+		</div>
+	`
+
+	mailer.SetBody("text/html", emailBody)
+	dialer := NewMailDialer()
+	err := dialer.DialAndSend(mailer);
+	return err;
+}
+
+func SendOTPEmail(toEmail string, code string) error{
+	mailer := newMail(toEmail,"Your Verification Code")
+
+	emailBody := fmt.Sprintf(`
+		<div style="font-family: Arial, sans-serif; color: #333; padding: 20px; max-width: 600px; margin: auto; border: 1px solid #ddd; border-radius: 8px;">
+			<h2 style="color: #ff5722;">Xin chào,</h2>
+			<p>Mã xác thực của bạn tại <strong>GoodFood24h</strong> là:</p>
+
+			<div style="text-align: center; margin: 30px 0;">
+				<span style="font-size: 32px; letter-spacing: 8px; font-weight: bold; color: #ff5722;">%s</span>
+			</div>
+
+			<p>Mã có hiệu lực trong 5 phút. Không chia sẻ mã này với bất kỳ ai.</p>
+			<hr style="margin: 30px 0; border: none; border-top: 1px solid #eee;">
+			<p style="font-size: 14px; color: #888;">Email này được gửi từ hệ thống của GoodFood24h. Vui lòng không trả lời lại email này.</p>
+		</div>
+	`, code)
+
+	mailer.SetBody("text/html", emailBody)
+	dialer := NewMailDialer()
 	err := dialer.DialAndSend(mailer);
 	return err;
 }
 
 func SendMessageCustomerSent(fromEmail string, message string) error {
-	mailer := gomail.NewMessage()
-	mailer.SetHeader("From", "williamdang0404@gmail.com")
-	mailer.SetHeader("To", "williamdang0404@gmail.com")
-	mailer.SetHeader("Subject", "📩 Contact Message from Customer")
+	mailer := newMail(MailSender(), "📩 Contact Message from Customer")
 
 	emailBody := fmt.Sprintf(`
 		<div style="font-family: Arial, sans-serif; color: #333; padding: 20px; max-width: 600px; margin: auto; border: 1px solid #ddd; border-radius: 8px;">
@@ -100,12 +137,39 @@ func SendMessageCustomerSent(fromEmail string, message string) error {
 
 	mailer.SetBody("text/html", emailBody)
 	
-	dialer := gomail.NewDialer("smtp.gmail.com", 587, "williamdang0404@gmail.com", "yhjd uzhk hhvp zfiq")
+	dialer := NewMailDialer()
 
 	err := dialer.DialAndSend(mailer)
 	return err
 }
 
+func SendVerifyEmail(toEmail string, verifyLink string) error{
+	mailer := newMail(toEmail,"Verify Your Email")
+
+	emailBody := fmt.Sprintf(`
+		<div style="font-family: Arial, sans-serif; color: #333; padding: 20px; max-width: 600px; margin: auto; border: 1px solid #ddd; border-radius: 8px;">
+			<h2 style="color: #ff5722;">Xin chào,</h2>
+			<p>Vui lòng <strong>xác thực địa chỉ email</strong> của tài khoản của bạn tại <strong>GoodFood24h</strong>.</p>
+			<p>Liên kết có hiệu lực trong 24 giờ. Nếu bạn không tạo tài khoản, bạn có thể <em>bỏ qua email này</em>.</p>
+
+			<div style="text-align: center; margin: 30px 0;">
+				<a href="%s" style="background-color: #ff5722; color: white; padding: 12px 24px; border-radius: 5px; text-decoration: none; font-weight: bold;">Xác thực email</a>
+			</div>
+
+			<p>Hoặc bạn có thể sao chép và dán đường dẫn sau vào trình duyệt:</p>
+			<p style="word-break: break-all;"><a href="%s">%s</a></p>
+
+			<hr style="margin: 30px 0; border: none; border-top: 1px solid #eee;">
+			<p style="font-size: 14px; color: #888;">Email này được gửi từ hệ thống của GoodFood24h. Vui lòng không trả lời lại email này.</p>
+		</div>
+	`, verifyLink, verifyLink, verifyLink)
+
+	mailer.SetBody("text/html", emailBody)
+	dialer := NewMailDialer()
+	err := dialer.DialAndSend(mailer);
+	return err;
+}
+
 
 func GetCache(key string, target any)(bool,error){
 	cached, err := redisdatabase.Client.Get(redisdatabase.Ctx,key).Result();
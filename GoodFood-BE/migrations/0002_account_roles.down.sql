@@ -0,0 +1,6 @@
+ALTER TABLE ONLY public.account
+    DROP CONSTRAINT IF EXISTS account_role_check;
+
+ALTER TABLE public.account
+    ALTER COLUMN role TYPE boolean
+    USING (role <> 'customer');
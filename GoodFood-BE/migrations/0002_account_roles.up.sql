@@ -0,0 +1,11 @@
+--
+-- Replace the boolean admin flag on account with a named role.
+-- Existing admins keep full access as superadmin, everyone else becomes a customer.
+--
+
+ALTER TABLE public.account
+    ALTER COLUMN role TYPE character varying(20)
+    USING (CASE WHEN role THEN 'superadmin' ELSE 'customer' END);
+
+ALTER TABLE ONLY public.account
+    ADD CONSTRAINT account_role_check CHECK (role IN ('customer', 'staff', 'manager', 'superadmin'));
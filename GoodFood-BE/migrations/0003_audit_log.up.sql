@@ -0,0 +1,20 @@
+--
+-- Append-only log of security and administrative events.
+-- actor columns are null for events raised by the system itself (e.g. an automatic lockout).
+--
+
+CREATE TABLE public.audit_log (
+    "auditLogID" bigserial PRIMARY KEY,
+    "actorID" integer REFERENCES public.account("accountID") ON DELETE SET NULL,
+    "actorUsername" character varying(255),
+    action character varying(100) NOT NULL,
+    "entityType" character varying(50) NOT NULL,
+    "entityID" character varying(100),
+    metadata jsonb,
+    ip character varying(64),
+    "userAgent" text,
+    "createdAt" timestamp with time zone DEFAULT now() NOT NULL
+);
+
+CREATE INDEX audit_log_created_at_idx ON public.audit_log ("createdAt");
+CREATE INDEX audit_log_entity_idx ON public.audit_log ("entityType", "entityID");
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS public.mfa_recovery_code;
+DROP TABLE IF EXISTS public.account_mfa;
@@ -0,0 +1,23 @@
+--
+-- TOTP two-factor authentication.
+-- secret is encrypted by the application; "lastUsedStep" blocks replaying a code inside its time window.
+-- Recovery codes are only stored as SHA-256 hashes and are single-use.
+--
+
+CREATE TABLE public.account_mfa (
+    "accountID" integer PRIMARY KEY REFERENCES public.account("accountID") ON DELETE CASCADE,
+    secret text NOT NULL,
+    enabled boolean DEFAULT false NOT NULL,
+    "lastUsedStep" bigint DEFAULT 0 NOT NULL,
+    "createdAt" timestamp with time zone DEFAULT now() NOT NULL,
+    "confirmedAt" timestamp with time zone
+);
+
+CREATE TABLE public.mfa_recovery_code (
+    "recoveryCodeID" bigserial PRIMARY KEY,
+    "accountID" integer NOT NULL REFERENCES public.account("accountID") ON DELETE CASCADE,
+    "codeHash" character(64) NOT NULL,
+    "usedAt" timestamp with time zone
+);
+
+CREATE INDEX mfa_recovery_code_account_idx ON public.mfa_recovery_code ("accountID");
@@ -0,0 +1 @@
+ALTER TABLE ONLY public.oauth_account DROP CONSTRAINT IF EXISTS "oauth_account_accountID_provider_key";
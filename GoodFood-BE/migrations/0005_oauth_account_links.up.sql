@@ -0,0 +1,13 @@
+--
+-- One linked identity per provider and account, on top of the existing UNIQUE (provider, "providerUserID").
+-- Older duplicates are collapsed to the first link before the constraint is added.
+--
+
+DELETE FROM public.oauth_account o
+USING public.oauth_account keep
+WHERE o."accountID" = keep."accountID"
+  AND o.provider = keep.provider
+  AND o."oauthID" > keep."oauthID";
+
+ALTER TABLE ONLY public.oauth_account
+    ADD CONSTRAINT "oauth_account_accountID_provider_key" UNIQUE ("accountID", provider);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS public.audit_log_action_idx;
+DROP INDEX IF EXISTS public.audit_log_actor_idx;
+
+ALTER TABLE public.audit_log
+    DROP COLUMN IF EXISTS changes,
+    DROP COLUMN IF EXISTS after,
+    DROP COLUMN IF EXISTS before;
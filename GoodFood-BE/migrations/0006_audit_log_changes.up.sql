@@ -0,0 +1,12 @@
+--
+-- Before/after snapshots of the audited entity and the fields that changed between them.
+-- changes maps each changed field to {"from": ..., "to": ...}; sensitive fields are redacted by the application.
+--
+
+ALTER TABLE public.audit_log
+    ADD COLUMN before jsonb,
+    ADD COLUMN after jsonb,
+    ADD COLUMN changes jsonb;
+
+CREATE INDEX audit_log_actor_idx ON public.audit_log ("actorID", "createdAt");
+CREATE INDEX audit_log_action_idx ON public.audit_log (action, "createdAt");
@@ -0,0 +1,16 @@
+--
+-- Personal data exports requested by customers. The archive is built by the worker under EXPORT_DIR
+-- and can be downloaded until "expiresAt", after which the file is removed and the row marked expired.
+--
+
+CREATE TABLE public.data_export (
+    "exportID" uuid PRIMARY KEY,
+    "accountID" integer NOT NULL REFERENCES public.account("accountID") ON DELETE CASCADE,
+    status character varying(20) DEFAULT 'pending' NOT NULL,
+    error text,
+    "createdAt" timestamp with time zone DEFAULT now() NOT NULL,
+    "completedAt" timestamp with time zone,
+    "expiresAt" timestamp with time zone
+);
+
+CREATE INDEX data_export_account_idx ON public.data_export ("accountID", "createdAt");
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS public.api_key_usage;
+DROP TABLE IF EXISTS public.api_key;
@@ -0,0 +1,28 @@
+--
+-- API keys for machine clients such as POS terminals and partner integrations.
+-- Only the SHA-256 hash of a key is stored; prefix is the public part of the key used to look it up.
+-- An empty "allowedIPs" accepts any address. Usage is counted per key and per day in api_key_usage.
+--
+
+CREATE TABLE public.api_key (
+    "apiKeyID" serial PRIMARY KEY,
+    name character varying(100) NOT NULL,
+    prefix character varying(16) NOT NULL UNIQUE,
+    "keyHash" character(64) NOT NULL,
+    scopes text[] NOT NULL,
+    "allowedIPs" text[] DEFAULT '{}' NOT NULL,
+    "expiresAt" timestamp with time zone,
+    "createdBy" integer REFERENCES public.account("accountID") ON DELETE SET NULL,
+    "createdAt" timestamp with time zone DEFAULT now() NOT NULL,
+    "revokedAt" timestamp with time zone,
+    "lastUsedAt" timestamp with time zone,
+    "lastUsedIP" character varying(64),
+    "usageCount" bigint DEFAULT 0 NOT NULL
+);
+
+CREATE TABLE public.api_key_usage (
+    "apiKeyID" integer NOT NULL REFERENCES public.api_key("apiKeyID") ON DELETE CASCADE,
+    day date NOT NULL,
+    requests bigint DEFAULT 0 NOT NULL,
+    PRIMARY KEY ("apiKeyID", day)
+);
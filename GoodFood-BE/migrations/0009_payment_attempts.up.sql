@@ -0,0 +1,22 @@
+--
+-- Online payment attempts of invoices. Each attempt gets its own random "txnRef", sent to the payment
+-- provider as the merchant reference, so an invoice can be paid again after a failed or abandoned attempt.
+-- Pending attempts past "expiresAt" are marked expired; a late success notification still completes them.
+--
+
+CREATE TABLE public.payment_attempt (
+    "attemptID" uuid PRIMARY KEY,
+    "invoiceID" integer NOT NULL REFERENCES public.invoice("invoiceID") ON DELETE CASCADE,
+    provider character varying(20) NOT NULL,
+    "txnRef" character varying(32) NOT NULL UNIQUE,
+    amount bigint NOT NULL,
+    status character varying(20) DEFAULT 'pending' NOT NULL,
+    "responseCode" character varying(10),
+    "transactionNo" character varying(255),
+    "createdAt" timestamp with time zone DEFAULT now() NOT NULL,
+    "expiresAt" timestamp with time zone NOT NULL,
+    "completedAt" timestamp with time zone
+);
+
+CREATE INDEX payment_attempt_invoice_idx ON public.payment_attempt ("invoiceID", "createdAt");
+CREATE INDEX payment_attempt_pending_idx ON public.payment_attempt ("expiresAt") WHERE status = 'pending';
@@ -0,0 +1,22 @@
+--
+-- Refunds of online payments, requested by admins on cancelled orders. A refund belongs to the successful
+-- transaction it gives money back from; "requestID" is the vnp_RequestId sent to VNPay.
+-- A refund stays pending when VNPay did not answer, and counts against the refundable amount until resolved.
+--
+
+CREATE TABLE public.refund (
+    "refundID" serial PRIMARY KEY,
+    "transactionID" integer NOT NULL REFERENCES public.transaction("transactionID") ON DELETE CASCADE,
+    "invoiceID" integer NOT NULL REFERENCES public.invoice("invoiceID") ON DELETE CASCADE,
+    amount bigint NOT NULL CHECK (amount > 0),
+    status character varying(20) DEFAULT 'pending' NOT NULL,
+    "requestID" character varying(32) NOT NULL UNIQUE,
+    "responseCode" character varying(10),
+    message text,
+    "createdBy" integer REFERENCES public.account("accountID") ON DELETE SET NULL,
+    "createdAt" timestamp with time zone DEFAULT now() NOT NULL,
+    "completedAt" timestamp with time zone
+);
+
+CREATE INDEX refund_transaction_idx ON public.refund ("transactionID");
+CREATE INDEX refund_invoice_idx ON public.refund ("invoiceID", "createdAt");
@@ -0,0 +1,5 @@
+UPDATE public.payment_attempt SET provider = lower(provider);
+
+ALTER TABLE public.invoice DROP COLUMN IF EXISTS "paymentMethodCode";
+
+DROP TABLE IF EXISTS public.payment_method;
@@ -0,0 +1,24 @@
+--
+-- Payment methods offered at checkout. A method is usable when it is enabled here and its provider is
+-- registered by the application. invoice."paymentMethodCode" replaces the boolean "paymentMethod", which is
+-- kept in sync for older readers (true for cash on delivery).
+--
+
+CREATE TABLE public.payment_method (
+    code character varying(20) PRIMARY KEY,
+    name character varying(100) NOT NULL,
+    enabled boolean DEFAULT true NOT NULL,
+    "sortOrder" integer DEFAULT 0 NOT NULL
+);
+
+INSERT INTO public.payment_method (code, name, "sortOrder") VALUES
+    ('COD', 'Cash on delivery', 1),
+    ('VNPAY', 'VNPay', 2);
+
+ALTER TABLE public.invoice
+    ADD COLUMN "paymentMethodCode" character varying(20) DEFAULT 'COD' NOT NULL
+    REFERENCES public.payment_method(code);
+
+UPDATE public.invoice SET "paymentMethodCode" = 'VNPAY' WHERE NOT "paymentMethod";
+
+UPDATE public.payment_attempt SET provider = upper(provider);
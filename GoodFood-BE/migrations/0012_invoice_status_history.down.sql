@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS public.invoice_status_history;
+ALTER TABLE public.invoice_status DROP COLUMN IF EXISTS code;
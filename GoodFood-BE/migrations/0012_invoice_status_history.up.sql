@@ -0,0 +1,44 @@
+--
+-- Statuses get a stable code, which the application looks them up by instead of their ids or display names.
+-- Existing rows are matched by display name; the migration fails on a status it does not recognize.
+--
+
+ALTER TABLE public.invoice_status ADD COLUMN code character varying(30);
+
+UPDATE public.invoice_status SET code = CASE "statusName"
+    WHEN 'Order Placed' THEN 'placed'
+    WHEN 'Order Confirmed' THEN 'confirmed'
+    WHEN 'Order Processing' THEN 'processing'
+    WHEN 'Shipping' THEN 'shipping'
+    WHEN 'Delivered' THEN 'delivered'
+    WHEN 'Cancelled' THEN 'cancelled'
+END;
+
+ALTER TABLE public.invoice_status ALTER COLUMN code SET NOT NULL;
+ALTER TABLE public.invoice_status ADD CONSTRAINT invoice_status_code_key UNIQUE (code);
+
+--
+-- Every status change of an order: who moved it from which status to which, and why.
+-- "fromStatusID" is null for the placement of the order. "actorRole" is customer, admin or system;
+-- actor columns are null for changes made by the system itself (e.g. a carrier webhook).
+-- Orders placed before this table existed get their placement entry back-filled.
+--
+
+CREATE TABLE public.invoice_status_history (
+    "historyID" bigserial PRIMARY KEY,
+    "invoiceID" integer NOT NULL REFERENCES public.invoice("invoiceID") ON DELETE CASCADE,
+    "fromStatusID" integer REFERENCES public.invoice_status("invoiceStatusID") ON DELETE CASCADE,
+    "toStatusID" integer NOT NULL REFERENCES public.invoice_status("invoiceStatusID") ON DELETE CASCADE,
+    "actorRole" character varying(20) NOT NULL,
+    "actorID" integer REFERENCES public.account("accountID") ON DELETE SET NULL,
+    "actorUsername" character varying(255),
+    reason text,
+    "createdAt" timestamp with time zone DEFAULT now() NOT NULL
+);
+
+CREATE INDEX invoice_status_history_invoice_idx ON public.invoice_status_history ("invoiceID", "createdAt");
+
+INSERT INTO public.invoice_status_history ("invoiceID", "toStatusID", "actorRole", "actorID", "createdAt")
+SELECT i."invoiceID", s."invoiceStatusID", 'customer', i."accountID", i."createdAt"
+FROM public.invoice i
+JOIN public.invoice_status s ON s.code = 'placed';
@@ -0,0 +1,9 @@
+ALTER TABLE public.transaction ALTER COLUMN amount TYPE real;
+
+ALTER TABLE public.invoice_detail ALTER COLUMN price TYPE real;
+
+ALTER TABLE public.invoice
+    ALTER COLUMN "shippingFee" TYPE real,
+    ALTER COLUMN "totalPrice" TYPE real;
+
+ALTER TABLE public.product ALTER COLUMN price TYPE real;
@@ -0,0 +1,17 @@
+--
+-- Amounts are whole VND stored as bigint. real only keeps about 7 significant digits, so large totals
+-- were silently rounded; values already stored are rounded to the nearest dong.
+--
+
+ALTER TABLE public.product
+    ALTER COLUMN price TYPE bigint USING round(price)::bigint;
+
+ALTER TABLE public.invoice
+    ALTER COLUMN "shippingFee" TYPE bigint USING round("shippingFee")::bigint,
+    ALTER COLUMN "totalPrice" TYPE bigint USING round("totalPrice")::bigint;
+
+ALTER TABLE public.invoice_detail
+    ALTER COLUMN price TYPE bigint USING round(price)::bigint;
+
+ALTER TABLE public.transaction
+    ALTER COLUMN amount TYPE bigint USING round(amount)::bigint;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS public.shipping_zone_area;
+DROP TABLE IF EXISTS public.shipping_zone;
+DROP TABLE IF EXISTS public.shipping_rate;
@@ -0,0 +1,43 @@
+--
+-- Shipping fees are computed from the weight of the order (product.weight is in grams) and the zone of the
+-- delivery address: (baseFee + perKgFee for every started kilogram above includedGrams) scaled by the
+-- multiplier of the zone. Orders reaching the free shipping threshold of their zone, or the global one when
+-- the zone has none, ship for free; a threshold of 0 never applies.
+--
+-- A zone covers provinces, districts or wards. The most specific area matching an address wins, and
+-- addresses outside every area belong to the DEFAULT zone.
+--
+
+CREATE TABLE public.shipping_rate (
+    "rateID" integer DEFAULT 1 PRIMARY KEY CHECK ("rateID" = 1),
+    "baseFee" bigint NOT NULL CHECK ("baseFee" >= 0),
+    "includedGrams" integer DEFAULT 0 NOT NULL CHECK ("includedGrams" >= 0),
+    "perKgFee" bigint DEFAULT 0 NOT NULL CHECK ("perKgFee" >= 0),
+    "freeShippingThreshold" bigint DEFAULT 0 NOT NULL CHECK ("freeShippingThreshold" >= 0),
+    "updatedAt" timestamp without time zone DEFAULT now() NOT NULL
+);
+
+-- The flat fee charged so far
+INSERT INTO public.shipping_rate ("baseFee") VALUES (30000);
+
+CREATE TABLE public.shipping_zone (
+    code character varying(20) PRIMARY KEY,
+    name character varying(100) NOT NULL,
+    "multiplierPercent" integer DEFAULT 100 NOT NULL CHECK ("multiplierPercent" > 0),
+    "freeShippingThreshold" bigint CHECK ("freeShippingThreshold" >= 0)
+);
+
+INSERT INTO public.shipping_zone (code, name) VALUES ('DEFAULT', 'Other areas');
+
+CREATE TABLE public.shipping_zone_area (
+    "areaID" serial PRIMARY KEY,
+    "zoneCode" character varying(20) NOT NULL REFERENCES public.shipping_zone(code) ON UPDATE CASCADE ON DELETE CASCADE,
+    "provinceID" integer NOT NULL REFERENCES public.province("provinceID") ON DELETE CASCADE,
+    "districtID" integer REFERENCES public.district("districtID") ON DELETE CASCADE,
+    "wardID" integer REFERENCES public.ward("wardID") ON DELETE CASCADE,
+    CHECK ("wardID" IS NULL OR "districtID" IS NOT NULL)
+);
+
+-- An area belongs to one zone at most
+CREATE UNIQUE INDEX shipping_zone_area_unique
+    ON public.shipping_zone_area ("provinceID", COALESCE("districtID", 0), COALESCE("wardID", 0));
@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS public.invoice_tracking_code_unique;
+
+ALTER TABLE public.invoice
+    DROP CONSTRAINT IF EXISTS invoice_tracking_code_check,
+    DROP COLUMN IF EXISTS "shipmentStatus",
+    DROP COLUMN IF EXISTS "trackingCode",
+    DROP COLUMN IF EXISTS "carrierCode",
+    DROP COLUMN IF EXISTS "receiveWardCode",
+    DROP COLUMN IF EXISTS "receiveDistrictCode";
@@ -0,0 +1,17 @@
+--
+-- Orders handed over to a shipping carrier. The destination is kept with the other receive* columns in the
+-- codes carriers identify areas by: "receiveDistrictCode" is district."districtCode" and "receiveWardCode" is
+-- address."wardCode". "carrierCode" and "trackingCode" identify the shipment at the carrier and
+-- "shipmentStatus" is its last known status; carrier notifications move the order along.
+--
+
+ALTER TABLE public.invoice
+    ADD COLUMN "receiveDistrictCode" integer,
+    ADD COLUMN "receiveWardCode" character varying(20),
+    ADD COLUMN "carrierCode" character varying(20),
+    ADD COLUMN "trackingCode" character varying(50),
+    ADD COLUMN "shipmentStatus" character varying(20),
+    ADD CONSTRAINT invoice_tracking_code_check CHECK (("carrierCode" IS NULL) = ("trackingCode" IS NULL));
+
+CREATE UNIQUE INDEX invoice_tracking_code_unique ON public.invoice ("carrierCode", "trackingCode")
+    WHERE "trackingCode" IS NOT NULL;
@@ -33,7 +33,7 @@ type Account struct {
 	Gender        bool        `boil:"gender" json:"gender" toml:"gender" yaml:"gender"`
 	Avatar        null.String `boil:"avatar" json:"avatar,omitempty" toml:"avatar" yaml:"avatar,omitempty"`
 	Status        bool        `boil:"status" json:"status" toml:"status" yaml:"status"`
-	Role          bool        `boil:"role" json:"role" toml:"role" yaml:"role"`
+	Role          string      `boil:"role" json:"role" toml:"role" yaml:"role"`
 	EmailVerified bool        `boil:"emailVerified" json:"emailVerified" toml:"emailVerified" yaml:"emailVerified"`
 
 	R *accountR `boil:"-" json:"-" toml:"-" yaml:"-"`
@@ -223,7 +223,7 @@ var AccountWhere = struct {
 	Gender        whereHelperbool
 	Avatar        whereHelpernull_String
 	Status        whereHelperbool
-	Role          whereHelperbool
+	Role          whereHelperstring
 	EmailVerified whereHelperbool
 }{
 	AccountID:     whereHelperint{field: "\"account\".\"accountID\""},
@@ -235,7 +235,7 @@ var AccountWhere = struct {
 	Gender:        whereHelperbool{field: "\"account\".\"gender\""},
 	Avatar:        whereHelpernull_String{field: "\"account\".\"avatar\""},
 	Status:        whereHelperbool{field: "\"account\".\"status\""},
-	Role:          whereHelperbool{field: "\"account\".\"role\""},
+	Role:          whereHelperstring{field: "\"account\".\"role\""},
 	EmailVerified: whereHelperbool{field: "\"account\".\"emailVerified\""},
 }
 
@@ -1640,7 +1640,7 @@ func testAccountsSelect(t *testing.T) {
 }
 
 var (
-	accountDBTypes = map[string]string{`AccountID`: `integer`, `Username`: `character varying`, `Password`: `character varying`, `PhoneNumber`: `character varying`, `Email`: `character varying`, `FullName`: `character varying`, `Gender`: `boolean`, `Avatar`: `text`, `Status`: `boolean`, `Role`: `boolean`, `EmailVerified`: `boolean`}
+	accountDBTypes = map[string]string{`AccountID`: `integer`, `Username`: `character varying`, `Password`: `character varying`, `PhoneNumber`: `character varying`, `Email`: `character varying`, `FullName`: `character varying`, `Gender`: `boolean`, `Avatar`: `text`, `Status`: `boolean`, `Role`: `character varying`, `EmailVerified`: `boolean`}
 	_              = bytes.MinRead
 )
 
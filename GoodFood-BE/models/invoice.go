@@ -13,6 +13,7 @@ import (
 	"sync"
 	"time"
 
+	"GoodFood-BE/internal/money"
 	"github.com/aarondl/null/v8"
 	"github.com/aarondl/sqlboiler/v4/boil"
 	"github.com/aarondl/sqlboiler/v4/queries"
@@ -25,8 +26,8 @@ import (
 // Invoice is an object representing the database table.
 type Invoice struct {
 	InvoiceID       int         `boil:"invoiceID" json:"invoiceID" toml:"invoiceID" yaml:"invoiceID"`
-	ShippingFee     float32     `boil:"shippingFee" json:"shippingFee" toml:"shippingFee" yaml:"shippingFee"`
-	TotalPrice      float32     `boil:"totalPrice" json:"totalPrice" toml:"totalPrice" yaml:"totalPrice"`
+	ShippingFee     money.Money `boil:"shippingFee" json:"shippingFee" toml:"shippingFee" yaml:"shippingFee"`
+	TotalPrice      money.Money `boil:"totalPrice" json:"totalPrice" toml:"totalPrice" yaml:"totalPrice"`
 	CreatedAt       time.Time   `boil:"createdAt" json:"createdAt" toml:"createdAt" yaml:"createdAt"`
 	PaymentMethod   bool        `boil:"paymentMethod" json:"paymentMethod" toml:"paymentMethod" yaml:"paymentMethod"`
 	Status          bool        `boil:"status" json:"status" toml:"status" yaml:"status"`
@@ -104,28 +105,34 @@ var InvoiceTableColumns = struct {
 
 // Generated where
 
-type whereHelperfloat32 struct{ field string }
+type whereHelpermoney_Money struct{ field string }
 
-func (w whereHelperfloat32) EQ(x float32) qm.QueryMod { return qmhelper.Where(w.field, qmhelper.EQ, x) }
-func (w whereHelperfloat32) NEQ(x float32) qm.QueryMod {
+func (w whereHelpermoney_Money) EQ(x money.Money) qm.QueryMod {
+	return qmhelper.Where(w.field, qmhelper.EQ, x)
+}
+func (w whereHelpermoney_Money) NEQ(x money.Money) qm.QueryMod {
 	return qmhelper.Where(w.field, qmhelper.NEQ, x)
 }
-func (w whereHelperfloat32) LT(x float32) qm.QueryMod { return qmhelper.Where(w.field, qmhelper.LT, x) }
-func (w whereHelperfloat32) LTE(x float32) qm.QueryMod {
+func (w whereHelpermoney_Money) LT(x money.Money) qm.QueryMod {
+	return qmhelper.Where(w.field, qmhelper.LT, x)
+}
+func (w whereHelpermoney_Money) LTE(x money.Money) qm.QueryMod {
 	return qmhelper.Where(w.field, qmhelper.LTE, x)
 }
-func (w whereHelperfloat32) GT(x float32) qm.QueryMod { return qmhelper.Where(w.field, qmhelper.GT, x) }
-func (w whereHelperfloat32) GTE(x float32) qm.QueryMod {
+func (w whereHelpermoney_Money) GT(x money.Money) qm.QueryMod {
+	return qmhelper.Where(w.field, qmhelper.GT, x)
+}
+func (w whereHelpermoney_Money) GTE(x money.Money) qm.QueryMod {
 	return qmhelper.Where(w.field, qmhelper.GTE, x)
 }
-func (w whereHelperfloat32) IN(slice []float32) qm.QueryMod {
+func (w whereHelpermoney_Money) IN(slice []money.Money) qm.QueryMod {
 	values := make([]interface{}, 0, len(slice))
 	for _, value := range slice {
 		values = append(values, value)
 	}
 	return qm.WhereIn(fmt.Sprintf("%s IN ?", w.field), values...)
 }
-func (w whereHelperfloat32) NIN(slice []float32) qm.QueryMod {
+func (w whereHelpermoney_Money) NIN(slice []money.Money) qm.QueryMod {
 	values := make([]interface{}, 0, len(slice))
 	for _, value := range slice {
 		values = append(values, value)
@@ -156,8 +163,8 @@ func (w whereHelpertime_Time) GTE(x time.Time) qm.QueryMod {
 
 var InvoiceWhere = struct {
 	InvoiceID       whereHelperint
-	ShippingFee     whereHelperfloat32
-	TotalPrice      whereHelperfloat32
+	ShippingFee     whereHelpermoney_Money
+	TotalPrice      whereHelpermoney_Money
 	CreatedAt       whereHelpertime_Time
 	PaymentMethod   whereHelperbool
 	Status          whereHelperbool
@@ -170,8 +177,8 @@ var InvoiceWhere = struct {
 	InvoiceStatusID whereHelperint
 }{
 	InvoiceID:       whereHelperint{field: "\"invoice\".\"invoiceID\""},
-	ShippingFee:     whereHelperfloat32{field: "\"invoice\".\"shippingFee\""},
-	TotalPrice:      whereHelperfloat32{field: "\"invoice\".\"totalPrice\""},
+	ShippingFee:     whereHelpermoney_Money{field: "\"invoice\".\"shippingFee\""},
+	TotalPrice:      whereHelpermoney_Money{field: "\"invoice\".\"totalPrice\""},
 	CreatedAt:       whereHelpertime_Time{field: "\"invoice\".\"createdAt\""},
 	PaymentMethod:   whereHelperbool{field: "\"invoice\".\"paymentMethod\""},
 	Status:          whereHelperbool{field: "\"invoice\".\"status\""},
@@ -13,6 +13,7 @@ import (
 	"sync"
 	"time"
 
+	"GoodFood-BE/internal/money"
 	"github.com/aarondl/sqlboiler/v4/boil"
 	"github.com/aarondl/sqlboiler/v4/queries"
 	"github.com/aarondl/sqlboiler/v4/queries/qm"
@@ -23,11 +24,11 @@ import (
 
 // InvoiceDetail is an object representing the database table.
 type InvoiceDetail struct {
-	InvoiceDetailID int     `boil:"invoiceDetailID" json:"invoiceDetailID" toml:"invoiceDetailID" yaml:"invoiceDetailID"`
-	Quantity        int     `boil:"quantity" json:"quantity" toml:"quantity" yaml:"quantity"`
-	Price           float32 `boil:"price" json:"price" toml:"price" yaml:"price"`
-	ProductID       int     `boil:"productID" json:"productID" toml:"productID" yaml:"productID"`
-	InvoiceID       int     `boil:"invoiceID" json:"invoiceID" toml:"invoiceID" yaml:"invoiceID"`
+	InvoiceDetailID int         `boil:"invoiceDetailID" json:"invoiceDetailID" toml:"invoiceDetailID" yaml:"invoiceDetailID"`
+	Quantity        int         `boil:"quantity" json:"quantity" toml:"quantity" yaml:"quantity"`
+	Price           money.Money `boil:"price" json:"price" toml:"price" yaml:"price"`
+	ProductID       int         `boil:"productID" json:"productID" toml:"productID" yaml:"productID"`
+	InvoiceID       int         `boil:"invoiceID" json:"invoiceID" toml:"invoiceID" yaml:"invoiceID"`
 
 	R *invoiceDetailR `boil:"-" json:"-" toml:"-" yaml:"-"`
 	L invoiceDetailL  `boil:"-" json:"-" toml:"-" yaml:"-"`
@@ -66,13 +67,13 @@ var InvoiceDetailTableColumns = struct {
 var InvoiceDetailWhere = struct {
 	InvoiceDetailID whereHelperint
 	Quantity        whereHelperint
-	Price           whereHelperfloat32
+	Price           whereHelpermoney_Money
 	ProductID       whereHelperint
 	InvoiceID       whereHelperint
 }{
 	InvoiceDetailID: whereHelperint{field: "\"invoice_detail\".\"invoiceDetailID\""},
 	Quantity:        whereHelperint{field: "\"invoice_detail\".\"quantity\""},
-	Price:           whereHelperfloat32{field: "\"invoice_detail\".\"price\""},
+	Price:           whereHelpermoney_Money{field: "\"invoice_detail\".\"price\""},
 	ProductID:       whereHelperint{field: "\"invoice_detail\".\"productID\""},
 	InvoiceID:       whereHelperint{field: "\"invoice_detail\".\"invoiceID\""},
 }
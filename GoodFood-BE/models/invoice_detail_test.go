@@ -805,7 +805,7 @@ func testInvoiceDetailsSelect(t *testing.T) {
 }
 
 var (
-	invoiceDetailDBTypes = map[string]string{`InvoiceDetailID`: `integer`, `Quantity`: `integer`, `Price`: `real`, `ProductID`: `integer`, `InvoiceID`: `integer`}
+	invoiceDetailDBTypes = map[string]string{`InvoiceDetailID`: `integer`, `Quantity`: `integer`, `Price`: `bigint`, `ProductID`: `integer`, `InvoiceID`: `integer`}
 	_                    = bytes.MinRead
 )
 
@@ -25,6 +25,7 @@ import (
 type InvoiceStatus struct {
 	InvoiceStatusID int    `boil:"invoiceStatusID" json:"invoiceStatusID" toml:"invoiceStatusID" yaml:"invoiceStatusID"`
 	StatusName      string `boil:"statusName" json:"statusName" toml:"statusName" yaml:"statusName"`
+	Code            string `boil:"code" json:"code" toml:"code" yaml:"code"`
 
 	R *invoiceStatusR `boil:"-" json:"-" toml:"-" yaml:"-"`
 	L invoiceStatusL  `boil:"-" json:"-" toml:"-" yaml:"-"`
@@ -33,17 +34,21 @@ type InvoiceStatus struct {
 var InvoiceStatusColumns = struct {
 	InvoiceStatusID string
 	StatusName      string
+	Code            string
 }{
 	InvoiceStatusID: "invoiceStatusID",
 	StatusName:      "statusName",
+	Code:            "code",
 }
 
 var InvoiceStatusTableColumns = struct {
 	InvoiceStatusID string
 	StatusName      string
+	Code            string
 }{
 	InvoiceStatusID: "invoice_status.invoiceStatusID",
 	StatusName:      "invoice_status.statusName",
+	Code:            "invoice_status.code",
 }
 
 // Generated where
@@ -51,9 +56,11 @@ var InvoiceStatusTableColumns = struct {
 var InvoiceStatusWhere = struct {
 	InvoiceStatusID whereHelperint
 	StatusName      whereHelperstring
+	Code            whereHelperstring
 }{
 	InvoiceStatusID: whereHelperint{field: "\"invoice_status\".\"invoiceStatusID\""},
 	StatusName:      whereHelperstring{field: "\"invoice_status\".\"statusName\""},
+	Code:            whereHelperstring{field: "\"invoice_status\".\"code\""},
 }
 
 // InvoiceStatusRels is where relationship names are stored.
@@ -93,8 +100,8 @@ func (r *invoiceStatusR) GetInvoiceStatusIDInvoices() InvoiceSlice {
 type invoiceStatusL struct{}
 
 var (
-	invoiceStatusAllColumns            = []string{"invoiceStatusID", "statusName"}
-	invoiceStatusColumnsWithoutDefault = []string{"statusName"}
+	invoiceStatusAllColumns            = []string{"invoiceStatusID", "statusName", "code"}
+	invoiceStatusColumnsWithoutDefault = []string{"statusName", "code"}
 	invoiceStatusColumnsWithDefault    = []string{"invoiceStatusID"}
 	invoiceStatusPrimaryKeyColumns     = []string{"invoiceStatusID"}
 	invoiceStatusGeneratedColumns      = []string{"invoiceStatusID"}
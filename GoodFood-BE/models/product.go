@@ -13,6 +13,7 @@ import (
 	"sync"
 	"time"
 
+	"GoodFood-BE/internal/money"
 	"github.com/aarondl/null/v8"
 	"github.com/aarondl/sqlboiler/v4/boil"
 	"github.com/aarondl/sqlboiler/v4/queries"
@@ -26,7 +27,7 @@ import (
 type Product struct {
 	ProductID     int         `boil:"productID" json:"productID" toml:"productID" yaml:"productID"`
 	ProductName   string      `boil:"productName" json:"productName" toml:"productName" yaml:"productName"`
-	Price         float32     `boil:"price" json:"price" toml:"price" yaml:"price"`
+	Price         money.Money `boil:"price" json:"price" toml:"price" yaml:"price"`
 	CoverImage    string      `boil:"coverImage" json:"coverImage" toml:"coverImage" yaml:"coverImage"`
 	Description   null.String `boil:"description" json:"description,omitempty" toml:"description" yaml:"description,omitempty"`
 	Status        bool        `boil:"status" json:"status" toml:"status" yaml:"status"`
@@ -84,10 +85,39 @@ var ProductTableColumns = struct {
 
 // Generated where
 
+type whereHelperfloat32 struct{ field string }
+
+func (w whereHelperfloat32) EQ(x float32) qm.QueryMod { return qmhelper.Where(w.field, qmhelper.EQ, x) }
+func (w whereHelperfloat32) NEQ(x float32) qm.QueryMod {
+	return qmhelper.Where(w.field, qmhelper.NEQ, x)
+}
+func (w whereHelperfloat32) LT(x float32) qm.QueryMod { return qmhelper.Where(w.field, qmhelper.LT, x) }
+func (w whereHelperfloat32) LTE(x float32) qm.QueryMod {
+	return qmhelper.Where(w.field, qmhelper.LTE, x)
+}
+func (w whereHelperfloat32) GT(x float32) qm.QueryMod { return qmhelper.Where(w.field, qmhelper.GT, x) }
+func (w whereHelperfloat32) GTE(x float32) qm.QueryMod {
+	return qmhelper.Where(w.field, qmhelper.GTE, x)
+}
+func (w whereHelperfloat32) IN(slice []float32) qm.QueryMod {
+	values := make([]interface{}, 0, len(slice))
+	for _, value := range slice {
+		values = append(values, value)
+	}
+	return qm.WhereIn(fmt.Sprintf("%s IN ?", w.field), values...)
+}
+func (w whereHelperfloat32) NIN(slice []float32) qm.QueryMod {
+	values := make([]interface{}, 0, len(slice))
+	for _, value := range slice {
+		values = append(values, value)
+	}
+	return qm.WhereNotIn(fmt.Sprintf("%s NOT IN ?", w.field), values...)
+}
+
 var ProductWhere = struct {
 	ProductID     whereHelperint
 	ProductName   whereHelperstring
-	Price         whereHelperfloat32
+	Price         whereHelpermoney_Money
 	CoverImage    whereHelperstring
 	Description   whereHelpernull_String
 	Status        whereHelperbool
@@ -97,7 +127,7 @@ var ProductWhere = struct {
 }{
 	ProductID:     whereHelperint{field: "\"product\".\"productID\""},
 	ProductName:   whereHelperstring{field: "\"product\".\"productName\""},
-	Price:         whereHelperfloat32{field: "\"product\".\"price\""},
+	Price:         whereHelpermoney_Money{field: "\"product\".\"price\""},
 	CoverImage:    whereHelperstring{field: "\"product\".\"coverImage\""},
 	Description:   whereHelpernull_String{field: "\"product\".\"description\""},
 	Status:        whereHelperbool{field: "\"product\".\"status\""},
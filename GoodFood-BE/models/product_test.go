@@ -1299,7 +1299,7 @@ func testProductsSelect(t *testing.T) {
 }
 
 var (
-	productDBTypes = map[string]string{`ProductID`: `integer`, `ProductName`: `character varying`, `Price`: `real`, `CoverImage`: `character varying`, `Description`: `character varying`, `Status`: `boolean`, `InsertDate`: `date`, `ProductTypeID`: `integer`, `Weight`: `real`}
+	productDBTypes = map[string]string{`ProductID`: `integer`, `ProductName`: `character varying`, `Price`: `bigint`, `CoverImage`: `character varying`, `Description`: `character varying`, `Status`: `boolean`, `InsertDate`: `date`, `ProductTypeID`: `integer`, `Weight`: `real`}
 	_              = bytes.MinRead
 )
 
@@ -13,6 +13,7 @@ import (
 	"sync"
 	"time"
 
+	"GoodFood-BE/internal/money"
 	"github.com/aarondl/sqlboiler/v4/boil"
 	"github.com/aarondl/sqlboiler/v4/queries"
 	"github.com/aarondl/sqlboiler/v4/queries/qm"
@@ -23,15 +24,15 @@ import (
 
 // Transaction is an object representing the database table.
 type Transaction struct {
-	TransactionID   int       `boil:"transactionID" json:"transactionID" toml:"transactionID" yaml:"transactionID"`
-	TransactionCode string    `boil:"transactionCode" json:"transactionCode" toml:"transactionCode" yaml:"transactionCode"`
-	CardNumber      string    `boil:"cardNumber" json:"cardNumber" toml:"cardNumber" yaml:"cardNumber"`
-	FullName        string    `boil:"fullName" json:"fullName" toml:"fullName" yaml:"fullName"`
-	TransactionDate time.Time `boil:"transactionDate" json:"transactionDate" toml:"transactionDate" yaml:"transactionDate"`
-	Status          bool      `boil:"status" json:"status" toml:"status" yaml:"status"`
-	Amount          float32   `boil:"amount" json:"amount" toml:"amount" yaml:"amount"`
-	AccountID       int       `boil:"accountID" json:"accountID" toml:"accountID" yaml:"accountID"`
-	InvoiceID       int       `boil:"invoiceID" json:"invoiceID" toml:"invoiceID" yaml:"invoiceID"`
+	TransactionID   int         `boil:"transactionID" json:"transactionID" toml:"transactionID" yaml:"transactionID"`
+	TransactionCode string      `boil:"transactionCode" json:"transactionCode" toml:"transactionCode" yaml:"transactionCode"`
+	CardNumber      string      `boil:"cardNumber" json:"cardNumber" toml:"cardNumber" yaml:"cardNumber"`
+	FullName        string      `boil:"fullName" json:"fullName" toml:"fullName" yaml:"fullName"`
+	TransactionDate time.Time   `boil:"transactionDate" json:"transactionDate" toml:"transactionDate" yaml:"transactionDate"`
+	Status          bool        `boil:"status" json:"status" toml:"status" yaml:"status"`
+	Amount          money.Money `boil:"amount" json:"amount" toml:"amount" yaml:"amount"`
+	AccountID       int         `boil:"accountID" json:"accountID" toml:"accountID" yaml:"accountID"`
+	InvoiceID       int         `boil:"invoiceID" json:"invoiceID" toml:"invoiceID" yaml:"invoiceID"`
 
 	R *transactionR `boil:"-" json:"-" toml:"-" yaml:"-"`
 	L transactionL  `boil:"-" json:"-" toml:"-" yaml:"-"`
@@ -90,7 +91,7 @@ var TransactionWhere = struct {
 	FullName        whereHelperstring
 	TransactionDate whereHelpertime_Time
 	Status          whereHelperbool
-	Amount          whereHelperfloat32
+	Amount          whereHelpermoney_Money
 	AccountID       whereHelperint
 	InvoiceID       whereHelperint
 }{
@@ -100,7 +101,7 @@ var TransactionWhere = struct {
 	FullName:        whereHelperstring{field: "\"transaction\".\"fullName\""},
 	TransactionDate: whereHelpertime_Time{field: "\"transaction\".\"transactionDate\""},
 	Status:          whereHelperbool{field: "\"transaction\".\"status\""},
-	Amount:          whereHelperfloat32{field: "\"transaction\".\"amount\""},
+	Amount:          whereHelpermoney_Money{field: "\"transaction\".\"amount\""},
 	AccountID:       whereHelperint{field: "\"transaction\".\"accountID\""},
 	InvoiceID:       whereHelperint{field: "\"transaction\".\"invoiceID\""},
 }
@@ -805,7 +805,7 @@ func testTransactionsSelect(t *testing.T) {
 }
 
 var (
-	transactionDBTypes = map[string]string{`TransactionID`: `integer`, `TransactionCode`: `character varying`, `CardNumber`: `character varying`, `FullName`: `character varying`, `TransactionDate`: `date`, `Status`: `boolean`, `Amount`: `real`, `AccountID`: `integer`, `InvoiceID`: `integer`}
+	transactionDBTypes = map[string]string{`TransactionID`: `integer`, `TransactionCode`: `character varying`, `CardNumber`: `character varying`, `FullName`: `character varying`, `TransactionDate`: `date`, `Status`: `boolean`, `Amount`: `bigint`, `AccountID`: `integer`, `InvoiceID`: `integer`}
 	_                  = bytes.MinRead
 )
 
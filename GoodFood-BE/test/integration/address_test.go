@@ -24,11 +24,11 @@ func TestFetchAddress(t *testing.T) {
 		checkData  bool
 	}{
 		{
-			name:       "Missing accountID",
+			name:       "Unauthenticated request",
 			url:        "/address/fetch?page=1",
 			seedData:   func() {},
-			wantStatus: http.StatusBadRequest,
-			wantMsg:    "Did not receive accountID",
+			wantStatus: http.StatusUnauthorized,
+			wantMsg:    "Unauthenticated",
 			checkData:  false,
 		},
 		{
@@ -221,7 +221,7 @@ func TestAddressInsert(t *testing.T) {
 			tt.seedData()
 
 			//Send request
-			req := httptest.NewRequest("POST", "/address/insert", strings.NewReader(tt.body))
+			req := httptest.NewRequest("POST", "/address/insert?accountID=1", strings.NewReader(tt.body))
 			req.Header.Set("Content-Type", "application/json")
 
 			resp, _ := app.Test(req, -1)
@@ -259,11 +259,11 @@ func TestAddressDetail(t *testing.T) {
 		checkData  bool
 	}{
 		{
-			name:       "Missing accountID",
+			name:       "Unauthenticated request",
 			url:        "/address/detail?addressID=1",
 			seedData:   func() {},
-			wantStatus: http.StatusBadRequest,
-			wantMsg:    "Did not receive accountID",
+			wantStatus: http.StatusUnauthorized,
+			wantMsg:    "Unauthenticated",
 			checkData:  false,
 		},
 		{
@@ -340,11 +340,11 @@ func TestAddressUpdate(t *testing.T) {
 		checkData  bool
 	}{
 		{
-			name:       "Missing accountID",
+			name:       "Unauthenticated request",
 			url:        "/address/update?addressID=1",
 			seedData:   func() {},
-			wantStatus: http.StatusBadRequest,
-			wantMsg:    "Did not receive accountID",
+			wantStatus: http.StatusUnauthorized,
+			wantMsg:    "Unauthenticated",
 			checkData:  false,
 		},
 		{
@@ -462,11 +462,11 @@ func TestAddressDelete(t *testing.T) {
 		checkData  bool
 	}{
 		{
-			name:       "Missing accountID",
+			name:       "Unauthenticated request",
 			url:        "/address/delete?addressID=1",
 			seedData:   func() {},
-			wantStatus: http.StatusBadRequest,
-			wantMsg:    "Did not receive accountID",
+			wantStatus: http.StatusUnauthorized,
+			wantMsg:    "Unauthenticated",
 			checkData:  false,
 		},
 		{
@@ -545,11 +545,11 @@ func TestAddressFill(t *testing.T) {
 		checkData  bool
 	}{
 		{
-			name:       "Missing accountID",
+			name:       "Unauthenticated request",
 			url:        "/address/fill?addressID=1",
 			seedData:   func() {},
-			wantStatus: http.StatusBadRequest,
-			wantMsg:    "Did not receive accountID",
+			wantStatus: http.StatusUnauthorized,
+			wantMsg:    "Unauthenticated",
 			checkData:  false,
 		},
 		{
@@ -610,11 +610,11 @@ func TestAddressQuickChange(t *testing.T) {
 		checkData  bool
 	}{
 		{
-			name:       "Missing accountID",
+			name:       "Unauthenticated request",
 			url:        "/address/quickChange?addressID=1&toBeDisabled=2",
 			seedData:   func() {},
-			wantStatus: http.StatusBadRequest,
-			wantMsg:    "Did not receive accountID",
+			wantStatus: http.StatusUnauthorized,
+			wantMsg:    "Unauthenticated",
 			checkData:  false,
 		},
 		{
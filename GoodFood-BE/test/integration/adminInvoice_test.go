@@ -365,15 +365,31 @@ func TestAdminInvoice_Update(t *testing.T) {
 			url:        "/admin/order/update?invoiceID=1",
 			body:       `{"statusName":"NotExists"}`,
 			seedData:   func() {},
-			wantStatus: http.StatusInternalServerError,
+			wantStatus: http.StatusBadRequest,
+			wantMsg:    "Invoice status not found!",
+		},
+		{
+			name:       "Display names are matched exactly",
+			url:        "/admin/order/update?invoiceID=1",
+			body:       `{"statusName":"%"}`,
+			seedData:   func() { SeedData(t, SeedHappyPathInvoice) },
+			wantStatus: http.StatusBadRequest,
 			wantMsg:    "Invoice status not found!",
 		},
+		{
+			name:       "Unknown status code",
+			url:        "/admin/order/update?invoiceID=1",
+			body:       `{"status":"lost"}`,
+			seedData:   func() { SeedData(t, SeedHappyPathInvoice) },
+			wantStatus: http.StatusBadRequest,
+			wantMsg:    "Invalid status code!",
+		},
 		{
 			name:       "Invoice not found",
 			url:        "/admin/order/update?invoiceID=999",
 			body:       `{"statusName":"Order Placed"}`,
 			seedData:   func() { SeedData(t, SeedConfig{InvoiceStatuses: true}) },
-			wantStatus: http.StatusInternalServerError,
+			wantStatus: http.StatusNotFound,
 			wantMsg:    "Invoice not found!",
 		},
 		{
@@ -389,16 +405,40 @@ func TestAdminInvoice_Update(t *testing.T) {
 			},
 		},
 		{
-			name:       "Delivered → Paid (statusID = 5)",
+			name: "Delivered → Paid (statusID = 5)",
+			url:  "/admin/order/update?invoiceID=5",
+			body: `{"statusName":"Delivered"}`,
+			seedData: func() {
+				SeedData(t, SeedHappyPathInvoice)
+				_, err := testdb.Exec(`UPDATE invoice SET "invoiceStatusID" = 4, status = false WHERE "invoiceID" = 5`)
+				assert.NoError(t, err)
+			},
+			wantStatus: http.StatusOK,
+			wantMsg:    "Successfully updated invoice status!",
+			validateData: func(t *testing.T, body map[string]interface{}) {
+				invoice := body["invoice"].(map[string]interface{})
+				assert.Equal(t, float64(5), invoice["invoiceStatusID"])
+				assert.Equal(t, true, invoice["status"])
+			},
+		},
+		{
+			name:       "Skipping statuses is refused",
 			url:        "/admin/order/update?invoiceID=5",
 			body:       `{"statusName":"Delivered"}`,
 			seedData:   func() { SeedData(t, SeedHappyPathInvoice) },
+			wantStatus: http.StatusConflict,
+			wantMsg:    "The order cannot be moved to Delivered from its current status",
+		},
+		{
+			name:       "Status code instead of name",
+			url:        "/admin/order/update?invoiceID=1",
+			body:       `{"status":"confirmed"}`,
+			seedData:   func() { SeedData(t, SeedHappyPathInvoice) },
 			wantStatus: http.StatusOK,
 			wantMsg:    "Successfully updated invoice status!",
 			validateData: func(t *testing.T, body map[string]interface{}) {
 				invoice := body["invoice"].(map[string]interface{})
-				assert.Equal(t, float64(5), invoice["invoiceStatusID"])
-				assert.Equal(t, true, invoice["status"])
+				assert.Equal(t, float64(2), invoice["invoiceStatusID"])
 			},
 		},
 		{
@@ -414,6 +454,14 @@ func TestAdminInvoice_Update(t *testing.T) {
 				assert.Equal(t, "Out of stock", invoice["cancelReason"])
 			},
 		},
+		{
+			name:       "Cancelling without a reason",
+			url:        "/admin/order/update?invoiceID=1",
+			body:       `{"statusName":"Cancelled","cancelReason":"  "}`,
+			seedData:   func() { SeedData(t, SeedHappyPathInvoice) },
+			wantStatus: http.StatusBadRequest,
+			wantMsg:    "Please provide a cancel reason!",
+		},
 	}
 
 	for _, tt := range tests {
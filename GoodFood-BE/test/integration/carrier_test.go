@@ -0,0 +1,209 @@
+package integration
+
+import (
+	"GoodFood-BE/internal/shipping/ghnfake"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useGHN starts a fake GHN and makes it the active carrier.
+func useGHN(t *testing.T) *ghnfake.Server {
+	fake := ghnfake.New("TESTTOKEN", "885")
+	t.Cleanup(fake.Close)
+	t.Setenv("SHIPPING_CARRIER", "GHN")
+	t.Setenv("GHN_API_URL", fake.URL)
+	t.Setenv("GHN_TOKEN", "TESTTOKEN")
+	t.Setenv("GHN_SHOP_ID", "885")
+	t.Setenv("GHN_WEBHOOK_SECRET", "HOOKSECRET")
+	return fake
+}
+
+// placeProcessingOrder places a cash on delivery order of 3 products and moves it to processing.
+func placeProcessingOrder(t *testing.T, app *fiber.App) {
+	resetShipping(t)
+	SeedData(t, SeedCheckout)
+	status, body := sendJSON(t, app, http.MethodPost, "/invoice/pay?accountID=1",
+		`{"items":[{"productID":1,"quantity":3}],"addressID":1,"paymentMethod":"COD"}`)
+	require.Equal(t, http.StatusOK, status, body["message"])
+	for _, s := range []string{"confirmed", "processing"} {
+		status, body = sendJSON(t, app, http.MethodPut, "/admin/order/update?accountID=1&invoiceID=1", `{"status":"`+s+`"}`)
+		require.Equal(t, http.StatusOK, status, body["message"])
+	}
+}
+
+// shipmentColumns returns the carrier, tracking code and shipment status stored on invoice 1.
+func shipmentColumns(t *testing.T) (carrier, trackingCode, status sql.NullString) {
+	err := testdb.QueryRow(`SELECT "carrierCode", "trackingCode", "shipmentStatus" FROM invoice WHERE "invoiceID" = 1`).
+		Scan(&carrier, &trackingCode, &status)
+	assert.NoError(t, err)
+	return
+}
+
+func invoiceStatus(t *testing.T) int {
+	var status int
+	assert.NoError(t, testdb.QueryRow(`SELECT "invoiceStatusID" FROM invoice WHERE "invoiceID" = 1`).Scan(&status))
+	return status
+}
+
+func postWebhook(t *testing.T, app *fiber.App, token string, body []byte) (int, map[string]interface{}) {
+	return sendJSON(t, app, http.MethodPost, "/shipping/webhook/ghn?token="+token, string(body))
+}
+
+func TestCarrierDeliversOrder(t *testing.T) {
+	app := SetupApp()
+	fake := useGHN(t)
+	placeProcessingOrder(t, app)
+
+	//Entering processing handed the order over to GHN
+	carrier, trackingCode, status := shipmentColumns(t)
+	assert.Equal(t, "GHN", carrier.String)
+	assert.Equal(t, "pending", status.String)
+	order, ok := fake.Order(trackingCode.String)
+	require.True(t, ok)
+	assert.Equal(t, "GF-1", order.ClientOrderCode)
+	assert.Equal(t, 760, order.ToDistrictID)
+	assert.Equal(t, "26734", order.ToWardCode)
+	assert.Equal(t, 3600, order.Weight)
+	assert.Equal(t, int64(3*75000+30000), order.CODAmount)
+
+	code, body := sendJSON(t, app, http.MethodPut, "/admin/order/update?accountID=1&invoiceID=1", `{"status":"shipping"}`)
+	assert.Equal(t, http.StatusConflict, code, "the carrier moves the order")
+	assert.Contains(t, body["message"], "cancel its shipment first")
+
+	code, _ = postWebhook(t, app, "GUESS", fake.Webhook(trackingCode.String, "picked", ""))
+	assert.Equal(t, http.StatusUnauthorized, code)
+	assert.Equal(t, 3, invoiceStatus(t))
+
+	code, body = postWebhook(t, app, "HOOKSECRET", fake.Webhook(trackingCode.String, "picked", ""))
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "shipping", body["data"].(map[string]interface{})["orderStatus"])
+	assert.Equal(t, 4, invoiceStatus(t))
+
+	code, _ = postWebhook(t, app, "HOOKSECRET", fake.Webhook(trackingCode.String, "delivered", ""))
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 5, invoiceStatus(t))
+	var paid bool
+	assert.NoError(t, testdb.QueryRow(`SELECT status FROM invoice WHERE "invoiceID" = 1`).Scan(&paid))
+	assert.True(t, paid, "cash on delivery was collected by the carrier")
+
+	//A late notification changes nothing
+	code, _ = postWebhook(t, app, "HOOKSECRET", fake.Webhook(trackingCode.String, "delivering", ""))
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 5, invoiceStatus(t))
+	_, _, status = shipmentColumns(t)
+	assert.Equal(t, "in_transit", status.String)
+
+	var systemChanges int
+	assert.NoError(t, testdb.QueryRow(`
+		SELECT count(*) FROM invoice_status_history WHERE "invoiceID" = 1 AND "actorRole" = 'system'`).Scan(&systemChanges))
+	assert.Equal(t, 2, systemChanges)
+
+	code, _ = postWebhook(t, app, "HOOKSECRET", []byte(`{"OrderCode":"UNKNOWN","Status":"picked"}`))
+	assert.Equal(t, http.StatusNotFound, code)
+}
+
+func TestCarrierMissedNotification(t *testing.T) {
+	app := SetupApp()
+	fake := useGHN(t)
+	placeProcessingOrder(t, app)
+	_, trackingCode, _ := shipmentColumns(t)
+
+	code, _ := postWebhook(t, app, "HOOKSECRET", fake.Webhook(trackingCode.String, "delivered", ""))
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 5, invoiceStatus(t), "the order goes through shipping to delivered")
+}
+
+func TestCarrierReturnsOrder(t *testing.T) {
+	app := SetupApp()
+	fake := useGHN(t)
+	placeProcessingOrder(t, app)
+	_, trackingCode, _ := shipmentColumns(t)
+
+	postWebhook(t, app, "HOOKSECRET", fake.Webhook(trackingCode.String, "picked", ""))
+	code, _ := postWebhook(t, app, "HOOKSECRET", fake.Webhook(trackingCode.String, "returned", "Customer refused the parcel"))
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 6, invoiceStatus(t))
+	var reason sql.NullString
+	assert.NoError(t, testdb.QueryRow(`SELECT "cancelReason" FROM invoice WHERE "invoiceID" = 1`).Scan(&reason))
+	assert.Equal(t, "Returned by GHN: Customer refused the parcel", reason.String)
+}
+
+func TestCancelledOrderCancelsShipment(t *testing.T) {
+	app := SetupApp()
+	fake := useGHN(t)
+	placeProcessingOrder(t, app)
+	_, trackingCode, _ := shipmentColumns(t)
+
+	code, body := sendJSON(t, app, http.MethodPut, "/admin/order/update?accountID=1&invoiceID=1",
+		`{"status":"cancelled","cancelReason":"Out of stock"}`)
+	require.Equal(t, http.StatusOK, code, body["message"])
+
+	order, _ := fake.Order(trackingCode.String)
+	assert.Equal(t, "cancel", order.Status)
+	carrier, tracking, status := shipmentColumns(t)
+	assert.False(t, carrier.Valid)
+	assert.False(t, tracking.Valid)
+	assert.Equal(t, "cancelled", status.String)
+}
+
+func TestAdminShipment(t *testing.T) {
+	app := SetupApp()
+	fake := useGHN(t)
+	placeProcessingOrder(t, app)
+	_, first, _ := shipmentColumns(t)
+
+	code, body := sendJSON(t, app, http.MethodGet, "/admin/order/shipment?accountID=1&invoiceID=1", "")
+	assert.Equal(t, http.StatusOK, code)
+	data := body["data"].(map[string]interface{})
+	assert.Equal(t, first.String, data["trackingCode"])
+	assert.Equal(t, "ready_to_pick", data["tracking"].(map[string]interface{})["carrierStatus"])
+
+	code, _ = sendJSON(t, app, http.MethodPost, "/admin/order/shipment/create?accountID=1&invoiceID=1", "")
+	assert.Equal(t, http.StatusConflict, code, "the order is shipped already")
+
+	code, _ = sendJSON(t, app, http.MethodPost, "/admin/order/shipment/cancel?accountID=1&invoiceID=1", "")
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = sendJSON(t, app, http.MethodGet, "/admin/order/shipment?accountID=1&invoiceID=1", "")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, body = sendJSON(t, app, http.MethodPost, "/admin/order/shipment/create?accountID=1&invoiceID=1", "")
+	require.Equal(t, http.StatusOK, code, body["message"])
+	_, second, _ := shipmentColumns(t)
+	assert.NotEqual(t, first.String, second.String)
+	assert.Equal(t, 2, fake.Orders())
+
+	//Once picked up, GHN refuses to cancel
+	fake.SetStatus(second.String, "picked")
+	code, _ = sendJSON(t, app, http.MethodPost, "/admin/order/shipment/cancel?accountID=1&invoiceID=1", "")
+	assert.Equal(t, http.StatusBadGateway, code)
+}
+
+func TestShippingWithoutCarrier(t *testing.T) {
+	app := SetupApp()
+	t.Setenv("SHIPPING_CARRIER", "")
+	placeProcessingOrder(t, app)
+
+	_, tracking, _ := shipmentColumns(t)
+	assert.False(t, tracking.Valid)
+	code, _ := sendJSON(t, app, http.MethodPost, "/admin/order/shipment/create?accountID=1&invoiceID=1", "")
+	assert.Equal(t, http.StatusConflict, code)
+
+	//Admins move orders shipped by the shop
+	code, _ = sendJSON(t, app, http.MethodPut, "/admin/order/update?accountID=1&invoiceID=1", `{"status":"shipping"}`)
+	assert.Equal(t, http.StatusOK, code)
+}
+
+func TestShippingWebhookUnknownCarrier(t *testing.T) {
+	app := SetupApp()
+	req := httptest.NewRequest(http.MethodPost, "/shipping/webhook/pigeon", strings.NewReader(`{}`))
+	resp, err := app.Test(req, -1)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
@@ -0,0 +1,153 @@
+package integration
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInvoicePay(t *testing.T) {
+	app := SetupApp()
+
+	tests := []struct {
+		name         string
+		url          string
+		body         string
+		seedData     func()
+		wantStatus   int
+		wantMsg      string
+		validateData func(t *testing.T, body map[string]interface{})
+	}{
+		{
+			name:       "Unauthenticated request",
+			url:        "/invoice/pay",
+			body:       `{"items":[{"productID":1,"quantity":1}],"addressID":1,"paymentMethod":"COD"}`,
+			seedData:   func() {},
+			wantStatus: http.StatusUnauthorized,
+			wantMsg:    "Unauthenticated",
+		},
+		{
+			name:       "Empty order",
+			url:        "/invoice/pay?accountID=1",
+			body:       `{"items":[],"addressID":1,"paymentMethod":"COD"}`,
+			seedData:   func() { SeedData(t, SeedCheckout) },
+			wantStatus: http.StatusBadRequest,
+			wantMsg:    "the order has no products",
+		},
+		{
+			name:       "Unknown payment method",
+			url:        "/invoice/pay?accountID=1",
+			body:       `{"items":[{"productID":1,"quantity":1}],"addressID":1,"paymentMethod":"FREE"}`,
+			seedData:   func() { SeedData(t, SeedCheckout) },
+			wantStatus: http.StatusBadRequest,
+			wantMsg:    "unknown payment method",
+		},
+		{
+			name:       "Address of another account",
+			url:        "/invoice/pay?accountID=1",
+			body:       `{"items":[{"productID":1,"quantity":1}],"addressID":99,"paymentMethod":"COD"}`,
+			seedData:   func() { SeedData(t, SeedCheckout) },
+			wantStatus: http.StatusNotFound,
+			wantMsg:    "Delivery address not found",
+		},
+		{
+			name: "Inactive product",
+			url:  "/invoice/pay?accountID=1",
+			body: `{"items":[{"productID":2,"quantity":1}],"addressID":1,"paymentMethod":"COD"}`,
+			seedData: func() {
+				SeedData(t, SeedCheckout)
+				_, err := testdb.Exec(`UPDATE product SET status = false WHERE "productID" = 2`)
+				assert.NoError(t, err)
+			},
+			wantStatus: http.StatusConflict,
+			wantMsg:    "Some products are no longer available: product is unavailable: 2",
+		},
+		{
+			name: "Client prices are ignored",
+			url:  "/invoice/pay?accountID=1",
+			body: `{"items":[{"productID":1,"quantity":2,"price":0},{"productID":3,"quantity":1}],
+				"addressID":1,"paymentMethod":"ONLINE","totalPrice":0,"shippingFee":0}`,
+			seedData: func() {
+				SeedData(t, SeedCheckout)
+				_, err := testdb.Exec(`INSERT INTO cart_detail (quantity, "productID", "accountID") VALUES (2, 1, 1), (1, 4, 1)`)
+				assert.NoError(t, err)
+			},
+			wantStatus: http.StatusOK,
+			wantMsg:    "Successfully created new invoice!",
+			validateData: func(t *testing.T, body map[string]interface{}) {
+				data := body["data"].(map[string]interface{})
+				invoice := data["invoice"].(map[string]interface{})
+				assert.Equal(t, float64(3*75000+30000), invoice["totalPrice"])
+				assert.Equal(t, float64(30000), invoice["shippingFee"])
+				assert.Equal(t, false, invoice["paymentMethod"])
+				assert.Equal(t, "VNPAY", data["paymentMethod"], "ONLINE is the former name of VNPay")
+				assert.Equal(t, false, invoice["status"])
+				assert.Len(t, data["invoiceDetails"], 2)
+
+				var methodCode string
+				err := testdb.QueryRow(`SELECT "paymentMethodCode" FROM invoice WHERE "invoiceID" = $1`, invoice["invoiceID"]).Scan(&methodCode)
+				assert.NoError(t, err)
+				assert.Equal(t, "VNPAY", methodCode)
+
+				var cartItems int
+				err = testdb.QueryRow(`SELECT COUNT(*) FROM cart_detail WHERE "accountID" = 1`).Scan(&cartItems)
+				assert.NoError(t, err)
+				assert.Equal(t, 1, cartItems, "only the ordered products leave the cart")
+			},
+		},
+		{
+			name: "Large totals are exact",
+			url:  "/invoice/pay?accountID=1",
+			body: `{"items":[{"productID":1,"quantity":99}],"addressID":1,"paymentMethod":"COD"}`,
+			seedData: func() {
+				SeedData(t, SeedCheckout)
+				_, err := testdb.Exec(`UPDATE product SET price = 123456789 WHERE "productID" = 1`)
+				assert.NoError(t, err)
+			},
+			wantStatus: http.StatusOK,
+			wantMsg:    "Successfully created new invoice!",
+			validateData: func(t *testing.T, body map[string]interface{}) {
+				invoice := body["data"].(map[string]interface{})["invoice"].(map[string]interface{})
+				assert.Equal(t, float64(99*123456789+30000), invoice["totalPrice"])
+
+				var stored int64
+				err := testdb.QueryRow(`SELECT "totalPrice" FROM invoice WHERE "invoiceID" = $1`, invoice["invoiceID"]).Scan(&stored)
+				assert.NoError(t, err)
+				assert.Equal(t, int64(99*123456789+30000), stored)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.seedData()
+
+			req := httptest.NewRequest("POST", tt.url, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			resp, _ := app.Test(req, -1)
+
+			assert.Equal(t, tt.wantStatus, resp.StatusCode)
+
+			var body map[string]interface{}
+			_ = json.NewDecoder(resp.Body).Decode(&body)
+			assert.Equal(t, tt.wantMsg, body["message"])
+
+			if tt.validateData != nil {
+				tt.validateData(t, body)
+			}
+		})
+	}
+}
+
+func TestInvoicePayVNPAYRemoved(t *testing.T) {
+	app := SetupApp()
+
+	req := httptest.NewRequest("POST", "/invoice/pay/vnpay?accountID=1", strings.NewReader(`{"totalPrice":150000}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, _ := app.Test(req, -1)
+	assert.Equal(t, 410, resp.StatusCode)
+}
@@ -2,15 +2,48 @@ package integration
 
 import (
 	"GoodFood-BE/internal/server/handlers"
+	"GoodFood-BE/models"
+	"encoding/json"
 	"fmt"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"github.com/aarondl/sqlboiler/v4/boil"
 	"github.com/gofiber/fiber/v2"
 	"github.com/stretchr/testify/assert"
 )
 
+// actAsAccount stands in for auth.AuthMiddleware + auth.AccountMiddleware.
+// Requests act as the account given by the accountID query param; requests without it are unauthenticated.
+func actAsAccount(c *fiber.Ctx) error {
+	accountID := c.QueryInt("accountID", 0)
+	if accountID == 0 {
+		return c.Next()
+	}
+	account, err := models.FindAccount(c.Context(), boil.GetContextDB(), accountID)
+	if err != nil {
+		account = &models.Account{AccountID: accountID}
+	}
+	c.Locals("username", account.Username)
+	c.Locals("account", account)
+	return c.Next()
+}
+
+// sendJSON sends body as JSON and returns the status code with the decoded response.
+func sendJSON(t *testing.T, app *fiber.App, method, url, body string) (int, map[string]interface{}) {
+	req := httptest.NewRequest(method, url, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req, -1)
+	assert.NoError(t, err)
+	var decoded map[string]interface{}
+	_ = json.NewDecoder(resp.Body).Decode(&decoded)
+	return resp.StatusCode, decoded
+}
+
 func SetupApp() *fiber.App {
 	app := fiber.New()
+	app.Use(actAsAccount)
 
 	// User
 	app.Post("/user/register", handlers.HandleRegister)
@@ -21,7 +54,10 @@ func SetupApp() *fiber.App {
 	app.Put("/user/update", handlers.HandleUpdateAccount)
 	app.Post("/user/forgot-password/sendOTP", handlers.HandleForgotPassword)
 	app.Get("/user/forgot-password/validate", handlers.ValidateResetToken)
+	app.Post("/user/forgot-password/verifyOTP", handlers.HandleVerifyResetOTP)
 	app.Post("/user/forgot-password/reset", handlers.HandleResetPassword)
+	app.Put("/user/phone/verify", handlers.HandleVerifyPhone)
+	app.Delete("/user/account", handlers.DeleteAccount)
 	app.Post("/user/contact", handlers.HandleContact)
 
 	// Products
@@ -50,13 +86,22 @@ func SetupApp() *fiber.App {
 	app.Put("/address/quickChange", handlers.AddressQuickChange)
 
 	// Invoice
+	app.Post("/invoice/quote", handlers.InvoiceQuote)
 	app.Post("/invoice/pay", handlers.InvoicePay)
-	app.Post("/invoice/pay/vnpay", handlers.InvoicePayVNPAY)
+	app.Post("/invoice/pay/online", handlers.InvoicePayOnline)
+	app.Post("/invoice/pay/vnpay", handlers.InvoicePayVNPAYRemoved)
+	app.Post("/shipping/quote", handlers.ShippingQuote)
+	app.Post("/shipping/webhook/:carrier", handlers.ShippingWebhook)
+	app.Get("/payment/methods", handlers.GetPaymentMethods)
+	app.Get("/payment/:method/return", handlers.PaymentReturn)
+	app.Get("/payment/:method/ipn", handlers.PaymentCallback)
+	app.Post("/payment/:method/ipn", handlers.PaymentCallback)
 
 	// Order history
 	app.Get("/order-history", handlers.GetOrderHistory)
 	app.Put("/order-history/update", handlers.CancelOrder)
 	app.Get("/order-history/details", handlers.GetOrderHistoryDetail)
+	app.Get("/order-history/timeline", handlers.GetOrderHistoryTimeline)
 
 	// Review
 	app.Get("/review", handlers.GetReviewData)
@@ -77,12 +122,27 @@ func SetupApp() *fiber.App {
 	app.Get("/admin/order", handlers.GetAdminInvoice)
 	app.Get("/admin/order/detail", handlers.GetAdminInvoiceDetail)
 	app.Put("/admin/order/update", handlers.UpdateInvoice)
+	app.Get("/admin/order/timeline", handlers.GetAdminInvoiceTimeline)
+	app.Get("/admin/order/payments", handlers.GetAdminInvoicePayments)
+	app.Get("/admin/order/payments/status", handlers.GetAdminInvoicePaymentStatus)
+	app.Post("/admin/order/refund", handlers.AdminInvoiceRefund)
+	app.Post("/admin/order/refund/resolve", handlers.AdminInvoiceRefundResolve)
+	app.Get("/admin/order/shipment", handlers.GetAdminInvoiceShipment)
+	app.Post("/admin/order/shipment/create", handlers.AdminInvoiceShipmentCreate)
+	app.Post("/admin/order/shipment/cancel", handlers.AdminInvoiceShipmentCancel)
+
+	// Sessions
+	app.Get("/sessions", handlers.GetSessions)
+	app.Delete("/sessions/revoke", handlers.RevokeSession)
+	app.Delete("/sessions/revoke-others", handlers.RevokeOtherSessions)
 
 	// Admin user
 	app.Get("/admin/user", handlers.GetAdminUsers)
 	app.Get("/admin/user/detail", handlers.GetAdminUserDetail)
 	app.Post("/admin/user/create", handlers.AdminUserCreate)
 	app.Put("/admin/user/update", handlers.AdminUserUpdate)
+	app.Get("/admin/user/sessions", handlers.GetAdminUserSessions)
+	app.Delete("/admin/user/sessions/revoke", handlers.AdminRevokeUserSession)
 
 	// Admin product type
 	app.Get("/admin/product-type", handlers.GetAdminProductTypes)
@@ -96,6 +156,15 @@ func SetupApp() *fiber.App {
 	app.Post("/admin/product/create", handlers.AdminProductCreate)
 	app.Put("/admin/product/update", handlers.AdminProductUpdate)
 
+	// Admin shipping
+	app.Get("/admin/shipping", handlers.GetAdminShipping)
+	app.Put("/admin/shipping/rates/update", handlers.AdminShippingRatesUpdate)
+	app.Post("/admin/shipping/zone/create", handlers.AdminShippingZoneCreate)
+	app.Put("/admin/shipping/zone/update", handlers.AdminShippingZoneUpdate)
+	app.Delete("/admin/shipping/zone/delete", handlers.AdminShippingZoneDelete)
+	app.Post("/admin/shipping/area/create", handlers.AdminShippingAreaCreate)
+	app.Delete("/admin/shipping/area/delete", handlers.AdminShippingAreaDelete)
+
 	// Admin statistics
 	app.Get("/admin/statistic", handlers.GetAdminStatistics)
 
@@ -118,7 +187,7 @@ func SeedData(t *testing.T, cfg SeedConfig) {
 	if cfg.Accounts != nil && cfg.Accounts.seedAccount {
 		for i := 0; i < cfg.Accounts.numberOfRecords; i++ {
 			_, err := testdb.Exec(`INSERT INTO account (username,password,"phoneNumber",email,"fullName",gender,avatar,status,role,"emailVerified") 
-			VALUES($1, 'pwd', $2, $3, 'Test User', true, '', true, true, true)`,
+			VALUES($1, 'pwd', $2, $3, 'Test User', true, '', true, 'superadmin', true)`,
 				fmt.Sprintf("user%d", i), fmt.Sprintf("00%d", i), fmt.Sprintf("u%d@gmail.com", i),
 			)
 			assert.NoError(t, err)
@@ -161,12 +230,15 @@ func SeedData(t *testing.T, cfg SeedConfig) {
 
 	//Seed data for table invoice status
 	if cfg.InvoiceStatuses{
-		statuses := []string{"Order Placed","Order Confirmed", "Order Processing", "Shipping", "Delivered", "Cancelled"}
+		statuses := [][2]string{
+			{"Order Placed", "placed"}, {"Order Confirmed", "confirmed"}, {"Order Processing", "processing"},
+			{"Shipping", "shipping"}, {"Delivered", "delivered"}, {"Cancelled", "cancelled"},
+		}
         for _, status := range statuses{
             _, err := testdb.Exec(`
                 INSERT INTO invoice_status
-                ("statusName")
-            VALUES($1)`,status)
+                ("statusName", code)
+            VALUES($1, $2)`,status[0],status[1])
             assert.NoError(t, err)
         }
 	}
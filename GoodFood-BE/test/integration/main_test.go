@@ -112,6 +112,7 @@ func TestMain(m *testing.M) {
 
 	// 8) Cleanup
 	_ = testdb.Close()
+	stopRedis()
 	os.Exit(code)
 }
 
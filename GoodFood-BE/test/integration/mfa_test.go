@@ -0,0 +1,110 @@
+package integration
+
+import (
+	"GoodFood-BE/internal/auth"
+	"GoodFood-BE/internal/server/handlers"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupAdminMFA returns an app with an admin route behind the real auth middlewares and the two-factor login.
+func setupAdminMFA(t *testing.T) *fiber.App {
+	useRedis(t)
+	useSigningKeys(t)
+	t.Setenv("ADMIN_2FA_REQUIRED", "true")
+	SeedData(t, SeedConfig{Accounts: &AccountSeed{seedAccount: true, numberOfRecords: 1}})
+	_, err := testdb.Exec(`DELETE FROM account_mfa`)
+	require.NoError(t, err)
+
+	app := fiber.New()
+	app.Get("/admin", auth.AuthMiddleware, auth.RequirePermission(auth.PermAdminAccess), func(c *fiber.Ctx) error {
+		return c.JSON(fiber.Map{"status": "Success"})
+	})
+	app.Post("/user/login/2fa", handlers.HandleLoginMFA)
+	app.Post("/user/refresh-token", handlers.RefreshToken)
+	return app
+}
+
+// enrollMFA turns two-factor on for the account and returns one of its recovery codes.
+func enrollMFA(t *testing.T, accountID int) string {
+	ctx := context.Background()
+	secret, err := auth.BeginMFAEnrollment(ctx, accountID)
+	require.NoError(t, err)
+	code, err := auth.TOTPCode(secret, time.Now())
+	require.NoError(t, err)
+	recoveryCodes, err := auth.ConfirmMFAEnrollment(ctx, accountID, code)
+	require.NoError(t, err)
+	return recoveryCodes[0]
+}
+
+// loginMFA completes the second step of a login and returns the access token and refresh cookie it issued.
+func loginMFA(t *testing.T, app *fiber.App, accountID int, code string) (access, refresh string) {
+	challenge, err := auth.CreateMFAChallenge(accountID)
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPost, "/user/login/2fa",
+		strings.NewReader(fmt.Sprintf(`{"mfaToken":%q,"code":%q}`, challenge, code)))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req, -1)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var body struct {
+		Data struct {
+			AccessToken string `json:"accessToken"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == "refreshToken" {
+			refresh = cookie.Value
+		}
+	}
+	return body.Data.AccessToken, refresh
+}
+
+func TestAdminRequiresMFAEnrollment(t *testing.T) {
+	app := setupAdminMFA(t)
+	access, _ := login(t, "user0", "laptop")
+
+	assert.Equal(t, http.StatusForbidden, sendAs(t, app, http.MethodGet, "/admin", access, ""))
+
+	t.Setenv("ADMIN_2FA_REQUIRED", "")
+	assert.Equal(t, http.StatusOK, sendAs(t, app, http.MethodGet, "/admin", access, ""), "the policy is off by default")
+}
+
+func TestAdminRequiresMFASession(t *testing.T) {
+	app := setupAdminMFA(t)
+	recoveryCode := enrollMFA(t, 1)
+
+	//Two-factor is enabled, but this session only passed the password step
+	access, _ := login(t, "user0", "laptop")
+	assert.Equal(t, http.StatusForbidden, sendAs(t, app, http.MethodGet, "/admin", access, ""))
+
+	access, refresh := loginMFA(t, app, 1, recoveryCode)
+	claims, err := auth.VerifyActiveToken(access, auth.TokenTypeAccess)
+	require.NoError(t, err)
+	assert.True(t, claims.MFA)
+	assert.Equal(t, http.StatusOK, sendAs(t, app, http.MethodGet, "/admin", access, ""))
+
+	//The rotated tokens keep the second factor of the session
+	req := httptest.NewRequest(http.MethodPost, "/user/refresh-token", nil)
+	req.AddCookie(&http.Cookie{Name: "refreshToken", Value: refresh})
+	resp, err := app.Test(req, -1)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var rotated struct {
+		AccessToken string `json:"accessToken"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&rotated))
+	assert.Equal(t, http.StatusOK, sendAs(t, app, http.MethodGet, "/admin", rotated.AccessToken, ""))
+}
@@ -0,0 +1,145 @@
+package integration
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// seedOrders seeds statuses 1 to 6 and three placed invoices of account 1, with a second account.
+func seedOrders(t *testing.T) {
+	_, err := testdb.Exec(`TRUNCATE TABLE invoice_status RESTART IDENTITY CASCADE`)
+	assert.NoError(t, err)
+	SeedData(t, SeedConfig{
+		Accounts:        &AccountSeed{seedAccount: true, numberOfRecords: 2},
+		InvoiceStatuses: true,
+		Invoices:        &InvoiceSeed{seedInvoice: true, numberOfRecords: 3},
+	})
+	_, err = testdb.Exec(`UPDATE invoice SET status = false`)
+	assert.NoError(t, err)
+}
+
+func TestCancelOrder(t *testing.T) {
+	app := SetupApp()
+	tests := []struct {
+		name       string
+		url        string
+		body       string
+		prepare    func()
+		wantStatus int
+		wantMsg    string
+	}{
+		{
+			name:       "Customer cancels a placed order",
+			url:        "/order-history/update?invoiceID=1&accountID=1",
+			wantStatus: 200,
+			wantMsg:    "Successfully canceled the order!",
+		},
+		{
+			name: "Confirmed orders can no longer be cancelled by the customer",
+			url:  "/order-history/update?invoiceID=1&accountID=1",
+			prepare: func() {
+				_, err := testdb.Exec(`UPDATE invoice SET "invoiceStatusID" = 2 WHERE "invoiceID" = 1`)
+				assert.NoError(t, err)
+			},
+			wantStatus: 409,
+			wantMsg:    "Only orders that have just been placed can be cancelled",
+		},
+		{
+			name:       "Order of another account",
+			url:        "/order-history/update?invoiceID=1&accountID=2",
+			wantStatus: 403,
+			wantMsg:    "Invoice belongs to another account!",
+		},
+		{
+			name:       "Unknown order",
+			url:        "/order-history/update?invoiceID=99&accountID=1",
+			wantStatus: 404,
+			wantMsg:    "Invoice not found!",
+		},
+		{
+			name:       "Cancelling without a reason",
+			url:        "/order-history/update?invoiceID=1&accountID=1",
+			body:       `{"cancelReason":""}`,
+			wantStatus: 400,
+			wantMsg:    "Please provide a cancel reason!",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seedOrders(t)
+			if tt.prepare != nil {
+				tt.prepare()
+			}
+			reqBody := tt.body
+			if reqBody == "" {
+				reqBody = `{"cancelReason":"Changed my mind"}`
+			}
+			status, body := sendJSON(t, app, "PUT", tt.url, reqBody)
+			assert.Equal(t, tt.wantStatus, status)
+			assert.Equal(t, tt.wantMsg, body["message"])
+		})
+	}
+}
+
+func TestOrderTimeline(t *testing.T) {
+	app := SetupApp()
+	seedOrders(t)
+
+	for _, statusName := range []string{"Order Confirmed", "Order Processing", "Shipping", "Delivered"} {
+		status, body := sendJSON(t, app, "PUT", "/admin/order/update?invoiceID=1&accountID=1", `{"statusName":"`+statusName+`"}`)
+		assert.Equal(t, 200, status, body["message"])
+	}
+	status, _ := sendJSON(t, app, "PUT", "/admin/order/update?invoiceID=1&accountID=1", `{"statusName":"Cancelled"}`)
+	assert.Equal(t, 409, status, "delivered orders are final")
+
+	status, body := sendJSON(t, app, "GET", "/admin/order/timeline?invoiceID=1", "")
+	assert.Equal(t, 200, status)
+	entries := body["data"].([]interface{})
+	if assert.Len(t, entries, 4) {
+		first := entries[0].(map[string]interface{})
+		assert.Equal(t, "placed", first["fromStatus"])
+		assert.Equal(t, "confirmed", first["toStatus"])
+		assert.Equal(t, "Order Confirmed", first["toStatusName"])
+		assert.Equal(t, "admin", first["actorRole"])
+		assert.Equal(t, "user0", first["actorUsername"])
+		last := entries[3].(map[string]interface{})
+		assert.Equal(t, "delivered", last["toStatus"])
+	}
+	var paid bool
+	assert.NoError(t, testdb.QueryRow(`SELECT status FROM invoice WHERE "invoiceID" = 1`).Scan(&paid))
+	assert.True(t, paid, "delivered orders are paid")
+
+	//Customers see the same changes without the staff member behind them
+	status, body = sendJSON(t, app, "GET", "/order-history/timeline?invoiceID=1&accountID=1", "")
+	assert.Equal(t, 200, status)
+	entries = body["data"].([]interface{})
+	if assert.Len(t, entries, 4) {
+		assert.Nil(t, entries[0].(map[string]interface{})["actorUsername"])
+	}
+
+	status, _ = sendJSON(t, app, "GET", "/order-history/timeline?invoiceID=1&accountID=2", "")
+	assert.Equal(t, 403, status)
+}
+
+func TestCheckoutRecordsPlacement(t *testing.T) {
+	app := SetupApp()
+	_, err := testdb.Exec(`TRUNCATE TABLE invoice_status RESTART IDENTITY CASCADE`)
+	assert.NoError(t, err)
+	SeedData(t, SeedCheckout)
+
+	status, body := sendJSON(t, app, "POST", "/invoice/pay?accountID=1",
+		`{"items":[{"productID":1,"quantity":1}],"addressID":1,"paymentMethod":"COD"}`)
+	assert.Equal(t, 200, status, body["message"])
+
+	status, body = sendJSON(t, app, "GET", "/order-history/timeline?invoiceID=1&accountID=1", "")
+	assert.Equal(t, 200, status)
+	entries := body["data"].([]interface{})
+	if assert.Len(t, entries, 1) {
+		entry := entries[0].(map[string]interface{})
+		assert.Nil(t, entry["fromStatusID"])
+		assert.Equal(t, "placed", entry["toStatus"])
+		assert.Equal(t, "customer", entry["actorRole"])
+	}
+}
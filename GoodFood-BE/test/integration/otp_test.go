@@ -0,0 +1,164 @@
+package integration
+
+import (
+	"GoodFood-BE/internal/auth"
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOTPIssue(t *testing.T) {
+	useRedis(t)
+
+	code, err := auth.GenerateOTP(auth.OTPPurposePhone, "1", "0909999999")
+	require.NoError(t, err)
+	assert.Regexp(t, regexp.MustCompile(`^\d{6}$`), code)
+
+	stored, err := redisClient.HGetAll(context.Background(), "otp:phone:1").Result()
+	require.NoError(t, err)
+	assert.Equal(t, auth.HashToken(code), stored["code"], "only the hash of the code is stored")
+	assert.Equal(t, "0", stored["attempts"])
+	ttl, err := redisClient.TTL(context.Background(), "otp:phone:1").Result()
+	require.NoError(t, err)
+	assert.InDelta(t, auth.OTPTTL.Seconds(), ttl.Seconds(), 5)
+
+	//A new code replaces the previous one
+	newCode, err := auth.GenerateOTP(auth.OTPPurposePhone, "1", "0909999999")
+	require.NoError(t, err)
+	if newCode != code {
+		_, err = auth.VerifyOTP(auth.OTPPurposePhone, "1", code)
+		assert.ErrorIs(t, err, auth.ErrInvalidOTP)
+	}
+	data, err := auth.VerifyOTP(auth.OTPPurposePhone, "1", newCode)
+	assert.NoError(t, err)
+	assert.Equal(t, "0909999999", data)
+}
+
+func TestOTPVerify(t *testing.T) {
+	useRedis(t)
+	code, err := auth.GenerateOTP(auth.OTPPurposePasswordReset, "1", "")
+	require.NoError(t, err)
+
+	_, err = auth.VerifyOTP(auth.OTPPurposePhone, "1", code)
+	assert.ErrorIs(t, err, auth.ErrInvalidOTP, "codes only work for the purpose they were sent for")
+	_, err = auth.VerifyOTP(auth.OTPPurposePasswordReset, "2", code)
+	assert.ErrorIs(t, err, auth.ErrInvalidOTP)
+
+	_, err = auth.VerifyOTP(auth.OTPPurposePasswordReset, "1", code)
+	assert.NoError(t, err)
+	_, err = auth.VerifyOTP(auth.OTPPurposePasswordReset, "1", code)
+	assert.ErrorIs(t, err, auth.ErrInvalidOTP, "a code is used once")
+}
+
+func TestOTPExpiry(t *testing.T) {
+	useRedis(t)
+	code, err := auth.GenerateOTP(auth.OTPPurposePhone, "1", "0909999999")
+	require.NoError(t, err)
+
+	//Let the code run out instead of waiting for OTPTTL
+	require.NoError(t, redisClient.PExpire(context.Background(), "otp:phone:1", time.Millisecond).Err())
+	time.Sleep(10 * time.Millisecond)
+
+	_, err = auth.VerifyOTP(auth.OTPPurposePhone, "1", code)
+	assert.ErrorIs(t, err, auth.ErrInvalidOTP)
+	exists, err := redisClient.Exists(context.Background(), "otp:phone:1").Result()
+	require.NoError(t, err)
+	assert.Zero(t, exists, "verifying an expired code leaves nothing behind")
+}
+
+func TestOTPAttemptLimit(t *testing.T) {
+	useRedis(t)
+	code, err := auth.GenerateOTP(auth.OTPPurposePhone, "1", "0909999999")
+	require.NoError(t, err)
+	wrong := "000000"
+	if code == wrong {
+		wrong = "111111"
+	}
+
+	for i := 0; i < auth.OTPMaxAttempts-1; i++ {
+		_, err = auth.VerifyOTP(auth.OTPPurposePhone, "1", wrong)
+		assert.ErrorIs(t, err, auth.ErrInvalidOTP)
+	}
+	_, err = auth.VerifyOTP(auth.OTPPurposePhone, "1", code)
+	assert.NoError(t, err, "the last allowed attempt still works")
+
+	code, err = auth.GenerateOTP(auth.OTPPurposePhone, "1", "0909999999")
+	require.NoError(t, err)
+	for i := 0; i < auth.OTPMaxAttempts; i++ {
+		_, err = auth.VerifyOTP(auth.OTPPurposePhone, "1", wrong)
+		assert.ErrorIs(t, err, auth.ErrInvalidOTP)
+	}
+	_, err = auth.VerifyOTP(auth.OTPPurposePhone, "1", code)
+	assert.ErrorIs(t, err, auth.ErrOTPTooManyAttempts)
+	_, err = auth.VerifyOTP(auth.OTPPurposePhone, "1", code)
+	assert.ErrorIs(t, err, auth.ErrInvalidOTP, "the code is destroyed")
+}
+
+func TestVerifyPhone(t *testing.T) {
+	useRedis(t)
+	SeedData(t, SeedConfig{Accounts: &AccountSeed{seedAccount: true, numberOfRecords: 2}})
+	app := SetupApp()
+
+	code, err := auth.GenerateOTP(auth.OTPPurposePhone, "1", "0909999999")
+	require.NoError(t, err)
+	status, body := sendJSON(t, app, http.MethodPut, "/user/phone/verify?accountID=1", `{"codeOTP":"999999x"}`)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, "Invalid or expired code", body["message"])
+
+	status, body = sendJSON(t, app, http.MethodPut, "/user/phone/verify?accountID=1", fmt.Sprintf(`{"codeOTP":%q}`, code))
+	require.Equal(t, http.StatusOK, status, body["message"])
+	var phone string
+	require.NoError(t, testdb.QueryRow(`SELECT "phoneNumber" FROM account WHERE "accountID" = 1`).Scan(&phone))
+	assert.Equal(t, "0909999999", phone)
+
+	//The number was taken by another account while the code was pending
+	code, err = auth.GenerateOTP(auth.OTPPurposePhone, "2", "0909999999")
+	require.NoError(t, err)
+	status, _ = sendJSON(t, app, http.MethodPut, "/user/phone/verify?accountID=2", fmt.Sprintf(`{"codeOTP":%q}`, code))
+	assert.Equal(t, http.StatusBadRequest, status)
+}
+
+func TestUpdateAccountKeepsPhone(t *testing.T) {
+	useRedis(t)
+	SeedData(t, SeedConfig{Accounts: &AccountSeed{seedAccount: true, numberOfRecords: 1}})
+	app := SetupApp()
+
+	for _, phone := range []string{"000", "", "  "} {
+		status, body := sendJSON(t, app, http.MethodPut, "/user/update?accountID=1",
+			fmt.Sprintf(`{"fullName":"New Name","email":"u0@gmail.com","phoneNumber":%q}`, phone))
+		require.Equal(t, http.StatusOK, status, body["message"])
+		assert.Equal(t, false, body["phoneVerificationRequired"], "no code is sent for phone %q", phone)
+	}
+
+	keys, err := redisClient.Keys(context.Background(), "otp:*").Result()
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+	var phone, name string
+	require.NoError(t, testdb.QueryRow(`SELECT "phoneNumber", "fullName" FROM account WHERE "accountID" = 1`).Scan(&phone, &name))
+	assert.Equal(t, "000", phone)
+	assert.Equal(t, "New Name", name)
+}
+
+func TestVerifyResetOTP(t *testing.T) {
+	useRedis(t)
+	SeedData(t, SeedConfig{Accounts: &AccountSeed{seedAccount: true, numberOfRecords: 1}})
+	app := SetupApp()
+
+	code, err := auth.GenerateOTP(auth.OTPPurposePasswordReset, "1", "")
+	require.NoError(t, err)
+	status, body := sendJSON(t, app, http.MethodPost, "/user/forgot-password/verifyOTP",
+		fmt.Sprintf(`{"email":"u0@gmail.com","codeOTP":%q}`, code))
+	require.Equal(t, http.StatusOK, status, body["message"])
+	token := body["data"].(map[string]interface{})["token"].(string)
+	assert.Equal(t, http.StatusOK, validateResetToken(t, token), "the code is exchanged for a reset token")
+
+	status, _ = sendJSON(t, app, http.MethodPost, "/user/forgot-password/verifyOTP",
+		fmt.Sprintf(`{"email":"u0@gmail.com","codeOTP":%q}`, code))
+	assert.Equal(t, http.StatusBadRequest, status)
+}
@@ -0,0 +1,132 @@
+package integration
+
+import (
+	"GoodFood-BE/internal/auth"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const resetBody = `{"token":%q,"newPass":"newpassword","confirmPass":"newpassword"}`
+
+func resetPassword(t *testing.T, token string) (int, map[string]interface{}) {
+	return sendJSON(t, SetupApp(), http.MethodPost, "/user/forgot-password/reset", fmt.Sprintf(resetBody, token))
+}
+
+func validateResetToken(t *testing.T, token string) int {
+	code, _ := sendJSON(t, SetupApp(), http.MethodGet, "/user/forgot-password/validate?token="+url.QueryEscape(token), "")
+	return code
+}
+
+func storedPassword(t *testing.T, accountID int) string {
+	var password string
+	require.NoError(t, testdb.QueryRow(`SELECT password FROM account WHERE "accountID" = $1`, accountID).Scan(&password))
+	return password
+}
+
+func TestPasswordResetToken(t *testing.T) {
+	useRedis(t)
+	SeedData(t, SeedConfig{Accounts: &AccountSeed{seedAccount: true, numberOfRecords: 1}})
+
+	token, err := auth.CreatePasswordResetToken(1)
+	require.NoError(t, err)
+	assert.Len(t, token, 43, "32 random bytes, base64url encoded")
+
+	//Only the hash of the token is stored
+	keys, err := redisClient.Keys(context.Background(), "resetPass:*").Result()
+	require.NoError(t, err)
+	for _, key := range keys {
+		assert.NotContains(t, key, token)
+	}
+	ttl, err := redisClient.TTL(context.Background(), "resetPass:token="+auth.HashToken(token)).Result()
+	require.NoError(t, err)
+	assert.InDelta(t, auth.PasswordResetTTL.Seconds(), ttl.Seconds(), 5)
+
+	code, body := sendJSON(t, SetupApp(), http.MethodGet, "/user/forgot-password/validate?token="+url.QueryEscape(token), "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "u0@gmail.com", body["email"])
+	assert.Equal(t, http.StatusOK, validateResetToken(t, token), "validating does not use the token up")
+	assert.Equal(t, http.StatusBadRequest, validateResetToken(t, "guess"))
+
+	//A new link replaces the previous one
+	newToken, err := auth.CreatePasswordResetToken(1)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, validateResetToken(t, token))
+	assert.Equal(t, http.StatusOK, validateResetToken(t, newToken))
+}
+
+func TestPasswordResetSingleUse(t *testing.T) {
+	useRedis(t)
+	SeedData(t, SeedConfig{Accounts: &AccountSeed{seedAccount: true, numberOfRecords: 1}})
+	token, err := auth.CreatePasswordResetToken(1)
+	require.NoError(t, err)
+
+	code, body := sendJSON(t, SetupApp(), http.MethodPost, "/user/forgot-password/reset",
+		fmt.Sprintf(`{"token":%q,"newPass":"newpassword","confirmPass":"different"}`, token))
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "Password does not match!", body["message"])
+	assert.Equal(t, http.StatusOK, validateResetToken(t, token), "invalid input does not use the token up")
+
+	code, body = resetPassword(t, token)
+	require.Equal(t, http.StatusOK, code, body["message"])
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(storedPassword(t, 1)), []byte("newpassword")))
+
+	code, body = resetPassword(t, token)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "Invalid or expired token", body["message"])
+	assert.Equal(t, http.StatusBadRequest, validateResetToken(t, token))
+}
+
+func TestPasswordResetExpiry(t *testing.T) {
+	useRedis(t)
+	SeedData(t, SeedConfig{Accounts: &AccountSeed{seedAccount: true, numberOfRecords: 1}})
+	token, err := auth.CreatePasswordResetToken(1)
+	require.NoError(t, err)
+
+	//Let the token run out instead of waiting for PasswordResetTTL
+	require.NoError(t, redisClient.PExpire(context.Background(), "resetPass:token="+auth.HashToken(token), time.Millisecond).Err())
+	time.Sleep(10 * time.Millisecond)
+
+	assert.Equal(t, http.StatusBadRequest, validateResetToken(t, token))
+	code, _ := resetPassword(t, token)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "pwd", storedPassword(t, 1))
+}
+
+func TestPasswordResetRevokesTokens(t *testing.T) {
+	useRedis(t)
+	useSigningKeys(t)
+	SeedData(t, SeedConfig{Accounts: &AccountSeed{seedAccount: true, numberOfRecords: 2}})
+	access, refresh := login(t, "user0", "laptop")
+	otherAccess, _ := login(t, "user1", "laptop")
+
+	token, err := auth.CreatePasswordResetToken(1)
+	require.NoError(t, err)
+	code, body := resetPassword(t, token)
+	require.Equal(t, http.StatusOK, code, body["message"])
+
+	_, err = auth.VerifyActiveToken(access, auth.TokenTypeAccess)
+	assert.ErrorIs(t, err, auth.ErrTokenRevoked, "devices signed in with the old password are signed out")
+	assert.Empty(t, auth.SessionIDFromRefreshToken(refresh))
+	_, err = auth.VerifyActiveToken(otherAccess, auth.TokenTypeAccess)
+	assert.NoError(t, err)
+}
+
+func TestForgotPasswordUnknownEmail(t *testing.T) {
+	useRedis(t)
+	SeedData(t, SeedConfig{Accounts: &AccountSeed{seedAccount: true, numberOfRecords: 1}})
+
+	code, body := sendJSON(t, SetupApp(), http.MethodPost, "/user/forgot-password/sendOTP", `{"email":"nobody@gmail.com"}`)
+	assert.Equal(t, http.StatusOK, code, "the answer does not tell which emails are registered")
+	assert.Contains(t, body["message"], "If the email is registered")
+	keys, err := redisClient.Keys(context.Background(), "resetPass:*").Result()
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+}
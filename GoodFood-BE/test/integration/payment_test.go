@@ -0,0 +1,372 @@
+package integration
+
+import (
+	"GoodFood-BE/internal/payment"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// vnpayCallback returns the URL VNPay calls for a payment attempt and amount (VND), signed with VNPAY_SECRET.
+func vnpayCallback(path, txnRef, amount, responseCode, transactionNo string) string {
+	status := "00"
+	if responseCode != "00" {
+		status = "02"
+	}
+	params := map[string]string{
+		"vnp_Amount":            amount + "00",
+		"vnp_BankCode":          "NCB",
+		"vnp_OrderInfo":         "Paying for invoice: 1",
+		"vnp_PayDate":           "20251020153000",
+		"vnp_ResponseCode":      responseCode,
+		"vnp_TmnCode":           "GOODFOOD",
+		"vnp_TransactionNo":     transactionNo,
+		"vnp_TransactionStatus": status,
+		"vnp_TxnRef":            txnRef,
+	}
+	query := url.Values{}
+	for k, v := range params {
+		query.Set(k, v)
+	}
+	query.Set("vnp_SecureHash", payment.HashAllFields(params))
+	return path + "?" + query.Encode()
+}
+
+// Merchant references of the payment attempts seeded by seedUnpaidOnlineInvoice.
+const (
+	firstTxnRef  = "0b5e2d7a4c1f48e3a9d6b8c7e1f2a3b4"
+	secondTxnRef = "c4a1f9e8d7b64a3e9f0b1c2d3e4f5a6b"
+)
+
+// seedUnpaidOnlineInvoice seeds invoice 1 of account 1, an unpaid online order of 150000 VND with two pending payment attempts.
+func seedUnpaidOnlineInvoice(t *testing.T) {
+	SeedData(t, SeedAccountWithInvoicesNoDetail)
+	_, err := testdb.Exec(`UPDATE invoice SET status = false, "paymentMethod" = false, "paymentMethodCode" = 'VNPAY' WHERE "invoiceID" = 1`)
+	assert.NoError(t, err)
+	_, err = testdb.Exec(`
+		INSERT INTO payment_attempt ("attemptID", "invoiceID", provider, "txnRef", amount, "expiresAt")
+		VALUES (gen_random_uuid(), 1, 'VNPAY', $1, 150000, now() - interval '1 minute'),
+			(gen_random_uuid(), 1, 'VNPAY', $2, 150000, now() + interval '15 minutes')`, firstTxnRef, secondTxnRef)
+	assert.NoError(t, err)
+}
+
+func attemptStatus(t *testing.T, txnRef string) string {
+	var status string
+	assert.NoError(t, testdb.QueryRow(`SELECT status FROM payment_attempt WHERE "txnRef" = $1`, txnRef).Scan(&status))
+	return status
+}
+
+func invoicePaid(t *testing.T, invoiceID int) bool {
+	var paid bool
+	assert.NoError(t, testdb.QueryRow(`SELECT status FROM invoice WHERE "invoiceID" = $1`, invoiceID).Scan(&paid))
+	return paid
+}
+
+func transactionCount(t *testing.T, invoiceID int) int {
+	var count int
+	assert.NoError(t, testdb.QueryRow(`SELECT COUNT(*) FROM transaction WHERE "invoiceID" = $1`, invoiceID).Scan(&count))
+	return count
+}
+
+func TestVNPayIPN(t *testing.T) {
+	app := SetupApp()
+	t.Setenv("VNPAY_SECRET", "TESTSECRET")
+
+	tests := []struct {
+		name         string
+		url          func() string
+		seedData     func()
+		wantRspCode  string
+		validateData func(t *testing.T)
+	}{
+		{
+			name:        "Successful payment",
+			url:         func() string { return vnpayCallback("/payment/vnpay/ipn", secondTxnRef, "150000", "00", "14226112") },
+			seedData:    func() { seedUnpaidOnlineInvoice(t) },
+			wantRspCode: "00",
+			validateData: func(t *testing.T) {
+				assert.True(t, invoicePaid(t, 1))
+				assert.Equal(t, 1, transactionCount(t, 1))
+
+				var code string
+				var amount float64
+				var status bool
+				err := testdb.QueryRow(`SELECT "transactionCode", amount, status FROM transaction WHERE "invoiceID" = 1`).Scan(&code, &amount, &status)
+				assert.NoError(t, err)
+				assert.Equal(t, "14226112", code)
+				assert.Equal(t, float64(150000), amount)
+				assert.True(t, status)
+				assert.Equal(t, "succeeded", attemptStatus(t, secondTxnRef))
+				assert.Equal(t, "pending", attemptStatus(t, firstTxnRef))
+			},
+		},
+		{
+			name: "Duplicate notification",
+			url:  func() string { return vnpayCallback("/payment/vnpay/ipn", secondTxnRef, "150000", "00", "14226112") },
+			seedData: func() {
+				seedUnpaidOnlineInvoice(t)
+				req := httptest.NewRequest("GET", vnpayCallback("/payment/vnpay/ipn", secondTxnRef, "150000", "00", "14226112"), nil)
+				_, _ = app.Test(req, -1)
+			},
+			wantRspCode: "02",
+			validateData: func(t *testing.T) {
+				assert.True(t, invoicePaid(t, 1))
+				assert.Equal(t, 1, transactionCount(t, 1))
+			},
+		},
+		{
+			name:        "Cancelled payment",
+			url:         func() string { return vnpayCallback("/payment/vnpay/ipn", secondTxnRef, "150000", "24", "0") },
+			seedData:    func() { seedUnpaidOnlineInvoice(t) },
+			wantRspCode: "00",
+			validateData: func(t *testing.T) {
+				assert.False(t, invoicePaid(t, 1))
+				assert.Equal(t, 1, transactionCount(t, 1))
+				assert.Equal(t, "failed", attemptStatus(t, secondTxnRef))
+			},
+		},
+		{
+			name: "New attempt after a failed one",
+			url:  func() string { return vnpayCallback("/payment/vnpay/ipn", secondTxnRef, "150000", "00", "14226112") },
+			seedData: func() {
+				seedUnpaidOnlineInvoice(t)
+				req := httptest.NewRequest("GET", vnpayCallback("/payment/vnpay/ipn", firstTxnRef, "150000", "24", "0"), nil)
+				_, _ = app.Test(req, -1)
+			},
+			wantRspCode: "00",
+			validateData: func(t *testing.T) {
+				assert.True(t, invoicePaid(t, 1))
+				assert.Equal(t, 2, transactionCount(t, 1))
+				assert.Equal(t, "failed", attemptStatus(t, firstTxnRef))
+				assert.Equal(t, "succeeded", attemptStatus(t, secondTxnRef))
+			},
+		},
+		{
+			name: "Late success of an expired attempt",
+			url:  func() string { return vnpayCallback("/payment/vnpay/ipn", firstTxnRef, "150000", "00", "14226112") },
+			seedData: func() {
+				seedUnpaidOnlineInvoice(t)
+				_, _ = testdb.Exec(`UPDATE payment_attempt SET status = 'expired' WHERE "txnRef" = $1`, firstTxnRef)
+			},
+			wantRspCode: "00",
+			validateData: func(t *testing.T) {
+				assert.True(t, invoicePaid(t, 1))
+				assert.Equal(t, "succeeded", attemptStatus(t, firstTxnRef))
+			},
+		},
+		{
+			name:        "Amount does not match the invoice",
+			url:         func() string { return vnpayCallback("/payment/vnpay/ipn", secondTxnRef, "1000", "00", "14226112") },
+			seedData:    func() { seedUnpaidOnlineInvoice(t) },
+			wantRspCode: "04",
+			validateData: func(t *testing.T) {
+				assert.False(t, invoicePaid(t, 1))
+				assert.Equal(t, 0, transactionCount(t, 1))
+			},
+		},
+		{
+			name: "Unknown payment reference",
+			url: func() string {
+				return vnpayCallback("/payment/vnpay/ipn", "ffffffffffffffffffffffffffffffff", "150000", "00", "14226112")
+			},
+			seedData:    func() { seedUnpaidOnlineInvoice(t) },
+			wantRspCode: "01",
+		},
+		{
+			name: "Tampered signature",
+			url: func() string {
+				return vnpayCallback("/payment/vnpay/ipn", secondTxnRef, "150000", "00", "14226112") + "&vnp_BankCode=EVIL"
+			},
+			seedData:    func() { seedUnpaidOnlineInvoice(t) },
+			wantRspCode: "97",
+			validateData: func(t *testing.T) {
+				assert.False(t, invoicePaid(t, 1))
+				assert.Equal(t, 0, transactionCount(t, 1))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.seedData()
+
+			req := httptest.NewRequest("GET", tt.url(), nil)
+			resp, _ := app.Test(req, -1)
+
+			assert.Equal(t, 200, resp.StatusCode)
+
+			var body map[string]interface{}
+			_ = json.NewDecoder(resp.Body).Decode(&body)
+			assert.Equal(t, tt.wantRspCode, body["RspCode"])
+
+			if tt.validateData != nil {
+				tt.validateData(t)
+			}
+		})
+	}
+}
+
+func TestVNPayReturn(t *testing.T) {
+	app := SetupApp()
+	t.Setenv("VNPAY_SECRET", "TESTSECRET")
+
+	tests := []struct {
+		name       string
+		url        string
+		wantStatus int
+		wantMsg    string
+		wantPaid   bool
+	}{
+		{
+			name:       "Successful payment",
+			url:        vnpayCallback("/payment/vnpay/return", secondTxnRef, "150000", "00", "14226112"),
+			wantStatus: 200,
+			wantMsg:    "Payment successful",
+			wantPaid:   true,
+		},
+		{
+			name:       "Cancelled payment",
+			url:        vnpayCallback("/payment/vnpay/return", secondTxnRef, "150000", "24", "0"),
+			wantStatus: 200,
+			wantMsg:    "The payment was cancelled",
+		},
+		{
+			name:       "Tampered signature",
+			url:        vnpayCallback("/payment/vnpay/return", secondTxnRef, "150000", "24", "0") + "&vnp_ResponseCode=00",
+			wantStatus: 400,
+			wantMsg:    "Invalid payment signature",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seedUnpaidOnlineInvoice(t)
+
+			req := httptest.NewRequest("GET", tt.url, nil)
+			resp, _ := app.Test(req, -1)
+
+			assert.Equal(t, tt.wantStatus, resp.StatusCode)
+
+			var body map[string]interface{}
+			_ = json.NewDecoder(resp.Body).Decode(&body)
+			assert.Equal(t, tt.wantMsg, body["message"])
+			assert.Equal(t, tt.wantPaid, invoicePaid(t, 1))
+		})
+	}
+}
+
+func TestInvoicePayOnlineCreatesAttempts(t *testing.T) {
+	app := SetupApp()
+	t.Setenv("VNPAY_SECRET", "TESTSECRET")
+	SeedData(t, SeedAccountWithInvoicesNoDetail)
+	_, err := testdb.Exec(`UPDATE invoice SET status = false, "paymentMethod" = false, "paymentMethodCode" = 'VNPAY' WHERE "invoiceID" = 1`)
+	assert.NoError(t, err)
+
+	refs := []string{}
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest("POST", "/invoice/pay/online?accountID=1", strings.NewReader(`{"invoiceID":1}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, _ := app.Test(req, -1)
+		assert.Equal(t, 200, resp.StatusCode)
+
+		var body map[string]interface{}
+		_ = json.NewDecoder(resp.Body).Decode(&body)
+		paymentURL, err := url.Parse(body["data"].(string))
+		assert.NoError(t, err)
+		assert.Equal(t, "15000000", paymentURL.Query().Get("vnp_Amount"))
+		refs = append(refs, paymentURL.Query().Get("vnp_TxnRef"))
+	}
+
+	assert.NotEqual(t, refs[0], refs[1], "each payment url is a new attempt")
+	for _, ref := range refs {
+		assert.Equal(t, "pending", attemptStatus(t, ref))
+	}
+}
+
+func TestPaymentMethods(t *testing.T) {
+	app := SetupApp()
+
+	req := httptest.NewRequest("GET", "/payment/methods", nil)
+	resp, _ := app.Test(req, -1)
+	assert.Equal(t, 200, resp.StatusCode)
+
+	var body map[string]interface{}
+	_ = json.NewDecoder(resp.Body).Decode(&body)
+	methods := body["data"].([]interface{})
+	if assert.Len(t, methods, 2) {
+		assert.Equal(t, map[string]interface{}{"code": "COD", "name": "Cash on delivery", "online": false}, methods[0])
+		assert.Equal(t, map[string]interface{}{"code": "VNPAY", "name": "VNPay", "online": true}, methods[1])
+	}
+}
+
+func TestInvoicePayOnlineRejectsCOD(t *testing.T) {
+	app := SetupApp()
+	SeedData(t, SeedAccountWithInvoicesNoDetail)
+	_, err := testdb.Exec(`UPDATE invoice SET status = false WHERE "invoiceID" = 1`)
+	assert.NoError(t, err)
+
+	req := httptest.NewRequest("POST", "/invoice/pay/online?accountID=1", strings.NewReader(`{"invoiceID":1}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, _ := app.Test(req, -1)
+	assert.Equal(t, 400, resp.StatusCode)
+
+	var body map[string]interface{}
+	_ = json.NewDecoder(resp.Body).Decode(&body)
+	assert.Equal(t, "This order is paid on delivery", body["message"])
+}
+
+// TestFakeProvider runs an order through checkout, payment, callback and refund with the fake provider.
+func TestFakeProvider(t *testing.T) {
+	app := SetupApp()
+	fake := payment.NewFakeProvider()
+	payment.Register(fake)
+	SeedData(t, SeedCheckout)
+	_, err := testdb.Exec(`
+		INSERT INTO payment_method (code, name, "sortOrder") VALUES ('FAKE', 'Fake', 99)
+		ON CONFLICT (code) DO UPDATE SET enabled = true`)
+	assert.NoError(t, err)
+	t.Cleanup(func() {
+		_, _ = testdb.Exec(`UPDATE payment_method SET enabled = false WHERE code = 'FAKE'`)
+	})
+
+	post := func(url, contentType, body string) map[string]interface{} {
+		req := httptest.NewRequest("POST", url, strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		resp, _ := app.Test(req, -1)
+		assert.Equal(t, 200, resp.StatusCode, url)
+		var decoded map[string]interface{}
+		_ = json.NewDecoder(resp.Body).Decode(&decoded)
+		return decoded
+	}
+
+	body := post("/invoice/pay?accountID=1", "application/json",
+		`{"items":[{"productID":1,"quantity":1}],"addressID":1,"paymentMethod":"fake"}`)
+	data := body["data"].(map[string]interface{})
+	assert.Equal(t, "FAKE", data["paymentMethod"])
+	invoiceID := int(data["invoice"].(map[string]interface{})["invoiceID"].(float64))
+
+	body = post("/invoice/pay/online?accountID=1", "application/json", fmt.Sprintf(`{"invoiceID":%d}`, invoiceID))
+	payURL := body["data"].(string)
+	assert.True(t, strings.HasPrefix(payURL, "https://pay.fake.invalid/"))
+	txnRef := strings.TrimPrefix(payURL, "https://pay.fake.invalid/")
+
+	form := url.Values{}
+	for k, v := range fake.Callback(txnRef, true) {
+		form.Set(k, v)
+	}
+	body = post("/payment/fake/ipn", "application/x-www-form-urlencoded", form.Encode())
+	assert.Equal(t, "Success", body["status"])
+	assert.True(t, invoicePaid(t, invoiceID))
+	assert.Equal(t, "succeeded", attemptStatus(t, txnRef))
+
+	_, err = testdb.Exec(`UPDATE invoice SET "invoiceStatusID" = 6 WHERE "invoiceID" = $1`, invoiceID)
+	assert.NoError(t, err)
+	body = post(fmt.Sprintf("/admin/order/refund?invoiceID=%d&accountID=1", invoiceID), "application/json", `{"amount":10000}`)
+	assert.Equal(t, "Successfully refunded the payment", body["message"])
+	assert.Equal(t, int64(10000), fake.Refunded(txnRef))
+}
@@ -32,6 +32,18 @@ var(
 		InvoiceDetails: &InvoiceDetailSeed{seedInvoiceDetail: true,numberOfRecords: 6},
 	}
 
+	//Seed account + address + 6 products (75000 VND each) + invoice statuses for checkout
+	SeedCheckout = SeedConfig{
+		Accounts:        &AccountSeed{seedAccount: true, numberOfRecords: 1},
+		Provinces:       true,
+		Districts:       true,
+		Wards:           true,
+		Addresses:       &AddressSeed{seedAddress: true, numberOfRecords: 1},
+		ProductTypes:    &ProductTypeSeed{seedProductType: true, numberOfRecords: 1},
+		Products:        &ProductSeed{seedProduct: true, numberOfRecords: 6},
+		InvoiceStatuses: true,
+	}
+
 	//Seed basic products - 12 products
 	SeedProductsBasic = SeedConfig{
 		ProductTypes: &ProductTypeSeed{seedProductType: true,numberOfRecords: 12},
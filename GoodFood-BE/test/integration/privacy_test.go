@@ -0,0 +1,118 @@
+package integration
+
+import (
+	"GoodFood-BE/internal/auth"
+	"GoodFood-BE/internal/order"
+	"GoodFood-BE/internal/privacy"
+	"database/sql"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const deleteAccountBody = `{"password":"secret-password","confirm":"DELETE"}`
+
+// placeOrderToDelete signs user0 in with a placed order shipped to its address.
+func placeOrderToDelete(t *testing.T) (access, refresh string) {
+	useRedis(t)
+	useSigningKeys(t)
+	_, err := testdb.Exec(`TRUNCATE TABLE invoice_status RESTART IDENTITY CASCADE`)
+	require.NoError(t, err)
+	SeedData(t, SeedConfig{
+		Accounts:        &AccountSeed{seedAccount: true, numberOfRecords: 1},
+		Provinces:       true,
+		Districts:       true,
+		Wards:           true,
+		Addresses:       &AddressSeed{seedAddress: true, numberOfRecords: 1},
+		InvoiceStatuses: true,
+		Invoices:        &InvoiceSeed{seedInvoice: true, numberOfRecords: 1},
+	})
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret-password"), bcrypt.MinCost)
+	require.NoError(t, err)
+	_, err = testdb.Exec(`UPDATE account SET password = $1 WHERE "accountID" = 1`, string(hash))
+	require.NoError(t, err)
+	return login(t, "user0", "laptop")
+}
+
+func TestDeleteAccountWithOrderInProgress(t *testing.T) {
+	access, _ := placeOrderToDelete(t)
+
+	status, _ := sendJSON(t, SetupApp(), http.MethodDelete, "/user/account?accountID=1", deleteAccountBody)
+	assert.Equal(t, http.StatusConflict, status)
+	var username string
+	require.NoError(t, testdb.QueryRow(`SELECT username FROM account WHERE "accountID" = 1`).Scan(&username))
+	assert.Equal(t, "user0", username)
+	_, err := auth.VerifyActiveToken(access, auth.TokenTypeAccess)
+	assert.NoError(t, err)
+}
+
+func TestDeleteAccount(t *testing.T) {
+	access, refresh := placeOrderToDelete(t)
+	_, err := testdb.Exec(`UPDATE invoice SET "invoiceStatusID" = (SELECT "invoiceStatusID" FROM invoice_status WHERE code = $1)`,
+		string(order.StatusDelivered))
+	require.NoError(t, err)
+
+	_, err = testdb.Exec(`
+		INSERT INTO audit_log ("actorID", "actorUsername", action, "entityType", "entityID", metadata, before, after, changes)
+		VALUES (1, 'user0', 'account.update', 'account', '1', '{"username":"user0"}', '{"username":"user0"}', '{"username":"user1"}', '{}')`)
+	require.NoError(t, err)
+
+	status, _ := sendJSON(t, SetupApp(), http.MethodDelete, "/user/account?accountID=1", `{"password":"guess","confirm":"DELETE"}`)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	status, body := sendJSON(t, SetupApp(), http.MethodDelete, "/user/account?accountID=1", deleteAccountBody)
+	require.Equal(t, http.StatusOK, status, body["message"])
+
+	var (
+		username, email, fullName, password string
+		phone, avatar                       sql.NullString
+		active                              bool
+	)
+	require.NoError(t, testdb.QueryRow(`
+		SELECT username, email, "fullName", password, "phoneNumber", avatar, status FROM account WHERE "accountID" = 1`).
+		Scan(&username, &email, &fullName, &password, &phone, &avatar, &active))
+	assert.Equal(t, privacy.DeletedUsername(1), username)
+	assert.Equal(t, "deleted-1@deleted.invalid", email)
+	assert.Equal(t, privacy.DeletedFullName, fullName)
+	assert.Equal(t, auth.NoPasswordHash, password)
+	assert.False(t, phone.Valid)
+	assert.False(t, avatar.Valid)
+	assert.False(t, active)
+
+	var addressName, addressPhone, address, specificAddress string
+	var deleted bool
+	require.NoError(t, testdb.QueryRow(`
+		SELECT "fullName", "phoneNumber", address, "specificAddress", "deleteStatus" FROM address WHERE "accountID" = 1`).
+		Scan(&addressName, &addressPhone, &address, &specificAddress, &deleted))
+	assert.Empty(t, addressName+addressPhone+address+specificAddress)
+	assert.True(t, deleted)
+
+	var receiveName, receivePhone, receiveAddress string
+	var note sql.NullString
+	var invoices int
+	require.NoError(t, testdb.QueryRow(`SELECT count(*) FROM invoice WHERE "accountID" = 1`).Scan(&invoices))
+	assert.Equal(t, 1, invoices, "orders are kept for bookkeeping")
+	require.NoError(t, testdb.QueryRow(`
+		SELECT "receiveName", "receivePhone", "receiveAddress", note FROM invoice WHERE "accountID" = 1`).
+		Scan(&receiveName, &receivePhone, &receiveAddress, &note))
+	assert.Empty(t, receiveName+receivePhone+receiveAddress)
+	assert.False(t, note.Valid)
+
+	//Audit entries keep the account id only
+	var actorUsername string
+	var metadata, before sql.NullString
+	require.NoError(t, testdb.QueryRow(`
+		SELECT "actorUsername", metadata, before FROM audit_log WHERE action = 'account.update'`).
+		Scan(&actorUsername, &metadata, &before))
+	assert.Equal(t, privacy.DeletedUsername(1), actorUsername)
+	assert.JSONEq(t, `{}`, metadata.String)
+	assert.False(t, before.Valid)
+
+	//Every session of the account is signed out
+	_, err = auth.VerifyActiveToken(access, auth.TokenTypeAccess)
+	assert.ErrorIs(t, err, auth.ErrTokenRevoked)
+	assert.Empty(t, auth.SessionIDFromRefreshToken(refresh))
+}
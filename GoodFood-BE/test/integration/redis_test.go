@@ -0,0 +1,76 @@
+package integration
+
+import (
+	"GoodFood-BE/internal/auth"
+	redisdatabase "GoodFood-BE/internal/redis-database"
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+var (
+	redisOnce      sync.Once
+	redisContainer testcontainers.Container
+	redisClient    *redis.Client
+	redisErr       error
+)
+
+// startRedis starts the Redis container shared by the tests that need one.
+func startRedis() {
+	ctx := context.Background()
+	redisContainer, redisErr = testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:        "redis:7",
+			ExposedPorts: []string{"6379/tcp"},
+			WaitingFor:   wait.ForListeningPort("6379/tcp").WithStartupTimeout(60 * time.Second),
+		},
+		Started: true,
+	})
+	if redisErr != nil {
+		return
+	}
+	host, _ := redisContainer.Host(ctx)
+	p, _ := redisContainer.MappedPort(ctx, "6379")
+	redisClient = redis.NewClient(&redis.Options{Addr: fmt.Sprintf("%s:%s", host, p.Port())})
+	redisErr = redisClient.Ping(ctx).Err()
+}
+
+// stopRedis terminates the Redis container if a test started it.
+func stopRedis() {
+	if redisContainer != nil {
+		_ = redisContainer.Terminate(context.Background())
+	}
+}
+
+// useRedis points redisdatabase.Client at an empty Redis for the duration of the test.
+// The other tests keep running without Redis, as the caches they go through skip a nil client.
+func useRedis(t *testing.T) {
+	redisOnce.Do(startRedis)
+	require.NoError(t, redisErr, "cannot start Redis")
+	require.NoError(t, redisClient.FlushDB(context.Background()).Err())
+	redisdatabase.Client = redisClient
+	t.Cleanup(func() { redisdatabase.Client = nil })
+}
+
+// useSigningKeys makes the test sign and verify session tokens with a fresh EdDSA key set.
+func useSigningKeys(t *testing.T) {
+	dir := t.TempDir()
+	_, err := auth.RotateSigningKey(dir, auth.AlgEdDSA, 1)
+	require.NoError(t, err)
+	t.Setenv("JWT_KEYS_DIR", dir)
+}
+
+// login opens a session for username the way HandleLogin does and returns its access and refresh tokens.
+func login(t *testing.T, username, userAgent string) (access, refresh string) {
+	access, refresh, jti, err := auth.CreateToken(username, "user", false)
+	require.NoError(t, err)
+	require.NoError(t, auth.SaveRefreshToken(jti, refresh, username, userAgent, "127.0.0.1", time.Now().Add(7*24*time.Hour)))
+	return access, refresh
+}
@@ -0,0 +1,233 @@
+package integration
+
+import (
+	"GoodFood-BE/internal/payment/vnpayfake"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// seedPaidCancelledInvoice seeds invoice 1 of account 1, cancelled after a successful VNPay payment of 150000 VND,
+// and registers the payment with fake.
+func seedPaidCancelledInvoice(t *testing.T, fake *vnpayfake.Server) {
+	SeedData(t, SeedAccountWithInvoicesNoDetail)
+	_, err := testdb.Exec(`UPDATE invoice SET status = true, "paymentMethod" = false, "paymentMethodCode" = 'VNPAY', "invoiceStatusID" = 6 WHERE "invoiceID" = 1`)
+	assert.NoError(t, err)
+	_, err = testdb.Exec(`
+		INSERT INTO payment_attempt ("attemptID", "invoiceID", provider, "txnRef", amount, status, "transactionNo", "expiresAt", "completedAt")
+		VALUES (gen_random_uuid(), 1, 'VNPAY', $1, 150000, 'succeeded', '14226112', now(), now())`, firstTxnRef)
+	assert.NoError(t, err)
+	_, err = testdb.Exec(`
+		INSERT INTO transaction ("transactionCode", "cardNumber", "fullName", "transactionDate", status, amount, "accountID", "invoiceID")
+		VALUES ('14226112', 'VNP14226112', 'Usertest', now(), true, 150000, 1, 1)`)
+	assert.NoError(t, err)
+	fake.AddTransaction(vnpayfake.Transaction{TxnRef: firstTxnRef, TransactionNo: "14226112", Amount: 150000})
+}
+
+func refundedAmount(t *testing.T, invoiceID int) int64 {
+	var amount int64
+	err := testdb.QueryRow(`SELECT COALESCE(SUM(amount), 0) FROM refund WHERE "invoiceID" = $1 AND status = 'succeeded'`, invoiceID).Scan(&amount)
+	assert.NoError(t, err)
+	return amount
+}
+
+func TestAdminInvoiceRefund(t *testing.T) {
+	app := SetupApp()
+	t.Setenv("VNPAY_SECRET", "TESTSECRET")
+
+	refund := func(body string) (int, map[string]interface{}) {
+		req := httptest.NewRequest("POST", "/admin/order/refund?invoiceID=1&accountID=1", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, _ := app.Test(req, -1)
+		var data map[string]interface{}
+		_ = json.NewDecoder(resp.Body).Decode(&data)
+		return resp.StatusCode, data
+	}
+
+	tests := []struct {
+		name       string
+		seedData   func(fake *vnpayfake.Server)
+		body       string
+		wantStatus int
+		wantMsg    string
+		validate   func(t *testing.T, fake *vnpayfake.Server)
+	}{
+		{
+			name:       "Full refund",
+			seedData:   func(fake *vnpayfake.Server) { seedPaidCancelledInvoice(t, fake) },
+			body:       `{}`,
+			wantStatus: 200,
+			wantMsg:    "Successfully refunded the payment",
+			validate: func(t *testing.T, fake *vnpayfake.Server) {
+				assert.Equal(t, int64(150000), refundedAmount(t, 1))
+				assert.Equal(t, "02", fake.Requests[len(fake.Requests)-1]["vnp_TransactionType"])
+				assert.Equal(t, "user0", fake.Requests[len(fake.Requests)-1]["vnp_CreateBy"])
+			},
+		},
+		{
+			name: "Partial refunds up to the paid amount",
+			seedData: func(fake *vnpayfake.Server) {
+				seedPaidCancelledInvoice(t, fake)
+				status, _ := refund(`{"amount":50000}`)
+				assert.Equal(t, 200, status)
+			},
+			body:       `{"amount":100001}`,
+			wantStatus: 400,
+			wantMsg:    "The refund amount must be positive and at most the amount not refunded yet",
+			validate: func(t *testing.T, fake *vnpayfake.Server) {
+				assert.Equal(t, "03", fake.Requests[0]["vnp_TransactionType"])
+				status, _ := refund(`{}`)
+				assert.Equal(t, 200, status)
+				assert.Equal(t, int64(150000), refundedAmount(t, 1))
+				state, _ := fake.Transaction(firstTxnRef)
+				assert.Equal(t, int64(150000), state.Refunded)
+			},
+		},
+		{
+			name: "Order not cancelled",
+			seedData: func(fake *vnpayfake.Server) {
+				seedPaidCancelledInvoice(t, fake)
+				_, err := testdb.Exec(`UPDATE invoice SET "invoiceStatusID" = 2 WHERE "invoiceID" = 1`)
+				assert.NoError(t, err)
+			},
+			body:       `{}`,
+			wantStatus: 409,
+			wantMsg:    "Only cancelled orders paid online can be refunded",
+			validate: func(t *testing.T, fake *vnpayfake.Server) {
+				assert.Empty(t, fake.Requests)
+			},
+		},
+		{
+			name: "VNPay refuses the refund",
+			seedData: func(fake *vnpayfake.Server) {
+				seedPaidCancelledInvoice(t, fake)
+				fake.AddTransaction(vnpayfake.Transaction{TxnRef: firstTxnRef, TransactionNo: "14226112", Amount: 150000, Refunded: 150000})
+			},
+			body:       `{}`,
+			wantStatus: 502,
+			wantMsg:    "The payment provider refused the refund: ",
+			validate: func(t *testing.T, fake *vnpayfake.Server) {
+				var status string
+				assert.NoError(t, testdb.QueryRow(`SELECT status FROM refund WHERE "invoiceID" = 1`).Scan(&status))
+				assert.Equal(t, "failed", status)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := vnpayfake.New()
+			defer fake.Close()
+			t.Setenv("VNPAY_API_URL", fake.URL)
+			tt.seedData(fake)
+
+			status, body := refund(tt.body)
+			assert.Equal(t, tt.wantStatus, status)
+			assert.Equal(t, tt.wantMsg, body["message"])
+
+			if tt.validate != nil {
+				tt.validate(t, fake)
+			}
+		})
+	}
+}
+
+// refundStatus returns the status of the only refund of invoice 1.
+func refundStatus(t *testing.T) (refundID int, status string) {
+	assert.NoError(t, testdb.QueryRow(`SELECT "refundID", status FROM refund WHERE "invoiceID" = 1`).Scan(&refundID, &status))
+	return refundID, status
+}
+
+// startPendingRefund requests a full refund of invoice 1 while VNPay cannot be reached, which leaves it pending.
+func startPendingRefund(t *testing.T, app *fiber.App, fake *vnpayfake.Server) int {
+	seedPaidCancelledInvoice(t, fake)
+	unreachable := httptest.NewServer(nil)
+	unreachable.Close()
+	t.Setenv("VNPAY_API_URL", unreachable.URL)
+
+	status, body := sendJSON(t, app, "POST", "/admin/order/refund?invoiceID=1&accountID=1", `{}`)
+	assert.Equal(t, 502, status)
+	assert.Contains(t, body["message"], "it stays pending")
+	refundID, state := refundStatus(t)
+	assert.Equal(t, "pending", state)
+
+	//The pending refund may have gone through, its amount cannot be refunded again
+	status, _ = sendJSON(t, app, "POST", "/admin/order/refund?invoiceID=1&accountID=1", `{}`)
+	assert.Equal(t, 400, status)
+	t.Setenv("VNPAY_API_URL", fake.URL)
+	return refundID
+}
+
+func TestAdminInvoiceRefundResolve(t *testing.T) {
+	app := SetupApp()
+	t.Setenv("VNPAY_SECRET", "TESTSECRET")
+	resolve := func(refundID int, status string) (int, map[string]interface{}) {
+		return sendJSON(t, app, "POST", "/admin/order/refund/resolve?invoiceID=1&accountID=1",
+			fmt.Sprintf(`{"refundID":%d,"status":%q,"message":"Checked with VNPay"}`, refundID, status))
+	}
+
+	t.Run("Failed refund can be requested again", func(t *testing.T) {
+		fake := vnpayfake.New()
+		defer fake.Close()
+		refundID := startPendingRefund(t, app, fake)
+
+		status, _ := resolve(refundID, "pending")
+		assert.Equal(t, 400, status)
+		status, _ = resolve(refundID+1, "failed")
+		assert.Equal(t, 404, status)
+
+		status, body := resolve(refundID, "failed")
+		assert.Equal(t, 200, status)
+		assert.Equal(t, "Successfully resolved the refund", body["message"])
+		_, state := refundStatus(t)
+		assert.Equal(t, "failed", state)
+		status, body = resolve(refundID, "succeeded")
+		assert.Equal(t, 409, status)
+		assert.Equal(t, "The refund is no longer pending", body["message"])
+
+		status, _ = sendJSON(t, app, "POST", "/admin/order/refund?invoiceID=1&accountID=1", `{}`)
+		assert.Equal(t, 200, status)
+		assert.Equal(t, int64(150000), refundedAmount(t, 1))
+	})
+
+	t.Run("Succeeded refund uses up the payment", func(t *testing.T) {
+		fake := vnpayfake.New()
+		defer fake.Close()
+		refundID := startPendingRefund(t, app, fake)
+
+		status, _ := resolve(refundID, "succeeded")
+		assert.Equal(t, 200, status)
+		assert.Equal(t, int64(150000), refundedAmount(t, 1))
+
+		status, _ = sendJSON(t, app, "POST", "/admin/order/refund?invoiceID=1&accountID=1", `{}`)
+		assert.Equal(t, 400, status)
+		assert.Empty(t, fake.Requests)
+	})
+}
+
+func TestAdminInvoicePaymentStatus(t *testing.T) {
+	app := SetupApp()
+	t.Setenv("VNPAY_SECRET", "TESTSECRET")
+	fake := vnpayfake.New()
+	defer fake.Close()
+	t.Setenv("VNPAY_API_URL", fake.URL)
+	seedPaidCancelledInvoice(t, fake)
+
+	req := httptest.NewRequest("GET", "/admin/order/payments/status?invoiceID=1&accountID=1", nil)
+	resp, _ := app.Test(req, -1)
+	assert.Equal(t, 200, resp.StatusCode)
+
+	var body map[string]interface{}
+	_ = json.NewDecoder(resp.Body).Decode(&body)
+	data := body["data"].(map[string]interface{})
+	assert.Equal(t, "VNPAY", data["method"])
+	assert.Equal(t, true, data["paid"])
+	assert.Equal(t, "00", data["responseCode"])
+	assert.Equal(t, float64(150000), data["amount"])
+	assert.Equal(t, firstTxnRef, data["txnRef"])
+}
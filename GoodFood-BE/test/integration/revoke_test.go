@@ -0,0 +1,101 @@
+package integration
+
+import (
+	"GoodFood-BE/internal/auth"
+	"GoodFood-BE/internal/server/handlers"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupRevoke returns an app guarded by the real auth.AuthMiddleware, with the refresh and logout routes.
+func setupRevoke(t *testing.T) *fiber.App {
+	useRedis(t)
+	useSigningKeys(t)
+	SeedData(t, SeedConfig{Accounts: &AccountSeed{seedAccount: true, numberOfRecords: 2}})
+
+	app := fiber.New()
+	app.Get("/me", auth.AuthMiddleware, func(c *fiber.Ctx) error {
+		return c.JSON(fiber.Map{"status": "Success", "data": auth.GetAuthenticatedUser(c)})
+	})
+	app.Post("/user/refresh-token", handlers.RefreshToken)
+	app.Get("/user/logout", auth.AuthMiddleware, handlers.HandleLogout)
+	return app
+}
+
+// sendAs sends a request with an access token as bearer and a refresh token as cookie, either may be empty.
+func sendAs(t *testing.T, app *fiber.App, method, url, accessToken, refreshToken string) int {
+	req := httptest.NewRequest(method, url, nil)
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+	if refreshToken != "" {
+		req.AddCookie(&http.Cookie{Name: "refreshToken", Value: refreshToken})
+	}
+	resp, err := app.Test(req, -1)
+	require.NoError(t, err)
+	return resp.StatusCode
+}
+
+func TestRevokeUserTokens(t *testing.T) {
+	app := setupRevoke(t)
+	access0, refresh0 := login(t, "user0", "laptop")
+	access1, refresh1 := login(t, "user1", "laptop")
+	require.Equal(t, http.StatusOK, sendAs(t, app, http.MethodGet, "/me", access0, ""))
+
+	require.NoError(t, auth.RevokeUserTokens("user0"))
+
+	assert.Equal(t, http.StatusUnauthorized, sendAs(t, app, http.MethodGet, "/me", access0, ""))
+	assert.Equal(t, http.StatusUnauthorized, sendAs(t, app, http.MethodPost, "/user/refresh-token", "", refresh0))
+	_, err := auth.VerifyActiveToken(access0, auth.TokenTypeAccess)
+	assert.ErrorIs(t, err, auth.ErrTokenRevoked)
+
+	//The other user is not affected
+	assert.Equal(t, http.StatusOK, sendAs(t, app, http.MethodGet, "/me", access1, ""))
+	assert.Equal(t, http.StatusOK, sendAs(t, app, http.MethodPost, "/user/refresh-token", "", refresh1))
+
+	//Tokens issued after the revocation work again
+	access0, refresh0 = login(t, "user0", "laptop")
+	assert.Equal(t, http.StatusOK, sendAs(t, app, http.MethodGet, "/me", access0, ""))
+	assert.Equal(t, http.StatusOK, sendAs(t, app, http.MethodPost, "/user/refresh-token", "", refresh0))
+}
+
+func TestRevokeAccessToken(t *testing.T) {
+	app := setupRevoke(t)
+	laptopAccess, laptopRefresh := login(t, "user0", "laptop")
+	phoneAccess, _ := login(t, "user0", "phone")
+	otherAccess, _ := login(t, "user1", "laptop")
+
+	assert.Equal(t, http.StatusOK, sendAs(t, app, http.MethodGet, "/user/logout", laptopAccess, laptopRefresh))
+
+	assert.Equal(t, http.StatusUnauthorized, sendAs(t, app, http.MethodGet, "/me", laptopAccess, ""),
+		"the access token of the logged out device is denylisted")
+	assert.Equal(t, http.StatusUnauthorized, sendAs(t, app, http.MethodPost, "/user/refresh-token", "", laptopRefresh))
+	assert.Equal(t, http.StatusOK, sendAs(t, app, http.MethodGet, "/me", phoneAccess, ""),
+		"the other sessions of the user are kept")
+	assert.Equal(t, http.StatusOK, sendAs(t, app, http.MethodGet, "/me", otherAccess, ""))
+}
+
+func TestRevokedRefreshTokenReuse(t *testing.T) {
+	app := setupRevoke(t)
+	_, refresh := login(t, "user0", "laptop")
+	_, otherRefresh := login(t, "user1", "laptop")
+
+	require.Equal(t, http.StatusOK, sendAs(t, app, http.MethodPost, "/user/refresh-token", "", refresh))
+	assert.Equal(t, http.StatusUnauthorized, sendAs(t, app, http.MethodPost, "/user/refresh-token", "", refresh),
+		"a rotated refresh token is revoked")
+	assert.Equal(t, http.StatusOK, sendAs(t, app, http.MethodPost, "/user/refresh-token", "", otherRefresh))
+}
+
+func TestWrongTokenType(t *testing.T) {
+	app := setupRevoke(t)
+	access, refresh := login(t, "user0", "laptop")
+
+	assert.Equal(t, http.StatusUnauthorized, sendAs(t, app, http.MethodGet, "/me", refresh, ""),
+		"refresh tokens are not bearer tokens")
+	assert.Equal(t, http.StatusUnauthorized, sendAs(t, app, http.MethodPost, "/user/refresh-token", "", access))
+}
@@ -0,0 +1,151 @@
+package integration
+
+import (
+	"GoodFood-BE/internal/auth"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sendWithRefreshToken sends a request carrying refreshToken as the cookie the browser keeps it in.
+func sendWithRefreshToken(t *testing.T, app *fiber.App, method, url, refreshToken string) (int, map[string]interface{}) {
+	req := httptest.NewRequest(method, url, nil)
+	if refreshToken != "" {
+		req.AddCookie(&http.Cookie{Name: "refreshToken", Value: refreshToken})
+	}
+	resp, err := app.Test(req, -1)
+	require.NoError(t, err)
+	var decoded map[string]interface{}
+	_ = json.NewDecoder(resp.Body).Decode(&decoded)
+	return resp.StatusCode, decoded
+}
+
+// sessionsOf returns the sessions listed in a response, keyed by user agent.
+func sessionsOf(body map[string]interface{}) map[string]map[string]interface{} {
+	sessions := map[string]map[string]interface{}{}
+	data, _ := body["data"].([]interface{})
+	for _, s := range data {
+		session := s.(map[string]interface{})
+		sessions[session["userAgent"].(string)] = session
+	}
+	return sessions
+}
+
+func setupSessions(t *testing.T) *fiber.App {
+	useRedis(t)
+	useSigningKeys(t)
+	SeedData(t, SeedConfig{Accounts: &AccountSeed{seedAccount: true, numberOfRecords: 2}})
+	return SetupApp()
+}
+
+func TestListSessions(t *testing.T) {
+	app := setupSessions(t)
+	_, laptop := login(t, "user0", "laptop")
+	login(t, "user0", "phone")
+	login(t, "user1", "tablet")
+
+	code, body := sendWithRefreshToken(t, app, http.MethodGet, "/sessions?accountID=1", laptop)
+	require.Equal(t, http.StatusOK, code)
+	sessions := sessionsOf(body)
+	require.Len(t, sessions, 2, "the sessions of other accounts are not listed")
+	assert.Equal(t, true, sessions["laptop"]["current"])
+	assert.Equal(t, false, sessions["phone"]["current"])
+	assert.Equal(t, "127.0.0.1", sessions["laptop"]["ip"])
+
+	code, body = sendWithRefreshToken(t, app, http.MethodGet, "/admin/user/sessions?accountID=2", "")
+	require.Equal(t, http.StatusOK, code)
+	sessions = sessionsOf(body)
+	require.Len(t, sessions, 1)
+	assert.Equal(t, false, sessions["tablet"]["current"])
+
+	code, _ = sendWithRefreshToken(t, app, http.MethodGet, "/admin/user/sessions?accountID=9", "")
+	assert.Equal(t, http.StatusNotFound, code)
+}
+
+func TestRevokeSession(t *testing.T) {
+	app := setupSessions(t)
+	_, laptop := login(t, "user0", "laptop")
+	_, phone := login(t, "user0", "phone")
+	phoneSession := auth.SessionIDFromRefreshToken(phone)
+	require.NotEmpty(t, phoneSession)
+
+	code, _ := sendWithRefreshToken(t, app, http.MethodDelete, "/sessions/revoke?accountID=1&sessionID="+phoneSession, laptop)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Empty(t, auth.SessionIDFromRefreshToken(phone), "the revoked refresh token no longer opens its session")
+	assert.NotEmpty(t, auth.SessionIDFromRefreshToken(laptop))
+
+	code, _ = sendWithRefreshToken(t, app, http.MethodDelete, "/sessions/revoke?accountID=1&sessionID="+phoneSession, laptop)
+	assert.Equal(t, http.StatusNotFound, code)
+
+	//Users cannot revoke the sessions of someone else
+	_, tablet := login(t, "user1", "tablet")
+	code, _ = sendWithRefreshToken(t, app, http.MethodDelete,
+		"/sessions/revoke?accountID=1&sessionID="+auth.SessionIDFromRefreshToken(tablet), laptop)
+	assert.Equal(t, http.StatusNotFound, code)
+	assert.NotEmpty(t, auth.SessionIDFromRefreshToken(tablet))
+}
+
+func TestRevokeOtherSessions(t *testing.T) {
+	app := setupSessions(t)
+	_, laptop := login(t, "user0", "laptop")
+	_, phone := login(t, "user0", "phone")
+	_, tablet := login(t, "user1", "tablet")
+
+	code, _ := sendWithRefreshToken(t, app, http.MethodDelete, "/sessions/revoke-others?accountID=1", "")
+	assert.Equal(t, http.StatusUnauthorized, code, "the current session is needed to know which one to keep")
+
+	code, _ = sendWithRefreshToken(t, app, http.MethodDelete, "/sessions/revoke-others?accountID=1", laptop)
+	assert.Equal(t, http.StatusOK, code)
+	assert.NotEmpty(t, auth.SessionIDFromRefreshToken(laptop))
+	assert.Empty(t, auth.SessionIDFromRefreshToken(phone))
+	assert.NotEmpty(t, auth.SessionIDFromRefreshToken(tablet))
+
+	code, body := sendWithRefreshToken(t, app, http.MethodGet, "/sessions?accountID=1", laptop)
+	require.Equal(t, http.StatusOK, code)
+	assert.Len(t, sessionsOf(body), 1)
+}
+
+func TestAdminRevokeUserSessions(t *testing.T) {
+	app := setupSessions(t)
+	_, laptop := login(t, "user0", "laptop")
+	_, phone := login(t, "user0", "phone")
+	_, tablet := login(t, "user1", "tablet")
+
+	code, _ := sendWithRefreshToken(t, app, http.MethodDelete,
+		"/admin/user/sessions/revoke?accountID=1&sessionID="+auth.SessionIDFromRefreshToken(phone), "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Empty(t, auth.SessionIDFromRefreshToken(phone))
+	assert.NotEmpty(t, auth.SessionIDFromRefreshToken(laptop))
+
+	code, _ = sendWithRefreshToken(t, app, http.MethodDelete, "/admin/user/sessions/revoke?accountID=1", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Empty(t, auth.SessionIDFromRefreshToken(laptop))
+	assert.NotEmpty(t, auth.SessionIDFromRefreshToken(tablet), "only the sessions of that account are revoked")
+
+	code, _ = sendWithRefreshToken(t, app, http.MethodDelete, "/admin/user/sessions/revoke", "")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestPruneUserSessions(t *testing.T) {
+	useRedis(t)
+	useSigningKeys(t)
+	_, laptop := login(t, "user0", "laptop")
+	login(t, "user0", "phone")
+
+	//The refresh record of the laptop expired, its jti is left behind in the set
+	claims, err := auth.VerifyRefreshToken(laptop)
+	require.NoError(t, err)
+	require.NoError(t, redisClient.Del(context.Background(), "refresh:"+claims.ID).Err())
+	require.NoError(t, auth.PruneUserSessions("user0"))
+
+	members, err := redisClient.SMembers(context.Background(), "user_refresh:user0").Result()
+	require.NoError(t, err)
+	assert.Len(t, members, 1)
+	assert.NotContains(t, members, claims.ID)
+}
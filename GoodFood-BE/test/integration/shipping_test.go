@@ -0,0 +1,227 @@
+package integration
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// resetShipping restores the seeded rates and drops every zone but the default one, before and after the test.
+// Unlike the seeded tables, shipping_rate and shipping_zone are not truncated by SeedData.
+func resetShipping(t *testing.T) {
+	reset := func() {
+		_, err := testdb.Exec(`UPDATE shipping_rate SET "baseFee" = 30000, "includedGrams" = 0, "perKgFee" = 0, "freeShippingThreshold" = 0`)
+		assert.NoError(t, err)
+		_, err = testdb.Exec(`DELETE FROM shipping_zone WHERE code <> 'DEFAULT'`)
+		assert.NoError(t, err)
+		_, err = testdb.Exec(`UPDATE shipping_zone SET "multiplierPercent" = 100, "freeShippingThreshold" = NULL WHERE code = 'DEFAULT'`)
+		assert.NoError(t, err)
+	}
+	reset()
+	t.Cleanup(reset)
+}
+
+// seedWeightRates charges 20000 plus 5000 per started kilogram above 1kg, and puts province 1 in a zone
+// costing 150% that ships orders of 500000 for free.
+func seedWeightRates(t *testing.T) {
+	_, err := testdb.Exec(`UPDATE shipping_rate SET "baseFee" = 20000, "includedGrams" = 1000, "perKgFee" = 5000`)
+	assert.NoError(t, err)
+	_, err = testdb.Exec(`INSERT INTO shipping_zone (code, name, "multiplierPercent", "freeShippingThreshold") VALUES ('FAR', 'Far away', 150, 500000)`)
+	assert.NoError(t, err)
+	_, err = testdb.Exec(`INSERT INTO shipping_zone_area ("zoneCode", "provinceID") VALUES ('FAR', 1)`)
+	assert.NoError(t, err)
+}
+
+func TestShippingQuote(t *testing.T) {
+	app := SetupApp()
+	resetShipping(t)
+
+	tests := []struct {
+		name         string
+		body         string
+		seedData     func()
+		wantStatus   int
+		wantMsg      string
+		validateData func(t *testing.T, data map[string]interface{})
+	}{
+		{
+			name:       "Default zone keeps the flat fee",
+			body:       `{"items":[{"productID":1,"quantity":3}],"addressID":1}`,
+			seedData:   func() { SeedData(t, SeedCheckout) },
+			wantStatus: http.StatusOK,
+			validateData: func(t *testing.T, data map[string]interface{}) {
+				assert.Equal(t, "DEFAULT", data["zone"])
+				assert.Equal(t, float64(3600), data["weightGrams"])
+				assert.Equal(t, float64(30000), data["fee"])
+			},
+		},
+		{
+			name: "Weight and zone multiplier",
+			body: `{"items":[{"productID":1,"quantity":3}],"addressID":1}`,
+			seedData: func() {
+				SeedData(t, SeedCheckout)
+				seedWeightRates(t)
+			},
+			wantStatus: http.StatusOK,
+			validateData: func(t *testing.T, data map[string]interface{}) {
+				//3600g is 3 started kilograms above the included one: (20000 + 3*5000) * 150%
+				assert.Equal(t, "FAR", data["zone"])
+				assert.Equal(t, float64(3), data["chargedKg"])
+				assert.Equal(t, float64(15000), data["weightFee"])
+				assert.Equal(t, float64(52500), data["fee"])
+				assert.Equal(t, false, data["freeShipping"])
+			},
+		},
+		{
+			name: "Zone threshold ships for free",
+			body: `{"items":[{"productID":1,"quantity":7}],"provinceID":1}`,
+			seedData: func() {
+				SeedData(t, SeedCheckout)
+				seedWeightRates(t)
+			},
+			wantStatus: http.StatusOK,
+			validateData: func(t *testing.T, data map[string]interface{}) {
+				assert.Equal(t, "FAR", data["zone"])
+				assert.Equal(t, true, data["freeShipping"])
+				assert.Equal(t, float64(0), data["fee"])
+			},
+		},
+		{
+			name: "Ward area beats its province",
+			body: `{"items":[{"productID":1,"quantity":1}],"addressID":1}`,
+			seedData: func() {
+				SeedData(t, SeedCheckout)
+				seedWeightRates(t)
+				_, err := testdb.Exec(`INSERT INTO shipping_zone (code, name, "multiplierPercent") VALUES ('NEAR', 'Near', 50)`)
+				assert.NoError(t, err)
+				_, err = testdb.Exec(`INSERT INTO shipping_zone_area ("zoneCode", "provinceID", "districtID", "wardID") VALUES ('NEAR', 1, 1, 1)`)
+				assert.NoError(t, err)
+			},
+			wantStatus: http.StatusOK,
+			validateData: func(t *testing.T, data map[string]interface{}) {
+				//1200g is 1 started kilogram above the included one: (20000 + 5000) * 50%
+				assert.Equal(t, "NEAR", data["zone"])
+				assert.Equal(t, float64(12500), data["fee"])
+			},
+		},
+		{
+			name:       "Address of another account",
+			body:       `{"items":[{"productID":1,"quantity":1}],"addressID":99}`,
+			seedData:   func() { SeedData(t, SeedCheckout) },
+			wantStatus: http.StatusNotFound,
+			wantMsg:    "Delivery address not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetShipping(t)
+			tt.seedData()
+			status, body := sendJSON(t, app, http.MethodPost, "/shipping/quote?accountID=1", tt.body)
+			assert.Equal(t, tt.wantStatus, status)
+			if tt.wantMsg != "" {
+				assert.Equal(t, tt.wantMsg, body["message"])
+			}
+			if tt.validateData != nil {
+				data, ok := body["data"].(map[string]interface{})
+				if assert.True(t, ok, "response has data") {
+					tt.validateData(t, data)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckoutUsesShippingZone(t *testing.T) {
+	app := SetupApp()
+	resetShipping(t)
+	SeedData(t, SeedCheckout)
+	seedWeightRates(t)
+
+	status, body := sendJSON(t, app, http.MethodPost, "/invoice/pay?accountID=1",
+		`{"items":[{"productID":1,"quantity":3}],"addressID":1,"paymentMethod":"COD"}`)
+	assert.Equal(t, http.StatusOK, status)
+
+	var shippingFee, totalPrice int64
+	err := testdb.QueryRow(`SELECT "shippingFee", "totalPrice" FROM invoice ORDER BY "invoiceID" DESC LIMIT 1`).
+		Scan(&shippingFee, &totalPrice)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(52500), shippingFee)
+	assert.Equal(t, int64(3*75000+52500), totalPrice, fmt.Sprint(body["message"]))
+}
+
+func TestAdminShipping(t *testing.T) {
+	app := SetupApp()
+	resetShipping(t)
+	SeedData(t, SeedCheckout)
+
+	status, body := sendJSON(t, app, http.MethodPut, "/admin/shipping/rates/update?accountID=1",
+		`{"baseFee":-1,"includedGrams":0,"perKgFee":0,"freeShippingThreshold":0}`)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	status, _ = sendJSON(t, app, http.MethodPut, "/admin/shipping/rates/update?accountID=1",
+		`{"baseFee":25000,"includedGrams":2000,"perKgFee":4000,"freeShippingThreshold":300000}`)
+	assert.Equal(t, http.StatusOK, status)
+
+	status, body = sendJSON(t, app, http.MethodPost, "/admin/shipping/zone/create?accountID=1",
+		`{"code":"hn","name":"Ha Noi","multiplierPercent":120}`)
+	assert.Equal(t, http.StatusOK, status)
+	if data, ok := body["data"].(map[string]interface{}); assert.True(t, ok) {
+		assert.Equal(t, "HN", data["code"])
+	}
+
+	status, _ = sendJSON(t, app, http.MethodPost, "/admin/shipping/zone/create?accountID=1",
+		`{"code":"HN","name":"Again","multiplierPercent":100}`)
+	assert.Equal(t, http.StatusConflict, status)
+
+	status, _ = sendJSON(t, app, http.MethodPut, "/admin/shipping/zone/update?accountID=1",
+		`{"code":"HN","name":"Ha Noi","multiplierPercent":130,"freeShippingThreshold":200000}`)
+	assert.Equal(t, http.StatusOK, status)
+
+	status, _ = sendJSON(t, app, http.MethodPost, "/admin/shipping/area/create?accountID=1",
+		`{"zoneCode":"HN","provinceID":1,"wardID":1}`)
+	assert.Equal(t, http.StatusBadRequest, status, "a ward needs its district")
+
+	status, body = sendJSON(t, app, http.MethodPost, "/admin/shipping/area/create?accountID=1",
+		`{"zoneCode":"HN","provinceID":1,"districtID":1}`)
+	assert.Equal(t, http.StatusOK, status)
+	var areaID float64
+	if data, ok := body["data"].(map[string]interface{}); assert.True(t, ok) {
+		assert.Equal(t, "HCM", data["provinceName"])
+		assert.Equal(t, "Q1", data["districtName"])
+		areaID, _ = data["areaID"].(float64)
+	}
+
+	status, _ = sendJSON(t, app, http.MethodPost, "/admin/shipping/area/create?accountID=1",
+		`{"zoneCode":"DEFAULT","provinceID":1,"districtID":1}`)
+	assert.Equal(t, http.StatusConflict, status)
+
+	status, body = sendJSON(t, app, http.MethodGet, "/admin/shipping?accountID=1", "")
+	assert.Equal(t, http.StatusOK, status)
+	if data, ok := body["data"].(map[string]interface{}); assert.True(t, ok) {
+		rates := data["rates"].(map[string]interface{})
+		assert.Equal(t, float64(25000), rates["baseFee"])
+		zones := data["zones"].([]interface{})
+		assert.Len(t, zones, 2)
+		assert.Equal(t, "DEFAULT", zones[0].(map[string]interface{})["code"])
+		hn := zones[1].(map[string]interface{})
+		assert.Equal(t, float64(130), hn["multiplierPercent"])
+		assert.Len(t, hn["areas"], 1)
+	}
+
+	status, _ = sendJSON(t, app, http.MethodDelete, fmt.Sprintf("/admin/shipping/area/delete?accountID=1&areaID=%d", int(areaID)), "")
+	assert.Equal(t, http.StatusOK, status)
+	status, _ = sendJSON(t, app, http.MethodDelete, fmt.Sprintf("/admin/shipping/area/delete?accountID=1&areaID=%d", int(areaID)), "")
+	assert.Equal(t, http.StatusNotFound, status)
+
+	status, body = sendJSON(t, app, http.MethodDelete, "/admin/shipping/zone/delete?accountID=1&code=DEFAULT", "")
+	assert.Equal(t, http.StatusConflict, status)
+	assert.Equal(t, "the default shipping zone cannot be deleted", body["message"])
+
+	status, _ = sendJSON(t, app, http.MethodDelete, "/admin/shipping/zone/delete?accountID=1&code=HN", "")
+	assert.Equal(t, http.StatusOK, status)
+	status, _ = sendJSON(t, app, http.MethodDelete, "/admin/shipping/zone/delete?accountID=1&code=HN", "")
+	assert.Equal(t, http.StatusNotFound, status)
+}